@@ -50,6 +50,8 @@ pub struct TxResult {
     pub gas_used: u64,
     #[prost(message, repeated, tag = "4")]
     pub tags: ::prost::alloc::vec::Vec<Tag>,
+    #[prost(uint32, tag = "5")]
+    pub code: u32,
 }
 impl ::prost::Name for TxResult {
     const NAME: &'static str = "TxResult";
@@ -1450,6 +1450,9 @@ impl serde::Serialize for TxResult {
         if !self.tags.is_empty() {
             len += 1;
         }
+        if self.code != 0 {
+            len += 1;
+        }
         let mut struct_ser = serializer.serialize_struct("penumbra.util.tendermint_proxy.v1alpha1.TxResult", len)?;
         if !self.log.is_empty() {
             struct_ser.serialize_field("log", &self.log)?;
@@ -1465,6 +1468,9 @@ impl serde::Serialize for TxResult {
         if !self.tags.is_empty() {
             struct_ser.serialize_field("tags", &self.tags)?;
         }
+        if self.code != 0 {
+            struct_ser.serialize_field("code", &self.code)?;
+        }
         struct_ser.end()
     }
 }
@@ -1481,6 +1487,7 @@ impl<'de> serde::Deserialize<'de> for TxResult {
             "gas_used",
             "gasUsed",
             "tags",
+            "code",
         ];
 
         #[allow(clippy::enum_variant_names)]
@@ -1489,6 +1496,7 @@ impl<'de> serde::Deserialize<'de> for TxResult {
             GasWanted,
             GasUsed,
             Tags,
+            Code,
         }
         impl<'de> serde::Deserialize<'de> for GeneratedField {
             fn deserialize<D>(deserializer: D) -> std::result::Result<GeneratedField, D::Error>
@@ -1514,6 +1522,7 @@ impl<'de> serde::Deserialize<'de> for TxResult {
                             "gasWanted" | "gas_wanted" => Ok(GeneratedField::GasWanted),
                             "gasUsed" | "gas_used" => Ok(GeneratedField::GasUsed),
                             "tags" => Ok(GeneratedField::Tags),
+                            "code" => Ok(GeneratedField::Code),
                             _ => Err(serde::de::Error::unknown_field(value, FIELDS)),
                         }
                     }
@@ -1537,6 +1546,7 @@ impl<'de> serde::Deserialize<'de> for TxResult {
                 let mut gas_wanted__ = None;
                 let mut gas_used__ = None;
                 let mut tags__ = None;
+                let mut code__ = None;
                 while let Some(k) = map_.next_key()? {
                     match k {
                         GeneratedField::Log => {
@@ -1567,6 +1577,14 @@ impl<'de> serde::Deserialize<'de> for TxResult {
                             }
                             tags__ = Some(map_.next_value()?);
                         }
+                        GeneratedField::Code => {
+                            if code__.is_some() {
+                                return Err(serde::de::Error::duplicate_field("code"));
+                            }
+                            code__ = 
+                                Some(map_.next_value::<::pbjson::private::NumberDeserialize<_>>()?.0)
+                            ;
+                        }
                     }
                 }
                 Ok(TxResult {
@@ -1574,6 +1592,7 @@ impl<'de> serde::Deserialize<'de> for TxResult {
                     gas_wanted: gas_wanted__.unwrap_or_default(),
                     gas_used: gas_used__.unwrap_or_default(),
                     tags: tags__.unwrap_or_default(),
+                    code: code__.unwrap_or_default(),
                 })
             }
         }
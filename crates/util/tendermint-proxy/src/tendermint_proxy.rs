@@ -88,6 +88,7 @@ impl TendermintProxyService for TendermintProxy {
                         })
                     })
                     .collect(),
+                code: u32::from(rsp.tx_result.code),
             }),
             height: rsp.height.value(),
             index: rsp.index as u64,
@@ -0,0 +1,248 @@
+// Package asset provides denomination metadata helpers for formatting and
+// parsing values, mirroring `penumbra_asset`.
+package asset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// StakingTokenBase is the base denom of the staking token.
+const StakingTokenBase = "upenumbra"
+
+// Cache indexes denom metadata by asset ID.
+type Cache struct {
+	byId   map[string]*assetv1alpha1.DenomMetadata
+	byBase map[string]*assetv1alpha1.DenomMetadata
+}
+
+// NewCache builds a cache from the given denoms. Denoms without a Penumbra
+// asset ID are only indexed by base denom.
+func NewCache(denoms ...*assetv1alpha1.DenomMetadata) *Cache {
+	c := &Cache{
+		byId:   make(map[string]*assetv1alpha1.DenomMetadata),
+		byBase: make(map[string]*assetv1alpha1.DenomMetadata),
+	}
+	for _, d := range denoms {
+		c.Add(d)
+	}
+	return c
+}
+
+// Add inserts a denom into the cache.
+func (c *Cache) Add(d *assetv1alpha1.DenomMetadata) {
+	if id := d.GetPenumbraAssetId().GetInner(); len(id) != 0 {
+		c.byId[string(id)] = d
+	}
+	c.byBase[d.GetBase()] = d
+}
+
+// Clone returns a copy of the cache. Cloning a nil cache returns an empty one.
+func (c *Cache) Clone() *Cache {
+	clone := NewCache()
+	if c == nil {
+		return clone
+	}
+	for k, d := range c.byId {
+		clone.byId[k] = d
+	}
+	for k, d := range c.byBase {
+		clone.byBase[k] = d
+	}
+	return clone
+}
+
+// Get returns the denom for an asset ID.
+func (c *Cache) Get(id *assetv1alpha1.AssetId) (*assetv1alpha1.DenomMetadata, bool) {
+	if c == nil {
+		return nil, false
+	}
+	d, ok := c.byId[string(id.GetInner())]
+	return d, ok
+}
+
+// GetByBase returns the denom with the given base denom.
+func (c *Cache) GetByBase(base string) (*assetv1alpha1.DenomMetadata, bool) {
+	if c == nil {
+		return nil, false
+	}
+	d, ok := c.byBase[base]
+	return d, ok
+}
+
+// Unit is a display unit of a denomination.
+type Unit struct {
+	Denom    string
+	Exponent uint32
+}
+
+// String returns the unit's denom.
+func (u Unit) String() string {
+	return u.Denom
+}
+
+// Units returns the units of a denom in metadata order, with the base denom
+// appended (at exponent 0) if it is not listed.
+func Units(d *assetv1alpha1.DenomMetadata) []Unit {
+	units := make([]Unit, 0, len(d.GetDenomUnits())+1)
+	hasBase := false
+	for _, u := range d.GetDenomUnits() {
+		units = append(units, Unit{Denom: u.GetDenom(), Exponent: u.GetExponent()})
+		hasBase = hasBase || u.GetDenom() == d.GetBase()
+	}
+	if !hasBase {
+		units = append(units, Unit{Denom: d.GetBase()})
+	}
+	return units
+}
+
+// DefaultUnit returns the display unit of a denom.
+func DefaultUnit(d *assetv1alpha1.DenomMetadata) Unit {
+	units := Units(d)
+	for _, u := range units {
+		if d.GetDisplay() != "" && u.Denom == d.GetDisplay() {
+			return u
+		}
+	}
+	return units[0]
+}
+
+// BaseUnit returns the base unit of a denom.
+func BaseUnit(d *assetv1alpha1.DenomMetadata) Unit {
+	units := Units(d)
+	return units[len(units)-1]
+}
+
+// BestUnitFor returns the largest unit smaller than the amount, so that the
+// formatted value has no leading zeros.
+func BestUnitFor(d *assetv1alpha1.DenomMetadata, amount num.Amount) Unit {
+	if amount.IsZero() {
+		return DefaultUnit(d)
+	}
+	for _, u := range Units(d) {
+		unitAmount, ok := num.Pow10(u.Exponent)
+		if ok && amount.Cmp(unitAmount) >= 0 {
+			return u
+		}
+	}
+	return BaseUnit(d)
+}
+
+// FormatValue formats an amount of base units in this unit, without the
+// unit suffix.
+func (u Unit) FormatValue(amount num.Amount) string {
+	scale, ok := num.Pow10(u.Exponent)
+	if !ok {
+		return amount.String()
+	}
+	whole, frac := amount.QuoRem(scale)
+	if frac.IsZero() {
+		return whole.String()
+	}
+	fracStr := fmt.Sprintf("%0*s", int(u.Exponent), frac.String())
+	return whole.String() + "." + strings.TrimRight(fracStr, "0")
+}
+
+// ParseValue parses a decimal value expressed in this unit into base units.
+func (u Unit) ParseValue(s string) (num.Amount, error) {
+	left, right, hasPoint := strings.Cut(s, ".")
+	if strings.Contains(right, ".") {
+		return num.Zero, fmt.Errorf("expected only one decimal point")
+	}
+	if !hasPoint || right == "" {
+		right = "0"
+	}
+	if left == "" {
+		left = "0"
+	}
+	whole, err := num.ParseAmount(left)
+	if err != nil {
+		return num.Zero, err
+	}
+	// Trailing zeros after the decimal point carry no precision.
+	right = strings.TrimRight(right, "0")
+	if uint32(len(right)) > u.Exponent {
+		return num.Zero, fmt.Errorf("cannot represent %s in %s", s, u.Denom)
+	}
+	frac := num.Zero
+	if right != "" {
+		digits, err := num.ParseAmount(right)
+		if err != nil {
+			return num.Zero, err
+		}
+		fracScale, _ := num.Pow10(u.Exponent - uint32(len(right)))
+		var ok bool
+		if frac, ok = digits.CheckedMul(fracScale); !ok {
+			return num.Zero, num.ErrOverflow
+		}
+	}
+	return addScaled(whole, u.Exponent, frac)
+}
+
+func addScaled(whole num.Amount, exponent uint32, frac num.Amount) (num.Amount, error) {
+	scale, ok := num.Pow10(exponent)
+	if !ok {
+		return num.Zero, num.ErrOverflow
+	}
+	v, ok := whole.CheckedMul(scale)
+	if !ok {
+		return num.Zero, num.ErrOverflow
+	}
+	if v, ok = v.CheckedAdd(frac); !ok {
+		return num.Zero, num.ErrOverflow
+	}
+	return v, nil
+}
+
+// FormatAssetId returns the Bech32m encoding of an asset ID.
+func FormatAssetId(id *assetv1alpha1.AssetId) string {
+	if id.GetAltBech32M() != "" {
+		return id.GetAltBech32M()
+	}
+	return bech32str.Encode(id.GetInner(), bech32str.AssetIdPrefix, bech32str.Bech32m)
+}
+
+// FormatDenom returns the display name of an asset: its base denom if known,
+// otherwise its asset ID.
+func (c *Cache) FormatDenom(id *assetv1alpha1.AssetId) string {
+	if d, ok := c.Get(id); ok {
+		return d.GetBase()
+	}
+	return FormatAssetId(id)
+}
+
+// Format formats a value using the best unit of its denom, or in terms of
+// its asset ID if the denom is not known. A value without an asset ID is in
+// the staking token, as for fees.
+func (c *Cache) Format(v *assetv1alpha1.Value) string {
+	amount := num.AmountFromProto(v.GetAmount())
+	if v.GetAssetId() == nil {
+		if d, ok := c.GetByBase(StakingTokenBase); ok {
+			unit := BestUnitFor(d, amount)
+			return unit.FormatValue(amount) + unit.Denom
+		}
+		return amount.String() + StakingTokenBase
+	}
+	if d, ok := c.Get(v.GetAssetId()); ok {
+		unit := BestUnitFor(d, amount)
+		return unit.FormatValue(amount) + unit.Denom
+	}
+	return amount.String() + FormatAssetId(v.GetAssetId())
+}
+
+// FormatView formats a value view.
+func FormatView(v *assetv1alpha1.ValueView) string {
+	switch vv := v.GetValueView().(type) {
+	case *assetv1alpha1.ValueView_KnownDenom_:
+		amount := num.AmountFromProto(vv.KnownDenom.GetAmount())
+		unit := BestUnitFor(vv.KnownDenom.GetDenom(), amount)
+		return unit.FormatValue(amount) + unit.Denom
+	case *assetv1alpha1.ValueView_UnknownDenom_:
+		return num.AmountFromProto(vv.UnknownDenom.GetAmount()).String() + FormatAssetId(vv.UnknownDenom.GetAssetId())
+	}
+	return ""
+}
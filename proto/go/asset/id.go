@@ -0,0 +1,37 @@
+package asset
+
+import (
+	"bytes"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+)
+
+// IdLen is the length of an encoded asset ID.
+const IdLen = 32
+
+// IdEqual reports whether two asset IDs have the same encoding.
+func IdEqual(a, b *assetv1alpha1.AssetId) bool {
+	return bytes.Equal(a.GetInner(), b.GetInner())
+}
+
+// CompareIds orders asset IDs as `penumbra_asset::asset::Id` does: by the
+// field element they encode, which is stored little-endian. It returns -1,
+// 0 or 1.
+func CompareIds(a, b *assetv1alpha1.AssetId) int {
+	x, y := a.GetInner(), b.GetInner()
+	if len(x) != len(y) {
+		if len(x) < len(y) {
+			return -1
+		}
+		return 1
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		switch {
+		case x[i] < y[i]:
+			return -1
+		case x[i] > y[i]:
+			return 1
+		}
+	}
+	return 0
+}
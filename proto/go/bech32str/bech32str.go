@@ -0,0 +1,160 @@
+// Package bech32str implements the Bech32 string encodings used for Penumbra
+// keys, addresses and identifiers, mirroring
+// `penumbra_proto::serializers::bech32str`.
+//
+// Unlike BIP-173, Penumbra does not limit the length of encoded strings, so
+// this is a small self-contained implementation rather than a wrapper around a
+// general-purpose library.
+package bech32str
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Human-readable prefixes for the Penumbra types with Bech32 encodings.
+const (
+	ValidatorIdentityKeyPrefix   = "penumbravalid"
+	ValidatorGovernanceKeyPrefix = "penumbragovern"
+	AddressPrefix                = "penumbra"
+	AssetIdPrefix                = "passet"
+	FullViewingKeyPrefix         = "penumbrafullviewingkey"
+	WalletIdPrefix               = "penumbrawalletid"
+	SpendKeyPrefix               = "penumbraspendkey"
+	LpIdPrefix                   = "plpid"
+)
+
+// Variant selects the checksum constant.
+type Variant int
+
+const (
+	// Bech32 is the original BIP-173 encoding.
+	Bech32 Variant = iota
+	// Bech32m is the BIP-350 encoding, used for all Penumbra types.
+	Bech32m
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func (v Variant) constant() uint32 {
+	if v == Bech32m {
+		return 0x2bc830a3
+	}
+	return 1
+}
+
+func polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+func checksum(hrp string, data []byte, variant Variant) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := polymod(values) ^ variant.constant()
+	out := make([]byte, 6)
+	for i := range out {
+		out[i] = byte(mod>>(5*(5-i))) & 31
+	}
+	return out
+}
+
+// convertBits regroups a byte slice from groups of `from` bits to groups of
+// `to` bits.
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, b := range data {
+		if uint32(b)>>from != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<from | uint32(b)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+// Encode encodes data with the given human-readable prefix.
+func Encode(data []byte, hrp string, variant Variant) string {
+	values, _ := convertBits(data, 8, 5, true)
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + 6)
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range append(values, checksum(hrp, values, variant)...) {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String()
+}
+
+// Decode decodes a string, checking its human-readable prefix and variant.
+func Decode(s, expectedHrp string, expectedVariant Variant) ([]byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return nil, errors.New("bech32 string has mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return nil, errors.New("bech32 string has invalid separator position")
+	}
+	hrp := s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(charset, s[i])
+		if v < 0 {
+			return nil, fmt.Errorf("invalid bech32 character %q", s[i])
+		}
+		values = append(values, byte(v))
+	}
+	var variant Variant
+	switch polymod(append(hrpExpand(hrp), values...)) {
+	case Bech32.constant():
+		variant = Bech32
+	case Bech32m.constant():
+		variant = Bech32m
+	default:
+		return nil, errors.New("invalid bech32 checksum")
+	}
+	if variant != expectedVariant {
+		return nil, fmt.Errorf("wrong bech32 variant %d, expected %d", variant, expectedVariant)
+	}
+	if hrp != expectedHrp {
+		return nil, fmt.Errorf("wrong bech32 human readable part %s, expected %s", hrp, expectedHrp)
+	}
+	return convertBits(values[:len(values)-6], 5, 8, false)
+}
@@ -0,0 +1,198 @@
+// Package blake2b implements the BLAKE2b hash with the personalization
+// strings Penumbra uses for domain separation, as the `blake2b_simd` crate
+// does. golang.org/x/crypto/blake2b does not support personalization.
+package blake2b
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// BlockSize is the block size of BLAKE2b in bytes.
+	BlockSize = 128
+	// Size is the largest digest size in bytes.
+	Size = 64
+	// PersonalSize is the largest personalization string in bytes.
+	PersonalSize = 16
+	// KeySize is the largest key in bytes.
+	KeySize = 64
+)
+
+var iv = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var sigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+type digest struct {
+	h        [8]uint64
+	init     [8]uint64
+	t        [2]uint64
+	block    [BlockSize]byte
+	n        int
+	size     int
+	personal [PersonalSize]byte
+	key      [BlockSize]byte
+	keyLen   int
+}
+
+// New returns an unkeyed BLAKE2b hash with a digest of size bytes and a
+// personalization string of at most 16 bytes, padded with zeros.
+func New(size int, personal string) (hash.Hash, error) {
+	return NewKeyed(size, personal, nil)
+}
+
+// NewKeyed returns a BLAKE2b hash keyed with at most 64 bytes, as used by
+// the PRFs of `penumbra_keys`.
+func NewKeyed(size int, personal string, key []byte) (hash.Hash, error) {
+	if size < 1 || size > Size {
+		return nil, errors.New("blake2b: invalid digest size")
+	}
+	if len(personal) > PersonalSize {
+		return nil, errors.New("blake2b: personalization is longer than 16 bytes")
+	}
+	if len(key) > KeySize {
+		return nil, errors.New("blake2b: key is longer than 64 bytes")
+	}
+	d := &digest{size: size, keyLen: len(key)}
+	copy(d.personal[:], personal)
+	copy(d.key[:], key)
+	d.init = iv
+	d.init[0] ^= uint64(size) | uint64(len(key))<<8 | 1<<16 | 1<<24
+	d.init[6] ^= binary.LittleEndian.Uint64(d.personal[0:8])
+	d.init[7] ^= binary.LittleEndian.Uint64(d.personal[8:16])
+	d.Reset()
+	return d, nil
+}
+
+// Sum512 returns the 64-byte personalized hash of the concatenated data.
+func Sum512(personal string, data ...[]byte) [Size]byte {
+	h, err := New(Size, personal)
+	if err != nil {
+		panic(err)
+	}
+	for _, b := range data {
+		h.Write(b)
+	}
+	var sum [Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// SumKeyed512 returns the 64-byte personalized hash of the concatenated
+// data, keyed with key.
+func SumKeyed512(personal string, key []byte, data ...[]byte) [Size]byte {
+	h, err := NewKeyed(Size, personal, key)
+	if err != nil {
+		panic(err)
+	}
+	for _, b := range data {
+		h.Write(b)
+	}
+	var sum [Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+func (d *digest) Size() int      { return d.size }
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Reset() {
+	d.h = d.init
+	d.t = [2]uint64{}
+	d.n = 0
+	// A key is hashed as a first block of its own, padded with zeros.
+	if d.keyLen > 0 {
+		d.block = d.key
+		d.n = BlockSize
+	}
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// The last block is only compressed by Sum, with the final flag, so
+		// a full buffer is only compressed once more data arrives.
+		if d.n == BlockSize {
+			d.compress(BlockSize, false)
+			d.n = 0
+		}
+		n := copy(d.block[d.n:], p)
+		d.n += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	c := *d
+	for i := c.n; i < BlockSize; i++ {
+		c.block[i] = 0
+	}
+	c.compress(uint64(c.n), true)
+	var out [Size]byte
+	for i, v := range c.h {
+		binary.LittleEndian.PutUint64(out[8*i:], v)
+	}
+	return append(b, out[:c.size]...)
+}
+
+func (d *digest) compress(n uint64, last bool) {
+	var carry uint64
+	d.t[0], carry = bits.Add64(d.t[0], n, 0)
+	d.t[1] += carry
+
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(d.block[8*i:])
+	}
+	var v [16]uint64
+	copy(v[:8], d.h[:])
+	copy(v[8:], iv[:])
+	v[12] ^= d.t[0]
+	v[13] ^= d.t[1]
+	if last {
+		v[14] = ^v[14]
+	}
+	for _, s := range sigma {
+		g(&v, 0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(&v, 1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(&v, 2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(&v, 3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(&v, 0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(&v, 1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(&v, 2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(&v, 3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+	for i := range d.h {
+		d.h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+func g(v *[16]uint64, a, b, c, d int, x, y uint64) {
+	v[a] += v[b] + x
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] += v[b] + y
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
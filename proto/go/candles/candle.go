@@ -0,0 +1,119 @@
+package candles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/dex"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// MaxCandles is the largest number of intervals a candle query may span.
+const MaxCandles = 10_000
+
+// Candle is the open, high, low and close prices of a trading pair's batch
+// swaps over an interval, as quote per base, and its volumes, all in display
+// units. Intervals without batches have no candle.
+type Candle struct {
+	Start       time.Time `json:"start"`
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"`
+	BaseVolume  float64   `json:"base_volume"`
+	QuoteVolume float64   `json:"quote_volume"`
+	Batches     int       `json:"batches"`
+}
+
+// CandleQuery selects the candles of a pair over [From, To), aligned to
+// multiples of Interval since the Unix epoch.
+type CandleQuery struct {
+	Base, Quote *assetv1alpha1.AssetId
+	// BaseUnit and QuoteUnit are the display units of the assets.
+	BaseUnit, QuoteUnit asset.Unit
+	Interval            time.Duration
+	From, To            time.Time
+}
+
+// Candles aggregates the batches of a pair into candles.
+func (s *Store) Candles(ctx context.Context, q CandleQuery) ([]Candle, error) {
+	if q.Interval < time.Second || q.Interval%time.Second != 0 {
+		return nil, errors.New("interval must be a whole number of seconds")
+	}
+	if asset.IdEqual(q.Base, q.Quote) {
+		return nil, errors.New("base and quote are the same asset")
+	}
+	if !q.To.After(q.From) {
+		return nil, errors.New("time range is empty")
+	}
+	if q.To.Sub(q.From)/q.Interval > MaxCandles {
+		return nil, fmt.Errorf("time range spans more than %d intervals", MaxCandles)
+	}
+	pair, flipped := dex.CanonicalPair(&dexv1alpha1.DirectedTradingPair{Start: q.Base, End: q.Quote})
+	batches, err := s.Batches(ctx, pair, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(batches, q, flipped), nil
+}
+
+// aggregate buckets batches of a pair, ordered by time, into the candles of
+// a query. If flipped, the batches' pair is the reverse of the query's.
+func aggregate(batches []*Batch, q CandleQuery, flipped bool) []Candle {
+	// A price in base units of the quote per base unit of the base is
+	// scaled by 10^(base exponent - quote exponent) into display units.
+	scale := math.Pow10(int(q.BaseUnit.Exponent) - int(q.QuoteUnit.Exponent))
+
+	candles := []Candle{}
+	var baseVolume, quoteVolume *big.Int
+	flush := func() {
+		if len(candles) == 0 {
+			return
+		}
+		c := &candles[len(candles)-1]
+		c.BaseVolume = display(baseVolume, q.BaseUnit)
+		c.QuoteVolume = display(quoteVolume, q.QuoteUnit)
+	}
+	for _, b := range batches {
+		price, base, quote := b.Price, b.Volume1, b.Volume2
+		if flipped {
+			price, base, quote = 1/price, b.Volume2, b.Volume1
+		}
+		price *= scale
+		start := intervalStart(b.Time, q.Interval)
+		if len(candles) == 0 || !candles[len(candles)-1].Start.Equal(start) {
+			flush()
+			candles = append(candles, Candle{Start: start, Open: price, High: price, Low: price})
+			baseVolume, quoteVolume = new(big.Int), new(big.Int)
+		}
+		c := &candles[len(candles)-1]
+		c.High = max(c.High, price)
+		c.Low = min(c.Low, price)
+		c.Close = price
+		c.Batches++
+		baseVolume.Add(baseVolume, base.Big())
+		quoteVolume.Add(quoteVolume, quote.Big())
+	}
+	flush()
+	return candles
+}
+
+// intervalStart returns the start of the interval containing t.
+func intervalStart(t time.Time, interval time.Duration) time.Time {
+	unix, secs := t.Unix(), int64(interval/time.Second)
+	return time.Unix(unix-unix%secs, 0).UTC()
+}
+
+// display converts an amount of base units to a unit.
+func display(amount *big.Int, unit asset.Unit) float64 {
+	scale, _ := num.Pow10(unit.Exponent)
+	v, _ := new(big.Rat).SetFrac(amount, scale.Big()).Float64()
+	return v
+}
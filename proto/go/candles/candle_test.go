@@ -0,0 +1,170 @@
+package candles
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+func testAssetId(n byte) *assetv1alpha1.AssetId {
+	inner := make([]byte, 32)
+	inner[0] = n
+	return &assetv1alpha1.AssetId{Inner: inner}
+}
+
+// t0 is the start of a one-minute interval.
+var t0 = time.Unix(1_700_000_040, 0).UTC()
+
+func testBatch(offset time.Duration, volume1, volume2 uint64) *Batch {
+	return &Batch{
+		Time:    t0.Add(offset),
+		Price:   float64(volume2) / float64(volume1),
+		Volume1: num.NewAmount(volume1),
+		Volume2: num.NewAmount(volume2),
+	}
+}
+
+// testBatches fill the first interval, skip two, and trade once in the
+// fourth.
+var testBatches = []*Batch{
+	testBatch(5*time.Second, 100, 200),
+	testBatch(30*time.Second, 100, 400),
+	testBatch(59*time.Second, 200, 200),
+	testBatch(3*time.Minute+time.Second, 50, 150),
+}
+
+func TestAggregate(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		baseUnit, quoteUnit asset.Unit
+		flipped             bool
+		want                []Candle
+	}{
+		{
+			name: "base units",
+			want: []Candle{
+				{Start: t0, Open: 2, High: 4, Low: 1, Close: 1, BaseVolume: 400, QuoteVolume: 800, Batches: 3},
+				{Start: t0.Add(3 * time.Minute), Open: 3, High: 3, Low: 3, Close: 3, BaseVolume: 50, QuoteVolume: 150, Batches: 1},
+			},
+		},
+		{
+			name:    "flipped",
+			flipped: true,
+			want: []Candle{
+				{Start: t0, Open: 0.5, High: 1, Low: 0.25, Close: 1, BaseVolume: 800, QuoteVolume: 400, Batches: 3},
+				{Start: t0.Add(3 * time.Minute), Open: 1.0 / 3, High: 1.0 / 3, Low: 1.0 / 3, Close: 1.0 / 3, BaseVolume: 150, QuoteVolume: 50, Batches: 1},
+			},
+		},
+		{
+			name:      "display units",
+			baseUnit:  asset.Unit{Denom: "base", Exponent: 6},
+			quoteUnit: asset.Unit{Denom: "quote", Exponent: 3},
+			want: []Candle{
+				{Start: t0, Open: 2000, High: 4000, Low: 1000, Close: 1000, BaseVolume: 0.0004, QuoteVolume: 0.8, Batches: 3},
+				{Start: t0.Add(3 * time.Minute), Open: 3000, High: 3000, Low: 3000, Close: 3000, BaseVolume: 0.00005, QuoteVolume: 0.15, Batches: 1},
+			},
+		},
+	} {
+		q := CandleQuery{BaseUnit: tc.baseUnit, QuoteUnit: tc.quoteUnit, Interval: time.Minute}
+		got := aggregate(testBatches, q, tc.flipped)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %d candles, want %d", tc.name, len(got), len(tc.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: candle %d = %+v, want %+v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestAggregateInterval(t *testing.T) {
+	q := CandleQuery{Interval: 10 * time.Minute}
+	got := aggregate(testBatches, q, false)
+	if len(got) != 1 {
+		t.Fatalf("got %d candles, want 1", len(got))
+	}
+	want := Candle{Start: t0.Add(-4 * time.Minute), Open: 2, High: 4, Low: 1, Close: 3, BaseVolume: 450, QuoteVolume: 950, Batches: 4}
+	if got[0] != want {
+		t.Errorf("candle = %+v, want %+v", got[0], want)
+	}
+	if got := aggregate(nil, q, false); len(got) != 0 {
+		t.Errorf("no batches: got %d candles", len(got))
+	}
+}
+
+func TestCandlesValidation(t *testing.T) {
+	valid := CandleQuery{
+		Base:     testAssetId(1),
+		Quote:    testAssetId(2),
+		Interval: time.Minute,
+		From:     t0,
+		To:       t0.Add(time.Hour),
+	}
+	for _, tc := range []struct {
+		name   string
+		modify func(q *CandleQuery)
+	}{
+		{"zero interval", func(q *CandleQuery) { q.Interval = 0 }},
+		{"fractional interval", func(q *CandleQuery) { q.Interval = 1500 * time.Millisecond }},
+		{"same asset", func(q *CandleQuery) { q.Quote = testAssetId(1) }},
+		{"empty range", func(q *CandleQuery) { q.To = q.From }},
+		{"too many intervals", func(q *CandleQuery) { q.To = q.From.Add((MaxCandles + 1) * time.Minute) }},
+	} {
+		q := valid
+		tc.modify(&q)
+		// Invalid queries are rejected before the store is read.
+		if _, err := (&Store{}).Candles(context.Background(), q); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+func TestBatchFromOutputData(t *testing.T) {
+	for _, tc := range []struct {
+		name                      string
+		delta1, delta2, unfilled1 uint64
+		lambda1, lambda2          uint64
+		ok, wantErr               bool
+		wantVolume1, wantVolume2  uint64
+		wantPrice                 float64
+	}{
+		{name: "traded", delta1: 100, unfilled1: 20, lambda1: 30, lambda2: 220, ok: true, wantVolume1: 110, wantVolume2: 220, wantPrice: 2},
+		{name: "nothing traded", delta1: 100, unfilled1: 100},
+		{name: "unfilled exceeds input", delta1: 10, unfilled1: 20, wantErr: true},
+	} {
+		bsod := &dexv1alpha1.BatchSwapOutputData{
+			Delta_1:    num.NewAmount(tc.delta1).Proto(),
+			Delta_2:    num.NewAmount(tc.delta2).Proto(),
+			Lambda_1:   num.NewAmount(tc.lambda1).Proto(),
+			Lambda_2:   num.NewAmount(tc.lambda2).Proto(),
+			Unfilled_1: num.NewAmount(tc.unfilled1).Proto(),
+			Unfilled_2: num.NewAmount(0).Proto(),
+			Height:     7,
+		}
+		b, ok, err := BatchFromOutputData(bsod, t0)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if ok != tc.ok {
+			t.Errorf("%s: ok = %v, want %v", tc.name, ok, tc.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if b.Volume1 != num.NewAmount(tc.wantVolume1) || b.Volume2 != num.NewAmount(tc.wantVolume2) || b.Price != tc.wantPrice {
+			t.Errorf("%s: got volumes %s, %s at %v, want %d, %d at %v", tc.name, b.Volume1, b.Volume2, b.Price, tc.wantVolume1, tc.wantVolume2, tc.wantPrice)
+		}
+		if b.Height != 7 || !b.Time.Equal(t0) {
+			t.Errorf("%s: got height %d at %v", tc.name, b.Height, b.Time)
+		}
+	}
+}
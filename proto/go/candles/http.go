@@ -0,0 +1,142 @@
+package candles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// Arb is an arbitrage execution as served by the API, in base units.
+type Arb struct {
+	Height  uint64     `json:"height"`
+	Time    time.Time  `json:"time"`
+	AssetId string     `json:"asset_id"`
+	Input   num.Amount `json:"input"`
+	Output  num.Amount `json:"output"`
+	Profit  num.Amount `json:"profit"`
+	Traces  int        `json:"traces"`
+}
+
+// Status is the indexer's progress as served by the API.
+type Status struct {
+	Height uint64 `json:"height"`
+}
+
+// Handler returns an HTTP handler serving a store's data as JSON:
+//
+//	GET /candles?base=<denom>&quote=<denom>&interval=<duration>&from=<time>&to=<time>
+//	GET /arbs?from=<time>&to=<time>
+//	GET /status
+//
+// Denoms are base denoms known to cache, such as upenumbra, and candles are
+// in their display units. Intervals are Go durations, such as 1h; times are
+// RFC 3339 and default to the last day.
+func Handler(s *Store, cache *asset.Cache) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /candles", func(w http.ResponseWriter, r *http.Request) {
+		var q CandleQuery
+		var err error
+		base, ok := cache.GetByBase(r.FormValue("base"))
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown base denom %q", r.FormValue("base")))
+			return
+		}
+		quote, ok := cache.GetByBase(r.FormValue("quote"))
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown quote denom %q", r.FormValue("quote")))
+			return
+		}
+		if base.GetPenumbraAssetId() == nil || quote.GetPenumbraAssetId() == nil {
+			writeError(w, http.StatusInternalServerError, errors.New("denom metadata is missing an asset ID"))
+			return
+		}
+		q.Base, q.BaseUnit = base.GetPenumbraAssetId(), asset.DefaultUnit(base)
+		q.Quote, q.QuoteUnit = quote.GetPenumbraAssetId(), asset.DefaultUnit(quote)
+		if q.Interval, err = time.ParseDuration(r.FormValue("interval")); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid interval: %w", err))
+			return
+		}
+		if q.From, q.To, err = timeRange(r); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		candles, err := s.Candles(r.Context(), q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, candles)
+	})
+	mux.HandleFunc("GET /arbs", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := timeRange(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		executions, err := s.Arbs(r.Context(), from, to)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		arbs := make([]Arb, 0, len(executions))
+		for _, e := range executions {
+			profit, _ := e.Output.CheckedSub(e.Input)
+			arbs = append(arbs, Arb{
+				Height:  e.Height,
+				Time:    e.Time,
+				AssetId: asset.FormatAssetId(e.Pair.GetStart()),
+				Input:   e.Input,
+				Output:  e.Output,
+				Profit:  profit,
+				Traces:  e.Traces,
+			})
+		}
+		writeJSON(w, arbs)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		height, _, err := s.Height(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, Status{Height: height})
+	})
+	return mux
+}
+
+// timeRange parses the from and to parameters of a request.
+func timeRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now()
+	if v := r.FormValue("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if v := r.FormValue("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = t
+	}
+	return from, to, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,212 @@
+package candles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/compactblock"
+	"github.com/penumbra-zone/penumbra/proto/go/dex"
+	compact_blockv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/compact_block/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+)
+
+const (
+	// DefaultBatchSize is the number of blocks an indexer fetches at a time.
+	DefaultBatchSize = 1000
+	// DefaultPollInterval is how often an indexer that has caught up checks
+	// for new blocks.
+	DefaultPollInterval = 5 * time.Second
+)
+
+// Indexer backfills a store from a fullnode and then follows new blocks.
+// Batch clearings come from the compact blocks' swap outputs, and swap and
+// arbitrage executions from the DEX's query service; block times come from
+// the Tendermint proxy.
+type Indexer struct {
+	Blocks     compactblock.QueryService
+	Dex        dex.QueryService
+	Tendermint proxy.Service
+	// ChainId, if set, is sent with the compact block and execution
+	// queries, which the fullnode refuses if it serves another chain.
+	ChainId string
+	Store   *Store
+	// StartHeight is the first height indexed into an empty store.
+	StartHeight uint64
+	// BatchSize is the number of blocks fetched at a time,
+	// DefaultBatchSize if zero.
+	BatchSize uint64
+	// PollInterval is how often to check for new blocks once caught up,
+	// DefaultPollInterval if zero.
+	PollInterval time.Duration
+}
+
+// Run indexes blocks from where the store left off, and then follows the
+// chain until ctx is done.
+func (ix *Indexer) Run(ctx context.Context) error {
+	batchSize := ix.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+	pollInterval := ix.PollInterval
+	if pollInterval == 0 {
+		pollInterval = DefaultPollInterval
+	}
+	client := proxy.NewClient(ix.Tendermint)
+	for {
+		next := ix.StartHeight
+		last, ok, err := ix.Store.Height(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			next = last + 1
+		}
+		latest, err := client.LatestBlockHeight(ctx)
+		if err != nil {
+			return err
+		}
+		if next > latest {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		end := min(latest, next+batchSize-1)
+		if err := ix.Index(ctx, next, end); err != nil {
+			return fmt.Errorf("could not index heights %d to %d: %w", next, end, err)
+		}
+	}
+}
+
+// Index fetches and stores the blocks of [start, end].
+func (ix *Indexer) Index(ctx context.Context, start, end uint64) error {
+	blocks := make(map[uint64]*Block)
+	block := func(height uint64) *Block {
+		b, ok := blocks[height]
+		if !ok {
+			b = &Block{Height: height}
+			blocks[height] = b
+		}
+		return b
+	}
+
+	outputs, err := ix.swapOutputs(ctx, start, end)
+	if err != nil {
+		return fmt.Errorf("could not fetch compact blocks: %w", err)
+	}
+	swaps, err := ix.Dex.SwapExecutions(ctx, &dexv1alpha1.SwapExecutionsRequest{ChainId: ix.ChainId, StartHeight: start, EndHeight: end})
+	if err != nil {
+		return fmt.Errorf("could not fetch swap executions: %w", err)
+	}
+	var swapExecutions []*dexv1alpha1.SwapExecutionsResponse
+	for {
+		rsp, err := swaps.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not fetch swap executions: %w", err)
+		}
+		swapExecutions = append(swapExecutions, rsp)
+		block(rsp.GetHeight())
+	}
+	arbs, err := ix.Dex.ArbExecutions(ctx, &dexv1alpha1.ArbExecutionsRequest{ChainId: ix.ChainId, StartHeight: start, EndHeight: end})
+	if err != nil {
+		return fmt.Errorf("could not fetch arb executions: %w", err)
+	}
+	var arbExecutions []*dexv1alpha1.ArbExecutionsResponse
+	for {
+		rsp, err := arbs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not fetch arb executions: %w", err)
+		}
+		arbExecutions = append(arbExecutions, rsp)
+		block(rsp.GetHeight())
+	}
+	for _, bsod := range outputs {
+		block(bsod.GetHeight())
+	}
+
+	// Block times are only fetched for the blocks with trades.
+	times := make(map[uint64]time.Time, len(blocks))
+	for height := range blocks {
+		t, err := ix.blockTime(ctx, height)
+		if err != nil {
+			return err
+		}
+		times[height] = t
+	}
+	for _, bsod := range outputs {
+		batch, ok, err := BatchFromOutputData(bsod, times[bsod.GetHeight()])
+		if err != nil {
+			return err
+		}
+		if ok {
+			b := block(bsod.GetHeight())
+			b.Batches = append(b.Batches, batch)
+		}
+	}
+	for _, rsp := range swapExecutions {
+		b := block(rsp.GetHeight())
+		b.Swaps = append(b.Swaps, ExecutionFromProto(rsp.GetSwapExecution(), rsp.GetHeight(), times[rsp.GetHeight()]))
+	}
+	for _, rsp := range arbExecutions {
+		block(rsp.GetHeight()).Arb = ExecutionFromProto(rsp.GetSwapExecution(), rsp.GetHeight(), times[rsp.GetHeight()])
+	}
+
+	sorted := make([]*Block, 0, len(blocks))
+	for _, b := range blocks {
+		sorted = append(sorted, b)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Height < sorted[j].Height })
+	return ix.Store.Put(ctx, sorted, end)
+}
+
+// swapOutputs returns the batch swap outputs of the compact blocks of
+// [start, end].
+func (ix *Indexer) swapOutputs(ctx context.Context, start, end uint64) ([]*dexv1alpha1.BatchSwapOutputData, error) {
+	stream, err := ix.Blocks.CompactBlockRange(ctx, &compact_blockv1alpha1.CompactBlockRangeRequest{ChainId: ix.ChainId, StartHeight: start, EndHeight: end})
+	if err != nil {
+		return nil, err
+	}
+	var outputs []*dexv1alpha1.BatchSwapOutputData
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		block := rsp.GetCompactBlock()
+		for _, bsod := range block.GetSwapOutputs() {
+			if bsod.GetHeight() == 0 {
+				bsod.Height = block.GetHeight()
+			}
+			outputs = append(outputs, bsod)
+		}
+	}
+	return outputs, nil
+}
+
+func (ix *Indexer) blockTime(ctx context.Context, height uint64) (time.Time, error) {
+	rsp, err := ix.Tendermint.GetBlockByHeight(ctx, &tendermint_proxyv1alpha1.GetBlockByHeightRequest{Height: int64(height)})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not get block %d: %w", height, err)
+	}
+	header := rsp.GetBlock().GetHeader()
+	if header.GetTime() == nil {
+		return time.Time{}, fmt.Errorf("block %d has no time", height)
+	}
+	return header.GetTime().AsTime(), nil
+}
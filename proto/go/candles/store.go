@@ -0,0 +1,281 @@
+// Package candles indexes the DEX's batch swaps into a SQL database, and
+// aggregates them into OHLCV candles per trading pair, served over HTTP as
+// JSON. Arbitrage executions are indexed separately from user swaps.
+//
+// The SQL is written for SQLite; callers open the database with the driver
+// of their choice and pass it to NewStore. Command dex-candles runs the
+// indexer and the API over github.com/mattn/go-sqlite3.
+package candles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS batches (
+	height   INTEGER NOT NULL,
+	time     INTEGER NOT NULL,
+	asset_1  BLOB NOT NULL,
+	asset_2  BLOB NOT NULL,
+	price    REAL NOT NULL,
+	volume_1 TEXT NOT NULL,
+	volume_2 TEXT NOT NULL,
+	PRIMARY KEY (height, asset_1, asset_2)
+);
+CREATE INDEX IF NOT EXISTS batches_by_pair ON batches (asset_1, asset_2, time);
+CREATE TABLE IF NOT EXISTS swap_executions (
+	height      INTEGER NOT NULL,
+	time        INTEGER NOT NULL,
+	asset_start BLOB NOT NULL,
+	asset_end   BLOB NOT NULL,
+	input       TEXT NOT NULL,
+	output      TEXT NOT NULL,
+	traces      INTEGER NOT NULL,
+	max_hops    INTEGER NOT NULL,
+	PRIMARY KEY (height, asset_start, asset_end)
+);
+CREATE TABLE IF NOT EXISTS arb_executions (
+	height INTEGER PRIMARY KEY,
+	time   INTEGER NOT NULL,
+	asset  BLOB NOT NULL,
+	input  TEXT NOT NULL,
+	output TEXT NOT NULL,
+	traces INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS progress (
+	id     INTEGER PRIMARY KEY CHECK (id = 0),
+	height INTEGER NOT NULL
+);
+`
+
+// Batch is the clearing of a trading pair's batch swap in one block. Both
+// directions of the batch are combined: Volume1 and Volume2 are the amounts
+// of each asset traded, and Price is Volume2 / Volume1, the volume-weighted
+// price of asset 1 in base units of asset 2.
+type Batch struct {
+	Height           uint64
+	Time             time.Time
+	Pair             *dexv1alpha1.TradingPair
+	Price            float64
+	Volume1, Volume2 num.Amount
+}
+
+// BatchFromOutputData returns the clearing of a batch swap, or false if
+// nothing was traded.
+func BatchFromOutputData(bsod *dexv1alpha1.BatchSwapOutputData, t time.Time) (*Batch, bool, error) {
+	sold1, ok := num.AmountFromProto(bsod.GetDelta_1()).CheckedSub(num.AmountFromProto(bsod.GetUnfilled_1()))
+	if !ok {
+		return nil, false, fmt.Errorf("batch at height %d has more of asset 1 unfilled than input", bsod.GetHeight())
+	}
+	sold2, ok := num.AmountFromProto(bsod.GetDelta_2()).CheckedSub(num.AmountFromProto(bsod.GetUnfilled_2()))
+	if !ok {
+		return nil, false, fmt.Errorf("batch at height %d has more of asset 2 unfilled than input", bsod.GetHeight())
+	}
+	volume1, ok := sold1.CheckedAdd(num.AmountFromProto(bsod.GetLambda_1()))
+	if !ok {
+		return nil, false, num.ErrOverflow
+	}
+	volume2, ok := sold2.CheckedAdd(num.AmountFromProto(bsod.GetLambda_2()))
+	if !ok {
+		return nil, false, num.ErrOverflow
+	}
+	if volume1.IsZero() || volume2.IsZero() {
+		return nil, false, nil
+	}
+	price, _ := new(big.Rat).SetFrac(volume2.Big(), volume1.Big()).Float64()
+	return &Batch{
+		Height:  bsod.GetHeight(),
+		Time:    t,
+		Pair:    bsod.GetTradingPair(),
+		Price:   price,
+		Volume1: volume1,
+		Volume2: volume2,
+	}, true, nil
+}
+
+// Execution is a swap or arbitrage execution along a directed pair in one
+// block. An arbitrage starts and ends with the same asset.
+type Execution struct {
+	Height        uint64
+	Time          time.Time
+	Pair          *dexv1alpha1.DirectedTradingPair
+	Input, Output num.Amount
+	Traces        int
+	MaxHops       int
+}
+
+// ExecutionFromProto summarizes a swap execution.
+func ExecutionFromProto(se *dexv1alpha1.SwapExecution, height uint64, t time.Time) *Execution {
+	e := &Execution{
+		Height: height,
+		Time:   t,
+		Pair: &dexv1alpha1.DirectedTradingPair{
+			Start: se.GetInput().GetAssetId(),
+			End:   se.GetOutput().GetAssetId(),
+		},
+		Input:  num.AmountFromProto(se.GetInput().GetAmount()),
+		Output: num.AmountFromProto(se.GetOutput().GetAmount()),
+		Traces: len(se.GetTraces()),
+	}
+	for _, trace := range se.GetTraces() {
+		e.MaxHops = max(e.MaxHops, len(trace.GetValue())-1)
+	}
+	return e
+}
+
+// Block is the indexed data of one block.
+type Block struct {
+	Height  uint64
+	Batches []*Batch
+	Swaps   []*Execution
+	// Arb is the block's arbitrage execution, if any.
+	Arb *Execution
+}
+
+// Store holds indexed blocks in a SQL database. A Store is safe for
+// concurrent use, so the indexer can write while the API reads.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore returns a store over db, creating its tables if needed.
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("could not create tables: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Height returns the last indexed height, or false if nothing has been
+// indexed.
+func (s *Store) Height(ctx context.Context) (uint64, bool, error) {
+	var height uint64
+	err := s.db.QueryRowContext(ctx, `SELECT height FROM progress WHERE id = 0`).Scan(&height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return height, true, nil
+}
+
+// Put stores the blocks of a range of heights, and records that the range
+// up to height has been indexed, atomically.
+func (s *Store) Put(ctx context.Context, blocks []*Block, height uint64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, b := range blocks {
+		for _, batch := range b.Batches {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO batches (height, time, asset_1, asset_2, price, volume_1, volume_2) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				batch.Height, batch.Time.Unix(), batch.Pair.GetAsset_1().GetInner(), batch.Pair.GetAsset_2().GetInner(),
+				batch.Price, batch.Volume1.String(), batch.Volume2.String(),
+			); err != nil {
+				return err
+			}
+		}
+		for _, e := range b.Swaps {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO swap_executions (height, time, asset_start, asset_end, input, output, traces, max_hops) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				e.Height, e.Time.Unix(), e.Pair.GetStart().GetInner(), e.Pair.GetEnd().GetInner(),
+				e.Input.String(), e.Output.String(), e.Traces, e.MaxHops,
+			); err != nil {
+				return err
+			}
+		}
+		if e := b.Arb; e != nil {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO arb_executions (height, time, asset, input, output, traces) VALUES (?, ?, ?, ?, ?, ?)`,
+				e.Height, e.Time.Unix(), e.Pair.GetStart().GetInner(), e.Input.String(), e.Output.String(), e.Traces,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO progress (id, height) VALUES (0, ?)`, height); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Batches returns the batches of a trading pair in [from, to), ordered by
+// height.
+func (s *Store) Batches(ctx context.Context, pair *dexv1alpha1.TradingPair, from, to time.Time) ([]*Batch, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT height, time, price, volume_1, volume_2 FROM batches WHERE asset_1 = ? AND asset_2 = ? AND time >= ? AND time < ? ORDER BY height`,
+		pair.GetAsset_1().GetInner(), pair.GetAsset_2().GetInner(), from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var batches []*Batch
+	for rows.Next() {
+		var (
+			b                Batch
+			unix             int64
+			volume1, volume2 string
+		)
+		if err := rows.Scan(&b.Height, &unix, &b.Price, &volume1, &volume2); err != nil {
+			return nil, err
+		}
+		b.Time = time.Unix(unix, 0).UTC()
+		b.Pair = pair
+		if b.Volume1, err = num.ParseAmount(volume1); err != nil {
+			return nil, err
+		}
+		if b.Volume2, err = num.ParseAmount(volume2); err != nil {
+			return nil, err
+		}
+		batches = append(batches, &b)
+	}
+	return batches, rows.Err()
+}
+
+// Arbs returns the arbitrage executions in [from, to), ordered by height.
+func (s *Store) Arbs(ctx context.Context, from, to time.Time) ([]*Execution, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT height, time, asset, input, output, traces FROM arb_executions WHERE time >= ? AND time < ? ORDER BY height`,
+		from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var arbs []*Execution
+	for rows.Next() {
+		var (
+			e             Execution
+			unix          int64
+			id            []byte
+			input, output string
+		)
+		if err := rows.Scan(&e.Height, &unix, &id, &input, &output, &e.Traces); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(unix, 0).UTC()
+		asset := &assetv1alpha1.AssetId{Inner: id}
+		e.Pair = &dexv1alpha1.DirectedTradingPair{Start: asset, End: asset}
+		if e.Input, err = num.ParseAmount(input); err != nil {
+			return nil, err
+		}
+		if e.Output, err = num.ParseAmount(output); err != nil {
+			return nil, err
+		}
+		arbs = append(arbs, &e)
+	}
+	return arbs, rows.Err()
+}
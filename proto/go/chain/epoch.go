@@ -0,0 +1,421 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	compact_blockv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/compact_block/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+)
+
+// ErrUnknownEpochDuration is returned when the calculator has not learned
+// any epoch duration.
+var ErrUnknownEpochDuration = errors.New("epoch duration is unknown")
+
+// maxBlockTimeSamples is the number of block times kept to estimate the
+// block interval.
+const maxBlockTimeSamples = 64
+
+// DurationChange records that the epoch duration became EpochDuration at
+// Height: pd checks whether an epoch ends at the end of each block, after
+// applying that block's parameter changes.
+type DurationChange struct {
+	Height        uint64 `json:"height"`
+	EpochDuration uint64 `json:"epoch_duration"`
+}
+
+// epochStart is an epoch whose start height is known exactly.
+type epochStart struct {
+	Index       uint64 `json:"index"`
+	StartHeight uint64 `json:"start_height"`
+}
+
+// blockTime is the time a block was produced.
+type blockTime struct {
+	Height uint64    `json:"height"`
+	Time   time.Time `json:"time"`
+}
+
+// calculatorState is the persisted state of a calculator.
+type calculatorState struct {
+	Epochs     []epochStart     `json:"epochs"`
+	Durations  []DurationChange `json:"durations"`
+	BlockTimes []blockTime      `json:"block_times"`
+}
+
+// Calculator maps between epochs and block heights. It follows pd's
+// schedule, where an epoch ends at the first height at least the epoch
+// duration minus one past its start, and anchors it to epoch starts learned
+// from the chain, which also account for epochs that pd ended early. A
+// Calculator is safe for concurrent use.
+type Calculator struct {
+	mu    sync.Mutex
+	state calculatorState
+}
+
+// NewCalculator returns a calculator that only knows that epoch 0 starts at
+// genesis.
+func NewCalculator() *Calculator {
+	return &Calculator{state: calculatorState{Epochs: []epochStart{{}}}}
+}
+
+// ReadCalculatorFile loads a calculator saved by WriteFile.
+func ReadCalculatorFile(path string) (*Calculator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCalculator()
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	sort.Slice(c.state.Epochs, func(i, j int) bool { return c.state.Epochs[i].Index < c.state.Epochs[j].Index })
+	sort.Slice(c.state.Durations, func(i, j int) bool { return c.state.Durations[i].Height < c.state.Durations[j].Height })
+	sort.Slice(c.state.BlockTimes, func(i, j int) bool { return c.state.BlockTimes[i].Height < c.state.BlockTimes[j].Height })
+	return c, nil
+}
+
+// WriteFile saves what the calculator has learned as indented JSON.
+func (c *Calculator) WriteFile(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(&c.state, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// SetEpochDuration records that the epoch duration is duration from height
+// on.
+func (c *Calculator) SetEpochDuration(height, duration uint64) error {
+	if duration == 0 {
+		return errors.New("epoch duration must be positive")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ds := c.state.Durations
+	i := sort.Search(len(ds), func(i int) bool { return ds[i].Height >= height })
+	if i < len(ds) && ds[i].Height == height {
+		ds[i].EpochDuration = duration
+		return nil
+	}
+	c.state.Durations = append(ds[:i], append([]DurationChange{{Height: height, EpochDuration: duration}}, ds[i:]...)...)
+	return nil
+}
+
+// Durations returns the epoch duration changes learned, by height.
+func (c *Calculator) Durations() []DurationChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]DurationChange(nil), c.state.Durations...)
+}
+
+// ObserveAppParameters records the epoch duration of app parameters in
+// effect at a height, if it differs from the duration already in effect.
+func (c *Calculator) ObserveAppParameters(height uint64, app *appv1alpha1.AppParameters) error {
+	duration := app.GetChainParams().GetEpochDuration()
+	if current, err := c.EpochDuration(height); err == nil && current == duration {
+		return nil
+	}
+	return c.SetEpochDuration(height, duration)
+}
+
+// ObserveEpoch records an epoch's start height, as returned by
+// EpochByHeight.
+func (c *Calculator) ObserveEpoch(epoch *chainv1alpha1.Epoch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addEpoch(epochStart{Index: epoch.GetIndex(), StartHeight: epoch.GetStartHeight()})
+}
+
+func (c *Calculator) addEpoch(e epochStart) {
+	es := c.state.Epochs
+	i := sort.Search(len(es), func(i int) bool { return es[i].Index >= e.Index })
+	if i < len(es) && es[i].Index == e.Index {
+		es[i] = e
+		return
+	}
+	c.state.Epochs = append(es[:i], append([]epochStart{e}, es[i:]...)...)
+}
+
+// ObserveCompactBlock learns from a compact block: the epoch duration, if
+// app is the app parameters after a block that updated them, and the start
+// of the next epoch, if the block ended an epoch. Observing every compact
+// block in order keeps the calculator exact.
+func (c *Calculator) ObserveCompactBlock(block *compact_blockv1alpha1.CompactBlock, app *appv1alpha1.AppParameters) error {
+	if block.GetAppParametersUpdated() {
+		if app == nil {
+			return fmt.Errorf("block %d updated the app parameters, which are missing", block.GetHeight())
+		}
+		if err := c.ObserveAppParameters(block.GetHeight(), app); err != nil {
+			return err
+		}
+	}
+	if block.GetEpochRoot() == nil {
+		return nil
+	}
+	epoch, err := c.EpochByHeight(block.GetHeight())
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addEpoch(epochStart{Index: epoch.GetIndex() + 1, StartHeight: block.GetHeight() + 1})
+	return nil
+}
+
+// EpochDuration returns the epoch duration in effect at a height. Heights
+// before the first duration learned are assumed to use it.
+func (c *Calculator) EpochDuration(height uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.duration(height)
+}
+
+func (c *Calculator) duration(height uint64) (uint64, error) {
+	ds := c.state.Durations
+	if len(ds) == 0 {
+		return 0, ErrUnknownEpochDuration
+	}
+	i := sort.Search(len(ds), func(i int) bool { return ds[i].Height > height })
+	if i == 0 {
+		return ds[0].EpochDuration, nil
+	}
+	return ds[i-1].EpochDuration, nil
+}
+
+// lastHeight returns the last height of an epoch starting at start, if pd
+// ends it as scheduled.
+func (c *Calculator) lastHeight(start uint64) (uint64, error) {
+	ds := c.state.Durations
+	if len(ds) == 0 {
+		return 0, ErrUnknownEpochDuration
+	}
+	// Find the duration in effect at start, then each later change.
+	i := sort.Search(len(ds), func(i int) bool { return ds[i].Height > start })
+	i = max(i-1, 0)
+	from := start
+	for ; ; i++ {
+		end := max(from, start+ds[i].EpochDuration-1)
+		if i+1 == len(ds) || end < ds[i+1].Height {
+			return end, nil
+		}
+		from = ds[i+1].Height
+	}
+}
+
+// next returns the epoch following e, preferring a known start.
+func (c *Calculator) next(e epochStart, known int) (epochStart, int, error) {
+	if known+1 < len(c.state.Epochs) && c.state.Epochs[known+1].Index == e.Index+1 {
+		return c.state.Epochs[known+1], known + 1, nil
+	}
+	end, err := c.lastHeight(e.StartHeight)
+	if err != nil {
+		return epochStart{}, 0, err
+	}
+	return epochStart{Index: e.Index + 1, StartHeight: end + 1}, known, nil
+}
+
+// EpochByHeight returns the epoch containing a height.
+func (c *Calculator) EpochByHeight(height uint64) (*chainv1alpha1.Epoch, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	es := c.state.Epochs
+	known := sort.Search(len(es), func(i int) bool { return es[i].StartHeight > height }) - 1
+	if known < 0 {
+		return nil, fmt.Errorf("no epoch is known to start before height %d", height)
+	}
+	e := es[known]
+	for {
+		// Past the last duration change, whole epochs can be skipped.
+		if ds := c.state.Durations; known+1 == len(es) && len(ds) > 0 && e.StartHeight >= ds[len(ds)-1].Height {
+			d := ds[len(ds)-1].EpochDuration
+			n := (height - e.StartHeight) / d
+			return &chainv1alpha1.Epoch{Index: e.Index + n, StartHeight: e.StartHeight + n*d}, nil
+		}
+		next, k, err := c.next(e, known)
+		if err != nil {
+			return nil, err
+		}
+		if next.StartHeight > height {
+			return &chainv1alpha1.Epoch{Index: e.Index, StartHeight: e.StartHeight}, nil
+		}
+		e, known = next, k
+	}
+}
+
+// EpochStartHeight returns the first height of an epoch.
+func (c *Calculator) EpochStartHeight(index uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	es := c.state.Epochs
+	known := sort.Search(len(es), func(i int) bool { return es[i].Index > index }) - 1
+	if known < 0 {
+		return 0, fmt.Errorf("no epoch is known before epoch %d", index)
+	}
+	e := es[known]
+	for e.Index < index {
+		if ds := c.state.Durations; known+1 == len(es) && len(ds) > 0 && e.StartHeight >= ds[len(ds)-1].Height {
+			return e.StartHeight + (index-e.Index)*ds[len(ds)-1].EpochDuration, nil
+		}
+		var err error
+		if e, known, err = c.next(e, known); err != nil {
+			return 0, err
+		}
+	}
+	return e.StartHeight, nil
+}
+
+// EpochLastHeight returns the last height of an epoch.
+func (c *Calculator) EpochLastHeight(index uint64) (uint64, error) {
+	next, err := c.EpochStartHeight(index + 1)
+	if err != nil {
+		return 0, err
+	}
+	return next - 1, nil
+}
+
+// Exact reports whether the epoch containing a height is known from the
+// chain rather than from the schedule: both its start and the start of the
+// following epoch have been observed.
+func (c *Calculator) Exact(height uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	es := c.state.Epochs
+	i := sort.Search(len(es), func(i int) bool { return es[i].StartHeight > height })
+	return i > 0 && i < len(es) && es[i].Index == es[i-1].Index+1
+}
+
+// ObserveBlockTime records the time a block was produced.
+func (c *Calculator) ObserveBlockTime(height uint64, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bs := c.state.BlockTimes
+	i := sort.Search(len(bs), func(i int) bool { return bs[i].Height >= height })
+	if i < len(bs) && bs[i].Height == height {
+		bs[i].Time = t
+		return
+	}
+	bs = append(bs[:i], append([]blockTime{{Height: height, Time: t}}, bs[i:]...)...)
+	if len(bs) > maxBlockTimeSamples {
+		bs = bs[len(bs)-maxBlockTimeSamples:]
+	}
+	c.state.BlockTimes = bs
+}
+
+// ObserveSyncInfo records the time of a fullnode's latest block.
+func (c *Calculator) ObserveSyncInfo(info *tendermint_proxyv1alpha1.SyncInfo) {
+	if info.GetLatestBlockTime() == nil {
+		return
+	}
+	c.ObserveBlockTime(info.GetLatestBlockHeight(), info.GetLatestBlockTime().AsTime())
+}
+
+// BlockTime estimates the interval between blocks from the block times
+// observed. It is false until two block times have been observed.
+func (c *Calculator) BlockTime() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blockTime()
+}
+
+func (c *Calculator) blockTime() (time.Duration, bool) {
+	bs := c.state.BlockTimes
+	if len(bs) < 2 {
+		return 0, false
+	}
+	first, last := bs[0], bs[len(bs)-1]
+	return last.Time.Sub(first.Time) / time.Duration(last.Height-first.Height), true
+}
+
+// EstimateTime estimates the time of a block from the latest block time
+// observed.
+func (c *Calculator) EstimateTime(height uint64) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interval, ok := c.blockTime()
+	if !ok {
+		return time.Time{}, false
+	}
+	last := c.state.BlockTimes[len(c.state.BlockTimes)-1]
+	return last.Time.Add(time.Duration(int64(height)-int64(last.Height)) * interval), true
+}
+
+// EstimateHeight estimates the height of the block produced at a time.
+func (c *Calculator) EstimateHeight(t time.Time) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interval, ok := c.blockTime()
+	if !ok || interval <= 0 {
+		return 0, false
+	}
+	last := c.state.BlockTimes[len(c.state.BlockTimes)-1]
+	height := int64(last.Height) + int64(t.Sub(last.Time)/interval)
+	return uint64(max(height, 0)), true
+}
+
+// EstimateEpochStart estimates when an epoch starts.
+func (c *Calculator) EstimateEpochStart(index uint64) (time.Time, error) {
+	height, err := c.EpochStartHeight(index)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, ok := c.EstimateTime(height)
+	if !ok {
+		return time.Time{}, errors.New("block time is unknown")
+	}
+	return t, nil
+}
+
+// Fetch returns the epoch containing a height, asking the fullnode unless
+// the epoch is already known exactly, and records the answer.
+func (c *Calculator) Fetch(ctx context.Context, q QueryService, height uint64) (*chainv1alpha1.Epoch, error) {
+	if c.Exact(height) {
+		return c.EpochByHeight(height)
+	}
+	epoch, err := EpochByHeight(ctx, q, height)
+	if err != nil {
+		return nil, err
+	}
+	c.ObserveEpoch(epoch)
+	return epoch, nil
+}
+
+// Sync learns the latest block time, the current epoch and the current
+// epoch duration from a fullnode. A duration that differs from the one
+// learned is recorded from the latest height, since the fullnode does not
+// say when it changed; observing compact blocks records the exact height.
+func (c *Calculator) Sync(ctx context.Context, q QueryService, app params.QueryService, tm proxy.Service, chainId string) error {
+	status, err := tm.GetStatus(ctx, &tendermint_proxyv1alpha1.GetStatusRequest{})
+	if err != nil {
+		return fmt.Errorf("could not fetch status: %w", err)
+	}
+	info := status.GetSyncInfo()
+	c.ObserveSyncInfo(info)
+	appParams, err := params.Fetch(ctx, app, chainId)
+	if err != nil {
+		return fmt.Errorf("could not fetch app parameters: %w", err)
+	}
+	height := info.GetLatestBlockHeight()
+	if len(c.Durations()) == 0 {
+		height = 0
+	}
+	if err := c.ObserveAppParameters(height, appParams); err != nil {
+		return err
+	}
+	if _, err := c.Fetch(ctx, q, info.GetLatestBlockHeight()); err != nil {
+		return fmt.Errorf("could not fetch current epoch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package chain
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	compact_blockv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/compact_block/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+)
+
+// testCalculator has an epoch duration of 10 until height 35, and of 5 from
+// then on. pd checks `current_height - start_height >= epoch_duration - 1`
+// at the end of each block, so epoch 3, which starts at 30, ends at 35 under
+// the new duration.
+func testCalculator(t *testing.T) *Calculator {
+	t.Helper()
+	c := NewCalculator()
+	if err := c.SetEpochDuration(0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEpochDuration(35, 5); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCalculatorSchedule(t *testing.T) {
+	c := testCalculator(t)
+	tests := []struct {
+		height, index, start uint64
+	}{
+		{0, 0, 0},
+		{9, 0, 0},
+		{10, 1, 10},
+		{25, 2, 20},
+		{35, 3, 30},
+		{36, 4, 36},
+		{40, 4, 36},
+		{43, 5, 41},
+		{1000, 196, 996},
+	}
+	for _, tt := range tests {
+		epoch, err := c.EpochByHeight(tt.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if epoch.GetIndex() != tt.index || epoch.GetStartHeight() != tt.start {
+			t.Errorf("EpochByHeight(%d) = %d@%d, want %d@%d", tt.height, epoch.GetIndex(), epoch.GetStartHeight(), tt.index, tt.start)
+		}
+		start, err := c.EpochStartHeight(tt.index)
+		if err != nil || start != tt.start {
+			t.Errorf("EpochStartHeight(%d) = %d, %v; want %d", tt.index, start, err, tt.start)
+		}
+	}
+	if last, err := c.EpochLastHeight(3); err != nil || last != 35 {
+		t.Errorf("EpochLastHeight(3) = %d, %v; want 35", last, err)
+	}
+	if d, err := c.EpochDuration(34); err != nil || d != 10 {
+		t.Errorf("EpochDuration(34) = %d, %v; want 10", d, err)
+	}
+
+	if _, err := NewCalculator().EpochByHeight(20); !errors.Is(err, ErrUnknownEpochDuration) {
+		t.Errorf("EpochByHeight without a duration = %v", err)
+	}
+	if err := c.SetEpochDuration(50, 0); err == nil {
+		t.Errorf("SetEpochDuration accepted a zero duration")
+	}
+}
+
+func TestCalculatorObserved(t *testing.T) {
+	c := testCalculator(t)
+	// Epoch 6 is scheduled to start at 46, but pd ended epoch 5 early.
+	c.ObserveEpoch(&chainv1alpha1.Epoch{Index: 6, StartHeight: 44})
+	if epoch, _ := c.EpochByHeight(45); epoch.GetIndex() != 6 || epoch.GetStartHeight() != 44 {
+		t.Errorf("EpochByHeight(45) = %v, want 6@44", epoch)
+	}
+	if start, _ := c.EpochStartHeight(7); start != 49 {
+		t.Errorf("EpochStartHeight(7) = %d, want 49", start)
+	}
+	if c.Exact(42) {
+		t.Errorf("epoch 5 is exact before its start is observed")
+	}
+
+	// The block ending epoch 4 gives the start of epoch 5.
+	if err := c.ObserveCompactBlock(&compact_blockv1alpha1.CompactBlock{Height: 40, EpochRoot: &tctv1alpha1.MerkleRoot{}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Exact(42) {
+		t.Errorf("epoch 5 is not exact once its start and the next are observed")
+	}
+
+	// A block that changes the epoch duration records it at its height.
+	app := &appv1alpha1.AppParameters{ChainParams: &chainv1alpha1.ChainParameters{EpochDuration: 20}}
+	if err := c.ObserveCompactBlock(&compact_blockv1alpha1.CompactBlock{Height: 60, AppParametersUpdated: true}, app); err != nil {
+		t.Fatal(err)
+	}
+	want := []DurationChange{{0, 10}, {35, 5}, {60, 20}}
+	if got := c.Durations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Durations = %v, want %v", got, want)
+	}
+	if err := c.ObserveCompactBlock(&compact_blockv1alpha1.CompactBlock{Height: 61, AppParametersUpdated: true}, nil); err == nil {
+		t.Errorf("ObserveCompactBlock accepted a parameter change without the parameters")
+	}
+
+	path := filepath.Join(t.TempDir(), "epochs.json")
+	if err := c.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadCalculatorFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []uint64{42, 45, 100} {
+		a, _ := c.EpochByHeight(h)
+		b, _ := loaded.EpochByHeight(h)
+		if a.GetIndex() != b.GetIndex() || a.GetStartHeight() != b.GetStartHeight() {
+			t.Errorf("loaded calculator puts height %d in %v, want %v", h, b, a)
+		}
+	}
+}
+
+func TestCalculatorBlockTime(t *testing.T) {
+	c := testCalculator(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, ok := c.BlockTime(); ok {
+		t.Errorf("BlockTime is known without observations")
+	}
+	c.ObserveBlockTime(100, t0)
+	c.ObserveBlockTime(110, t0.Add(50*time.Second))
+	if d, ok := c.BlockTime(); !ok || d != 5*time.Second {
+		t.Errorf("BlockTime = %s, %v; want 5s", d, ok)
+	}
+	if at, ok := c.EstimateTime(120); !ok || !at.Equal(t0.Add(100*time.Second)) {
+		t.Errorf("EstimateTime(120) = %s, %v", at, ok)
+	}
+	if h, ok := c.EstimateHeight(t0.Add(75 * time.Second)); !ok || h != 115 {
+		t.Errorf("EstimateHeight = %d, %v; want 115", h, ok)
+	}
+	// Epoch 20 starts at 41 + 15 * 5 = 116.
+	if at, err := c.EstimateEpochStart(20); err != nil || !at.Equal(t0.Add(80*time.Second)) {
+		t.Errorf("EstimateEpochStart(20) = %s, %v", at, err)
+	}
+}
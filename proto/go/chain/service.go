@@ -0,0 +1,46 @@
+// Package chain provides a client for the chain component, which tracks
+// epochs and the chain parameters.
+package chain
+
+import (
+	"context"
+	"errors"
+
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of the chain component's QueryService.
+const QueryServiceName = "penumbra.core.component.chain.v1alpha1.QueryService"
+
+// QueryService is the chain component's QueryService API.
+type QueryService interface {
+	EpochByHeight(ctx context.Context, req *chainv1alpha1.EpochByHeightRequest) (*chainv1alpha1.EpochByHeightResponse, error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) EpochByHeight(ctx context.Context, req *chainv1alpha1.EpochByHeightRequest) (*chainv1alpha1.EpochByHeightResponse, error) {
+	return grpcclient.Call[chainv1alpha1.EpochByHeightResponse](ctx, s.svc, "EpochByHeight", req)
+}
+
+// EpochByHeight returns the epoch containing a block height.
+func EpochByHeight(ctx context.Context, q QueryService, height uint64) (*chainv1alpha1.Epoch, error) {
+	rsp, err := q.EpochByHeight(ctx, &chainv1alpha1.EpochByHeightRequest{Height: height})
+	if err != nil {
+		return nil, err
+	}
+	if rsp.GetEpoch() == nil {
+		return nil, errors.New("response is missing the epoch")
+	}
+	return rsp.GetEpoch(), nil
+}
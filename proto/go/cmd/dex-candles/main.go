@@ -0,0 +1,145 @@
+// Command dex-candles indexes a Penumbra fullnode's batch swaps into a
+// SQLite database, and serves OHLCV candles per trading pair over HTTP.
+//
+// Usage:
+//
+//	dex-candles [-node addr] [-chain-id id] [-tls] [-db file] [-listen addr] [-start-height n]
+//
+// The API's denoms are the ones registered on the chain when the command
+// starts; restart it to serve candles of assets created since.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/candles"
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	"github.com/penumbra-zone/penumbra/proto/go/compactblock"
+	"github.com/penumbra-zone/penumbra/proto/go/dex"
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+func main() {
+	node := flag.String("node", "localhost:8080", "gRPC address of the fullnode")
+	chainId := flag.String("chain-id", "", "chain ID to check the fullnode against")
+	useTLS := flag.Bool("tls", false, "connect to the fullnode over TLS")
+	dbPath := flag.String("db", "candles.db", "SQLite database to index into")
+	listen := flag.String("listen", "localhost:8081", "HTTP address to serve the API on")
+	startHeight := flag.Uint64("start-height", 1, "first height to index into an empty database")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dex-candles [-node addr] [-chain-id id] [-tls] [-db file] [-listen addr] [-start-height n]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, *node, *chainId, *useTLS, *dbPath, *listen, *startHeight); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "dex-candles:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, node, chainId string, useTLS bool, dbPath, listen string, startHeight uint64) error {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(node, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	store, err := candles.NewStore(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	cache, err := loadDenoms(ctx, cnidarium.NewGRPCQueryService(conn))
+	if err != nil {
+		return fmt.Errorf("could not load denoms: %w", err)
+	}
+	server := &http.Server{Addr: listen, Handler: candles.Handler(store, cache)}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+	defer server.Close()
+	fmt.Fprintf(os.Stderr, "serving candles on %s\n", listen)
+
+	ix := &candles.Indexer{
+		Blocks:      compactblock.NewGRPCQueryService(conn),
+		Dex:         dex.NewGRPCQueryService(conn),
+		Tendermint:  proxy.NewGRPCService(conn),
+		ChainId:     chainId,
+		Store:       store,
+		StartHeight: startHeight,
+	}
+	indexErr := make(chan error, 1)
+	go func() { indexErr <- ix.Run(ctx) }()
+	select {
+	case err := <-serveErr:
+		return err
+	case err := <-indexErr:
+		return err
+	}
+}
+
+// loadDenoms reads the denom metadata registered by the shielded pool.
+func loadDenoms(ctx context.Context, q cnidarium.QueryService) (*asset.Cache, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The prefix also holds each asset's token supply, which cnidarium.Scan
+	// would refuse to decode as denom metadata.
+	stream, err := q.PrefixValue(ctx, &cnidariumv1alpha1.PrefixValueRequest{
+		Prefix: cnidarium.DefaultSchema.KeyPrefix("shielded_pool/assets/{asset_id}/denom"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache := asset.NewCache()
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			return cache, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(rsp.GetKey(), "/denom") {
+			continue
+		}
+		d := &assetv1alpha1.DenomMetadata{}
+		if err := proto.Unmarshal(rsp.GetValue(), d); err != nil {
+			return nil, fmt.Errorf("could not decode %q: %w", rsp.GetKey(), err)
+		}
+		cache.Add(d)
+	}
+}
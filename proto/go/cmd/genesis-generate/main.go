@@ -0,0 +1,100 @@
+// Command genesis-generate writes the CometBFT genesis of a Penumbra devnet,
+// from an allocations CSV file and a validators JSON file in the formats used
+// by `pd testnet generate`.
+//
+// Usage:
+//
+//	genesis-generate -chain-id id -allocations allocations.csv -validators validators.json [-o genesis.json] [-keys-dir dir]
+//
+// As pd does, the command generates the keys of validators that have none,
+// and writes each one's `priv_validator_key.json` and
+// `validator_custody.json` to `node<i>/cometbft/config` under the keys
+// directory, where i is the validator's index in the validators file.
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/penumbra-zone/penumbra/proto/go/genesis"
+)
+
+func main() {
+	chainId := flag.String("chain-id", "", "chain ID of the network")
+	allocations := flag.String("allocations", "", "path to the allocations CSV file")
+	validators := flag.String("validators", "", "path to the validators JSON file")
+	out := flag.String("o", "genesis.json", "path to write the genesis file to")
+	keysDir := flag.String("keys-dir", "testnet_data", "directory to write the generated keys of validators to")
+	epochDuration := flag.Uint64("epoch-duration", 0, "number of blocks per epoch, if not the default")
+	unbondingEpochs := flag.Uint64("unbonding-epochs", 0, "number of epochs before unbonding stake is released, if not the default")
+	activeValidatorLimit := flag.Uint64("active-validator-limit", 0, "maximum number of active validators, if not the default")
+	proposalVotingBlocks := flag.Uint64("proposal-voting-blocks", 0, "number of blocks during which a proposal is voted on, if not the default")
+	flag.Parse()
+
+	if err := run(*chainId, *allocations, *validators, *out, *keysDir, func(b *genesis.Builder) {
+		if *epochDuration != 0 {
+			b.Content.ChainContent.ChainParams.EpochDuration = *epochDuration
+		}
+		if *unbondingEpochs != 0 {
+			b.Content.StakeContent.StakeParams.UnbondingEpochs = *unbondingEpochs
+		}
+		if *activeValidatorLimit != 0 {
+			b.Content.StakeContent.StakeParams.ActiveValidatorLimit = *activeValidatorLimit
+		}
+		if *proposalVotingBlocks != 0 {
+			b.Content.GovernanceContent.GovernanceParams.ProposalVotingBlocks = *proposalVotingBlocks
+		}
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "genesis-generate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(chainId, allocationsPath, validatorsPath, out, keysDir string, customize func(*genesis.Builder)) error {
+	if chainId == "" || allocationsPath == "" || validatorsPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	b := genesis.NewBuilder(chainId)
+	customize(b)
+
+	allocations, err := genesis.ReadAllocationsFile(allocationsPath)
+	if err != nil {
+		return err
+	}
+	b.AddAllocations(allocations...)
+
+	validators, err := genesis.ReadValidatorsFile(validatorsPath)
+	if err != nil {
+		return err
+	}
+	for i := range validators {
+		if validators[i].HasKeys() {
+			continue
+		}
+		keys, err := genesis.GenerateValidatorKeys(rand.Reader)
+		if err != nil {
+			return err
+		}
+		if err := validators[i].SetKeys(keys); err != nil {
+			return err
+		}
+		dir := filepath.Join(keysDir, fmt.Sprintf("node%d", i), "cometbft", "config")
+		if err := keys.WriteFiles(dir); err != nil {
+			return fmt.Errorf("could not write keys of validator %q: %w", validators[i].Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "generated keys for validator %q in %s\n", validators[i].Name, dir)
+	}
+	if err := b.AddValidators(validators...); err != nil {
+		return err
+	}
+
+	g, err := b.Genesis()
+	if err != nil {
+		return err
+	}
+	return g.WriteFile(out)
+}
@@ -0,0 +1,94 @@
+// Command genesis-inspect prints statistics about a Penumbra genesis file.
+// For a checkpoint genesis, it can also confirm that a migrated chain
+// started from the checkpoint, using a state export or a live fullnode.
+//
+// Usage:
+//
+//	genesis-inspect [-snapshot file] [-node addr [-tls]] genesis.json
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	"github.com/penumbra-zone/penumbra/proto/go/genesis"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+	"github.com/penumbra-zone/penumbra/proto/go/snapshot"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	snapshotPath := flag.String("snapshot", "", "state export of the migrated chain to compare a checkpoint to")
+	node := flag.String("node", "", "gRPC address of a fullnode of the migrated chain to check a checkpoint against")
+	useTLS := flag.Bool("tls", false, "connect to the fullnode over TLS")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *snapshotPath, *node, *useTLS); err != nil {
+		fmt.Fprintln(os.Stderr, "genesis-inspect:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, snapshotPath, node string, useTLS bool) error {
+	g, err := genesis.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	summary, err := g.Summarize()
+	if err != nil {
+		return err
+	}
+	fmt.Print(summary)
+	if snapshotPath == "" && node == "" {
+		return nil
+	}
+
+	checkpoint := summary.Checkpoint
+	if checkpoint == nil {
+		return genesis.ErrNotCheckpoint
+	}
+	if snapshotPath != "" {
+		s, err := snapshot.ReadFile(snapshotPath)
+		if err != nil {
+			return err
+		}
+		cmp := checkpoint.CompareSnapshot(s)
+		for _, sec := range cmp.Mismatched {
+			fmt.Printf("mismatch: %q was exported at height %d from app hash %X\n", sec.Prefix, sec.Height, sec.AppHash)
+		}
+		for _, p := range cmp.Problems {
+			fmt.Println("problem:", p)
+		}
+		fmt.Printf("snapshot: %d sections match the checkpoint, %d do not, %d cannot be compared\n",
+			len(cmp.Matched), len(cmp.Mismatched), len(cmp.Unverifiable))
+		if !cmp.OK() {
+			return errors.New("snapshot does not confirm the checkpoint")
+		}
+	}
+	if node != "" {
+		creds := insecure.NewCredentials()
+		if useTLS {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		conn, err := grpc.NewClient(node, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := checkpoint.VerifyLive(context.Background(), cnidarium.NewVerifier(proxy.NewGRPCService(conn))); err != nil {
+			return err
+		}
+		fmt.Println("fullnode: chain started from the checkpoint")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package cnidarium provides client-side helpers for Penumbra's verifiable
+// state storage, mirroring the `cnidarium` crate.
+package cnidarium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ics23 "github.com/cosmos/ics23/go"
+	crypto "github.com/penumbra-zone/penumbra/proto/go/gen/tendermint/crypto"
+)
+
+// ProofOpType is the type of the ABCI proof operations returned by pd for
+// `state/key` queries.
+const ProofOpType = "jmt:v"
+
+// Prefixes of the substores of pd's multistore, as in `penumbra_app`.
+const (
+	IbcSubstorePrefix      = "ibc-data"
+	CometBftSubstorePrefix = "cometbft-data"
+)
+
+// SubstorePrefixes are the prefixes of the substores of pd's multistore. The
+// root of each substore is stored in the main store under its prefix.
+var SubstorePrefixes = []string{IbcSubstorePrefix, CometBftSubstorePrefix}
+
+// ErrInvalidProof is returned when a proof does not verify.
+var ErrInvalidProof = errors.New("invalid proof")
+
+// JMT domain separators and placeholder, vendored from the jmt crate.
+var (
+	leafDomainSeparator         = []byte("JMT::LeafNode")
+	internalDomainSeparator     = []byte("JMT::IntrnalNode")
+	sparseMerklePlaceholderHash = []byte("SPARSE_MERKLE_PLACEHOLDER_HASH__")
+)
+
+// JMTSpec is the ICS-23 proof spec of Penumbra's jellyfish merkle tree.
+var JMTSpec = &ics23.ProofSpec{
+	LeafSpec: &ics23.LeafOp{
+		Hash:         ics23.HashOp_SHA256,
+		PrehashKey:   ics23.HashOp_SHA256,
+		PrehashValue: ics23.HashOp_SHA256,
+		Length:       ics23.LengthOp_NO_PREFIX,
+		Prefix:       leafDomainSeparator,
+	},
+	InnerSpec: &ics23.InnerSpec{
+		Hash:            ics23.HashOp_SHA256,
+		ChildOrder:      []int32{0, 1},
+		MinPrefixLength: int32(len(internalDomainSeparator)),
+		MaxPrefixLength: int32(len(internalDomainSeparator)),
+		ChildSize:       32,
+		EmptyChild:      sparseMerklePlaceholderHash,
+	},
+	MinDepth:                   0,
+	MaxDepth:                   64,
+	PrehashKeyBeforeComparison: true,
+}
+
+// RouteKey returns the substore prefix of a key and the key within that
+// substore, as `MultistoreConfig::route_key_bytes` does. Keys in the main
+// store have an empty prefix.
+func RouteKey(key string) (prefix, substoreKey string) {
+	for _, p := range SubstorePrefixes {
+		if !strings.HasPrefix(key, p) {
+			continue
+		}
+		// Keys must continue with a delimiter and be non-empty to be routed
+		// to the substore, so that `prefix_a/key` and `prefix_akey` do not
+		// collide.
+		rest, ok := strings.CutPrefix(key[len(p):], "/")
+		if !ok || rest == "" {
+			return "", key
+		}
+		return p, rest
+	}
+	return "", key
+}
+
+// ProofsFromOps decodes the commitment proofs carried by the proof operations
+// of an ABCI query response.
+func ProofsFromOps(ops *crypto.ProofOps) ([]*ics23.CommitmentProof, error) {
+	proofs := make([]*ics23.CommitmentProof, 0, len(ops.GetOps()))
+	for _, op := range ops.GetOps() {
+		if op.GetType() != ProofOpType {
+			return nil, fmt.Errorf("unexpected proof op type %q", op.GetType())
+		}
+		proof := new(ics23.CommitmentProof)
+		if err := proof.Unmarshal(op.GetData()); err != nil {
+			return nil, fmt.Errorf("could not decode commitment proof: %w", err)
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, nil
+}
+
+// Verify checks a proof for a key against an app hash. If value is nil, the
+// proof must show that the key is absent; otherwise it must show that the
+// key has exactly that value.
+//
+// The proofs are ordered as returned by `Snapshot::get_with_proof`: first
+// the proof of the key within its substore, then, for keys in a substore,
+// the proof of the substore root within the main store.
+func Verify(proofs []*ics23.CommitmentProof, appHash []byte, key string, value []byte) error {
+	prefix, substoreKey := RouteKey(key)
+	want := 1
+	if prefix != "" {
+		want = 2
+	}
+	if len(proofs) != want {
+		return fmt.Errorf("%w: expected %d commitment proofs for key %q, got %d", ErrInvalidProof, want, key, len(proofs))
+	}
+
+	root := appHash
+	if prefix != "" {
+		substoreRoot, err := proofs[0].Calculate()
+		if err != nil {
+			return fmt.Errorf("%w: could not compute substore root: %v", ErrInvalidProof, err)
+		}
+		if !ics23.VerifyMembership(JMTSpec, appHash, proofs[1], []byte(prefix), substoreRoot) {
+			return fmt.Errorf("%w: substore %q is not committed to by app hash %X", ErrInvalidProof, prefix, appHash)
+		}
+		root = substoreRoot
+	}
+
+	if value == nil {
+		if !ics23.VerifyNonMembership(JMTSpec, root, proofs[0], []byte(substoreKey)) {
+			return fmt.Errorf("%w: key %q is not proven absent", ErrInvalidProof, key)
+		}
+		return nil
+	}
+	if !ics23.VerifyMembership(JMTSpec, root, proofs[0], []byte(substoreKey), value) {
+		return fmt.Errorf("%w: key %q is not proven to have the given value", ErrInvalidProof, key)
+	}
+	return nil
+}
+
+// provesAbsence reports whether the key proof is a non-existence proof.
+func provesAbsence(proofs []*ics23.CommitmentProof) bool {
+	return len(proofs) > 0 && proofs[0].GetNonexist() != nil
+}
@@ -0,0 +1,243 @@
+package cnidarium
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"testing"
+
+	ics23 "github.com/cosmos/ics23/go"
+	crypto "github.com/penumbra-zone/penumbra/proto/go/gen/tendermint/crypto"
+)
+
+// testLeaf is a leaf of a testTree.
+type testLeaf struct {
+	key, value []byte
+	keyHash    [32]byte
+}
+
+// testTree is a jellyfish merkle tree built as the jmt crate hashes it: a
+// sparse binary tree over the key hashes, where subtrees holding a single
+// leaf are replaced by that leaf and empty subtrees by the placeholder.
+type testTree struct {
+	leaves []testLeaf
+}
+
+func newTestTree(kv map[string][]byte) *testTree {
+	t := &testTree{}
+	for k, v := range kv {
+		t.leaves = append(t.leaves, testLeaf{key: []byte(k), value: v, keyHash: sha256.Sum256([]byte(k))})
+	}
+	sort.Slice(t.leaves, func(i, j int) bool {
+		return bytes.Compare(t.leaves[i].keyHash[:], t.leaves[j].keyHash[:]) < 0
+	})
+	return t
+}
+
+func bit(h [32]byte, depth int) byte {
+	return h[depth/8] >> (7 - depth%8) & 1
+}
+
+// splitLeaves splits leaves sorted by key hash on the bit at depth.
+func splitLeaves(leaves []testLeaf, depth int) (left, right []testLeaf) {
+	i := sort.Search(len(leaves), func(i int) bool { return bit(leaves[i].keyHash, depth) == 1 })
+	return leaves[:i], leaves[i:]
+}
+
+func subtreeHash(leaves []testLeaf, depth int) []byte {
+	switch len(leaves) {
+	case 0:
+		return sparseMerklePlaceholderHash
+	case 1:
+		valueHash := sha256.Sum256(leaves[0].value)
+		h := sha256.Sum256(bytes.Join([][]byte{leafDomainSeparator, leaves[0].keyHash[:], valueHash[:]}, nil))
+		return h[:]
+	}
+	left, right := splitLeaves(leaves, depth)
+	h := sha256.Sum256(bytes.Join([][]byte{internalDomainSeparator, subtreeHash(left, depth+1), subtreeHash(right, depth+1)}, nil))
+	return h[:]
+}
+
+func (t *testTree) root() []byte {
+	return subtreeHash(t.leaves, 0)
+}
+
+// exist returns the existence proof of the i'th leaf, with its path ordered
+// from the leaf up as ICS-23 expects.
+func (t *testTree) exist(i int) *ics23.ExistenceProof {
+	leaf := t.leaves[i]
+	var path []*ics23.InnerOp
+	leaves := t.leaves
+	for depth := 0; len(leaves) > 1; depth++ {
+		left, right := splitLeaves(leaves, depth)
+		op := &ics23.InnerOp{Hash: ics23.HashOp_SHA256}
+		if bit(leaf.keyHash, depth) == 0 {
+			op.Prefix = internalDomainSeparator
+			op.Suffix = subtreeHash(right, depth+1)
+			leaves = left
+		} else {
+			op.Prefix = append(append([]byte{}, internalDomainSeparator...), subtreeHash(left, depth+1)...)
+			leaves = right
+		}
+		path = append([]*ics23.InnerOp{op}, path...)
+	}
+	return &ics23.ExistenceProof{Key: leaf.key, Value: leaf.value, Leaf: JMTSpec.LeafSpec, Path: path}
+}
+
+// prove returns an existence proof of key if it is in the tree, and
+// otherwise a non-existence proof from its neighbours by key hash.
+func (t *testTree) prove(key string) *ics23.CommitmentProof {
+	h := sha256.Sum256([]byte(key))
+	i := sort.Search(len(t.leaves), func(i int) bool { return bytes.Compare(t.leaves[i].keyHash[:], h[:]) >= 0 })
+	if i < len(t.leaves) && t.leaves[i].keyHash == h {
+		return &ics23.CommitmentProof{Proof: &ics23.CommitmentProof_Exist{Exist: t.exist(i)}}
+	}
+	nonexist := &ics23.NonExistenceProof{Key: []byte(key)}
+	if i > 0 {
+		nonexist.Left = t.exist(i - 1)
+	}
+	if i < len(t.leaves) {
+		nonexist.Right = t.exist(i)
+	}
+	return &ics23.CommitmentProof{Proof: &ics23.CommitmentProof_Nonexist{Nonexist: nonexist}}
+}
+
+// testStore is a multistore laid out as pd's, with the roots of the IBC and
+// CometBFT substores in the main store under their prefixes.
+type testStore struct {
+	main       *testTree
+	substores  map[string]*testTree
+	substoreKV map[string]map[string][]byte
+}
+
+func newTestStore() *testStore {
+	// The IBC keys follow cnidarium's test_substore_proofs.
+	s := &testStore{substoreKV: map[string]map[string][]byte{
+		IbcSubstorePrefix: {
+			"key_1": []byte("value_1a"),
+			"key_2": []byte("value_2"),
+			"key_3": []byte("value_3"),
+		},
+		CometBftSubstorePrefix: {
+			"key_1": []byte("cometbft_value_1"),
+		},
+	}}
+	mainKV := map[string][]byte{
+		"chain/block_height": []byte("height"),
+		"chain/epoch":        []byte("epoch"),
+		"sct/anchor":         []byte("anchor"),
+	}
+	s.substores = make(map[string]*testTree)
+	for prefix, kv := range s.substoreKV {
+		s.substores[prefix] = newTestTree(kv)
+		mainKV[prefix] = s.substores[prefix].root()
+	}
+	s.main = newTestTree(mainKV)
+	return s
+}
+
+func (s *testStore) appHash() []byte {
+	return s.main.root()
+}
+
+// getWithProof returns the value of a key, or nil if it is absent, and its
+// proofs as `Snapshot::get_with_proof` orders them.
+func (s *testStore) getWithProof(key string) ([]byte, []*ics23.CommitmentProof) {
+	prefix, substoreKey := RouteKey(key)
+	if prefix == "" {
+		return s.main.prove(key).GetExist().GetValue(), []*ics23.CommitmentProof{s.main.prove(key)}
+	}
+	proof := s.substores[prefix].prove(substoreKey)
+	return proof.GetExist().GetValue(), []*ics23.CommitmentProof{proof, s.main.prove(prefix)}
+}
+
+func TestVerify(t *testing.T) {
+	s := newTestStore()
+	appHash := s.appHash()
+	for _, key := range []string{
+		"chain/block_height",
+		"sct/anchor",
+		"ibc-data/key_1",
+		"ibc-data/key_3",
+		"cometbft-data/key_1",
+		"chain/doesntexist",
+		"ibc-data/doesntexist",
+		"ibc-data/key_4",
+		"cometbft-data/doesntexist",
+	} {
+		value, proofs := s.getWithProof(key)
+		if err := Verify(proofs, appHash, key, value); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+	if value, _ := s.getWithProof("ibc-data/key_1"); string(value) != "value_1a" {
+		t.Fatalf("ibc-data/key_1 = %q", value)
+	}
+
+	wrongHash := bytes.Clone(appHash)
+	wrongHash[0] ^= 1
+	_, mainProofs := s.getWithProof("sct/anchor")
+	_, ibcProofs := s.getWithProof("ibc-data/key_1")
+	_, absentProofs := s.getWithProof("ibc-data/doesntexist")
+	_, cometProofs := s.getWithProof("cometbft-data/key_1")
+	tests := []struct {
+		name    string
+		proofs  []*ics23.CommitmentProof
+		appHash []byte
+		key     string
+		value   []byte
+	}{
+		{"wrong app hash", mainProofs, wrongHash, "sct/anchor", []byte("anchor")},
+		{"wrong app hash in substore", ibcProofs, wrongHash, "ibc-data/key_1", []byte("value_1a")},
+		{"absent with wrong app hash", absentProofs, wrongHash, "ibc-data/doesntexist", nil},
+		{"wrong key", mainProofs, appHash, "chain/epoch", []byte("anchor")},
+		{"wrong key in substore", ibcProofs, appHash, "ibc-data/key_2", []byte("value_1a")},
+		{"wrong substore", cometProofs, appHash, "ibc-data/key_1", []byte("cometbft_value_1")},
+		{"wrong value", ibcProofs, appHash, "ibc-data/key_1", []byte("value_1b")},
+		{"present key claimed absent", ibcProofs, appHash, "ibc-data/key_1", nil},
+		{"absent key claimed present", absentProofs, appHash, "ibc-data/doesntexist", []byte("value")},
+		{"absence of another key", absentProofs, appHash, "ibc-data/key_2", nil},
+		{"missing substore proof", ibcProofs[:1], appHash, "ibc-data/key_1", []byte("value_1a")},
+		{"extra proof", append(mainProofs, mainProofs...), appHash, "sct/anchor", []byte("anchor")},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.proofs, tt.appHash, tt.key, tt.value); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("%s: Verify = %v, want ErrInvalidProof", tt.name, err)
+		}
+	}
+}
+
+func TestProofsFromOps(t *testing.T) {
+	_, proofs := newTestStore().getWithProof("ibc-data/key_1")
+	ops := proofOps(t, proofs)
+	got, err := ProofsFromOps(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !bytes.Equal(got[0].GetExist().GetValue(), []byte("value_1a")) || !bytes.Equal(got[1].GetExist().GetKey(), []byte(IbcSubstorePrefix)) {
+		t.Errorf("ProofsFromOps = %v", got)
+	}
+
+	ops.Ops[1].Type = "ics23:iavl"
+	if _, err := ProofsFromOps(ops); err == nil {
+		t.Errorf("ProofsFromOps accepted an op of another type")
+	}
+	ops.Ops[1] = &crypto.ProofOp{Type: ProofOpType, Data: []byte{0xff}}
+	if _, err := ProofsFromOps(ops); err == nil {
+		t.Errorf("ProofsFromOps accepted an undecodable proof")
+	}
+}
+
+// proofOps encodes proofs as pd returns them from an ABCI query.
+func proofOps(t *testing.T, proofs []*ics23.CommitmentProof) *crypto.ProofOps {
+	ops := &crypto.ProofOps{}
+	for _, p := range proofs {
+		data, err := p.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops.Ops = append(ops.Ops, &crypto.ProofOp{Type: ProofOpType, Data: data})
+	}
+	return ops
+}
@@ -0,0 +1,129 @@
+package cnidarium
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+)
+
+// StateKeyPath is the ABCI query path pd serves verifiable state reads on.
+const StateKeyPath = "state/key"
+
+// Verifier reads keys from pd's state through the TendermintProxyService and
+// verifies them against the app hash committed to in block headers.
+type Verifier struct {
+	Service proxy.Service
+}
+
+// NewVerifier returns a verifier using the given proxy service.
+func NewVerifier(service proxy.Service) *Verifier {
+	return &Verifier{Service: service}
+}
+
+// AppHash returns the app hash of the state as of the end of the block at
+// the given height, which is committed to by the header of the next block.
+func (v *Verifier) AppHash(ctx context.Context, height uint64) ([]byte, error) {
+	rsp, err := v.Service.GetBlockByHeight(ctx, &tendermint_proxyv1alpha1.GetBlockByHeightRequest{Height: int64(height + 1)})
+	if err != nil {
+		return nil, fmt.Errorf("could not get header committing to height %d: %w", height, err)
+	}
+	header := rsp.GetBlock().GetHeader()
+	if header == nil {
+		return nil, fmt.Errorf("block %d has no header", height+1)
+	}
+	return header.GetAppHash(), nil
+}
+
+// latest returns the latest height whose app hash is known, and that app
+// hash: the latest block header commits to the state after its parent.
+func (v *Verifier) latest(ctx context.Context) (uint64, []byte, error) {
+	rsp, err := v.Service.GetStatus(ctx, &tendermint_proxyv1alpha1.GetStatusRequest{})
+	if err != nil {
+		return 0, nil, err
+	}
+	info := rsp.GetSyncInfo()
+	if info == nil {
+		return 0, nil, fmt.Errorf("could not parse sync_info in gRPC response")
+	}
+	if info.GetLatestBlockHeight() == 0 {
+		return 0, nil, fmt.Errorf("chain has no blocks")
+	}
+	return info.GetLatestBlockHeight() - 1, info.GetLatestAppHash(), nil
+}
+
+// VerifiedGet reads the value of a key as of the end of the block at the
+// given height and verifies it against that state's app hash. A height of
+// zero reads the latest verifiable state, using `SyncInfo.LatestAppHash`.
+//
+// It returns the value and the height it was read at. A nil value means the
+// key was proven to be absent.
+func (v *Verifier) VerifiedGet(ctx context.Context, key string, height uint64) ([]byte, uint64, error) {
+	var (
+		appHash []byte
+		err     error
+	)
+	if height == 0 {
+		height, appHash, err = v.latest(ctx)
+	} else {
+		appHash, err = v.AppHash(ctx, height)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rsp, err := v.Service.ABCIQuery(ctx, &tendermint_proxyv1alpha1.ABCIQueryRequest{
+		// pd accepts hex-encoded keys, which avoids ambiguity for keys that
+		// happen to be valid hex.
+		Data:   []byte(hex.EncodeToString([]byte(key))),
+		Path:   StateKeyPath,
+		Height: int64(height),
+		Prove:  true,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if rsp.GetCode() != 0 {
+		return nil, 0, fmt.Errorf("query for key %q failed: code %d, log: %s", key, rsp.GetCode(), rsp.GetLog())
+	}
+	if uint64(rsp.GetHeight()) != height {
+		return nil, 0, fmt.Errorf("query for key %q answered at height %d, expected %d", key, rsp.GetHeight(), height)
+	}
+
+	proofs, err := ProofsFromOps(rsp.GetProofOps())
+	if err != nil {
+		return nil, 0, err
+	}
+	var value []byte
+	if !provesAbsence(proofs) {
+		value = rsp.GetValue()
+		if value == nil {
+			value = []byte{}
+		}
+	}
+	if err := Verify(proofs, appHash, key, value); err != nil {
+		return nil, 0, err
+	}
+	return value, height, nil
+}
+
+// VerifyKeyValue verifies the response to a `KeyValue` request made with
+// `proof` set, against the app hash of the state it was read from. It
+// returns the value of the key, or nil if the key was proven to be absent.
+func VerifyKeyValue(rsp *cnidariumv1alpha1.KeyValueResponse, key string, appHash []byte) ([]byte, error) {
+	proofs := rsp.GetProof().GetProofs()
+	var value []byte
+	if rsp.GetValue() != nil {
+		value = rsp.GetValue().GetValue()
+		if value == nil {
+			value = []byte{}
+		}
+	}
+	if err := Verify(proofs, appHash, key, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,149 @@
+package cnidarium
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	types "github.com/cosmos/ibc-go/v8/modules/core/23-commitment/types"
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	tmtypes "github.com/penumbra-zone/penumbra/proto/go/gen/tendermint/types"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+)
+
+// fakeProxy answers queries from a testStore, whose app hash is committed
+// to by the header of every block.
+type fakeProxy struct {
+	proxy.Service
+	t       *testing.T
+	store   *testStore
+	appHash []byte
+	latest  uint64
+	// skew is added to the height queries are answered at.
+	skew int64
+	code uint32
+
+	blocks  []int64
+	queries []*tendermint_proxyv1alpha1.ABCIQueryRequest
+}
+
+func (f *fakeProxy) GetStatus(context.Context, *tendermint_proxyv1alpha1.GetStatusRequest) (*tendermint_proxyv1alpha1.GetStatusResponse, error) {
+	return &tendermint_proxyv1alpha1.GetStatusResponse{SyncInfo: &tendermint_proxyv1alpha1.SyncInfo{
+		LatestBlockHeight: f.latest,
+		LatestAppHash:     f.appHash,
+	}}, nil
+}
+
+func (f *fakeProxy) GetBlockByHeight(_ context.Context, req *tendermint_proxyv1alpha1.GetBlockByHeightRequest) (*tendermint_proxyv1alpha1.GetBlockByHeightResponse, error) {
+	f.blocks = append(f.blocks, req.GetHeight())
+	return &tendermint_proxyv1alpha1.GetBlockByHeightResponse{Block: &tmtypes.Block{Header: &tmtypes.Header{AppHash: f.appHash}}}, nil
+}
+
+func (f *fakeProxy) ABCIQuery(_ context.Context, req *tendermint_proxyv1alpha1.ABCIQueryRequest) (*tendermint_proxyv1alpha1.ABCIQueryResponse, error) {
+	f.queries = append(f.queries, req)
+	key, err := hex.DecodeString(string(req.GetData()))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	value, proofs := f.store.getWithProof(string(key))
+	return &tendermint_proxyv1alpha1.ABCIQueryResponse{
+		Code:     f.code,
+		Key:      key,
+		Value:    value,
+		ProofOps: proofOps(f.t, proofs),
+		Height:   req.GetHeight() + f.skew,
+	}, nil
+}
+
+func TestVerifiedGet(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore()
+	p := &fakeProxy{t: t, store: store, appHash: store.appHash(), latest: 11}
+	v := NewVerifier(p)
+
+	// The latest header commits to the state after its parent.
+	value, height, err := v.VerifiedGet(ctx, "ibc-data/key_1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "value_1a" || height != 10 {
+		t.Errorf("VerifiedGet = %q at %d, want value_1a at 10", value, height)
+	}
+	q := p.queries[0]
+	if string(q.GetData()) != hex.EncodeToString([]byte("ibc-data/key_1")) || q.GetPath() != StateKeyPath || q.GetHeight() != 10 || !q.GetProve() {
+		t.Errorf("query = %v", q)
+	}
+
+	// An explicit height takes the app hash from the next block's header.
+	value, height, err = v.VerifiedGet(ctx, "chain/epoch", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "epoch" || height != 7 || len(p.blocks) != 1 || p.blocks[0] != 8 {
+		t.Errorf("VerifiedGet = %q at %d, with headers %v", value, height, p.blocks)
+	}
+
+	for _, key := range []string{"chain/doesntexist", "ibc-data/doesntexist"} {
+		if value, _, err := v.VerifiedGet(ctx, key, 7); err != nil || value != nil {
+			t.Errorf("VerifiedGet(%q) = %q, %v, want a proven absence", key, value, err)
+		}
+	}
+
+	p.skew = 1
+	if _, _, err := v.VerifiedGet(ctx, "ibc-data/key_1", 7); err == nil {
+		t.Errorf("VerifiedGet accepted an answer at another height")
+	}
+	p.skew = 0
+
+	p.code = 1
+	if _, _, err := v.VerifiedGet(ctx, "ibc-data/key_1", 7); err == nil {
+		t.Errorf("VerifiedGet accepted a failed query")
+	}
+	p.code = 0
+
+	p.appHash = bytes.Clone(p.appHash)
+	p.appHash[0] ^= 1
+	for _, height := range []uint64{0, 7} {
+		if _, _, err := v.VerifiedGet(ctx, "ibc-data/key_1", height); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("VerifiedGet at %d against the wrong app hash = %v, want ErrInvalidProof", height, err)
+		}
+	}
+}
+
+func TestVerifyKeyValue(t *testing.T) {
+	store := newTestStore()
+	appHash := store.appHash()
+	response := func(key string) *cnidariumv1alpha1.KeyValueResponse {
+		value, proofs := store.getWithProof(key)
+		rsp := &cnidariumv1alpha1.KeyValueResponse{Proof: &types.MerkleProof{Proofs: proofs}}
+		if value != nil {
+			rsp.Value = &cnidariumv1alpha1.KeyValueResponse_Value{Value: value}
+		}
+		return rsp
+	}
+
+	if value, err := VerifyKeyValue(response("ibc-data/key_1"), "ibc-data/key_1", appHash); err != nil || string(value) != "value_1a" {
+		t.Errorf("VerifyKeyValue = %q, %v", value, err)
+	}
+	if value, err := VerifyKeyValue(response("ibc-data/doesntexist"), "ibc-data/doesntexist", appHash); err != nil || value != nil {
+		t.Errorf("VerifyKeyValue of an absent key = %q, %v", value, err)
+	}
+
+	wrongHash := bytes.Clone(appHash)
+	wrongHash[0] ^= 1
+	if _, err := VerifyKeyValue(response("ibc-data/key_1"), "ibc-data/key_1", wrongHash); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("VerifyKeyValue against the wrong app hash = %v", err)
+	}
+	if _, err := VerifyKeyValue(response("ibc-data/key_1"), "ibc-data/key_2", appHash); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("VerifyKeyValue of the wrong key = %v", err)
+	}
+	// Dropping the value of a present key must not pass as an absence.
+	rsp := response("ibc-data/key_1")
+	rsp.Value = nil
+	if _, err := VerifyKeyValue(rsp, "ibc-data/key_1", appHash); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("VerifyKeyValue without the value = %v", err)
+	}
+}
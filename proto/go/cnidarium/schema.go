@@ -0,0 +1,318 @@
+package cnidarium
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	daov1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dao/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	distributionsv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/distributions/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	sctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/sct/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ErrUnknownKey is returned when a key does not match any pattern of a
+// schema.
+var ErrUnknownKey = errors.New("unknown state key")
+
+// Entry describes the keys matching a pattern and the type of their values.
+type Entry struct {
+	// Pattern is a '/'-separated key pattern, in which a segment of the
+	// form `{name}` matches any single segment.
+	Pattern string
+	Type    protoreflect.MessageType
+
+	segments []string
+}
+
+// Match reports whether a key matches the entry's pattern, and if so
+// returns the values of its wildcard segments by name.
+func (e Entry) Match(key string) (map[string]string, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != len(e.segments) {
+		return nil, false
+	}
+	var vars map[string]string
+	for i, seg := range e.segments {
+		if name, ok := wildcard(seg); ok {
+			if parts[i] == "" {
+				return nil, false
+			}
+			if vars == nil {
+				vars = make(map[string]string)
+			}
+			vars[name] = parts[i]
+			continue
+		}
+		if parts[i] != seg {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+// Prefix returns the literal part of the pattern, up to its first wildcard.
+func (e Entry) Prefix() string {
+	if i := strings.IndexByte(e.Pattern, '{'); i >= 0 {
+		return e.Pattern[:i]
+	}
+	return e.Pattern
+}
+
+func wildcard(seg string) (string, bool) {
+	if len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}' {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
+
+// Key fills the wildcards of the pattern with vars, in order. It panics
+// unless vars has one value per wildcard.
+func (e Entry) Key(vars ...string) string {
+	parts := append([]string(nil), e.segments...)
+	n := 0
+	for i, seg := range parts {
+		if _, ok := wildcard(seg); ok {
+			if n == len(vars) {
+				panic(fmt.Sprintf("cnidarium: too few values for %q", e.Pattern))
+			}
+			parts[i] = vars[n]
+			n++
+		}
+	}
+	if n != len(vars) {
+		panic(fmt.Sprintf("cnidarium: too many values for %q", e.Pattern))
+	}
+	return strings.Join(parts, "/")
+}
+
+// KeyPrefix fills the first len(vars) wildcards of the pattern, and returns
+// the key up to the next wildcard, for scanning the keys under it.
+func (e Entry) KeyPrefix(vars ...string) string {
+	parts := make([]string, 0, len(e.segments))
+	n := 0
+	for _, seg := range e.segments {
+		if _, ok := wildcard(seg); ok {
+			if n == len(vars) {
+				return strings.Join(parts, "/") + "/"
+			}
+			seg = vars[n]
+			n++
+		}
+		parts = append(parts, seg)
+	}
+	if n != len(vars) {
+		panic(fmt.Sprintf("cnidarium: too many values for %q", e.Pattern))
+	}
+	return strings.Join(parts, "/")
+}
+
+// Schema maps state key patterns to the protobuf types of their values.
+type Schema struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewSchema returns an empty schema.
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Register adds a key pattern whose values are encodings of msg's type.
+// Patterns registered later take precedence over earlier ones.
+func (s *Schema) Register(pattern string, msg proto.Message) {
+	e := Entry{
+		Pattern:  pattern,
+		Type:     msg.ProtoReflect().Type(),
+		segments: strings.Split(pattern, "/"),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+}
+
+// Entries returns the registered entries, sorted by pattern.
+func (s *Schema) Entries() []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := append([]Entry(nil), s.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Pattern < entries[j].Pattern })
+	return entries
+}
+
+// Entry returns the entry registered with a pattern. It panics if the
+// pattern is not registered, as callers pass constant patterns.
+func (s *Schema) Entry(pattern string) Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, e := range s.entries {
+		if e.Pattern == pattern {
+			return e
+		}
+	}
+	panic(fmt.Sprintf("cnidarium: pattern %q is not registered", pattern))
+}
+
+// Key returns the key of a registered pattern with its wildcards filled
+// by vars, in order. It panics if the pattern is not registered or vars
+// does not fill every wildcard.
+func (s *Schema) Key(pattern string, vars ...string) string {
+	return s.Entry(pattern).Key(vars...)
+}
+
+// KeyPrefix returns the prefix of the keys of a registered pattern whose
+// first wildcards are filled by vars.
+func (s *Schema) KeyPrefix(pattern string, vars ...string) string {
+	return s.Entry(pattern).KeyPrefix(vars...)
+}
+
+// Lookup returns the entry matching a key.
+func (s *Schema) Lookup(key string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if _, ok := s.entries[i].Match(key); ok {
+			return s.entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// Decode decodes the value of a key into a new message of its registered
+// type.
+func (s *Schema) Decode(key string, value []byte) (proto.Message, error) {
+	e, ok := s.Lookup(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	}
+	msg := e.Type.New().Interface()
+	if err := proto.Unmarshal(value, msg); err != nil {
+		return nil, fmt.Errorf("could not decode %q as %s: %w", key, e.Type.Descriptor().FullName(), err)
+	}
+	return msg, nil
+}
+
+// Roots returns the distinct top-level prefixes of the registered patterns,
+// such as `staking/`, sorted. Scanning every root visits every key of the
+// components described by the schema.
+func (s *Schema) Roots() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool)
+	var roots []string
+	for _, e := range s.entries {
+		root, _, _ := strings.Cut(e.Pattern, "/")
+		root += "/"
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// DefaultSchema describes the verifiable state written by pd's components,
+// following each component's `state_key` module. Integers and strings
+// written with `put_proto` are encoded as the corresponding protobuf
+// wrapper types. Keys in the IBC substore are not described.
+var DefaultSchema = NewSchema()
+
+func init() {
+	u64 := &wrapperspb.UInt64Value{}
+
+	// penumbra_app
+	DefaultSchema.Register("genesis/app_state", &appv1alpha1.GenesisAppState{})
+
+	// penumbra_chain
+	DefaultSchema.Register("chain/params", &chainv1alpha1.ChainParameters{})
+	DefaultSchema.Register("chain/block_height", u64)
+	DefaultSchema.Register("chain/block_timestamp", &wrapperspb.StringValue{})
+	DefaultSchema.Register("chain/fmd_parameters/current", &chainv1alpha1.FmdParameters{})
+	DefaultSchema.Register("chain/fmd_parameters/previous", &chainv1alpha1.FmdParameters{})
+	DefaultSchema.Register("chain/halt_count", u64)
+	DefaultSchema.Register("chain/epoch_by_height/{height}", &chainv1alpha1.Epoch{})
+
+	// penumbra_fee
+	DefaultSchema.Register("fee/params", &feev1alpha1.FeeParameters{})
+	DefaultSchema.Register("fee/gas_prices", &feev1alpha1.GasPrices{})
+
+	// penumbra_distributions
+	DefaultSchema.Register("distributions/parameters", &distributionsv1alpha1.DistributionsParameters{})
+
+	// penumbra_dao
+	DefaultSchema.Register("dao/params", &daov1alpha1.DaoParameters{})
+	DefaultSchema.Register("dao/asset/{asset_id}", &numv1alpha1.Amount{})
+
+	// penumbra_shielded_pool
+	DefaultSchema.Register("shielded_pool/assets/{asset_id}/token_supply", &numv1alpha1.Amount{})
+	DefaultSchema.Register("shielded_pool/assets/{asset_id}/denom", &assetv1alpha1.DenomMetadata{})
+
+	// penumbra_sct
+	DefaultSchema.Register("sct/nf/{nullifier}", &sctv1alpha1.NullificationInfo{})
+	DefaultSchema.Register("sct/anchor/{height}", &tctv1alpha1.MerkleRoot{})
+	DefaultSchema.Register("sct/valid_anchors/{anchor}", u64)
+
+	// penumbra_stake
+	DefaultSchema.Register("staking/params", &stakev1alpha1.StakeParameters{})
+	DefaultSchema.Register("staking/base_rate/current", &stakev1alpha1.BaseRateData{})
+	DefaultSchema.Register("staking/base_rate/next", &stakev1alpha1.BaseRateData{})
+	DefaultSchema.Register("staking/validator/{identity_key}", &stakev1alpha1.Validator{})
+	DefaultSchema.Register("staking/penalty_in_epoch/{identity_key}/{epoch}", &stakev1alpha1.Penalty{})
+	DefaultSchema.Register("staking/validator_state/{identity_key}", &stakev1alpha1.ValidatorState{})
+	DefaultSchema.Register("staking/validator_rate/current/{identity_key}", &stakev1alpha1.RateData{})
+	DefaultSchema.Register("staking/validator_rate/next/{identity_key}", &stakev1alpha1.RateData{})
+	DefaultSchema.Register("staking/validator_power/{identity_key}", u64)
+	DefaultSchema.Register("staking/validator_bonding_state/{identity_key}", &stakev1alpha1.BondingState{})
+	DefaultSchema.Register("staking/validator_uptime/{identity_key}", &stakev1alpha1.Uptime{})
+	DefaultSchema.Register("staking/validator_id_by_consensus_key/{consensus_key}", &keysv1alpha1.IdentityKey{})
+	DefaultSchema.Register("staking/consensus_key_by_tendermint_address/{address}", &keysv1alpha1.ConsensusKey{})
+	// pd reserves this key for the validators slashed at a height, but
+	// does not write it yet; a list of identity keys is a ValidatorList.
+	DefaultSchema.Register("staking/slashed_validators/{height}", &stakev1alpha1.ValidatorList{})
+	DefaultSchema.Register("staking/delegation_changes/{height}", &stakev1alpha1.DelegationChanges{})
+	DefaultSchema.Register("staking/current_consensus_keys", &stakev1alpha1.CurrentConsensusKeys{})
+
+	// penumbra_governance
+	DefaultSchema.Register("governance/params", &governancev1alpha1.GovernanceParameters{})
+	DefaultSchema.Register("governance/next_proposal_id", u64)
+	DefaultSchema.Register("governance/proposal/{proposal_id}/data", &governancev1alpha1.Proposal{})
+	DefaultSchema.Register("governance/proposal/{proposal_id}/dao_transaction", &transactionv1alpha1.Transaction{})
+	DefaultSchema.Register("governance/proposal/{proposal_id}/state", &governancev1alpha1.ProposalState{})
+	DefaultSchema.Register("governance/proposal/{proposal_id}/deposit_amount", &numv1alpha1.Amount{})
+	DefaultSchema.Register("governance/proposal/{proposal_id}/voting_start", u64)
+	DefaultSchema.Register("governance/proposal/{proposal_id}/voting_start_position", u64)
+	DefaultSchema.Register("governance/proposal/{proposal_id}/voting_end", u64)
+	DefaultSchema.Register("governance/proposal/{proposal_id}/voted_nullifiers/{nullifier}", u64)
+	DefaultSchema.Register("governance/proposal/{proposal_id}/rate_data_at_start/{identity_key}", &stakev1alpha1.RateData{})
+	DefaultSchema.Register("governance/proposal/{proposal_id}/voting_power_at_start/{identity_key}", u64)
+	DefaultSchema.Register("governance/unfinished_proposals/{proposal_id}", &emptypb.Empty{})
+	DefaultSchema.Register("governance/validator_vote/{proposal_id}/{identity_key}", &governancev1alpha1.Vote{})
+	DefaultSchema.Register("governance/validator_vote_reason/{proposal_id}/{identity_key}", &governancev1alpha1.ValidatorVoteReason{})
+	DefaultSchema.Register("governance/tallied_delegator_votes/{proposal_id}/{identity_key}", &governancev1alpha1.Tally{})
+	DefaultSchema.Register("governance/untallied_delegator_vote/{proposal_id}/{identity_key}/{nullifier}", &governancev1alpha1.Tally{})
+	DefaultSchema.Register("governance/deliver_dao_transactions/{height}/{proposal_id}", u64)
+	DefaultSchema.Register("app/change_app_params/{height}/", &governancev1alpha1.ChangedAppParametersSet{})
+
+	// penumbra_dex
+	DefaultSchema.Register("dex/position/{position_id}", &dexv1alpha1.Position{})
+	DefaultSchema.Register("dex/output/{height}/{asset_1}/{asset_2}", &dexv1alpha1.BatchSwapOutputData{})
+	DefaultSchema.Register("dex/swap_execution/{height}/{start}/{end}", &dexv1alpha1.SwapExecution{})
+	DefaultSchema.Register("dex/arb_execution/{height}", &dexv1alpha1.SwapExecution{})
+}
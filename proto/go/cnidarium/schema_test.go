@@ -0,0 +1,255 @@
+package cnidarium
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEntryMatch(t *testing.T) {
+	e, ok := DefaultSchema.Lookup("staking/penalty_in_epoch/penumbravalid1abc/12")
+	if !ok || e.Pattern != "staking/penalty_in_epoch/{identity_key}/{epoch}" {
+		t.Fatalf("Lookup = %q, %v", e.Pattern, ok)
+	}
+	vars, _ := e.Match("staking/penalty_in_epoch/penumbravalid1abc/12")
+	if want := map[string]string{"identity_key": "penumbravalid1abc", "epoch": "12"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("Match vars = %v, want %v", vars, want)
+	}
+	if got := e.Prefix(); got != "staking/penalty_in_epoch/" {
+		t.Errorf("Prefix = %q", got)
+	}
+
+	for _, key := range []string{
+		"staking/penalty_in_epoch/penumbravalid1abc",
+		"staking/penalty_in_epoch//12",
+		"staking/penalty_in_epoch/penumbravalid1abc/12/extra",
+	} {
+		if _, ok := e.Match(key); ok {
+			t.Errorf("%q matches %q", key, e.Pattern)
+		}
+	}
+	if _, ok := DefaultSchema.Lookup("ibc/clients/07-tendermint-0"); ok {
+		t.Errorf("the IBC substore is described")
+	}
+	// The trailing slash of pd's key is part of the pattern.
+	if _, ok := DefaultSchema.Lookup("app/change_app_params/10/"); !ok {
+		t.Errorf("app/change_app_params/10/ is not described")
+	}
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	tests := []struct {
+		key string
+		msg proto.Message
+	}{
+		{"chain/block_height", wrapperspb.UInt64(1234)},
+		{"dao/asset/passet1abc", &numv1alpha1.Amount{Lo: 5, Hi: 1}},
+		{"staking/validator_state/penumbravalid1abc", &stakev1alpha1.ValidatorState{State: stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE}},
+		{"staking/validator_uptime/penumbravalid1abc", &stakev1alpha1.Uptime{AsOfBlockHeight: 9, WindowLen: 4, Bitvec: []byte{0xff}}},
+	}
+	for _, tt := range tests {
+		value, err := proto.Marshal(tt.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DefaultSchema.Decode(tt.key, value)
+		if err != nil {
+			t.Errorf("Decode(%q): %v", tt.key, err)
+			continue
+		}
+		if !proto.Equal(got, tt.msg) {
+			t.Errorf("Decode(%q) = %v, want %v", tt.key, got, tt.msg)
+		}
+	}
+
+	if _, err := DefaultSchema.Decode("unknown/key", nil); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decode of an unknown key = %v, want ErrUnknownKey", err)
+	}
+	if got := DefaultSchema.Format("unknown/key", []byte{0xab}); got != "ab" {
+		t.Errorf("Format of an unknown key = %q, want hex", got)
+	}
+	if got := DefaultSchema.Format("chain/block_height", []byte{0x08, 0x07}); got != `"7"` {
+		t.Errorf("Format(chain/block_height) = %q", got)
+	}
+}
+
+func TestSchemaPrecedence(t *testing.T) {
+	s := NewSchema()
+	s.Register("a/{x}", &numv1alpha1.Amount{})
+	s.Register("a/b", wrapperspb.UInt64(0))
+	s.Register("c/{y}/d", &numv1alpha1.Amount{})
+	if e, _ := s.Lookup("a/b"); e.Pattern != "a/b" {
+		t.Errorf("Lookup(a/b) = %q, want the later pattern", e.Pattern)
+	}
+	if e, _ := s.Lookup("a/c"); e.Pattern != "a/{x}" {
+		t.Errorf("Lookup(a/c) = %q", e.Pattern)
+	}
+	if got, want := s.Roots(), []string{"a/", "c/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots = %v, want %v", got, want)
+	}
+}
+
+// fakeState serves keys from a map, in key order.
+type fakeState struct {
+	keys   []string
+	values map[string][]byte
+}
+
+func (f *fakeState) KeyValue(_ context.Context, req *cnidariumv1alpha1.KeyValueRequest) (*cnidariumv1alpha1.KeyValueResponse, error) {
+	rsp := &cnidariumv1alpha1.KeyValueResponse{}
+	if v, ok := f.values[req.GetKey()]; ok {
+		rsp.Value = &cnidariumv1alpha1.KeyValueResponse_Value{Value: v}
+	}
+	return rsp, nil
+}
+
+func (f *fakeState) PrefixValue(_ context.Context, req *cnidariumv1alpha1.PrefixValueRequest) (grpcclient.Stream[cnidariumv1alpha1.PrefixValueResponse], error) {
+	s := &fakeStream{}
+	for _, k := range f.keys {
+		if strings.HasPrefix(k, req.GetPrefix()) {
+			s.rsps = append(s.rsps, &cnidariumv1alpha1.PrefixValueResponse{Key: k, Value: f.values[k]})
+		}
+	}
+	return s, nil
+}
+
+type fakeStream struct {
+	rsps []*cnidariumv1alpha1.PrefixValueResponse
+}
+
+func (s *fakeStream) Recv() (*cnidariumv1alpha1.PrefixValueResponse, error) {
+	if len(s.rsps) == 0 {
+		return nil, io.EOF
+	}
+	rsp := s.rsps[0]
+	s.rsps = s.rsps[1:]
+	return rsp, nil
+}
+
+func TestGetAndScan(t *testing.T) {
+	height, _ := proto.Marshal(wrapperspb.UInt64(42))
+	a, _ := proto.Marshal(&numv1alpha1.Amount{Lo: 1})
+	b, _ := proto.Marshal(&numv1alpha1.Amount{Lo: 2})
+	state := &fakeState{
+		keys: []string{"chain/block_height", "dao/asset/a", "dao/asset/b"},
+		values: map[string][]byte{
+			"chain/block_height": height,
+			"dao/asset/a":        a,
+			"dao/asset/b":        b,
+		},
+	}
+	ctx := context.Background()
+
+	got, err := Get[*wrapperspb.UInt64Value](ctx, state, "chain/block_height")
+	if err != nil || got.GetValue() != 42 {
+		t.Errorf("Get(chain/block_height) = %v, %v", got, err)
+	}
+	if _, err := Get[*numv1alpha1.Amount](ctx, state, "chain/block_height"); err == nil {
+		t.Errorf("Get accepted a type other than the registered one")
+	}
+	if _, err := Get[*wrapperspb.UInt64Value](ctx, state, "chain/halt_count"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of an absent key = %v, want ErrNotFound", err)
+	}
+
+	var total uint64
+	err = Scan(ctx, state, "dao/asset/", func(key string, value *numv1alpha1.Amount) error {
+		total += value.GetLo()
+		return nil
+	})
+	if err != nil || total != 3 {
+		t.Errorf("Scan(dao/asset/) summed %d, %v", total, err)
+	}
+
+	var out strings.Builder
+	if err := DumpPrefixes(ctx, state, DefaultSchema, []string{"chain/"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "chain/block_height = \"42\"\n"; out.String() != want {
+		t.Errorf("DumpPrefixes wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		vars    []string
+		key     string
+		prefix  []string
+		want    string
+	}{
+		{
+			pattern: "staking/validator_state/{identity_key}",
+			vars:    []string{"penumbravalid1abc"},
+			key:     "staking/validator_state/penumbravalid1abc",
+			want:    "staking/validator_state/",
+		},
+		{
+			pattern: "governance/untallied_delegator_vote/{proposal_id}/{identity_key}/{nullifier}",
+			vars:    []string{"00000000000000000007", "penumbravalid1abc", "nf"},
+			key:     "governance/untallied_delegator_vote/00000000000000000007/penumbravalid1abc/nf",
+			prefix:  []string{"00000000000000000007"},
+			want:    "governance/untallied_delegator_vote/00000000000000000007/",
+		},
+		{
+			pattern: "staking/slashed_validators/{height}",
+			vars:    []string{"12"},
+			key:     "staking/slashed_validators/12",
+			want:    "staking/slashed_validators/",
+		},
+		{
+			pattern: "staking/consensus_key_by_tendermint_address/{address}",
+			vars:    []string{"00ff"},
+			key:     "staking/consensus_key_by_tendermint_address/00ff",
+			want:    "staking/consensus_key_by_tendermint_address/",
+		},
+		{
+			pattern: "app/change_app_params/{height}/",
+			vars:    []string{"10"},
+			key:     "app/change_app_params/10/",
+			want:    "app/change_app_params/",
+		},
+		{
+			pattern: "chain/block_height",
+			key:     "chain/block_height",
+			want:    "chain/block_height",
+		},
+	}
+	for _, tt := range tests {
+		key := DefaultSchema.Key(tt.pattern, tt.vars...)
+		if key != tt.key {
+			t.Errorf("Key(%q, %q) = %q, want %q", tt.pattern, tt.vars, key, tt.key)
+		}
+		if e, ok := DefaultSchema.Lookup(key); !ok || e.Pattern != tt.pattern {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", key, e.Pattern, ok, tt.pattern)
+		}
+		if got := DefaultSchema.KeyPrefix(tt.pattern, tt.prefix...); got != tt.want {
+			t.Errorf("KeyPrefix(%q, %q) = %q, want %q", tt.pattern, tt.prefix, got, tt.want)
+		}
+	}
+
+	for _, f := range []func(){
+		func() { DefaultSchema.Key("staking/unregistered/{x}", "a") },
+		func() { DefaultSchema.Key("staking/validator_state/{identity_key}") },
+		func() { DefaultSchema.Key("staking/validator_state/{identity_key}", "a", "b") },
+		func() { DefaultSchema.KeyPrefix("chain/block_height", "a") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("misuse of Key or KeyPrefix did not panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,37 @@
+package cnidarium
+
+import (
+	"context"
+
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of cnidarium's QueryService.
+const QueryServiceName = "penumbra.cnidarium.v1alpha1.QueryService"
+
+// QueryService is cnidarium's QueryService API. The responses to a
+// PrefixValue request are streamed in key order.
+type QueryService interface {
+	KeyValue(ctx context.Context, req *cnidariumv1alpha1.KeyValueRequest) (*cnidariumv1alpha1.KeyValueResponse, error)
+	PrefixValue(ctx context.Context, req *cnidariumv1alpha1.PrefixValueRequest) (grpcclient.Stream[cnidariumv1alpha1.PrefixValueResponse], error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) KeyValue(ctx context.Context, req *cnidariumv1alpha1.KeyValueRequest) (*cnidariumv1alpha1.KeyValueResponse, error) {
+	return grpcclient.Call[cnidariumv1alpha1.KeyValueResponse](ctx, s.svc, "KeyValue", req)
+}
+
+func (s *grpcQueryService) PrefixValue(ctx context.Context, req *cnidariumv1alpha1.PrefixValueRequest) (grpcclient.Stream[cnidariumv1alpha1.PrefixValueResponse], error) {
+	return grpcclient.OpenStream[cnidariumv1alpha1.PrefixValueResponse](ctx, s.svc, "PrefixValue", req)
+}
@@ -0,0 +1,130 @@
+package cnidarium
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotFound is returned by Get when a key is absent from the state.
+var ErrNotFound = errors.New("key not found")
+
+// newMessage returns a new, empty message of type T.
+func newMessage[T proto.Message]() T {
+	var zero T
+	return zero.ProtoReflect().Type().New().Interface().(T)
+}
+
+// checkType returns an error if DefaultSchema registers a key with a type
+// other than T.
+func checkType[T proto.Message](key string, msg T) error {
+	e, ok := DefaultSchema.Lookup(key)
+	if !ok {
+		return nil
+	}
+	if got, want := msg.ProtoReflect().Descriptor().FullName(), e.Type.Descriptor().FullName(); got != want {
+		return fmt.Errorf("key %q holds a %s, not a %s", key, want, got)
+	}
+	return nil
+}
+
+// Get reads a key from the latest state and decodes its value as a T. Keys
+// described by DefaultSchema must be read as their registered type.
+func Get[T proto.Message](ctx context.Context, q QueryService, key string) (T, error) {
+	msg := newMessage[T]()
+	if err := checkType(key, msg); err != nil {
+		return msg, err
+	}
+	rsp, err := q.KeyValue(ctx, &cnidariumv1alpha1.KeyValueRequest{Key: key})
+	if err != nil {
+		return msg, err
+	}
+	if rsp.GetValue() == nil {
+		return msg, fmt.Errorf("%w: %q", ErrNotFound, key)
+	}
+	if err := proto.Unmarshal(rsp.GetValue().GetValue(), msg); err != nil {
+		return msg, fmt.Errorf("could not decode %q: %w", key, err)
+	}
+	return msg, nil
+}
+
+// Scan calls fn with every key under a prefix, in key order, with its value
+// decoded as a T. It stops at the first error returned by fn.
+func Scan[T proto.Message](ctx context.Context, q QueryService, prefix string, fn func(key string, value T) error) error {
+	return scanRaw(ctx, q, prefix, func(key string, value []byte) error {
+		msg := newMessage[T]()
+		if err := checkType(key, msg); err != nil {
+			return err
+		}
+		if err := proto.Unmarshal(value, msg); err != nil {
+			return fmt.Errorf("could not decode %q: %w", key, err)
+		}
+		return fn(key, msg)
+	})
+}
+
+// scanRaw calls fn with every key under a prefix and its encoded value.
+func scanRaw(ctx context.Context, q QueryService, prefix string, fn func(key string, value []byte) error) error {
+	// Cancel the stream if fn stops the scan early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := q.PrefixValue(ctx, &cnidariumv1alpha1.PrefixValueRequest{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(rsp.GetKey(), rsp.GetValue()); err != nil {
+			return err
+		}
+	}
+}
+
+// Dump writes every key under the roots of a schema to w, one per line, as
+// `key = value`. Values of known keys are written as ProtoJSON; other values
+// are written as hex.
+func Dump(ctx context.Context, q QueryService, schema *Schema, w io.Writer) error {
+	return DumpPrefixes(ctx, q, schema, schema.Roots(), w)
+}
+
+// DumpPrefixes is like Dump, but only writes the keys under the given
+// prefixes.
+func DumpPrefixes(ctx context.Context, q QueryService, schema *Schema, prefixes []string, w io.Writer) error {
+	for _, prefix := range prefixes {
+		err := scanRaw(ctx, q, prefix, func(key string, value []byte) error {
+			_, err := fmt.Fprintf(w, "%s = %s\n", key, schema.Format(key, value))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("could not dump %q: %w", prefix, err)
+		}
+	}
+	return nil
+}
+
+// Format renders the value of a key as ProtoJSON if the schema can decode it,
+// and as hex otherwise.
+func (s *Schema) Format(key string, value []byte) string {
+	msg, err := s.Decode(key, value)
+	if err != nil {
+		return hex.EncodeToString(value)
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return hex.EncodeToString(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,34 @@
+// Package compactblock provides a client for the compact block component,
+// which serves the per-block data that clients scan.
+package compactblock
+
+import (
+	"context"
+
+	compact_blockv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/compact_block/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of the compact block component's
+// QueryService.
+const QueryServiceName = "penumbra.core.component.compact_block.v1alpha1.QueryService"
+
+// QueryService is the compact block component's QueryService API.
+type QueryService interface {
+	CompactBlockRange(ctx context.Context, req *compact_blockv1alpha1.CompactBlockRangeRequest) (grpcclient.Stream[compact_blockv1alpha1.CompactBlockRangeResponse], error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) CompactBlockRange(ctx context.Context, req *compact_blockv1alpha1.CompactBlockRangeRequest) (grpcclient.Stream[compact_blockv1alpha1.CompactBlockRangeResponse], error) {
+	return grpcclient.OpenStream[compact_blockv1alpha1.CompactBlockRangeResponse](ctx, s.svc, "CompactBlockRange", req)
+}
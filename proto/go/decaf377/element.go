@@ -0,0 +1,213 @@
+package decaf377
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ElementLen is the length of an encoded group element.
+const ElementLen = 32
+
+// ErrInvalidEncoding is returned when bytes are not the canonical encoding of
+// a group element.
+var ErrInvalidEncoding = errors.New("invalid decaf377 encoding")
+
+var (
+	// The curve is -x^2 + y^2 = 1 + d x^2 y^2 over Fq.
+	curveA = fq(-1)
+	curveD = fq(3021)
+	// zeta is the non-square used by sqrtRatioZeta.
+	zeta, _ = new(big.Int).SetString("2841681278031794617739547238867782961338435681360110683443920362658525667816", 10)
+
+	basepointX, _ = new(big.Int).SetString("4959445789346820725352484487855828915252512307947624787834978378872129235627", 10)
+	basepointY, _ = new(big.Int).SetString("6060471950081851567114691557659790004756535011754163002297540472747064943288", 10)
+)
+
+// Element is an element of the decaf377 group, held as an internal
+// representative on the Edwards curve in extended coordinates (X:Y:Z:T).
+// Distinct representatives of the same element compare equal and encode
+// identically.
+//
+// Arithmetic is on big.Int and is not constant time.
+type Element struct {
+	x, y, z, t *big.Int
+}
+
+// Identity returns the identity element.
+func Identity() *Element {
+	return &Element{x: fq(0), y: fq(1), z: fq(1), t: fq(0)}
+}
+
+// Basepoint returns the conventional generator, encoded as 0x08 followed by
+// zeros.
+func Basepoint() *Element {
+	return fromAffine(basepointX, basepointY)
+}
+
+func fromAffine(x, y *big.Int) *Element {
+	return &Element{x: fq(0).Set(x), y: fq(0).Set(y), z: fq(1), t: mul(x, y)}
+}
+
+// Affine returns the affine coordinates of the element's internal
+// representative.
+func (p *Element) Affine() (x, y *big.Int) {
+	zInv := inv(p.z)
+	return mul(p.x, zInv), mul(p.y, zInv)
+}
+
+// Add returns p + q.
+func (p *Element) Add(q *Element) *Element {
+	a := mul(p.x, q.x)
+	b := mul(p.y, q.y)
+	c := mul(curveD, mul(p.t, q.t))
+	d := mul(p.z, q.z)
+	e := sub(sub(mul(add(p.x, p.y), add(q.x, q.y)), a), b)
+	f := sub(d, c)
+	g := add(d, c)
+	h := sub(b, mul(curveA, a))
+	return &Element{x: mul(e, f), y: mul(g, h), z: mul(f, g), t: mul(e, h)}
+}
+
+// Neg returns -p.
+func (p *Element) Neg() *Element {
+	return &Element{x: neg(p.x), y: fq(0).Set(p.y), z: fq(0).Set(p.z), t: neg(p.t)}
+}
+
+// ScalarMul returns [k]p. Scalars are taken modulo the group order.
+func (p *Element) ScalarMul(k *big.Int) *Element {
+	k = new(big.Int).Mod(k, FrModulus)
+	acc := Identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		acc = acc.Add(acc)
+		if k.Bit(i) == 1 {
+			acc = acc.Add(p)
+		}
+	}
+	return acc
+}
+
+// Equal reports whether p and q are the same group element.
+func (p *Element) Equal(q *Element) bool {
+	return mul(p.x, q.y).Cmp(mul(p.y, q.x)) == 0
+}
+
+// Encode returns the canonical 32-byte encoding of p.
+func (p *Element) Encode() []byte {
+	return EncodeScalar(p.CompressToField())
+}
+
+// CompressToField returns the encoding of p as an element of Fq, as
+// `vartime_compress_to_field` does.
+func (p *Element) CompressToField() *big.Int {
+	aMinusD := sub(curveA, curveD)
+	u1 := mul(add(p.x, p.t), sub(p.x, p.t))
+	_, v := sqrtRatioZeta(fq(1), mul(mul(u1, aMinusD), mul(p.x, p.x)))
+	u2 := abs(mul(v, u1))
+	u3 := sub(mul(u2, p.z), p.t)
+	return abs(mul(mul(mul(aMinusD, v), u3), p.x))
+}
+
+// Decode decodes the canonical encoding of a group element.
+func Decode(b []byte) (*Element, error) {
+	if len(b) != ElementLen || b[ElementLen-1]>>5 != 0 {
+		return nil, ErrInvalidEncoding
+	}
+	s, ok := DecodeScalar(b, FqModulus)
+	if !ok || isNegative(s) {
+		return nil, ErrInvalidEncoding
+	}
+	ss := mul(s, s)
+	u1 := add(fq(1), mul(curveA, ss))
+	u2 := sub(mul(u1, u1), mul(fq(4), mul(curveD, ss)))
+	square, v := sqrtRatioZeta(fq(1), mul(u2, mul(u1, u1)))
+	if !square {
+		return nil, ErrInvalidEncoding
+	}
+	if isNegative(mul(mul(fq(2), s), mul(u1, v))) {
+		v = neg(v)
+	}
+	x := mul(mul(fq(2), s), mul(mul(v, v), mul(u1, u2)))
+	y := mul(mul(sub(fq(1), mul(curveA, ss)), v), u1)
+	return fromAffine(x, y), nil
+}
+
+// EncodeToCurve maps a field element to a group element with a single
+// application of the Elligator map, as `Element::encode_to_curve` does.
+func EncodeToCurve(r0 *big.Int) *Element {
+	r0 = new(big.Int).Mod(r0, FqModulus)
+	r := mul(zeta, mul(r0, r0))
+	dr := mul(curveD, r)
+	u1 := mul(add(sub(dr, curveD), curveA), sub(sub(dr, mul(curveA, r)), curveD))
+	aMinus2D := sub(curveA, mul(fq(2), curveD))
+	n1 := mul(add(r, fq(1)), aMinus2D)
+	square, x := sqrtRatioZeta(fq(1), mul(u1, n1))
+	q := fq(1)
+	if !square {
+		q = fq(-1)
+		x = mul(r0, x)
+	}
+	s := mul(x, n1)
+	t := sub(neg(mul(mul(mul(q, x), s), mul(sub(r, fq(1)), mul(aMinus2D, aMinus2D)))), fq(1))
+	if isNegative(s) == square {
+		s = neg(s)
+	}
+	// From the Jacobi quartic point (s, t) to extended coordinates.
+	ss := mul(s, s)
+	e := mul(fq(2), s)
+	f := add(fq(1), mul(curveA, ss))
+	g := sub(fq(1), mul(curveA, ss))
+	return &Element{x: mul(e, t), y: mul(f, g), z: mul(f, t), t: mul(e, g)}
+}
+
+// sqrtRatioZeta returns (true, sqrt(n/d)) if n/d is square, (false,
+// sqrt(zeta n/d)) if it is not, (true, 0) if n is zero and (false, 0) if d
+// is zero. The sign of the root is unspecified.
+func sqrtRatioZeta(n, d *big.Int) (bool, *big.Int) {
+	if n.Sign() == 0 {
+		return true, fq(0)
+	}
+	if d.Sign() == 0 {
+		return false, fq(0)
+	}
+	u := mul(n, inv(d))
+	if root := new(big.Int).ModSqrt(u, FqModulus); root != nil {
+		return true, root
+	}
+	return false, new(big.Int).ModSqrt(mul(zeta, u), FqModulus)
+}
+
+// isNegative reports whether the canonical encoding of x is odd.
+func isNegative(x *big.Int) bool {
+	return x.Bit(0) == 1
+}
+
+func abs(x *big.Int) *big.Int {
+	if isNegative(x) {
+		return neg(x)
+	}
+	return x
+}
+
+func fq(v int64) *big.Int {
+	return new(big.Int).Mod(big.NewInt(v), FqModulus)
+}
+
+func add(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Add(a, b), FqModulus)
+}
+
+func sub(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Sub(a, b), FqModulus)
+}
+
+func mul(a, b *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(a, b), FqModulus)
+}
+
+func neg(a *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Neg(a), FqModulus)
+}
+
+func inv(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(a, FqModulus)
+}
@@ -0,0 +1,142 @@
+package decaf377
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// Test vectors from the decaf377 section of the protocol specification.
+var basepointMultiples = []string{
+	"0000000000000000000000000000000000000000000000000000000000000000",
+	"0800000000000000000000000000000000000000000000000000000000000000",
+	"b2ecf9b9082d6306538be73b0d6ee741141f3222152da78685d6596efc8c1506",
+	"2ebd42dd3a2307083c834e79fb9e787e352dd33e0d719f86ae4adb02fe382409",
+	"6acd327d70f9588fac373d165f4d9d5300510274dffdfdf2bf0955acd78da50d",
+	"460f913e516441c286d95dd30b0a2d2bf14264f325528b06455d7cb93ba13a0b",
+	"ec8798bcbb3bf29329549d769f89cf7993e15e2c68ec7aa2a956edf5ec62ae07",
+	"48b01e513dd37d94c3b48940dc133b92ccba7f546e99d3fc2e602d284f609f00",
+	"a4e85dddd19c80ecf5ef10b9d27b6626ac1a4f90bd10d263c717ecce4da6570a",
+	"1a8fea8cbfbc91236d8c7924e3e7e617f9dd544b710ee83827737fe8dc63ae00",
+	"0a0f86eaac0c1af30eb138467c49381edb2808904c81a4b81d2b02a2d7816006",
+	"588125a8f4e2bab8d16affc4ca60c5f64b50d38d2bb053148021631f72e99b06",
+	"f43f4cefbe7326eaab1584722b1b4860de554b23a14490a03f3fd63a089add0b",
+	"76c739a33ffd15cf6554a8e705dc573f26490b64de0c5bd4e4ac75ed5af8e60b",
+	"200136952d18d3f6c70347032ba3fef4f60c240d706be2950b4f42f1a7087705",
+	"bcb0f922df1c7aa9579394020187a2e19e2d8073452c6ab9b0c4b052aa50f505",
+}
+
+func TestBasepointMultiples(t *testing.T) {
+	b := Basepoint()
+	p := Identity()
+	for i, want := range basepointMultiples {
+		if got := hex.EncodeToString(p.Encode()); got != want {
+			t.Errorf("[%d]B encodes to %s, want %s", i, got, want)
+		}
+		if got := hex.EncodeToString(b.ScalarMul(big.NewInt(int64(i))).Encode()); got != want {
+			t.Errorf("ScalarMul(%d) encodes to %s, want %s", i, got, want)
+		}
+		p = p.Add(b)
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for i, enc := range basepointMultiples {
+		b, _ := hex.DecodeString(enc)
+		p, err := Decode(b)
+		if err != nil {
+			t.Fatalf("Decode([%d]B): %v", i, err)
+		}
+		if got := hex.EncodeToString(p.Encode()); got != enc {
+			t.Errorf("[%d]B re-encodes to %s", i, got)
+		}
+		if !p.Equal(Basepoint().ScalarMul(big.NewInt(int64(i)))) {
+			t.Errorf("Decode([%d]B) is not [%d]B", i, i)
+		}
+	}
+}
+
+func TestDecodeRejectsNonCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  string
+	}{
+		// 1 is negative, so its negation 0x01 is not a valid s.
+		{"negative", "0100000000000000000000000000000000000000000000000000000000000000"},
+		{"top bits set", "08000000000000000000000000000000000000000000000000000000000000e0"},
+		{"short", "0800"},
+		// The modulus q itself, which is not reduced.
+		{"unreduced", hex.EncodeToString(EncodeScalar(FqModulus))},
+	}
+	for _, tt := range tests {
+		b, _ := hex.DecodeString(tt.enc)
+		if _, err := Decode(b); err == nil {
+			t.Errorf("%s: Decode(%s) succeeded", tt.name, tt.enc)
+		}
+	}
+}
+
+func TestEncodeToCurve(t *testing.T) {
+	tests := []struct {
+		input, x, y string
+	}{
+		{
+			"2873166235834220037104482467644394559952202754715866736878534498814378075613",
+			"1267955849280145133999011095767946180059440909377398529682813961428156596086",
+			"5356565093348124788258444273601808083900527100008973995409157974880178412098",
+		},
+		{
+			"7664634080946480262422274939177258683377350652451958930279692300451854076695",
+			"1502379126429822955521756759528876454108853047288874182661923263559139887582",
+			"7074060208122316523843780248565740332109149189893811936352820920606931717751",
+		},
+		{
+			"707087697291448463178823336344479808196630248514167087002061771344499604401",
+			"2943006201157313879823661217587757631000260143892726691725524748591717287835",
+			"4988568968545687084099497807398918406354768651099165603393269329811556860241",
+		},
+		{
+			"4040687156656275865790182426684295234932961916167736272791705576788972921292",
+			"2893226299356126359042735859950249532894422276065676168505232431940642875576",
+			"5540423804567408742733533031617546054084724133604190833318816134173899774745",
+		},
+		{
+			"6012393175004325154204026250961812614679561282637871416475605431319079196219",
+			"2950911977149336430054248283274523588551527495862004038190631992225597951816",
+			"4487595759841081228081250163499667279979722963517149877172642608282938805393",
+		},
+		{
+			"7255180635786717958849398836099816771666363291918359850790043721721417277258",
+			"3318574188155535806336376903248065799756521242795466350457330678746659358665",
+			"7706453242502782485686954136003233626318476373744684895503194201695334921001",
+		},
+		{
+			"6609366864829739556945402594963920739176902000316365292959221199804402230199",
+			"3753408652523927772367064460787503971543824818235418436841486337042861871179",
+			"2820605049615187268236268737743168629279853653807906481532750947771625104256",
+		},
+		{
+			"6875465950337820928985371259904709015074922314668494500948688901607284806973",
+			"7803875556376973796629423752730968724982795310878526731231718944925551226171",
+			"7033839813997913565841973681083930410776455889380940679209912201081069572111",
+		},
+	}
+	for _, tt := range tests {
+		r0, _ := new(big.Int).SetString(tt.input, 10)
+		x, y := EncodeToCurve(r0).Affine()
+		if x.String() != tt.x || y.String() != tt.y {
+			t.Errorf("EncodeToCurve(%s) = (%s, %s), want (%s, %s)", tt.input, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestGroupLaw(t *testing.T) {
+	b := Basepoint()
+	order := b.ScalarMul(new(big.Int).Sub(FrModulus, big.NewInt(1))).Add(b)
+	if !order.Equal(Identity()) {
+		t.Errorf("[r]B is not the identity")
+	}
+	if !b.Add(b.Neg()).Equal(Identity()) {
+		t.Errorf("B - B is not the identity")
+	}
+}
@@ -0,0 +1,82 @@
+// Package decaf377 implements the decaf377 group and its scalar fields, as
+// specified in the cryptography section of the protocol specification: the
+// group operations, encoding and decoding, and the Elligator map used to
+// hash to the group.
+package decaf377
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+)
+
+// ScalarLen is the length of an encoded field element.
+const ScalarLen = 32
+
+var (
+	// FqModulus is the order of the base field Fq, the scalar field of
+	// BLS12-377.
+	FqModulus, _ = new(big.Int).SetString("8444461749428370424248824938781546531375899335154063827935233455917409239041", 10)
+	// FrModulus is the order r of the decaf377 group.
+	FrModulus, _ = new(big.Int).SetString("2111115437357092606062206234695386632838870926408408195193685246394721360383", 10)
+)
+
+// RandomFq returns the encoding of a uniformly random element of Fq, read
+// from r, or from crypto/rand if r is nil.
+func RandomFq(r io.Reader) ([]byte, error) {
+	return randomScalar(r, FqModulus)
+}
+
+// RandomFr returns the encoding of a uniformly random element of Fr, read
+// from r, or from crypto/rand if r is nil.
+func RandomFr(r io.Reader) ([]byte, error) {
+	return randomScalar(r, FrModulus)
+}
+
+// randomScalar reduces 512 random bits modulo the field order, which is
+// statistically indistinguishable from uniform.
+func randomScalar(r io.Reader, modulus *big.Int) ([]byte, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	wide := make([]byte, 2*ScalarLen)
+	if _, err := io.ReadFull(r, wide); err != nil {
+		return nil, err
+	}
+	return EncodeScalar(new(big.Int).Mod(new(big.Int).SetBytes(wide), modulus)), nil
+}
+
+// EncodeScalar encodes a reduced field element as 32 little-endian bytes,
+// as arkworks does.
+func EncodeScalar(x *big.Int) []byte {
+	b := x.FillBytes(make([]byte, ScalarLen))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// ReduceScalar interprets bytes of any length as a little-endian integer and
+// reduces it modulo the given field order, as arkworks'
+// `from_le_bytes_mod_order` does.
+func ReduceScalar(b []byte, modulus *big.Int) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(be), modulus)
+}
+
+// DecodeScalar decodes a little-endian field element, reporting whether it
+// is reduced modulo the given field order.
+func DecodeScalar(b []byte, modulus *big.Int) (*big.Int, bool) {
+	if len(b) != ScalarLen {
+		return nil, false
+	}
+	be := make([]byte, ScalarLen)
+	for i := range b {
+		be[ScalarLen-1-i] = b[i]
+	}
+	x := new(big.Int).SetBytes(be)
+	return x, x.Cmp(modulus) < 0
+}
@@ -0,0 +1,118 @@
+// Package decaf377rdsa implements decaf377-rdsa spend authorization
+// signatures, as the `decaf377-rdsa` crate does: Schnorr signatures over the
+// decaf377 group, with the conventional basepoint as generator.
+//
+// Like the decaf377 package it builds on, it is not constant time, and is
+// meant for tooling such as validator operation rather than for custody of
+// funds.
+package decaf377rdsa
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+)
+
+// Lengths of encoded keys and signatures.
+const (
+	SigningKeyLen      = decaf377.ScalarLen
+	VerificationKeyLen = decaf377.ElementLen
+	SignatureLen       = decaf377.ElementLen + decaf377.ScalarLen
+)
+
+// ErrInvalidSignature is returned when a signature does not verify.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SigningKey is a SpendAuth signing key, a nonzero element of Fr.
+type SigningKey struct {
+	a  *big.Int
+	vk []byte
+}
+
+// NewSigningKey returns the signing key of a scalar, reduced modulo the group
+// order.
+func NewSigningKey(a *big.Int) (*SigningKey, error) {
+	a = new(big.Int).Mod(a, decaf377.FrModulus)
+	if a.Sign() == 0 {
+		return nil, errors.New("signing key is zero")
+	}
+	return &SigningKey{a: a, vk: decaf377.Basepoint().ScalarMul(a).Encode()}, nil
+}
+
+// SigningKeyFromBytes decodes a signing key from its canonical 32-byte
+// little-endian encoding.
+func SigningKeyFromBytes(b []byte) (*SigningKey, error) {
+	a, ok := decaf377.DecodeScalar(b, decaf377.FrModulus)
+	if !ok {
+		return nil, errors.New("signing key is not a canonical scalar")
+	}
+	return NewSigningKey(a)
+}
+
+// Bytes returns the encoding of the signing key.
+func (k *SigningKey) Bytes() []byte {
+	return decaf377.EncodeScalar(k.a)
+}
+
+// VerificationKey returns the encoding of the verification key [a]B.
+func (k *SigningKey) VerificationKey() []byte {
+	return append([]byte(nil), k.vk...)
+}
+
+// Sign returns the signature of msg, using randomness from crypto/rand.
+func (k *SigningKey) Sign(msg []byte) ([]byte, error) {
+	return k.SignWithRand(rand.Reader, msg)
+}
+
+// SignWithRand returns the signature of msg, hedging the nonce with 80 bytes
+// read from r.
+func (k *SigningKey) SignWithRand(r io.Reader, msg []byte) ([]byte, error) {
+	random := make([]byte, 80)
+	if _, err := io.ReadFull(r, random); err != nil {
+		return nil, fmt.Errorf("could not read randomness: %w", err)
+	}
+	nonce := hStar(random, k.vk, msg)
+	rBytes := decaf377.Basepoint().ScalarMul(nonce).Encode()
+	c := hStar(rBytes, k.vk, msg)
+	s := new(big.Int).Mul(k.a, c)
+	s.Add(s, nonce).Mod(s, decaf377.FrModulus)
+	return append(rBytes, decaf377.EncodeScalar(s)...), nil
+}
+
+// Verify checks a signature of msg under an encoded verification key.
+func Verify(vk, msg, sig []byte) error {
+	a, err := decaf377.Decode(vk)
+	if err != nil {
+		return fmt.Errorf("invalid verification key: %w", err)
+	}
+	if len(sig) != SignatureLen {
+		return fmt.Errorf("%w: length %d", ErrInvalidSignature, len(sig))
+	}
+	rBytes, sBytes := sig[:decaf377.ElementLen], sig[decaf377.ElementLen:]
+	r, err := decaf377.Decode(rBytes)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	s, ok := decaf377.DecodeScalar(sBytes, decaf377.FrModulus)
+	if !ok {
+		return ErrInvalidSignature
+	}
+	c := hStar(rBytes, vk, msg)
+	// R = [s]B - [c]A
+	expected := decaf377.Basepoint().ScalarMul(s).Add(a.ScalarMul(c).Neg())
+	if !r.Equal(expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// hStar is the hash to Fr of the signature scheme.
+func hStar(parts ...[]byte) *big.Int {
+	sum := blake2b.Sum512("decaf377-rdsa---", parts...)
+	return decaf377.ReduceScalar(sum[:], decaf377.FrModulus)
+}
@@ -0,0 +1,99 @@
+package decaf377rdsa
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+)
+
+func testKey(t *testing.T) *SigningKey {
+	t.Helper()
+	k, err := NewSigningKey(big.NewInt(0x1234567))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSignVerify(t *testing.T) {
+	k := testKey(t)
+	msg := []byte("validator definition")
+	sig, err := k.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != SignatureLen {
+		t.Fatalf("signature has length %d", len(sig))
+	}
+	if err := Verify(k.VerificationKey(), msg, sig); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	if err := Verify(k.VerificationKey(), []byte("validator definitioN"), sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of another message = %v", err)
+	}
+	other, _ := NewSigningKey(big.NewInt(7))
+	if err := Verify(other.VerificationKey(), msg, sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify under another key = %v", err)
+	}
+	for _, i := range []int{0, SignatureLen - 1} {
+		bad := append([]byte(nil), sig...)
+		bad[i] ^= 1
+		if err := Verify(k.VerificationKey(), msg, bad); err == nil {
+			t.Errorf("Verify accepted a signature with byte %d flipped", i)
+		}
+	}
+	// s + r is the same scalar as s, but not canonically encoded.
+	s, _ := decaf377.DecodeScalar(sig[32:], decaf377.FrModulus)
+	unreduced := new(big.Int).Add(s, decaf377.FrModulus)
+	if unreduced.BitLen() <= 256 {
+		bad := append(append([]byte(nil), sig[:32]...), decaf377.EncodeScalar(unreduced)...)
+		if err := Verify(k.VerificationKey(), msg, bad); err == nil {
+			t.Errorf("Verify accepted a non-canonical s")
+		}
+	}
+	if err := Verify(k.VerificationKey(), msg, sig[:63]); err == nil {
+		t.Errorf("Verify accepted a short signature")
+	}
+}
+
+func TestSignWithRand(t *testing.T) {
+	k := testKey(t)
+	msg := []byte("vote")
+	random := bytes.Repeat([]byte{1}, 80)
+	a, err := k.SignWithRand(bytes.NewReader(random), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := k.SignWithRand(bytes.NewReader(random), msg)
+	if !bytes.Equal(a, b) {
+		t.Errorf("signatures with the same randomness differ")
+	}
+	c, _ := k.Sign(msg)
+	if bytes.Equal(a, c) {
+		t.Errorf("signatures with different randomness are equal")
+	}
+	if _, err := k.SignWithRand(bytes.NewReader(random[:79]), msg); err == nil {
+		t.Errorf("SignWithRand succeeded with 79 random bytes")
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	k := testKey(t)
+	if !bytes.Equal(k.VerificationKey(), decaf377.Basepoint().ScalarMul(big.NewInt(0x1234567)).Encode()) {
+		t.Errorf("verification key is not [a]B")
+	}
+	decoded, err := SigningKeyFromBytes(k.Bytes())
+	if err != nil || !bytes.Equal(decoded.VerificationKey(), k.VerificationKey()) {
+		t.Errorf("SigningKeyFromBytes(Bytes()) = %v, %v", decoded, err)
+	}
+	if _, err := NewSigningKey(decaf377.FrModulus); err == nil {
+		t.Errorf("NewSigningKey accepted zero")
+	}
+	if _, err := SigningKeyFromBytes(decaf377.EncodeScalar(decaf377.FrModulus)); err == nil {
+		t.Errorf("SigningKeyFromBytes accepted an unreduced scalar")
+	}
+}
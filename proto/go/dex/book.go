@@ -0,0 +1,466 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/proto"
+)
+
+// Side is a side of an order book.
+type Side int
+
+const (
+	// Bids are the positions buying the base asset, holding the quote
+	// asset.
+	Bids Side = iota
+	// Asks are the positions selling the base asset.
+	Asks
+)
+
+func (s Side) String() string {
+	if s == Bids {
+		return "bids"
+	}
+	return "asks"
+}
+
+// Level is the liquidity at one price of a side of the book.
+type Level struct {
+	// Price is the effective price including fees, in quote display units
+	// per base display unit.
+	Price float64
+	// Base and Quote are the base units of each asset traded if the level
+	// is taken entirely: for asks, the base asset offered and the quote
+	// asset it costs; for bids, the base asset they absorb and the quote
+	// asset offered.
+	Base, Quote num.Amount
+	Positions   int
+}
+
+// Quote is a simulated trade against the book.
+type Quote struct {
+	Side Side
+	// Input, Output and Unfilled are in base units.
+	Input, Output, Unfilled num.Amount
+	// Price is the average price of the filled input, in quote display
+	// units per base display unit.
+	Price float64
+	// Slippage is the relative difference between Price and the best price
+	// on the side, positive when Price is worse.
+	Slippage float64
+}
+
+// Book is a local order book of one pair, built from the fullnode's price
+// index of open positions. Each position with reserves of the base asset is
+// an ask, and each with reserves of the quote asset is a bid; a partly
+// filled position is both.
+type Book struct {
+	Dex QueryService
+	// ChainId, if set, is sent with the liquidity and price queries the book
+	// is built from, which the fullnode refuses if it serves another chain.
+	ChainId string
+	// Precision is the number of significant digits prices are grouped by
+	// into levels. If zero, only equal prices are grouped.
+	Precision int
+
+	base, quote         *assetv1alpha1.AssetId
+	baseUnit, quoteUnit asset.Unit
+	pair                *dexv1alpha1.TradingPair
+
+	mu        sync.RWMutex
+	positions map[string]*dexv1alpha1.Position
+}
+
+// NewBook returns an empty book of a pair, quoting prices in the assets'
+// display units.
+func NewBook(dex QueryService, chainId string, base, quote *assetv1alpha1.DenomMetadata) (*Book, error) {
+	if base.GetPenumbraAssetId() == nil || quote.GetPenumbraAssetId() == nil {
+		return nil, errors.New("denom metadata is missing the asset ID")
+	}
+	if asset.IdEqual(base.GetPenumbraAssetId(), quote.GetPenumbraAssetId()) {
+		return nil, errors.New("base and quote assets are the same")
+	}
+	return &Book{
+		Dex:       dex,
+		ChainId:   chainId,
+		base:      base.GetPenumbraAssetId(),
+		quote:     quote.GetPenumbraAssetId(),
+		baseUnit:  asset.DefaultUnit(base),
+		quoteUnit: asset.DefaultUnit(quote),
+		pair:      NewTradingPair(base.GetPenumbraAssetId(), quote.GetPenumbraAssetId()),
+		positions: make(map[string]*dexv1alpha1.Position),
+	}, nil
+}
+
+// Pair returns the canonical trading pair of the book.
+func (b *Book) Pair() *dexv1alpha1.TradingPair {
+	return b.pair
+}
+
+// Load replaces the book's positions with the fullnode's open positions in
+// both directions.
+func (b *Book) Load(ctx context.Context) error {
+	positions := make(map[string]*dexv1alpha1.Position)
+	for _, dir := range []*dexv1alpha1.DirectedTradingPair{
+		{Start: b.base, End: b.quote},
+		{Start: b.quote, End: b.base},
+	} {
+		stream, err := b.Dex.LiquidityPositionsByPrice(ctx, &dexv1alpha1.LiquidityPositionsByPriceRequest{
+			ChainId:     b.ChainId,
+			TradingPair: dir,
+		})
+		if err != nil {
+			return err
+		}
+		for {
+			rsp, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			id, err := PositionId(rsp.GetData())
+			if err != nil {
+				return err
+			}
+			positions[string(id.GetInner())] = rsp.GetData()
+		}
+	}
+	b.mu.Lock()
+	b.positions = positions
+	b.mu.Unlock()
+	return nil
+}
+
+// Apply updates the book from a DEX event: opened positions on the pair
+// are fetched from the fullnode, since the event does not include their
+// trading function, and closed or withdrawn positions are removed. Other
+// events are ignored.
+func (b *Book) Apply(ctx context.Context, msg proto.Message) error {
+	switch e := msg.(type) {
+	case *dexv1alpha1.EventPositionOpen:
+		pair := e.GetTradingPair()
+		if !asset.IdEqual(pair.GetAsset_1(), b.pair.GetAsset_1()) || !asset.IdEqual(pair.GetAsset_2(), b.pair.GetAsset_2()) {
+			return nil
+		}
+		rsp, err := b.Dex.LiquidityPositionById(ctx, &dexv1alpha1.LiquidityPositionByIdRequest{
+			ChainId:    b.ChainId,
+			PositionId: e.GetPositionId(),
+		})
+		if err != nil {
+			return err
+		}
+		if rsp.GetData().GetState().GetState() != dexv1alpha1.PositionState_POSITION_STATE_ENUM_OPENED {
+			return nil
+		}
+		b.mu.Lock()
+		b.positions[string(e.GetPositionId().GetInner())] = rsp.GetData()
+		b.mu.Unlock()
+	case *dexv1alpha1.EventPositionClose:
+		b.remove(e.GetPositionId())
+	case *dexv1alpha1.EventPositionWithdraw:
+		b.remove(e.GetPositionId())
+	}
+	return nil
+}
+
+func (b *Book) remove(id *dexv1alpha1.PositionId) {
+	b.mu.Lock()
+	delete(b.positions, string(id.GetInner()))
+	b.mu.Unlock()
+}
+
+// Touches reports whether an execution traded through the book's pair, in
+// either direction.
+func (b *Book) Touches(se *dexv1alpha1.SwapExecution) bool {
+	for _, trace := range se.GetTraces() {
+		values := trace.GetValue()
+		for i := 1; i < len(values); i++ {
+			from, to := values[i-1].GetAssetId(), values[i].GetAssetId()
+			if asset.IdEqual(from, b.base) && asset.IdEqual(to, b.quote) ||
+				asset.IdEqual(from, b.quote) && asset.IdEqual(to, b.base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ObserveBlock reloads the book if any swap or arbitrage executed at a
+// height traded through the pair, since the executions do not say which
+// positions were filled.
+func (b *Book) ObserveBlock(ctx context.Context, height uint64) error {
+	touched := false
+	swaps, err := b.Dex.SwapExecutions(ctx, &dexv1alpha1.SwapExecutionsRequest{
+		ChainId:     b.ChainId,
+		StartHeight: height,
+		EndHeight:   height,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		rsp, err := swaps.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		touched = touched || b.Touches(rsp.GetSwapExecution())
+	}
+	arbs, err := b.Dex.ArbExecutions(ctx, &dexv1alpha1.ArbExecutionsRequest{
+		ChainId:     b.ChainId,
+		StartHeight: height,
+		EndHeight:   height,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		rsp, err := arbs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		touched = touched || b.Touches(rsp.GetSwapExecution())
+	}
+	if !touched {
+		return nil
+	}
+	return b.Load(ctx)
+}
+
+// offer is one position's liquidity on a side, oriented so that the trader
+// provides asset 1.
+type offer struct {
+	f        BareTradingFunction
+	reserves Reserves
+	// price is the exact effective price for the trader, input per output.
+	price num.U128x128
+}
+
+// offers returns the liquidity on a side from the cheapest for the trader.
+func (b *Book) offers(side Side) ([]offer, error) {
+	input := b.base
+	if side == Asks {
+		input = b.quote
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	offers := make([]offer, 0, len(b.positions))
+	for _, pos := range b.positions {
+		f, ok, err := Orient(pos.GetPhi(), input)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		reserves, err := ReservesFromProto(pos.GetReserves())
+		if err != nil {
+			return nil, err
+		}
+		if !asset.IdEqual(input, pos.GetPhi().GetPair().GetAsset_1()) {
+			reserves = reserves.Flip()
+		}
+		if reserves.R2.IsZero() {
+			continue
+		}
+		price, err := f.EffectivePrice()
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, offer{f: f, reserves: reserves, price: price})
+	}
+	sort.SliceStable(offers, func(i, j int) bool { return offers[i].price.Cmp(offers[j].price) < 0 })
+	return offers, nil
+}
+
+// displayPrice converts an amount of quote per amount of base, both in
+// base units, to display units.
+func (b *Book) displayPrice(quote, base float64) float64 {
+	return quote / base * math.Pow10(int(b.baseUnit.Exponent)-int(b.quoteUnit.Exponent))
+}
+
+// offerPrice returns the display price of an offer, in quote per base.
+func (b *Book) offerPrice(side Side, o offer) float64 {
+	if side == Asks {
+		return b.displayPrice(o.price.Float64(), 1)
+	}
+	return b.displayPrice(1, o.price.Float64())
+}
+
+func (b *Book) levelKey(price float64) string {
+	if b.Precision > 0 {
+		return strconv.FormatFloat(price, 'g', b.Precision, 64)
+	}
+	return strconv.FormatFloat(price, 'g', -1, 64)
+}
+
+// Levels returns the levels of a side from the best price.
+func (b *Book) Levels(side Side) ([]Level, error) {
+	offers, err := b.offers(side)
+	if err != nil {
+		return nil, err
+	}
+	var levels []Level
+	var last string
+	for _, o := range offers {
+		input, err := o.f.MaxInput(o.reserves)
+		if err != nil {
+			return nil, err
+		}
+		base, quote := o.reserves.R2, input
+		if side == Bids {
+			base, quote = input, o.reserves.R2
+		}
+		price := b.offerPrice(side, o)
+		key := b.levelKey(price)
+		if len(levels) == 0 || key != last {
+			if b.Precision > 0 {
+				price, _ = strconv.ParseFloat(key, 64)
+			}
+			levels = append(levels, Level{Price: price})
+			last = key
+		}
+		l := &levels[len(levels)-1]
+		var ok1, ok2 bool
+		l.Base, ok1 = l.Base.CheckedAdd(base)
+		l.Quote, ok2 = l.Quote.CheckedAdd(quote)
+		if !ok1 || !ok2 {
+			return nil, num.ErrOverflow
+		}
+		l.Positions++
+	}
+	return levels, nil
+}
+
+// Best returns the best level of a side, or false if it is empty.
+func (b *Book) Best(side Side) (Level, bool, error) {
+	levels, err := b.Levels(side)
+	if err != nil || len(levels) == 0 {
+		return Level{}, false, err
+	}
+	return levels[0], true, nil
+}
+
+// BestBid returns the highest bid, or false if there are none.
+func (b *Book) BestBid() (Level, bool, error) {
+	return b.Best(Bids)
+}
+
+// BestAsk returns the lowest ask, or false if there are none.
+func (b *Book) BestAsk() (Level, bool, error) {
+	return b.Best(Asks)
+}
+
+// Depth returns the liquidity of a side priced within pct percent of the
+// mid price, or of the side's best price if the other side is empty, as a
+// single level priced at the worst price included.
+func (b *Book) Depth(side Side, pct float64) (Level, error) {
+	levels, err := b.Levels(side)
+	if err != nil || len(levels) == 0 {
+		return Level{}, err
+	}
+	ref := levels[0].Price
+	other := Asks
+	if side == Asks {
+		other = Bids
+	}
+	if best, ok, err := b.Best(other); err != nil {
+		return Level{}, err
+	} else if ok {
+		ref = (ref + best.Price) / 2
+	}
+	limit := ref * (1 + pct/100)
+	if side == Bids {
+		limit = ref * (1 - pct/100)
+	}
+	var depth Level
+	for _, l := range levels {
+		if side == Asks && l.Price > limit || side == Bids && l.Price < limit {
+			break
+		}
+		var ok1, ok2 bool
+		depth.Base, ok1 = depth.Base.CheckedAdd(l.Base)
+		depth.Quote, ok2 = depth.Quote.CheckedAdd(l.Quote)
+		if !ok1 || !ok2 {
+			return Level{}, num.ErrOverflow
+		}
+		depth.Positions += l.Positions
+		depth.Price = l.Price
+	}
+	return depth, nil
+}
+
+// Simulate fills an input of either asset against the book's positions
+// from the best price, as a single-hop trade on the pair would be, and
+// returns the average price and slippage. Selling the base asset takes the
+// bids; selling the quote asset takes the asks.
+func (b *Book) Simulate(input *assetv1alpha1.Value) (*Quote, error) {
+	var side Side
+	switch {
+	case asset.IdEqual(input.GetAssetId(), b.base):
+		side = Bids
+	case asset.IdEqual(input.GetAssetId(), b.quote):
+		side = Asks
+	default:
+		return nil, fmt.Errorf("asset %s is not in the book's pair", asset.FormatAssetId(input.GetAssetId()))
+	}
+	offers, err := b.offers(side)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quote{Side: side, Unfilled: num.AmountFromProto(input.GetAmount())}
+	for _, o := range offers {
+		if q.Unfilled.IsZero() {
+			break
+		}
+		unfilled, _, output, err := o.f.Fill(q.Unfilled, o.reserves)
+		if err != nil {
+			return nil, err
+		}
+		filled, _ := q.Unfilled.CheckedSub(unfilled)
+		var ok1, ok2 bool
+		q.Input, ok1 = q.Input.CheckedAdd(filled)
+		q.Output, ok2 = q.Output.CheckedAdd(output)
+		if !ok1 || !ok2 {
+			return nil, num.ErrOverflow
+		}
+		q.Unfilled = unfilled
+	}
+	if q.Input.IsZero() || q.Output.IsZero() {
+		return q, nil
+	}
+	in, out := bigFloat(q.Input), bigFloat(q.Output)
+	best := b.offerPrice(side, offers[0])
+	if side == Asks {
+		q.Price = b.displayPrice(in, out)
+		q.Slippage = q.Price/best - 1
+	} else {
+		q.Price = b.displayPrice(out, in)
+		q.Slippage = 1 - q.Price/best
+	}
+	return q, nil
+}
+
+func bigFloat(a num.Amount) float64 {
+	f, _ := new(big.Float).SetInt(a.Big()).Float64()
+	return f
+}
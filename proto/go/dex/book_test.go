@@ -0,0 +1,238 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+type sliceStream[T any] struct {
+	items []*T
+}
+
+func (s *sliceStream[T]) Recv() (*T, error) {
+	if len(s.items) == 0 {
+		return nil, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, nil
+}
+
+// testPositions builds positions with distinct nonces.
+type testPositions struct {
+	t         *testing.T
+	positions []*dexv1alpha1.Position
+}
+
+func (tp *testPositions) add(start, end *assetv1alpha1.AssetId, p, q num.Amount, reserves Reserves) {
+	nonce := bytes.Repeat([]byte{byte(len(tp.positions) + 1)}, NonceLen)
+	pos, err := NewPosition(bytes.NewReader(nonce), &dexv1alpha1.DirectedTradingPair{Start: start, End: end}, 0, p, q, reserves)
+	if err != nil {
+		tp.t.Fatal(err)
+	}
+	tp.positions = append(tp.positions, pos)
+}
+
+// bookDex serves a fixed set of positions in both directions.
+type bookDex struct {
+	QueryService
+	positions []*dexv1alpha1.Position
+}
+
+func (d *bookDex) LiquidityPositionsByPrice(context.Context, *dexv1alpha1.LiquidityPositionsByPriceRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsByPriceResponse], error) {
+	s := &sliceStream[dexv1alpha1.LiquidityPositionsByPriceResponse]{}
+	for _, pos := range d.positions {
+		s.items = append(s.items, &dexv1alpha1.LiquidityPositionsByPriceResponse{Data: pos})
+	}
+	return s, nil
+}
+
+func (d *bookDex) LiquidityPositionById(_ context.Context, req *dexv1alpha1.LiquidityPositionByIdRequest) (*dexv1alpha1.LiquidityPositionByIdResponse, error) {
+	for _, pos := range d.positions {
+		if id, _ := PositionId(pos); bytes.Equal(id.GetInner(), req.GetPositionId().GetInner()) {
+			return &dexv1alpha1.LiquidityPositionByIdResponse{Data: pos}, nil
+		}
+	}
+	return &dexv1alpha1.LiquidityPositionByIdResponse{}, nil
+}
+
+// units returns n display units of a test denom.
+func units(n uint64) num.Amount {
+	u, _ := num.Pow10(6)
+	a, _ := num.NewAmount(n).CheckedMul(u)
+	return a
+}
+
+func testDenom(id *assetv1alpha1.AssetId, base, display string) *assetv1alpha1.DenomMetadata {
+	return &assetv1alpha1.DenomMetadata{
+		Base:    base,
+		Display: display,
+		DenomUnits: []*assetv1alpha1.DenomUnit{
+			{Denom: display, Exponent: 6},
+			{Denom: base},
+		},
+		PenumbraAssetId: id,
+	}
+}
+
+// testBook returns a loaded book of base against quote, with asks of 150
+// base at 2 over two positions, and of 10 at 3, and bids of 100 quote at 1
+// and 50 at 0.5. The positions are opened along the base to quote
+// direction, so whether their pair is flipped depends on which asset ID
+// sorts first.
+func testBook(t *testing.T, base, quote *assetv1alpha1.AssetId) (*Book, *bookDex) {
+	tp := &testPositions{t: t}
+	tp.add(base, quote, num.NewAmount(2), num.NewAmount(1), Reserves{R1: units(100)})
+	tp.add(base, quote, num.NewAmount(2), num.NewAmount(1), Reserves{R1: units(50)})
+	tp.add(base, quote, num.NewAmount(3), num.NewAmount(1), Reserves{R1: units(10)})
+	tp.add(base, quote, num.NewAmount(1), num.NewAmount(1), Reserves{R2: units(100)})
+	tp.add(base, quote, num.NewAmount(1), num.NewAmount(2), Reserves{R2: units(50)})
+	dex := &bookDex{positions: tp.positions}
+	b, err := NewBook(dex, "", testDenom(base, "ubase", "base"), testDenom(quote, "uquote", "quote"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return b, dex
+}
+
+func TestBookLevels(t *testing.T) {
+	want := map[Side][]Level{
+		Asks: {
+			{Price: 2, Base: units(150), Quote: units(300), Positions: 2},
+			{Price: 3, Base: units(10), Quote: units(30), Positions: 1},
+		},
+		Bids: {
+			{Price: 1, Base: units(100), Quote: units(100), Positions: 1},
+			{Price: 0.5, Base: units(100), Quote: units(50), Positions: 1},
+		},
+	}
+	for _, dir := range []struct {
+		name        string
+		base, quote *assetv1alpha1.AssetId
+	}{
+		{"canonical", testAssetId(1), testAssetId(2)},
+		{"flipped", testAssetId(2), testAssetId(1)},
+	} {
+		b, _ := testBook(t, dir.base, dir.quote)
+		for _, side := range []Side{Asks, Bids} {
+			levels, err := b.Levels(side)
+			if err != nil {
+				t.Fatalf("%s: %v", dir.name, err)
+			}
+			if len(levels) != len(want[side]) {
+				t.Fatalf("%s: %s = %+v, want %+v", dir.name, side, levels, want[side])
+			}
+			for i, l := range levels {
+				if l != want[side][i] {
+					t.Errorf("%s: %s level %d = %+v, want %+v", dir.name, side, i, l, want[side][i])
+				}
+			}
+		}
+		if bid, ok, _ := b.BestBid(); !ok || bid.Price != 1 {
+			t.Errorf("%s: BestBid = %+v", dir.name, bid)
+		}
+		if ask, ok, _ := b.BestAsk(); !ok || ask.Price != 2 {
+			t.Errorf("%s: BestAsk = %+v", dir.name, ask)
+		}
+		// The mid price is 1.5, so asks within 50% are those up to 2.25.
+		if d, _ := b.Depth(Asks, 50); d.Base != units(150) || d.Price != 2 || d.Positions != 2 {
+			t.Errorf("%s: Depth = %+v", dir.name, d)
+		}
+	}
+}
+
+func TestBookPrecision(t *testing.T) {
+	base, quote := testAssetId(1), testAssetId(2)
+	b, dex := testBook(t, base, quote)
+	tp := &testPositions{t: t, positions: dex.positions}
+	tp.add(base, quote, num.NewAmount(2001), num.NewAmount(1000), Reserves{R1: num.NewAmount(1000)})
+	dex.positions = tp.positions
+	if err := b.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if levels, _ := b.Levels(Asks); len(levels) != 3 || levels[1].Price != 2.001 {
+		t.Errorf("Levels grouping equal prices = %+v", levels)
+	}
+	b.Precision = 2
+	levels, _ := b.Levels(Asks)
+	if len(levels) != 2 || levels[0].Price != 2 || levels[0].Positions != 3 {
+		t.Errorf("Levels at 2 significant digits = %+v", levels)
+	}
+}
+
+func TestBookSimulate(t *testing.T) {
+	base, quote := testAssetId(2), testAssetId(1)
+	b, _ := testBook(t, base, quote)
+	tests := []struct {
+		name              string
+		input             *assetv1alpha1.Value
+		side              Side
+		in, out, unfilled num.Amount
+		price, slippage   float64
+	}{
+		// Selling base takes the bid at 1, then half of the bid at 0.5.
+		{"sell base", &assetv1alpha1.Value{AssetId: base, Amount: units(150).Proto()}, Bids, units(150), units(125), num.Zero, 125.0 / 150, 1 - 125.0/150},
+		// Taking the ask at 3 exactly computes 1/3 in fixed point, and the
+		// output rounds down, as pd's fills do.
+		{"buy base", &assetv1alpha1.Value{AssetId: quote, Amount: units(330).Proto()}, Asks, units(330), num.NewAmount(159_999_999), num.Zero, 330.0 / 159.999999, 330.0/159.999999/2 - 1},
+		// Exhausting it computes the input from the reserves instead.
+		{"beyond the book", &assetv1alpha1.Value{AssetId: quote, Amount: units(1000).Proto()}, Asks, units(330), units(160), units(670), 330.0 / 160, 330.0/160/2 - 1},
+	}
+	for _, tt := range tests {
+		q, err := b.Simulate(tt.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if q.Side != tt.side || q.Input != tt.in || q.Output != tt.out || q.Unfilled != tt.unfilled {
+			t.Errorf("%s: Simulate = %+v", tt.name, q)
+		}
+		if math.Abs(q.Price-tt.price) > 1e-12 || math.Abs(q.Slippage-tt.slippage) > 1e-12 {
+			t.Errorf("%s: price %v and slippage %v, want %v and %v", tt.name, q.Price, q.Slippage, tt.price, tt.slippage)
+		}
+	}
+	if _, err := b.Simulate(&assetv1alpha1.Value{AssetId: testAssetId(3), Amount: units(1).Proto()}); err == nil {
+		t.Errorf("Simulate of an asset outside the pair succeeded")
+	}
+}
+
+func TestBookApply(t *testing.T) {
+	base, quote := testAssetId(1), testAssetId(2)
+	b, dex := testBook(t, base, quote)
+	tp := &testPositions{t: t, positions: dex.positions}
+	tp.add(base, quote, num.NewAmount(5), num.NewAmount(1), Reserves{R1: num.NewAmount(1)})
+	dex.positions = tp.positions
+	pos := tp.positions[len(tp.positions)-1]
+	id, _ := PositionId(pos)
+
+	ctx := context.Background()
+	other := &dexv1alpha1.EventPositionOpen{PositionId: id, TradingPair: NewTradingPair(base, testAssetId(3))}
+	if err := b.Apply(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if levels, _ := b.Levels(Asks); len(levels) != 2 {
+		t.Errorf("a position opened on another pair was added")
+	}
+	if err := b.Apply(ctx, &dexv1alpha1.EventPositionOpen{PositionId: id, TradingPair: b.Pair()}); err != nil {
+		t.Fatal(err)
+	}
+	if levels, _ := b.Levels(Asks); len(levels) != 3 || levels[2].Price != 5 {
+		t.Errorf("Levels after the position opened = %+v", levels)
+	}
+	if err := b.Apply(ctx, &dexv1alpha1.EventPositionClose{PositionId: id}); err != nil {
+		t.Fatal(err)
+	}
+	if levels, _ := b.Levels(Asks); len(levels) != 2 {
+		t.Errorf("Levels after the position closed = %+v", levels)
+	}
+}
@@ -0,0 +1,328 @@
+package dex
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+)
+
+// Ladder is a market maker's liquidity across a price range, as evenly
+// spaced rungs of one position each. Rungs priced below the mid price are
+// bids, holding the quote asset to buy the base asset; the others are asks,
+// holding the base asset to sell. Each rung trades both ways at its price,
+// so a filled bid becomes an ask and vice versa, unless CloseOnFill is set.
+type Ladder struct {
+	// Pair is the market, from the base asset to the quote asset.
+	Pair *dexv1alpha1.DirectedTradingPair
+	// BaseUnit and QuoteUnit are the units prices are quoted in, usually
+	// the assets' display units. Their exponents scale the positions'
+	// coefficients to base units.
+	BaseUnit, QuoteUnit asset.Unit
+	// Low and High bound the rung prices, in QuoteUnit per BaseUnit.
+	Low, High *big.Rat
+	// Mid separates bids from asks. If nil, it is the middle of the range.
+	Mid   *big.Rat
+	Rungs int
+	// BaseInventory and QuoteInventory are the base units of each asset
+	// split evenly over the asks and the bids respectively.
+	BaseInventory, QuoteInventory num.Amount
+	// Fee is the fee tier of every rung, in basis points.
+	Fee         uint32
+	CloseOnFill bool
+}
+
+// Prices returns the price of each rung, from the lowest.
+func (l *Ladder) Prices() ([]*big.Rat, error) {
+	switch {
+	case l.Rungs < 1:
+		return nil, errors.New("ladder has no rungs")
+	case l.Low == nil || l.High == nil:
+		return nil, errors.New("ladder is missing a price bound")
+	case l.Low.Sign() <= 0:
+		return nil, errors.New("ladder prices must be positive")
+	case l.Low.Cmp(l.High) > 0:
+		return nil, errors.New("ladder's low price is above its high price")
+	}
+	prices := make([]*big.Rat, l.Rungs)
+	if l.Rungs == 1 {
+		prices[0] = new(big.Rat).Set(l.Low)
+		return prices, nil
+	}
+	step := new(big.Rat).Sub(l.High, l.Low)
+	step.Quo(step, new(big.Rat).SetInt64(int64(l.Rungs-1)))
+	for i := range prices {
+		prices[i] = new(big.Rat).Mul(step, new(big.Rat).SetInt64(int64(i)))
+		prices[i].Add(prices[i], l.Low)
+	}
+	return prices, nil
+}
+
+// Positions returns the ladder's positions, from the lowest rung, with
+// nonces from r, or crypto/rand if r is nil. Rungs left without inventory
+// because it does not divide far enough are omitted.
+func (l *Ladder) Positions(r io.Reader) ([]*dexv1alpha1.Position, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	if l.Fee > MaxFeeBps {
+		return nil, fmt.Errorf("fee of %d bps exceeds the maximum of %d", l.Fee, MaxFeeBps)
+	}
+	prices, err := l.Prices()
+	if err != nil {
+		return nil, err
+	}
+	mid := l.Mid
+	if mid == nil {
+		mid = new(big.Rat).Add(l.Low, l.High)
+		mid.Quo(mid, big.NewRat(2, 1))
+	}
+	bids := 0
+	for bids < len(prices) && prices[bids].Cmp(mid) < 0 {
+		bids++
+	}
+	bidReserves := split(l.QuoteInventory, bids)
+	askReserves := split(l.BaseInventory, len(prices)-bids)
+
+	var positions []*dexv1alpha1.Position
+	for i, price := range prices {
+		var reserves Reserves
+		if i < bids {
+			reserves.R2 = bidReserves[i]
+		} else {
+			reserves.R1 = askReserves[i-bids]
+		}
+		if reserves.R1.IsZero() && reserves.R2.IsZero() {
+			continue
+		}
+		p, q, err := Coefficients(price, l.BaseUnit.Exponent, l.QuoteUnit.Exponent)
+		if err != nil {
+			return nil, fmt.Errorf("rung at %s: %w", price.FloatString(6), err)
+		}
+		pos, err := NewPosition(r, l.Pair, l.Fee, p, q, reserves)
+		if err != nil {
+			return nil, err
+		}
+		pos.CloseOnFill = l.CloseOnFill
+		if err := ValidatePosition(pos); err != nil {
+			return nil, fmt.Errorf("rung at %s: %w", price.FloatString(6), err)
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+// Opens returns the planner requests opening the ladder's positions.
+func (l *Ladder) Opens(r io.Reader) ([]*viewv1alpha1.TransactionPlannerRequest_PositionOpen, error) {
+	positions, err := l.Positions(r)
+	if err != nil {
+		return nil, err
+	}
+	opens := make([]*viewv1alpha1.TransactionPlannerRequest_PositionOpen, len(positions))
+	for i, pos := range positions {
+		opens[i] = &viewv1alpha1.TransactionPlannerRequest_PositionOpen{Position: pos}
+	}
+	return opens, nil
+}
+
+// split divides an amount into n parts, the first ones larger by one if it
+// does not divide evenly.
+func split(total num.Amount, n int) []num.Amount {
+	if n == 0 {
+		return nil
+	}
+	each, rem := total.QuoRem(num.NewAmount(uint64(n)))
+	parts := make([]num.Amount, n)
+	for i := range parts {
+		parts[i] = each
+		if num.NewAmount(uint64(i)).Cmp(rem) < 0 {
+			parts[i], _ = each.CheckedAdd(num.NewAmount(1))
+		}
+	}
+	return parts
+}
+
+// Coefficients returns the trading function coefficients of a position
+// trading at a price given in units with the base and quote exponents: p/q
+// is the price in base units. The ratio is exact unless p or q would exceed
+// MaxReserveAmount, in which case both are scaled down.
+func Coefficients(price *big.Rat, baseExponent, quoteExponent uint32) (num.Amount, num.Amount, error) {
+	if price.Sign() <= 0 {
+		return num.Zero, num.Zero, errors.New("price must be positive")
+	}
+	p := new(big.Int).Mul(price.Num(), pow10(quoteExponent))
+	q := new(big.Int).Mul(price.Denom(), pow10(baseExponent))
+	gcd := new(big.Int).GCD(nil, nil, p, q)
+	p.Quo(p, gcd)
+	q.Quo(q, gcd)
+
+	max := MaxReserveAmount.Big()
+	largest := p
+	if q.Cmp(p) > 0 {
+		largest = q
+	}
+	if largest.Cmp(max) > 0 {
+		// Divide both by ceil(largest / max), keeping the ratio as close as
+		// the coefficients' precision allows.
+		scale := new(big.Int).Add(largest, max)
+		scale.Sub(scale, big.NewInt(1))
+		scale.Quo(scale, max)
+		p.Quo(p, scale)
+		q.Quo(q, scale)
+	}
+	if p.Sign() == 0 || q.Sign() == 0 {
+		return num.Zero, num.Zero, errors.New("price is out of the representable range")
+	}
+	pa, err := num.AmountFromBig(p)
+	if err != nil {
+		return num.Zero, num.Zero, err
+	}
+	qa, err := num.AmountFromBig(q)
+	if err != nil {
+		return num.Zero, num.Zero, err
+	}
+	return pa, qa, nil
+}
+
+func pow10(n uint32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Unwind is the plan to take down a wallet's positions on a pair. pd only
+// closes positions at the end of the block, and only closed positions can
+// be withdrawn, so it takes two transactions: one closing the open
+// positions, then, once it has been confirmed, one withdrawing them. Either
+// request is nil if there are no such positions.
+type Unwind struct {
+	Closes    *viewv1alpha1.TransactionPlannerRequest
+	Withdraws *viewv1alpha1.TransactionPlannerRequest
+}
+
+// Unwinder plans the closing and withdrawal of a wallet's positions.
+type Unwinder struct {
+	View view.Service
+	Dex  QueryService
+	// ChainId, if set, is sent when fetching the positions to unwind, which
+	// the fullnode refuses if it serves another chain.
+	ChainId  string
+	WalletId *keysv1alpha1.WalletId
+	// Source, if set, is the account paying the fees.
+	Source *keysv1alpha1.AddressIndex
+}
+
+// Plan returns the requests closing the wallet's open positions on a pair
+// and withdrawing its closed ones. If ids is not empty, only those
+// positions are included, such as the positions of one ladder.
+func (u *Unwinder) Plan(ctx context.Context, pair *dexv1alpha1.TradingPair, ids []*dexv1alpha1.PositionId) (*Unwind, error) {
+	include := func(*dexv1alpha1.PositionId) bool { return true }
+	if len(ids) > 0 {
+		set := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			set[string(id.GetInner())] = true
+		}
+		include = func(id *dexv1alpha1.PositionId) bool { return set[string(id.GetInner())] }
+	}
+
+	opened, err := u.owned(ctx, pair, dexv1alpha1.PositionState_POSITION_STATE_ENUM_OPENED, include)
+	if err != nil {
+		return nil, err
+	}
+	closed, err := u.owned(ctx, pair, dexv1alpha1.PositionState_POSITION_STATE_ENUM_CLOSED, include)
+	if err != nil {
+		return nil, err
+	}
+
+	unwind := new(Unwind)
+	if len(opened) > 0 {
+		unwind.Closes = u.request()
+		for _, id := range opened {
+			unwind.Closes.PositionCloses = append(unwind.Closes.PositionCloses,
+				&viewv1alpha1.TransactionPlannerRequest_PositionClose{PositionId: id})
+		}
+	}
+	if len(closed) > 0 {
+		positions, err := u.positions(ctx, closed)
+		if err != nil {
+			return nil, err
+		}
+		unwind.Withdraws = u.request()
+		for _, id := range closed {
+			pos, ok := positions[string(id.GetInner())]
+			if !ok {
+				return nil, fmt.Errorf("fullnode has no position %s", FormatPositionId(id))
+			}
+			unwind.Withdraws.PositionWithdraws = append(unwind.Withdraws.PositionWithdraws,
+				&viewv1alpha1.TransactionPlannerRequest_PositionWithdraw{
+					PositionId:  id,
+					Reserves:    pos.GetReserves(),
+					TradingPair: pos.GetPhi().GetPair(),
+				})
+		}
+	}
+	return unwind, nil
+}
+
+func (u *Unwinder) request() *viewv1alpha1.TransactionPlannerRequest {
+	return &viewv1alpha1.TransactionPlannerRequest{WalletId: u.WalletId, Source: u.Source}
+}
+
+// owned returns the IDs of the wallet's positions on a pair in a state.
+func (u *Unwinder) owned(ctx context.Context, pair *dexv1alpha1.TradingPair, state dexv1alpha1.PositionState_PositionStateEnum, include func(*dexv1alpha1.PositionId) bool) ([]*dexv1alpha1.PositionId, error) {
+	stream, err := u.View.OwnedPositionIds(ctx, &viewv1alpha1.OwnedPositionIdsRequest{
+		PositionState: &dexv1alpha1.PositionState{State: state},
+		TradingPair:   pair,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ids []*dexv1alpha1.PositionId
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if include(rsp.GetPositionId()) {
+			ids = append(ids, rsp.GetPositionId())
+		}
+	}
+	return ids, nil
+}
+
+// positions fetches positions from the fullnode, keyed by ID.
+func (u *Unwinder) positions(ctx context.Context, ids []*dexv1alpha1.PositionId) (map[string]*dexv1alpha1.Position, error) {
+	stream, err := u.Dex.LiquidityPositionsById(ctx, &dexv1alpha1.LiquidityPositionsByIdRequest{
+		ChainId:    u.ChainId,
+		PositionId: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]*dexv1alpha1.Position, len(ids))
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := PositionId(rsp.GetData())
+		if err != nil {
+			return nil, err
+		}
+		positions[string(id.GetInner())] = rsp.GetData()
+	}
+	return positions, nil
+}
@@ -0,0 +1,219 @@
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+func TestCoefficients(t *testing.T) {
+	tests := []struct {
+		price                       *big.Rat
+		baseExponent, quoteExponent uint32
+		p, q                        string
+	}{
+		{big.NewRat(3, 2), 6, 6, "3", "2"},
+		// 1.5 quote per base is 1.5e12 quote base units per base base unit.
+		{big.NewRat(3, 2), 6, 18, "1500000000000", "1"},
+		{big.NewRat(1, 3), 18, 6, "1", "3000000000000"},
+		{big.NewRat(1000, 1), 0, 0, "1000", "1"},
+	}
+	for _, tt := range tests {
+		p, q, err := Coefficients(tt.price, tt.baseExponent, tt.quoteExponent)
+		if err != nil {
+			t.Errorf("Coefficients(%s, %d, %d): %v", tt.price, tt.baseExponent, tt.quoteExponent, err)
+			continue
+		}
+		if p.String() != tt.p || q.String() != tt.q {
+			t.Errorf("Coefficients(%s, %d, %d) = %s/%s, want %s/%s", tt.price, tt.baseExponent, tt.quoteExponent, p, q, tt.p, tt.q)
+		}
+	}
+
+	// Coefficients over MaxReserveAmount are scaled down, by 3 here.
+	num81 := new(big.Int).Lsh(big.NewInt(1), 81)
+	num81.Add(num81, big.NewInt(1))
+	price := new(big.Rat).SetFrac(num81, new(big.Int).Lsh(big.NewInt(1), 70))
+	p, q, err := Coefficients(price, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Cmp(MaxReserveAmount) > 0 || q.Cmp(MaxReserveAmount) > 0 {
+		t.Errorf("scaled coefficients %s/%s exceed MaxReserveAmount", p, q)
+	}
+	wantQ := new(big.Int).Quo(new(big.Int).Lsh(big.NewInt(1), 70), big.NewInt(3))
+	if q.Big().Cmp(wantQ) != 0 {
+		t.Errorf("scaled q = %s, want %s", q, wantQ)
+	}
+	got := new(big.Rat).SetFrac(p.Big(), q.Big())
+	diff := new(big.Rat).Sub(got, price)
+	diff.Quo(diff, price)
+	if f, _ := diff.Abs(diff).Float64(); f > 1e-20 {
+		t.Errorf("scaled coefficients %s/%s are off the price by %g", p, q, f)
+	}
+
+	for _, price := range []*big.Rat{big.NewRat(0, 1), big.NewRat(-1, 2), new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), 100))} {
+		if p, q, err := Coefficients(price, 0, 0); err == nil {
+			t.Errorf("Coefficients(%s) = %s/%s, want an error", price, p, q)
+		}
+	}
+}
+
+func TestLadderPrices(t *testing.T) {
+	l := &Ladder{Low: big.NewRat(1, 1), High: big.NewRat(2, 1), Rungs: 5}
+	prices, err := l.Prices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*big.Rat{big.NewRat(1, 1), big.NewRat(5, 4), big.NewRat(3, 2), big.NewRat(7, 4), big.NewRat(2, 1)}
+	if len(prices) != len(want) {
+		t.Fatalf("Prices = %v, want %v", prices, want)
+	}
+	for i := range prices {
+		if prices[i].Cmp(want[i]) != 0 {
+			t.Errorf("rung %d at %s, want %s", i, prices[i], want[i])
+		}
+	}
+	l.Rungs = 1
+	if prices, _ := l.Prices(); len(prices) != 1 || prices[0].Cmp(l.Low) != 0 {
+		t.Errorf("Prices of one rung = %v, want the low price", prices)
+	}
+
+	for _, bad := range []*Ladder{
+		{Low: big.NewRat(1, 1), High: big.NewRat(2, 1)},
+		{Low: big.NewRat(1, 1), Rungs: 2},
+		{Low: big.NewRat(0, 1), High: big.NewRat(2, 1), Rungs: 2},
+		{Low: big.NewRat(3, 1), High: big.NewRat(2, 1), Rungs: 2},
+	} {
+		if _, err := bad.Prices(); err == nil {
+			t.Errorf("Prices of %+v succeeded", bad)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		total uint64
+		n     int
+		want  []uint64
+	}{
+		{10, 3, []uint64{4, 3, 3}},
+		{9, 3, []uint64{3, 3, 3}},
+		{2, 3, []uint64{1, 1, 0}},
+		{5, 0, nil},
+	}
+	for _, tt := range tests {
+		parts := split(num.NewAmount(tt.total), tt.n)
+		if len(parts) != len(tt.want) {
+			t.Errorf("split(%d, %d) = %v, want %v", tt.total, tt.n, parts, tt.want)
+			continue
+		}
+		for i := range parts {
+			if parts[i] != num.NewAmount(tt.want[i]) {
+				t.Errorf("split(%d, %d) = %v, want %v", tt.total, tt.n, parts, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLadderPositions(t *testing.T) {
+	for _, dir := range []struct {
+		name        string
+		base, quote *assetv1alpha1.AssetId
+	}{
+		{"canonical", testAssetId(1), testAssetId(2)},
+		{"flipped", testAssetId(2), testAssetId(1)},
+	} {
+		l := &Ladder{
+			Pair:           &dexv1alpha1.DirectedTradingPair{Start: dir.base, End: dir.quote},
+			BaseUnit:       asset.Unit{Denom: "base", Exponent: 6},
+			QuoteUnit:      asset.Unit{Denom: "quote", Exponent: 18},
+			Low:            big.NewRat(1, 1),
+			High:           big.NewRat(2, 1),
+			Rungs:          5,
+			BaseInventory:  num.NewAmount(1001),
+			QuoteInventory: num.NewAmount(1001),
+			Fee:            30,
+			CloseOnFill:    true,
+		}
+		positions, err := l.Positions(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", dir.name, err)
+		}
+		prices, _ := l.Prices()
+		if len(positions) != len(prices) {
+			t.Fatalf("%s: %d positions, want one per rung", dir.name, len(positions))
+		}
+		scale := new(big.Rat).SetInt(pow10(12))
+		var base, quote num.Amount
+		ids := make(map[string]bool)
+		for i, pos := range positions {
+			f, _, err := Orient(pos.GetPhi(), dir.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := new(big.Rat).Mul(prices[i], scale)
+			if got := new(big.Rat).SetFrac(f.P.Big(), f.Q.Big()); got.Cmp(want) != 0 {
+				t.Errorf("%s: rung %d has p/q %s, want %s", dir.name, i, got, want)
+			}
+			if f.Fee != 30 || !pos.GetCloseOnFill() {
+				t.Errorf("%s: rung %d has fee %d and close on fill %v", dir.name, i, f.Fee, pos.GetCloseOnFill())
+			}
+			// The rungs below the mid price of 1.5 are bids.
+			rb, _ := ReservesFor(pos, dir.base)
+			rq, _ := ReservesFor(pos, dir.quote)
+			if bid := i < 2; bid && !rb.IsZero() || !bid && !rq.IsZero() {
+				t.Errorf("%s: rung %d holds %s base and %s quote", dir.name, i, rb, rq)
+			}
+			base, _ = base.CheckedAdd(rb)
+			quote, _ = quote.CheckedAdd(rq)
+			id, _ := PositionId(pos)
+			ids[string(id.GetInner())] = true
+		}
+		if base != l.BaseInventory || quote != l.QuoteInventory {
+			t.Errorf("%s: positions hold %s base and %s quote, want the inventories", dir.name, base, quote)
+		}
+		if len(ids) != len(positions) {
+			t.Errorf("%s: positions share IDs", dir.name)
+		}
+	}
+}
+
+func TestLadderPositionsSplit(t *testing.T) {
+	l := &Ladder{
+		Pair:          &dexv1alpha1.DirectedTradingPair{Start: testAssetId(1), End: testAssetId(2)},
+		Low:           big.NewRat(1, 1),
+		High:          big.NewRat(2, 1),
+		Mid:           big.NewRat(5, 4),
+		Rungs:         5,
+		BaseInventory: num.NewAmount(2),
+	}
+	// With the mid price at 1.25 only the lowest rung is a bid, and it has
+	// no inventory; of the four asks, the two highest get none.
+	positions, err := l.Positions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("%d positions, want 2", len(positions))
+	}
+	for i, pos := range positions {
+		f, _, _ := Orient(pos.GetPhi(), testAssetId(1))
+		want := []*big.Rat{big.NewRat(5, 4), big.NewRat(3, 2)}[i]
+		if got := new(big.Rat).SetFrac(f.P.Big(), f.Q.Big()); got.Cmp(want) != 0 {
+			t.Errorf("position %d at %s, want %s", i, got, want)
+		}
+		if r, _ := ReservesFor(pos, testAssetId(1)); r != num.NewAmount(1) {
+			t.Errorf("position %d holds %s base, want 1", i, r)
+		}
+	}
+
+	l.Fee = MaxFeeBps + 1
+	if _, err := l.Positions(nil); err == nil {
+		t.Errorf("Positions with a fee over the maximum succeeded")
+	}
+}
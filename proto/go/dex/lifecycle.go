@@ -0,0 +1,484 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	sctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/sct/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+	"google.golang.org/protobuf/proto"
+)
+
+// SwapStage is how far a managed swap has progressed.
+type SwapStage string
+
+const (
+	// SwapBuilt is a swap whose transaction has been built but not yet seen
+	// on chain.
+	SwapBuilt SwapStage = "built"
+	// SwapDetected is a swap that has been included in a block, and so
+	// executed in that block's batch.
+	SwapDetected SwapStage = "detected"
+	// SwapClaimBuilt is a swap whose claim transaction has been built but
+	// not yet seen on chain.
+	SwapClaimBuilt SwapStage = "claim_built"
+	// SwapClaimed is a swap whose outputs have been claimed.
+	SwapClaimed SwapStage = "claimed"
+)
+
+// ManagedSwap is a swap followed by a SwapManager from its submission to
+// its claim.
+type ManagedSwap struct {
+	Commitment []byte    `json:"commitment"`
+	Stage      SwapStage `json:"stage"`
+	// Height is the height the swap was included at, whose batch it was
+	// executed in.
+	Height uint64 `json:"height,omitempty"`
+	// Output1 and Output2 are the swap's outputs of each asset of its
+	// trading pair, known once it is detected.
+	Output1 num.Amount `json:"output_1"`
+	Output2 num.Amount `json:"output_2"`
+	// ClaimHeight is the height the claim was included at.
+	ClaimHeight uint64 `json:"claim_height,omitempty"`
+	// Transaction is the encoded transaction of the current stage, kept
+	// until it is seen on chain so that it can be rebroadcast.
+	Transaction []byte `json:"transaction,omitempty"`
+}
+
+// DefaultDetectionTimeout is the default DetectionTimeout of a SwapManager.
+const DefaultDetectionTimeout = 2 * time.Minute
+
+// swapManagerState is the persisted state of a swap manager.
+type swapManagerState struct {
+	Swaps []*ManagedSwap `json:"swaps"`
+}
+
+// SwapManager submits swaps and claims their outputs once their batch has
+// executed, which takes two transactions. It saves its progress to a file
+// before each broadcast, so that swaps interrupted by a restart are resumed
+// rather than left unclaimed; a saved transaction is only rebroadcast if it
+// has not already been included. Transactions are authorized by the view
+// server's custody service. A SwapManager is not safe for concurrent use.
+type SwapManager struct {
+	View view.Service
+	Dex  QueryService
+	// ChainId, if set, is sent when fetching batch swap outputs, which the
+	// fullnode refuses if it serves another chain.
+	ChainId  string
+	WalletId *keysv1alpha1.WalletId
+	// Source, if set, restricts the swaps to the notes of one account.
+	Source *keysv1alpha1.AddressIndex
+	// Path is the file the manager's state is saved to, if set.
+	Path string
+	// DetectionTimeout bounds the wait for the view server to detect a
+	// saved transaction that is not rebroadcast because it has already
+	// been included. Zero means DefaultDetectionTimeout.
+	DetectionTimeout time.Duration
+
+	state swapManagerState
+}
+
+// Load reads the manager's state from Path. A missing file is an empty
+// state.
+func (m *SwapManager) Load() error {
+	data, err := os.ReadFile(m.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		m.state = swapManagerState{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state swapManagerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("could not parse %s: %w", m.Path, err)
+	}
+	m.state = state
+	return nil
+}
+
+// save writes the manager's state to Path, replacing it atomically so that
+// a crash never leaves a truncated file.
+func (m *SwapManager) save() error {
+	if m.Path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(&m.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.Path), filepath.Base(m.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.Path)
+}
+
+// Swaps returns the managed swaps, in the order they were submitted.
+func (m *SwapManager) Swaps() []ManagedSwap {
+	swaps := make([]ManagedSwap, len(m.state.Swaps))
+	for i, s := range m.state.Swaps {
+		swaps[i] = *s
+	}
+	return swaps
+}
+
+// Swap submits a swap of input for the target asset, prepaying the claim
+// fee, and follows it until its outputs are claimed to claimAddress, or to
+// the source account if claimAddress is nil. On error the swap's progress
+// is kept, and Resume picks it up.
+func (m *SwapManager) Swap(ctx context.Context, input *assetv1alpha1.Value, target *assetv1alpha1.AssetId, claimFee *feev1alpha1.Fee, claimAddress *keysv1alpha1.Address) (*ManagedSwap, error) {
+	if claimAddress == nil {
+		rsp, err := m.View.AddressByIndex(ctx, &viewv1alpha1.AddressByIndexRequest{AddressIndex: m.Source})
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch claim address: %w", err)
+		}
+		claimAddress = rsp.GetAddress()
+	}
+	tx, err := m.build(ctx, &viewv1alpha1.TransactionPlannerRequest{
+		Source:   m.Source,
+		WalletId: m.WalletId,
+		Swaps: []*viewv1alpha1.TransactionPlannerRequest_Swap{{
+			Value:        input,
+			TargetAsset:  target,
+			Fee:          claimFee,
+			ClaimAddress: claimAddress,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not build swap: %w", err)
+	}
+	commitment, err := swapCommitment(tx)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	s := &ManagedSwap{Commitment: commitment, Stage: SwapBuilt, Transaction: encoded}
+	m.state.Swaps = append(m.state.Swaps, s)
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return s, m.advance(ctx, s)
+}
+
+// Resume advances every swap that has not been claimed, as after a
+// restart. It keeps going after a swap fails to advance.
+func (m *SwapManager) Resume(ctx context.Context) error {
+	var errs []error
+	for _, s := range m.state.Swaps {
+		if s.Stage == SwapClaimed {
+			continue
+		}
+		if err := m.advance(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("swap %x: %w", s.Commitment, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Prune forgets the claimed swaps.
+func (m *SwapManager) Prune() error {
+	swaps := m.state.Swaps[:0]
+	for _, s := range m.state.Swaps {
+		if s.Stage != SwapClaimed {
+			swaps = append(swaps, s)
+		}
+	}
+	m.state.Swaps = swaps
+	return m.save()
+}
+
+// advance takes a swap through its remaining stages, saving after each.
+func (m *SwapManager) advance(ctx context.Context, s *ManagedSwap) error {
+	for {
+		var err error
+		switch s.Stage {
+		case SwapBuilt:
+			err = m.awaitSwap(ctx, s)
+		case SwapDetected:
+			err = m.buildClaim(ctx, s)
+		case SwapClaimBuilt:
+			err = m.awaitClaim(ctx, s)
+		case SwapClaimed:
+			return nil
+		default:
+			return fmt.Errorf("unknown swap stage %q", s.Stage)
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.save(); err != nil {
+			return err
+		}
+	}
+}
+
+// awaitSwap broadcasts a built swap, unless it has already been included,
+// and waits for its detection.
+func (m *SwapManager) awaitSwap(ctx context.Context, s *ManagedSwap) error {
+	record, err := m.unclaimed(ctx, s.Commitment)
+	if err != nil {
+		return err
+	}
+	var height uint64
+	awaitCtx := ctx
+	if record == nil {
+		var broadcast bool
+		height, broadcast, err = m.submit(ctx, s.Transaction)
+		if err != nil {
+			return fmt.Errorf("could not broadcast swap: %w", err)
+		}
+		if !broadcast {
+			var cancel context.CancelFunc
+			awaitCtx, cancel = context.WithTimeout(ctx, m.detectionTimeout())
+			defer cancel()
+		}
+	}
+	rsp, err := m.View.SwapByCommitment(awaitCtx, &viewv1alpha1.SwapByCommitmentRequest{
+		SwapCommitment: &tctv1alpha1.StateCommitment{Inner: s.Commitment},
+		AwaitDetection: true,
+		WalletId:       m.WalletId,
+	})
+	if err != nil {
+		return fmt.Errorf("could not await swap: %w", err)
+	}
+	record = rsp.GetSwap()
+	if h := record.GetOutputData().GetHeight(); h != 0 {
+		height = h
+	}
+	if height == 0 {
+		return errors.New("swap height is unknown")
+	}
+	swap := record.GetSwap()
+	bsod, err := m.Dex.BatchSwapOutputData(ctx, &dexv1alpha1.BatchSwapOutputDataRequest{
+		ChainId:     m.ChainId,
+		Height:      height,
+		TradingPair: swap.GetTradingPair(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not fetch batch output data at height %d: %w", height, err)
+	}
+	s.Output1, s.Output2, err = ProRataOutputs(bsod.GetData(), num.AmountFromProto(swap.GetDelta_1I()), num.AmountFromProto(swap.GetDelta_2I()))
+	if err != nil {
+		return err
+	}
+	s.Height = height
+	s.Stage = SwapDetected
+	s.Transaction = nil
+	return nil
+}
+
+// buildClaim plans and builds the claim of a detected swap.
+func (m *SwapManager) buildClaim(ctx context.Context, s *ManagedSwap) error {
+	record, err := m.unclaimed(ctx, s.Commitment)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return m.checkClaimed(ctx, s)
+	}
+	tx, err := m.build(ctx, &viewv1alpha1.TransactionPlannerRequest{
+		Source:     m.Source,
+		WalletId:   m.WalletId,
+		SwapClaims: []*viewv1alpha1.TransactionPlannerRequest_SwapClaim{{SwapCommitment: record.GetSwapCommitment()}},
+	})
+	if err != nil {
+		return fmt.Errorf("could not build swap claim: %w", err)
+	}
+	if s.Transaction, err = proto.Marshal(tx); err != nil {
+		return err
+	}
+	s.Stage = SwapClaimBuilt
+	return nil
+}
+
+// awaitClaim broadcasts a built claim, unless the swap has already been
+// claimed, and waits for its detection.
+func (m *SwapManager) awaitClaim(ctx context.Context, s *ManagedSwap) error {
+	record, err := m.unclaimed(ctx, s.Commitment)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return m.checkClaimed(ctx, s)
+	}
+	height, broadcast, err := m.submit(ctx, s.Transaction)
+	if err != nil {
+		return fmt.Errorf("could not broadcast swap claim: %w", err)
+	}
+	if !broadcast {
+		// The claim was included before the view server caught up with it:
+		// wait until it has, so that its record has the claim height.
+		awaitCtx, cancel := context.WithTimeout(ctx, m.detectionTimeout())
+		defer cancel()
+		_, err := m.View.NullifierStatus(awaitCtx, &viewv1alpha1.NullifierStatusRequest{
+			Nullifier:      record.GetNullifier(),
+			AwaitDetection: true,
+			WalletId:       m.WalletId,
+		})
+		if err != nil {
+			return fmt.Errorf("could not await swap claim: %w", err)
+		}
+		return m.checkClaimed(ctx, s)
+	}
+	s.ClaimHeight = height
+	s.Stage = SwapClaimed
+	s.Transaction = nil
+	return nil
+}
+
+// checkClaimed marks a swap the view server no longer lists as unclaimed as
+// claimed, once its record confirms it.
+func (m *SwapManager) checkClaimed(ctx context.Context, s *ManagedSwap) error {
+	rsp, err := m.View.SwapByCommitment(ctx, &viewv1alpha1.SwapByCommitmentRequest{
+		SwapCommitment: &tctv1alpha1.StateCommitment{Inner: s.Commitment},
+		WalletId:       m.WalletId,
+	})
+	if err != nil {
+		return err
+	}
+	if rsp.GetSwap().GetHeightClaimed() == 0 {
+		return errors.New("swap is neither unclaimed nor claimed")
+	}
+	s.ClaimHeight = rsp.GetSwap().GetHeightClaimed()
+	s.Stage = SwapClaimed
+	s.Transaction = nil
+	return nil
+}
+
+// unclaimed returns the view server's record of an unclaimed swap, or nil
+// if it has none.
+func (m *SwapManager) unclaimed(ctx context.Context, commitment []byte) (*viewv1alpha1.SwapRecord, error) {
+	stream, err := m.View.UnclaimedSwaps(ctx, &viewv1alpha1.UnclaimedSwapsRequest{WalletId: m.WalletId})
+	if err != nil {
+		return nil, err
+	}
+	var found *viewv1alpha1.SwapRecord
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(rsp.GetSwap().GetSwapCommitment().GetInner(), commitment) {
+			found = rsp.GetSwap()
+		}
+	}
+	return found, nil
+}
+
+// build plans a transaction and has the view server authorize and build
+// it.
+func (m *SwapManager) build(ctx context.Context, req *viewv1alpha1.TransactionPlannerRequest) (*transactionv1alpha1.Transaction, error) {
+	plan, err := m.View.TransactionPlanner(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	built, err := m.View.AuthorizeAndBuild(ctx, &viewv1alpha1.AuthorizeAndBuildRequest{TransactionPlan: plan.GetPlan()})
+	if err != nil {
+		return nil, err
+	}
+	return built.GetTransaction(), nil
+}
+
+// submit broadcasts a saved transaction, waiting until the view server
+// detects it, unless it has already been included: a previous run may have
+// broadcast it and stopped before saving, and the view server may not have
+// caught up with its block yet. A transaction is taken to be included if
+// the view server has seen one of its nullifiers spent, or if the fullnode
+// rejects it as spending a spent nullifier or as already in its mempool.
+// submit reports whether the transaction was broadcast, and if so the
+// height it was detected at.
+func (m *SwapManager) submit(ctx context.Context, encoded []byte) (uint64, bool, error) {
+	tx := new(transactionv1alpha1.Transaction)
+	if err := proto.Unmarshal(encoded, tx); err != nil {
+		return 0, false, fmt.Errorf("could not decode saved transaction: %w", err)
+	}
+	for _, nf := range nullifiers(tx) {
+		rsp, err := m.View.NullifierStatus(ctx, &viewv1alpha1.NullifierStatusRequest{Nullifier: nf, WalletId: m.WalletId})
+		if err != nil {
+			return 0, false, fmt.Errorf("could not check nullifier status: %w", err)
+		}
+		if rsp.GetSpent() {
+			return 0, false, nil
+		}
+	}
+	rsp, err := m.View.BroadcastTransaction(ctx, &viewv1alpha1.BroadcastTransactionRequest{Transaction: tx, AwaitDetection: true})
+	if err != nil {
+		if kind := proxy.ClassifyLog(err.Error()); kind == proxy.ErrNullifierSpent || kind == proxy.ErrAlreadyInMempool {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return rsp.GetDetectionHeight(), true, nil
+}
+
+// detectionTimeout returns DetectionTimeout, or its default.
+func (m *SwapManager) detectionTimeout() time.Duration {
+	if m.DetectionTimeout == 0 {
+		return DefaultDetectionTimeout
+	}
+	return m.DetectionTimeout
+}
+
+// nullifiers returns the nullifiers a transaction reveals, of the notes it
+// spends and the swaps it claims.
+func nullifiers(tx *transactionv1alpha1.Transaction) []*sctv1alpha1.Nullifier {
+	var nfs []*sctv1alpha1.Nullifier
+	for _, action := range tx.GetBody().GetActions() {
+		if spend := action.GetSpend(); spend != nil {
+			nfs = append(nfs, &sctv1alpha1.Nullifier{Inner: spend.GetBody().GetNullifier()})
+		}
+		if claim := action.GetSwapClaim(); claim != nil {
+			nfs = append(nfs, claim.GetBody().GetNullifier())
+		}
+	}
+	return nfs
+}
+
+// swapCommitment returns the commitment of the swap in a transaction.
+func swapCommitment(tx *transactionv1alpha1.Transaction) ([]byte, error) {
+	for _, action := range tx.GetBody().GetActions() {
+		if swap := action.GetSwap(); swap != nil {
+			commitment := swap.GetBody().GetPayload().GetCommitment().GetInner()
+			if len(commitment) == 0 {
+				return nil, errors.New("swap has no commitment")
+			}
+			return commitment, nil
+		}
+	}
+	return nil, errors.New("transaction has no swap")
+}
@@ -0,0 +1,398 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	sctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/sct/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeView is a chain and a view server following it. Broadcasting a
+// transaction includes it in a new block, and syncs the view server unless
+// the broadcast is made to fail; the view server otherwise only syncs when
+// asked to await detection. Methods the swap manager does not use panic.
+type fakeView struct {
+	view.Service
+
+	height uint64
+	// spent and swaps are the chain's spent nullifiers and swap records,
+	// and viewSpent and viewSwaps the view server's copies as of its last
+	// sync.
+	spent, viewSpent map[string]bool
+	swaps, viewSwaps []*viewv1alpha1.SwapRecord
+
+	pair    *dexv1alpha1.TradingPair
+	planned *viewv1alpha1.TransactionPlannerRequest
+	built   byte
+	// failBroadcast, if set, fails the next broadcast with this error; the
+	// transaction is included, but the view server does not sync.
+	failBroadcast error
+	// rejectBroadcast, if set, fails the next broadcast without including
+	// the transaction.
+	rejectBroadcast error
+	broadcasts      int
+}
+
+func newFakeView() *fakeView {
+	return &fakeView{
+		spent:     map[string]bool{},
+		viewSpent: map[string]bool{},
+		pair:      &dexv1alpha1.TradingPair{Asset_1: testAssetId(1), Asset_2: testAssetId(2)},
+	}
+}
+
+// swapNullifier is the nullifier of the swap with a commitment.
+func swapNullifier(commitment []byte) *sctv1alpha1.Nullifier {
+	return &sctv1alpha1.Nullifier{Inner: append([]byte("nf"), commitment...)}
+}
+
+func (f *fakeView) sync() {
+	f.viewSpent = map[string]bool{}
+	for nf := range f.spent {
+		f.viewSpent[nf] = true
+	}
+	f.viewSwaps = nil
+	for _, r := range f.swaps {
+		f.viewSwaps = append(f.viewSwaps, proto.Clone(r).(*viewv1alpha1.SwapRecord))
+	}
+}
+
+func (f *fakeView) AddressByIndex(context.Context, *viewv1alpha1.AddressByIndexRequest) (*viewv1alpha1.AddressByIndexResponse, error) {
+	return &viewv1alpha1.AddressByIndexResponse{Address: &keysv1alpha1.Address{Inner: []byte{1}}}, nil
+}
+
+func (f *fakeView) TransactionPlanner(_ context.Context, req *viewv1alpha1.TransactionPlannerRequest) (*viewv1alpha1.TransactionPlannerResponse, error) {
+	f.planned = req
+	return &viewv1alpha1.TransactionPlannerResponse{Plan: &transactionv1alpha1.TransactionPlan{}}, nil
+}
+
+// AuthorizeAndBuild builds the last planned transaction: a swap spending one
+// note, or a swap claim.
+func (f *fakeView) AuthorizeAndBuild(context.Context, *viewv1alpha1.AuthorizeAndBuildRequest) (*viewv1alpha1.AuthorizeAndBuildResponse, error) {
+	var actions []*transactionv1alpha1.Action
+	for _, s := range f.planned.GetSwaps() {
+		f.built++
+		actions = append(actions,
+			&transactionv1alpha1.Action{Action: &transactionv1alpha1.Action_Spend{Spend: &shielded_poolv1alpha1.Spend{
+				Body: &shielded_poolv1alpha1.SpendBody{Nullifier: []byte{0xee, f.built}},
+			}}},
+			&transactionv1alpha1.Action{Action: &transactionv1alpha1.Action_Swap{Swap: &dexv1alpha1.Swap{
+				Body: &dexv1alpha1.SwapBody{
+					TradingPair: f.pair,
+					Delta_1I:    s.GetValue().GetAmount(),
+					Payload:     &dexv1alpha1.SwapPayload{Commitment: &tctv1alpha1.StateCommitment{Inner: bytes.Repeat([]byte{f.built}, 32)}},
+				},
+			}}})
+	}
+	for _, c := range f.planned.GetSwapClaims() {
+		actions = append(actions, &transactionv1alpha1.Action{Action: &transactionv1alpha1.Action_SwapClaim{SwapClaim: &dexv1alpha1.SwapClaim{
+			Body: &dexv1alpha1.SwapClaimBody{Nullifier: swapNullifier(c.GetSwapCommitment().GetInner())},
+		}}})
+	}
+	return &viewv1alpha1.AuthorizeAndBuildResponse{
+		Transaction: &transactionv1alpha1.Transaction{Body: &transactionv1alpha1.TransactionBody{Actions: actions}},
+	}, nil
+}
+
+func (f *fakeView) BroadcastTransaction(_ context.Context, req *viewv1alpha1.BroadcastTransactionRequest) (*viewv1alpha1.BroadcastTransactionResponse, error) {
+	if err := f.rejectBroadcast; err != nil {
+		f.rejectBroadcast = nil
+		return nil, err
+	}
+	tx := req.GetTransaction()
+	for _, nf := range nullifiers(tx) {
+		if f.spent[string(nf.GetInner())] {
+			return nil, errors.New("Error submitting transaction: code 1, log: nullifier 0x1234 was already spent")
+		}
+	}
+	f.height++
+	for _, nf := range nullifiers(tx) {
+		f.spent[string(nf.GetInner())] = true
+	}
+	for _, action := range tx.GetBody().GetActions() {
+		if swap := action.GetSwap().GetBody(); swap != nil {
+			commitment := swap.GetPayload().GetCommitment()
+			f.swaps = append(f.swaps, &viewv1alpha1.SwapRecord{
+				SwapCommitment: commitment,
+				Swap:           &dexv1alpha1.SwapPlaintext{TradingPair: swap.GetTradingPair(), Delta_1I: swap.GetDelta_1I(), Delta_2I: swap.GetDelta_2I()},
+				Nullifier:      swapNullifier(commitment.GetInner()),
+				OutputData:     &dexv1alpha1.BatchSwapOutputData{Height: f.height},
+			})
+		}
+		if claim := action.GetSwapClaim().GetBody(); claim != nil {
+			for _, r := range f.swaps {
+				if proto.Equal(r.GetNullifier(), claim.GetNullifier()) {
+					r.HeightClaimed = f.height
+				}
+			}
+		}
+	}
+	if err := f.failBroadcast; err != nil {
+		f.failBroadcast = nil
+		return nil, err
+	}
+	f.sync()
+	f.broadcasts++
+	return &viewv1alpha1.BroadcastTransactionResponse{DetectionHeight: f.height}, nil
+}
+
+func (f *fakeView) UnclaimedSwaps(context.Context, *viewv1alpha1.UnclaimedSwapsRequest) (grpcclient.Stream[viewv1alpha1.UnclaimedSwapsResponse], error) {
+	s := &sliceStream[viewv1alpha1.UnclaimedSwapsResponse]{}
+	for _, r := range f.viewSwaps {
+		if r.GetHeightClaimed() == 0 {
+			s.items = append(s.items, &viewv1alpha1.UnclaimedSwapsResponse{Swap: r})
+		}
+	}
+	return s, nil
+}
+
+func (f *fakeView) SwapByCommitment(ctx context.Context, req *viewv1alpha1.SwapByCommitmentRequest) (*viewv1alpha1.SwapByCommitmentResponse, error) {
+	if req.GetAwaitDetection() {
+		f.sync()
+	}
+	for _, r := range f.viewSwaps {
+		if proto.Equal(r.GetSwapCommitment(), req.GetSwapCommitment()) {
+			return &viewv1alpha1.SwapByCommitmentResponse{Swap: r}, nil
+		}
+	}
+	if req.GetAwaitDetection() {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return nil, errors.New("swap not found")
+}
+
+func (f *fakeView) NullifierStatus(ctx context.Context, req *viewv1alpha1.NullifierStatusRequest) (*viewv1alpha1.NullifierStatusResponse, error) {
+	if req.GetAwaitDetection() {
+		f.sync()
+	}
+	spent := f.viewSpent[string(req.GetNullifier().GetInner())]
+	if !spent && req.GetAwaitDetection() {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &viewv1alpha1.NullifierStatusResponse{Spent: spent}, nil
+}
+
+// fakeDex serves the output data of batches in which 1024 of asset 1
+// traded for 2048 of asset 2, or fails if err is set.
+type fakeDex struct {
+	QueryService
+	err error
+}
+
+func (d *fakeDex) BatchSwapOutputData(_ context.Context, req *dexv1alpha1.BatchSwapOutputDataRequest) (*dexv1alpha1.BatchSwapOutputDataResponse, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &dexv1alpha1.BatchSwapOutputDataResponse{Data: &dexv1alpha1.BatchSwapOutputData{
+		Delta_1:     num.NewAmount(1024).Proto(),
+		Delta_2:     num.NewAmount(0).Proto(),
+		Lambda_1:    num.NewAmount(0).Proto(),
+		Lambda_2:    num.NewAmount(2048).Proto(),
+		Unfilled_1:  num.NewAmount(0).Proto(),
+		Unfilled_2:  num.NewAmount(0).Proto(),
+		Height:      req.GetHeight(),
+		TradingPair: req.GetTradingPair(),
+	}}, nil
+}
+
+func newTestManager(t *testing.T, f *fakeView, d *fakeDex, path string) *SwapManager {
+	m := &SwapManager{View: f, Dex: d, Path: path, DetectionTimeout: 50 * time.Millisecond}
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func submitTestSwap(t *testing.T, m *SwapManager) (*ManagedSwap, error) {
+	input := &assetv1alpha1.Value{Amount: num.NewAmount(100).Proto(), AssetId: testAssetId(1)}
+	return m.Swap(context.Background(), input, testAssetId(2), nil, nil)
+}
+
+// checkClaimed checks that the manager, and one loaded from its file, have
+// claimed the only swap.
+func checkClaimed(t *testing.T, name string, m *SwapManager) {
+	t.Helper()
+	for _, m := range []*SwapManager{m, newTestManager(t, nil, nil, m.Path)} {
+		swaps := m.Swaps()
+		if len(swaps) != 1 {
+			t.Errorf("%s: %d swaps, want 1", name, len(swaps))
+			return
+		}
+		s := swaps[0]
+		if s.Stage != SwapClaimed || s.Height == 0 || s.ClaimHeight <= s.Height || s.Transaction != nil {
+			t.Errorf("%s: swap = %+v", name, s)
+		}
+		if s.Output1 != num.Zero || s.Output2 != num.NewAmount(200) {
+			t.Errorf("%s: outputs %s, %s, want 0, 200", name, s.Output1, s.Output2)
+		}
+	}
+}
+
+func TestSwapManagerSwap(t *testing.T) {
+	f := newFakeView()
+	m := newTestManager(t, f, &fakeDex{}, filepath.Join(t.TempDir(), "swaps.json"))
+	if _, err := submitTestSwap(t, m); err != nil {
+		t.Fatal(err)
+	}
+	checkClaimed(t, "swap", m)
+	if f.broadcasts != 2 {
+		t.Errorf("%d broadcasts, want the swap and its claim", f.broadcasts)
+	}
+	if m.Swaps()[0].Height != 1 || m.Swaps()[0].ClaimHeight != 2 {
+		t.Errorf("swap = %+v", m.Swaps()[0])
+	}
+
+	if err := m.Prune(); err != nil || len(m.Swaps()) != 0 {
+		t.Errorf("Prune left %d swaps, %v", len(m.Swaps()), err)
+	}
+	if swaps := newTestManager(t, nil, nil, m.Path).Swaps(); len(swaps) != 0 {
+		t.Errorf("Prune saved %d swaps", len(swaps))
+	}
+}
+
+func TestSwapManagerStages(t *testing.T) {
+	f := newFakeView()
+	d := &fakeDex{err: errors.New("fullnode unavailable")}
+	path := filepath.Join(t.TempDir(), "swaps.json")
+	m := newTestManager(t, f, d, path)
+
+	// The swap is included, but its output data cannot be fetched: it is
+	// left built, with its transaction saved.
+	if _, err := submitTestSwap(t, m); err == nil {
+		t.Fatal("Swap succeeded without output data")
+	}
+	s := newTestManager(t, nil, nil, path).Swaps()[0]
+	if s.Stage != SwapBuilt || s.Transaction == nil {
+		t.Errorf("after a failed fetch, saved swap = %+v", s)
+	}
+
+	// The claim fails to broadcast: the swap is left with the claim built.
+	d.err = nil
+	f.rejectBroadcast = errors.New("connection refused")
+	if err := m.Resume(context.Background()); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("Resume = %v, want the broadcast error", err)
+	}
+	s = newTestManager(t, nil, nil, path).Swaps()[0]
+	if s.Stage != SwapClaimBuilt || s.Transaction == nil || s.Output2 != num.NewAmount(200) {
+		t.Errorf("after a failed claim, saved swap = %+v", s)
+	}
+
+	// A restarted manager claims the swap.
+	m = newTestManager(t, f, d, path)
+	if err := m.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkClaimed(t, "resumed", m)
+	if f.broadcasts != 2 {
+		t.Errorf("%d broadcasts accepted, want 2", f.broadcasts)
+	}
+}
+
+// TestSwapManagerResumeIncluded resumes swaps whose transaction was included
+// although the manager did not see its broadcast succeed, as when it crashes
+// after broadcasting but before saving.
+func TestSwapManagerResumeIncluded(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// synced is whether the view server has caught up with the
+		// transaction when the manager resumes.
+		synced bool
+	}{
+		{"view server synced", true},
+		{"view server lagging", false},
+	} {
+		// The swap is included, but its broadcast fails.
+		f := newFakeView()
+		d := &fakeDex{}
+		m := newTestManager(t, f, d, filepath.Join(t.TempDir(), "swaps.json"))
+		f.failBroadcast = errors.New("connection reset")
+		if _, err := submitTestSwap(t, m); err == nil {
+			t.Fatalf("%s: Swap succeeded", tc.name)
+		}
+		if tc.synced {
+			f.sync()
+		}
+		m = newTestManager(t, f, d, m.Path)
+		if err := m.Resume(context.Background()); err != nil {
+			t.Fatalf("%s: resuming the swap: %v", tc.name, err)
+		}
+		checkClaimed(t, tc.name+", swap", m)
+		if f.broadcasts != 1 {
+			t.Errorf("%s, swap: %d broadcasts accepted, want only the claim", tc.name, f.broadcasts)
+		}
+
+		// The claim is included, but its broadcast fails.
+		f = newFakeView()
+		m = newTestManager(t, f, d, filepath.Join(t.TempDir(), "swaps.json"))
+		m.View = &failNth{fakeView: f, n: 2, err: errors.New("connection reset")}
+		if _, err := submitTestSwap(t, m); err == nil {
+			t.Fatalf("%s: Swap succeeded", tc.name)
+		}
+		if tc.synced {
+			f.sync()
+		}
+		m = newTestManager(t, f, d, m.Path)
+		if err := m.Resume(context.Background()); err != nil {
+			t.Fatalf("%s: resuming the claim: %v", tc.name, err)
+		}
+		checkClaimed(t, tc.name+", claim", m)
+		if f.broadcasts != 1 {
+			t.Errorf("%s, claim: %d broadcasts accepted, want only the swap", tc.name, f.broadcasts)
+		}
+	}
+}
+
+// failNth fails the nth broadcast to a fakeView after including its
+// transaction.
+type failNth struct {
+	*fakeView
+	n   int
+	err error
+}
+
+func (f *failNth) BroadcastTransaction(ctx context.Context, req *viewv1alpha1.BroadcastTransactionRequest) (*viewv1alpha1.BroadcastTransactionResponse, error) {
+	f.n--
+	if f.n == 0 {
+		f.fakeView.failBroadcast = f.err
+	}
+	return f.fakeView.BroadcastTransaction(ctx, req)
+}
+
+func TestSwapManagerConflictingSpend(t *testing.T) {
+	f := newFakeView()
+	m := newTestManager(t, f, &fakeDex{}, filepath.Join(t.TempDir(), "swaps.json"))
+	f.rejectBroadcast = errors.New("connection refused")
+	if _, err := submitTestSwap(t, m); err == nil {
+		t.Fatal("Swap succeeded")
+	}
+
+	// Another transaction spends the swap's note: the swap is never
+	// detected, and resuming it times out rather than hanging.
+	f.spent[string([]byte{0xee, 1})] = true
+	f.sync()
+	err := m.Resume(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Resume = %v, want a timeout", err)
+	}
+	if s := m.Swaps()[0]; s.Stage != SwapBuilt || f.broadcasts != 0 {
+		t.Errorf("swap = %+v after %d broadcasts", s, f.broadcasts)
+	}
+}
@@ -0,0 +1,48 @@
+// Package dex implements the math of the DEX's concentrated-liquidity
+// positions, mirroring `penumbra_dex`.
+package dex
+
+import (
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+)
+
+// NewTradingPair returns the canonical trading pair of two assets, which
+// orders them by asset ID.
+func NewTradingPair(a, b *assetv1alpha1.AssetId) *dexv1alpha1.TradingPair {
+	if asset.CompareIds(a, b) < 0 {
+		return &dexv1alpha1.TradingPair{Asset_1: a, Asset_2: b}
+	}
+	return &dexv1alpha1.TradingPair{Asset_1: b, Asset_2: a}
+}
+
+// IsCanonical reports whether a trading pair orders its assets by asset ID,
+// as pd requires.
+func IsCanonical(pair *dexv1alpha1.TradingPair) bool {
+	return asset.CompareIds(pair.GetAsset_1(), pair.GetAsset_2()) < 0
+}
+
+// CanonicalPair returns the canonical trading pair of a directed pair, and
+// whether the direction is from asset 2 to asset 1.
+func CanonicalPair(pair *dexv1alpha1.DirectedTradingPair) (*dexv1alpha1.TradingPair, bool) {
+	canonical := NewTradingPair(pair.GetStart(), pair.GetEnd())
+	return canonical, !asset.IdEqual(pair.GetStart(), canonical.GetAsset_1())
+}
+
+// Flip returns the directed pair in the opposite direction.
+func Flip(pair *dexv1alpha1.DirectedTradingPair) *dexv1alpha1.DirectedTradingPair {
+	return &dexv1alpha1.DirectedTradingPair{Start: pair.GetEnd(), End: pair.GetStart()}
+}
+
+// Direct returns the directed pair of a trading pair starting at an asset,
+// or false if the asset is not in the pair.
+func Direct(pair *dexv1alpha1.TradingPair, start *assetv1alpha1.AssetId) (*dexv1alpha1.DirectedTradingPair, bool) {
+	switch {
+	case asset.IdEqual(start, pair.GetAsset_1()):
+		return &dexv1alpha1.DirectedTradingPair{Start: pair.GetAsset_1(), End: pair.GetAsset_2()}, true
+	case asset.IdEqual(start, pair.GetAsset_2()):
+		return &dexv1alpha1.DirectedTradingPair{Start: pair.GetAsset_2(), End: pair.GetAsset_1()}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,143 @@
+package dex
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+const (
+	// MaxFeeBps is the largest fee a position may charge, 50%.
+	MaxFeeBps = 5000
+	// NonceLen is the length of a position nonce.
+	NonceLen = 32
+	// PositionIdLen is the length of a position ID.
+	PositionIdLen = 32
+
+	positionIdPersonal = "penumbra_lp_id"
+)
+
+// MaxReserveAmount is the largest reserve or coefficient a position may
+// have, 2^80 - 1, which keeps the trading function's products in range.
+var MaxReserveAmount = num.NewAmountHiLo(1<<16-1, ^uint64(0))
+
+// NewPosition returns an opened position trading along a directed pair with
+// price p/q of the start asset in the end asset, and reserves of each. Like
+// `Position::new`, it orients the pair canonically, swapping the
+// coefficients and reserves if needed, and picks a random nonce from r, or
+// crypto/rand if r is nil.
+func NewPosition(r io.Reader, pair *dexv1alpha1.DirectedTradingPair, fee uint32, p, q num.Amount, reserves Reserves) (*dexv1alpha1.Position, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	nonce := make([]byte, NonceLen)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, err
+	}
+	canonical, flipped := CanonicalPair(pair)
+	f := BareTradingFunction{Fee: fee, P: p, Q: q}
+	if flipped {
+		f, reserves = f.Flip(), reserves.Flip()
+	}
+	return &dexv1alpha1.Position{
+		Phi:      &dexv1alpha1.TradingFunction{Component: f.Proto(), Pair: canonical},
+		Nonce:    nonce,
+		State:    &dexv1alpha1.PositionState{State: dexv1alpha1.PositionState_POSITION_STATE_ENUM_OPENED},
+		Reserves: reserves.Proto(),
+	}, nil
+}
+
+// PositionId returns the ID of a position, which commits to its nonce and
+// trading function but not its state or reserves.
+func PositionId(pos *dexv1alpha1.Position) (*dexv1alpha1.PositionId, error) {
+	phi := pos.GetPhi()
+	f, err := BareTradingFunctionFromProto(phi.GetComponent())
+	if err != nil {
+		return nil, err
+	}
+	if len(pos.GetNonce()) != NonceLen {
+		return nil, fmt.Errorf("position nonce has length %d, expected %d", len(pos.GetNonce()), NonceLen)
+	}
+	fee := binary.LittleEndian.AppendUint32(nil, f.Fee)
+	sum := blake2b.Sum512(positionIdPersonal,
+		pos.GetNonce(),
+		phi.GetPair().GetAsset_1().GetInner(),
+		phi.GetPair().GetAsset_2().GetInner(),
+		fee,
+		f.P.LEBytes(),
+		f.Q.LEBytes(),
+	)
+	return &dexv1alpha1.PositionId{Inner: sum[:PositionIdLen]}, nil
+}
+
+// FormatPositionId returns the Bech32m encoding of a position ID.
+func FormatPositionId(id *dexv1alpha1.PositionId) string {
+	if id.GetAltBech32M() != "" {
+		return id.GetAltBech32M()
+	}
+	return bech32str.Encode(id.GetInner(), bech32str.LpIdPrefix, bech32str.Bech32m)
+}
+
+// ParsePositionId decodes a Bech32m position ID.
+func ParsePositionId(s string) (*dexv1alpha1.PositionId, error) {
+	inner, err := bech32str.Decode(s, bech32str.LpIdPrefix, bech32str.Bech32m)
+	if err != nil {
+		return nil, err
+	}
+	if len(inner) != PositionIdLen {
+		return nil, fmt.Errorf("position ID has length %d, expected %d", len(inner), PositionIdLen)
+	}
+	return &dexv1alpha1.PositionId{Inner: inner}, nil
+}
+
+// ValidatePosition checks a position as pd does before opening it.
+func ValidatePosition(pos *dexv1alpha1.Position) error {
+	f, err := BareTradingFunctionFromProto(pos.GetPhi().GetComponent())
+	if err != nil {
+		return err
+	}
+	reserves, err := ReservesFromProto(pos.GetReserves())
+	if err != nil {
+		return err
+	}
+	pair := pos.GetPhi().GetPair()
+	switch {
+	case reserves.R1.Cmp(MaxReserveAmount) > 0 || reserves.R2.Cmp(MaxReserveAmount) > 0:
+		return fmt.Errorf("reserves exceed the maximum of %s", MaxReserveAmount)
+	case reserves.R1.IsZero() && reserves.R2.IsZero():
+		return errors.New("position has no reserves")
+	case f.P.IsZero() || f.Q.IsZero():
+		return errors.New("trading function has a zero coefficient")
+	case f.P.Cmp(MaxReserveAmount) > 0 || f.Q.Cmp(MaxReserveAmount) > 0:
+		return fmt.Errorf("trading function coefficients exceed the maximum of %s", MaxReserveAmount)
+	case asset.IdEqual(pair.GetAsset_1(), pair.GetAsset_2()):
+		return errors.New("trading pair has the same asset twice")
+	case !IsCanonical(pair):
+		return errors.New("trading pair is not canonically ordered")
+	case f.Fee > MaxFeeBps:
+		return fmt.Errorf("fee of %d bps exceeds the maximum of %d", f.Fee, MaxFeeBps)
+	}
+	return nil
+}
+
+// ReservesFor returns a position's reserves of an asset, or false if the
+// asset is not in its pair.
+func ReservesFor(pos *dexv1alpha1.Position, id *assetv1alpha1.AssetId) (num.Amount, bool) {
+	pair := pos.GetPhi().GetPair()
+	switch {
+	case asset.IdEqual(id, pair.GetAsset_1()):
+		return num.AmountFromProto(pos.GetReserves().GetR1()), true
+	case asset.IdEqual(id, pair.GetAsset_2()):
+		return num.AmountFromProto(pos.GetReserves().GetR2()), true
+	}
+	return num.Zero, false
+}
@@ -0,0 +1,100 @@
+package dex
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+func TestPositionId(t *testing.T) {
+	pos, err := NewPosition(bytes.NewReader(bytes.Repeat([]byte{1}, NonceLen)),
+		&dexv1alpha1.DirectedTradingPair{Start: testAssetId(0), End: testAssetId(1)},
+		30, num.NewAmount(1), num.NewAmount(2), Reserves{R1: num.NewAmount(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := PositionId(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// blake2b-512 personalized with "penumbra_lp_id" of nonce || asset 1 ||
+	// asset 2 || fee (LE u32) || p (LE u128) || q (LE u128), truncated.
+	const want = "f438bcb130a674793eb1033f1c7e4de2c404f06a4caaa288ba1c44881210be26"
+	if got := hex.EncodeToString(id.GetInner()); got != want {
+		t.Errorf("PositionId = %s, want %s", got, want)
+	}
+	parsed, err := ParsePositionId(FormatPositionId(id))
+	if err != nil || !bytes.Equal(parsed.GetInner(), id.GetInner()) {
+		t.Errorf("ParsePositionId(FormatPositionId) = %x, %v", parsed.GetInner(), err)
+	}
+
+	// The ID does not commit to the reserves or state.
+	pos.Reserves = Reserves{R2: num.NewAmount(5)}.Proto()
+	pos.State = &dexv1alpha1.PositionState{State: dexv1alpha1.PositionState_POSITION_STATE_ENUM_CLOSED}
+	if other, _ := PositionId(pos); !bytes.Equal(other.GetInner(), id.GetInner()) {
+		t.Errorf("PositionId changed with the reserves")
+	}
+}
+
+// TestNewPositionOrientation follows `test_position` in
+// `penumbra_dex::lp::position`: a position along the reversed pair is the
+// same as one along the canonical pair with the price and reserves flipped.
+func TestNewPositionOrientation(t *testing.T) {
+	small, big := testAssetId(0), testAssetId(1)
+	forward := &dexv1alpha1.DirectedTradingPair{Start: small, End: big}
+	backward := Flip(forward)
+	r := Reserves{R1: num.NewAmount(150)}
+	nonce := bytes.Repeat([]byte{7}, NonceLen)
+
+	a, _ := NewPosition(bytes.NewReader(nonce), forward, 0, num.NewAmount(100), num.NewAmount(1), r)
+	b, _ := NewPosition(bytes.NewReader(nonce), backward, 0, num.NewAmount(1), num.NewAmount(100), r.Flip())
+	idA, _ := PositionId(a)
+	idB, _ := PositionId(b)
+	if !bytes.Equal(idA.GetInner(), idB.GetInner()) {
+		t.Errorf("positions along both directions differ")
+	}
+	if err := ValidatePosition(b); err != nil {
+		t.Errorf("ValidatePosition: %v", err)
+	}
+
+	c, _ := NewPosition(bytes.NewReader(nonce), backward, 0, num.NewAmount(100), num.NewAmount(1), r)
+	if idC, _ := PositionId(c); bytes.Equal(idA.GetInner(), idC.GetInner()) {
+		t.Errorf("positions at inverse prices have the same ID")
+	}
+}
+
+func TestValidatePosition(t *testing.T) {
+	valid := func() *dexv1alpha1.Position {
+		pos, _ := NewPosition(nil, &dexv1alpha1.DirectedTradingPair{Start: testAssetId(0), End: testAssetId(1)},
+			30, num.NewAmount(1), num.NewAmount(2), Reserves{R1: num.NewAmount(100)})
+		return pos
+	}
+	if err := ValidatePosition(valid()); err != nil {
+		t.Fatalf("ValidatePosition: %v", err)
+	}
+	tests := []struct {
+		name   string
+		modify func(*dexv1alpha1.Position)
+	}{
+		{"no reserves", func(p *dexv1alpha1.Position) { p.Reserves = Reserves{}.Proto() }},
+		{"reserves too large", func(p *dexv1alpha1.Position) {
+			p.Reserves = Reserves{R1: num.NewAmountHiLo(1<<16, 0)}.Proto()
+		}},
+		{"zero coefficient", func(p *dexv1alpha1.Position) { p.Phi.Component.P = num.Zero.Proto() }},
+		{"fee too large", func(p *dexv1alpha1.Position) { p.Phi.Component.Fee = MaxFeeBps + 1 }},
+		{"same asset", func(p *dexv1alpha1.Position) { p.Phi.Pair.Asset_2 = p.Phi.Pair.Asset_1 }},
+		{"not canonical", func(p *dexv1alpha1.Position) {
+			p.Phi.Pair.Asset_1, p.Phi.Pair.Asset_2 = p.Phi.Pair.Asset_2, p.Phi.Pair.Asset_1
+		}},
+	}
+	for _, tt := range tests {
+		pos := valid()
+		tt.modify(pos)
+		if err := ValidatePosition(pos); err == nil {
+			t.Errorf("%s: ValidatePosition accepted the position", tt.name)
+		}
+	}
+}
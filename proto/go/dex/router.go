@@ -0,0 +1,821 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultMaxHops is the longest route pd searches by default.
+	DefaultMaxHops = 4
+	// DynamicCandidateLimit is the number of assets pd considers routing
+	// through from each asset, besides the fixed candidates, picked by the
+	// liquidity of the pairs.
+	DynamicCandidateLimit = 10
+)
+
+// DefaultCandidateDenoms are the base denoms of pd's fixed routing
+// candidates, which are always considered as intermediate assets.
+var DefaultCandidateDenoms = []string{
+	"wtest_usd", "upenumbra", "ugm", "ugn", "utest_atom", "utest_osmo", "test_sat",
+}
+
+// ErrInsufficientLiquidity is returned when a route has a pair without
+// positions left to fill against.
+var ErrInsufficientLiquidity = errors.New("insufficient liquidity on route")
+
+// RoutingParams control route search, mirroring
+// `penumbra_dex::component::router::RoutingParams`.
+type RoutingParams struct {
+	// PriceLimit, if set, stops routing once the price of a route, input per
+	// output, reaches it.
+	PriceLimit *num.U128x128
+	// FixedCandidates are always considered as intermediate assets.
+	FixedCandidates []*assetv1alpha1.AssetId
+	MaxHops         int
+}
+
+// DefaultRoutingParams returns the parameters pd uses for the Default
+// routing setting, resolving the fixed candidates from a cache of denoms.
+// Denoms missing from the cache are skipped.
+func DefaultRoutingParams(cache *asset.Cache) RoutingParams {
+	params := RoutingParams{MaxHops: DefaultMaxHops}
+	for _, base := range DefaultCandidateDenoms {
+		if d, ok := cache.GetByBase(base); ok && d.GetPenumbraAssetId() != nil {
+			params.FixedCandidates = append(params.FixedCandidates, d.GetPenumbraAssetId())
+		}
+	}
+	return params
+}
+
+// RoutingParamsFor returns the parameters pd uses for a SimulateTrade
+// routing setting.
+func RoutingParamsFor(routing *dexv1alpha1.SimulateTradeRequest_Routing, cache *asset.Cache) RoutingParams {
+	params := DefaultRoutingParams(cache)
+	if routing.GetSingleHop() != nil {
+		params.MaxHops = 1
+	}
+	return params
+}
+
+// Snapshot is a set of positions that trades can be routed and filled
+// against locally, following pd's routing and execution. Fills match pd's
+// for the same route, but route search can pick a different route where pd
+// itself is not deterministic; see candidates and PathSearch. Routing
+// updates the positions, as executing a batch swap would.
+type Snapshot struct {
+	positions map[string]*dexv1alpha1.Position
+	// byPrice caches the open positions able to trade along each directed
+	// pair, from the cheapest, as pd's price index does.
+	byPrice map[string][]string
+}
+
+// NewSnapshot returns a snapshot of copies of positions.
+func NewSnapshot(positions []*dexv1alpha1.Position) (*Snapshot, error) {
+	s := &Snapshot{positions: make(map[string]*dexv1alpha1.Position, len(positions))}
+	for _, pos := range positions {
+		id, err := PositionId(pos)
+		if err != nil {
+			return nil, err
+		}
+		s.positions[string(id.GetInner())] = proto.Clone(pos).(*dexv1alpha1.Position)
+	}
+	return s, nil
+}
+
+// LoadSnapshot returns a snapshot of the fullnode's open positions.
+func LoadSnapshot(ctx context.Context, q QueryService, chainId string) (*Snapshot, error) {
+	stream, err := q.LiquidityPositions(ctx, &dexv1alpha1.LiquidityPositionsRequest{ChainId: chainId})
+	if err != nil {
+		return nil, err
+	}
+	var positions []*dexv1alpha1.Position
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, rsp.GetData())
+	}
+	return NewSnapshot(positions)
+}
+
+// Clone returns an independent copy of the snapshot.
+func (s *Snapshot) Clone() *Snapshot {
+	c := &Snapshot{positions: make(map[string]*dexv1alpha1.Position, len(s.positions))}
+	for id, pos := range s.positions {
+		c.positions[id] = proto.Clone(pos).(*dexv1alpha1.Position)
+	}
+	return c
+}
+
+// Positions returns copies of the snapshot's positions.
+func (s *Snapshot) Positions() []*dexv1alpha1.Position {
+	positions := make([]*dexv1alpha1.Position, 0, len(s.positions))
+	for _, pos := range s.positions {
+		positions = append(positions, proto.Clone(pos).(*dexv1alpha1.Position))
+	}
+	return positions
+}
+
+func pairKey(start, end *assetv1alpha1.AssetId) string {
+	return string(start.GetInner()) + string(end.GetInner())
+}
+
+func isOpen(pos *dexv1alpha1.Position) bool {
+	return pos.GetState().GetState() == dexv1alpha1.PositionState_POSITION_STATE_ENUM_OPENED
+}
+
+// positionsByPrice returns the IDs of the open positions with reserves of
+// the end asset, ordered by effective price and then ID.
+func (s *Snapshot) positionsByPrice(start, end *assetv1alpha1.AssetId) []string {
+	key := pairKey(start, end)
+	if ids, ok := s.byPrice[key]; ok {
+		return ids
+	}
+	type entry struct {
+		id    string
+		price []byte
+	}
+	var entries []entry
+	for id, pos := range s.positions {
+		if !isOpen(pos) {
+			continue
+		}
+		pair := pos.GetPhi().GetPair()
+		if !(asset.IdEqual(start, pair.GetAsset_1()) && asset.IdEqual(end, pair.GetAsset_2()) ||
+			asset.IdEqual(start, pair.GetAsset_2()) && asset.IdEqual(end, pair.GetAsset_1())) {
+			continue
+		}
+		if r, _ := ReservesFor(pos, end); r.IsZero() {
+			continue
+		}
+		f, _, err := Orient(pos.GetPhi(), start)
+		if err != nil {
+			continue
+		}
+		price, err := f.EffectivePriceKey()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{id: id, price: price})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if c := bytes.Compare(entries[i].price, entries[j].price); c != 0 {
+			return c < 0
+		}
+		return entries[i].id < entries[j].id
+	})
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.id
+	}
+	if s.byPrice == nil {
+		s.byPrice = make(map[string][]string)
+	}
+	s.byPrice[key] = ids
+	return ids
+}
+
+// put stores an updated position, closing filled limit orders as pd does.
+func (s *Snapshot) put(id string, pos *dexv1alpha1.Position) {
+	if _, ok := s.positions[id]; ok && pos.GetCloseOnFill() && isOpen(pos) {
+		if num.AmountFromProto(pos.GetReserves().GetR1()).IsZero() || num.AmountFromProto(pos.GetReserves().GetR2()).IsZero() {
+			pos.State = &dexv1alpha1.PositionState{State: dexv1alpha1.PositionState_POSITION_STATE_ENUM_CLOSED}
+		}
+	}
+	s.positions[id] = pos
+	s.byPrice = nil
+}
+
+func (s *Snapshot) closePosition(id string) {
+	if pos, ok := s.positions[id]; ok {
+		pos.State = &dexv1alpha1.PositionState{State: dexv1alpha1.PositionState_POSITION_STATE_ENUM_CLOSED}
+		s.byPrice = nil
+	}
+}
+
+// candidates returns the assets to consider routing to from an asset: the
+// fixed candidates, then the assets it has open positions with, ordered by
+// the reserves of the asset on the pair, as pd's liquidity index iterates.
+//
+// This approximates pd's candidates rather than reproducing them. pd keys
+// its liquidity index by `dex/ra/ || from || be(liquidity)`, with the other
+// asset as the value, so of the pairs with the same liquidity from an asset
+// only the last one written is a candidate; this function keeps all of them,
+// ordered by asset ID. pd also maintains each pair's liquidity with
+// saturating arithmetic as positions change, where this sums the reserves.
+func (s *Snapshot) candidates(from *assetv1alpha1.AssetId, fixed []*assetv1alpha1.AssetId) []*assetv1alpha1.AssetId {
+	liquidity := make(map[string]num.Amount)
+	others := make(map[string]*assetv1alpha1.AssetId)
+	for _, pos := range s.positions {
+		if !isOpen(pos) {
+			continue
+		}
+		pair := pos.GetPhi().GetPair()
+		var other *assetv1alpha1.AssetId
+		switch {
+		case asset.IdEqual(from, pair.GetAsset_1()):
+			other = pair.GetAsset_2()
+		case asset.IdEqual(from, pair.GetAsset_2()):
+			other = pair.GetAsset_1()
+		default:
+			continue
+		}
+		r, _ := ReservesFor(pos, from)
+		key := string(other.GetInner())
+		liquidity[key], _ = liquidity[key].CheckedAdd(r)
+		others[key] = other
+	}
+	var dynamic []string
+	for key := range others {
+		fixedCandidate := false
+		for _, c := range fixed {
+			fixedCandidate = fixedCandidate || string(c.GetInner()) == key
+		}
+		if !fixedCandidate {
+			dynamic = append(dynamic, key)
+		}
+	}
+	sort.Slice(dynamic, func(i, j int) bool {
+		if c := liquidity[dynamic[i]].Cmp(liquidity[dynamic[j]]); c != 0 {
+			return c < 0
+		}
+		return dynamic[i] < dynamic[j]
+	})
+	if len(dynamic) > DynamicCandidateLimit {
+		dynamic = dynamic[:DynamicCandidateLimit]
+	}
+	candidates := append([]*assetv1alpha1.AssetId(nil), fixed...)
+	for _, key := range dynamic {
+		candidates = append(candidates, others[key])
+	}
+	return candidates
+}
+
+// path is a candidate route from the start asset, with an estimate of its
+// price. used holds the positions its price estimate consumed, which are
+// not used again further along it.
+type path struct {
+	start *assetv1alpha1.AssetId
+	nodes []*assetv1alpha1.AssetId
+	price num.U128x128
+	used  map[string]bool
+}
+
+func (p *path) end() *assetv1alpha1.AssetId {
+	if len(p.nodes) == 0 {
+		return p.start
+	}
+	return p.nodes[len(p.nodes)-1]
+}
+
+// comparePaths orders paths by price, then length, then assets, as pd does.
+func comparePaths(a, b *path) int {
+	if c := a.price.Cmp(b.price); c != 0 {
+		return c
+	}
+	if len(a.nodes) != len(b.nodes) {
+		if len(a.nodes) < len(b.nodes) {
+			return -1
+		}
+		return 1
+	}
+	if c := asset.CompareIds(a.start, b.start); c != 0 {
+		return c
+	}
+	for i := range a.nodes {
+		if c := asset.CompareIds(a.nodes[i], b.nodes[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// extend returns the path extended by a hop through the best position to
+// an asset, or nil if there is none.
+func (s *Snapshot) extend(p *path, to *assetv1alpha1.AssetId) *path {
+	for _, id := range s.positionsByPrice(p.end(), to) {
+		if p.used[id] {
+			continue
+		}
+		f, _, err := Orient(s.positions[id].GetPhi(), p.end())
+		if err != nil {
+			return nil
+		}
+		hop, err := f.EffectivePrice()
+		if err != nil {
+			return nil
+		}
+		price, err := p.price.Mul(hop)
+		if err != nil {
+			return nil
+		}
+		used := make(map[string]bool, len(p.used)+1)
+		for k := range p.used {
+			used[k] = true
+		}
+		used[id] = true
+		return &path{
+			start: p.start,
+			nodes: append(append([]*assetv1alpha1.AssetId(nil), p.nodes...), to),
+			price: price,
+			used:  used,
+		}
+	}
+	return nil
+}
+
+// pathEntry is the best known path to an asset, and the second best, whose
+// price is the spill price.
+type pathEntry struct {
+	path   *path
+	spill  *path
+	active bool
+}
+
+func (e *pathEntry) update(p *path) {
+	if comparePaths(p, e.path) < 0 {
+		e.spill, e.path, e.active = e.path, p, true
+		return
+	}
+	if e.spill == nil || p.price.Cmp(e.spill.price) < 0 {
+		e.spill, e.active = p, true
+	}
+}
+
+// PathSearch finds the best route from src to dst with the Bellman-Ford
+// search pd uses, returning the assets after src and the spill price of the
+// next best route, if any. The route is nil if there is none, or if its
+// estimated price reaches the price limit.
+//
+// pd relaxes the active paths, and the candidates of each, concurrently, so
+// the order in which it considers paths of equal price, and so which one it
+// keeps and which becomes the spill price, depends on task scheduling. This
+// search relaxes them in asset order, which is one of the orders pd may
+// take.
+func (s *Snapshot) PathSearch(src, dst *assetv1alpha1.AssetId, params RoutingParams) ([]*assetv1alpha1.AssetId, *num.U128x128, error) {
+	one := num.U128x128FromAmount(num.NewAmount(1))
+	cache := map[string]*pathEntry{
+		string(src.GetInner()): {path: &path{start: src, price: one}, active: true},
+	}
+	for i := 0; i < params.MaxHops; i++ {
+		// Relax the active paths in asset order, one of the orders pd's
+		// concurrent relaxation may take.
+		keys := make([]string, 0, len(cache))
+		for k, e := range cache {
+			if e.active {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return asset.CompareIds(cache[keys[i]].path.end(), cache[keys[j]].path.end()) < 0
+		})
+		active := make([]*path, len(keys))
+		for i, k := range keys {
+			cache[k].active = false
+			active[i] = cache[k].path
+		}
+		for _, p := range active {
+			for _, to := range s.candidates(p.end(), params.FixedCandidates) {
+				extended := s.extend(p, to)
+				if extended == nil {
+					continue
+				}
+				key := string(to.GetInner())
+				if e, ok := cache[key]; ok {
+					e.update(extended)
+				} else {
+					cache[key] = &pathEntry{path: extended, active: true}
+				}
+			}
+		}
+	}
+	e, ok := cache[string(dst.GetInner())]
+	if !ok || asset.IdEqual(src, dst) {
+		return nil, nil, nil
+	}
+	if params.PriceLimit != nil && e.path.price.Cmp(*params.PriceLimit) >= 0 {
+		return nil, nil, nil
+	}
+	var spill *num.U128x128
+	if e.spill != nil {
+		spill = &e.spill.price
+	}
+	return e.path.nodes, spill, nil
+}
+
+// overflowError reports a position whose fill overflowed, which pd closes
+// before routing again.
+type overflowError struct {
+	id string
+}
+
+func (e *overflowError) Error() string {
+	return fmt.Sprintf("overflow when executing against position %s",
+		FormatPositionId(&dexv1alpha1.PositionId{Inner: []byte(e.id)}))
+}
+
+// frontier is the best position of each hop of a route being filled,
+// mirroring pd's `Frontier`. Position updates are kept in writes until the
+// fill completes.
+type frontier struct {
+	s         *Snapshot
+	route     []*assetv1alpha1.AssetId
+	ids       []string
+	positions []*dexv1alpha1.Position
+	used      map[string]bool
+	streams   map[string]*positionStream
+	writes    map[string]*dexv1alpha1.Position
+	traces    []*dexv1alpha1.SwapExecution_Trace
+}
+
+type positionStream struct {
+	ids  []string
+	next int
+}
+
+// frontierTx is one fill along the frontier, not yet applied.
+type frontierTx struct {
+	reserves []Reserves
+	trace    []num.Amount
+}
+
+func (f *frontier) get(id string) *dexv1alpha1.Position {
+	if pos, ok := f.writes[id]; ok {
+		return proto.Clone(pos).(*dexv1alpha1.Position)
+	}
+	return proto.Clone(f.s.positions[id]).(*dexv1alpha1.Position)
+}
+
+// pull sets hop i to the next unused position of its pair, returning false
+// if there is none.
+func (f *frontier) pull(i int) bool {
+	stream := f.streams[pairKey(f.route[i], f.route[i+1])]
+	for stream.next < len(stream.ids) {
+		id := stream.ids[stream.next]
+		stream.next++
+		if f.used[id] {
+			continue
+		}
+		f.used[id] = true
+		f.ids[i], f.positions[i] = id, f.get(id)
+		return true
+	}
+	return false
+}
+
+func (s *Snapshot) loadFrontier(route []*assetv1alpha1.AssetId) (*frontier, error) {
+	hops := len(route) - 1
+	f := &frontier{
+		s:         s,
+		route:     route,
+		ids:       make([]string, hops),
+		positions: make([]*dexv1alpha1.Position, hops),
+		used:      make(map[string]bool),
+		streams:   make(map[string]*positionStream),
+		writes:    make(map[string]*dexv1alpha1.Position),
+	}
+	for i := 0; i < hops; i++ {
+		key := pairKey(route[i], route[i+1])
+		if _, ok := f.streams[key]; !ok {
+			ids := s.positionsByPrice(route[i], route[i+1])
+			f.streams[key] = &positionStream{ids: append([]string(nil), ids...)}
+		}
+	}
+	for i := 0; i < hops; i++ {
+		if !f.pull(i) {
+			return nil, ErrInsufficientLiquidity
+		}
+	}
+	return f, nil
+}
+
+func value(id *assetv1alpha1.AssetId, amount num.Amount) *assetv1alpha1.Value {
+	return &assetv1alpha1.Value{AssetId: id, Amount: amount.Proto()}
+}
+
+func (f *frontier) reserves(i int) Reserves {
+	r, _ := ReservesFromProto(f.positions[i].GetReserves())
+	return r
+}
+
+// senseCapacityConstraint returns the index of the last hop that cannot
+// fill all of its input, or -1 if the input fits.
+func (f *frontier) senseCapacityConstraint(input num.Amount) (int, error) {
+	constraint := -1
+	current := value(f.route[0], input)
+	for i, pos := range f.positions {
+		unfilled, _, output, err := Fill(pos.GetPhi(), f.reserves(i), current)
+		if err != nil {
+			return 0, &overflowError{f.ids[i]}
+		}
+		if !num.AmountFromProto(unfilled.GetAmount()).IsZero() {
+			constraint = i
+		}
+		current = output
+	}
+	return constraint, nil
+}
+
+func (f *frontier) newTx() *frontierTx {
+	return &frontierTx{
+		reserves: make([]Reserves, len(f.positions)),
+		trace:    make([]num.Amount, len(f.positions)+1),
+	}
+}
+
+func (f *frontier) fillForward(tx *frontierTx, start int, input *assetv1alpha1.Value) error {
+	current := input
+	for i := start; i < len(f.positions); i++ {
+		unfilled, reserves, output, err := Fill(f.positions[i].GetPhi(), f.reserves(i), current)
+		if err != nil {
+			return err
+		}
+		if !num.AmountFromProto(unfilled.GetAmount()).IsZero() {
+			return errors.New("forward fill left input unfilled")
+		}
+		tx.reserves[i] = reserves
+		tx.trace[i+1] = num.AmountFromProto(output.GetAmount())
+		current = output
+	}
+	return nil
+}
+
+func (f *frontier) fillBackward(tx *frontierTx, start int, output *assetv1alpha1.Value) error {
+	current := output
+	for i := start; i >= 0; i-- {
+		tx.trace[i+1] = num.AmountFromProto(current.GetAmount())
+		reserves, input, ok, err := FillOutput(f.positions[i].GetPhi(), f.reserves(i), current)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("backward fill exceeded reserves")
+		}
+		tx.reserves[i] = reserves
+		current = input
+	}
+	tx.trace[0] = num.AmountFromProto(current.GetAmount())
+	return nil
+}
+
+// fillConstrained exhausts the constraining position, working backwards to
+// the input and forwards to the output.
+func (f *frontier) fillConstrained(constraint int) (*frontierTx, error) {
+	tx := f.newTx()
+	end := f.route[constraint+1]
+	exact, _ := ReservesFor(f.positions[constraint], end)
+	if err := f.fillBackward(tx, constraint, value(end, exact)); err != nil {
+		return nil, err
+	}
+	if err := f.fillForward(tx, constraint+1, value(end, exact)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (f *frontier) fillUnconstrained(input num.Amount) (*frontierTx, error) {
+	tx := f.newTx()
+	tx.trace[0] = input
+	if err := f.fillForward(tx, 0, value(f.route[0], input)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (tx *frontierTx) actualPrice() (num.U128x128, error) {
+	return num.Ratio(tx.trace[0], tx.trace[len(tx.trace)-1])
+}
+
+func (f *frontier) apply(tx *frontierTx) (num.Amount, num.Amount) {
+	trace := &dexv1alpha1.SwapExecution_Trace{Value: []*assetv1alpha1.Value{value(f.route[0], tx.trace[0])}}
+	for i, r := range tx.reserves {
+		f.positions[i].Reserves = r.Proto()
+		trace.Value = append(trace.Value, value(f.route[i+1], tx.trace[i+1]))
+	}
+	f.traces = append(f.traces, trace)
+	return tx.trace[0], tx.trace[len(tx.trace)-1]
+}
+
+// replaceEmptyPositions replaces every exhausted position, returning false
+// if a hop has no positions left.
+func (f *frontier) replaceEmptyPositions() bool {
+	for i := range f.positions {
+		if r, _ := ReservesFor(f.positions[i], f.route[i+1]); r.IsZero() {
+			f.writes[f.ids[i]] = f.positions[i]
+			if !f.pull(i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *frontier) commit() {
+	for i, pos := range f.positions {
+		f.writes[f.ids[i]] = pos
+	}
+	for id, pos := range f.writes {
+		f.s.put(id, pos)
+	}
+}
+
+// FillRoute fills an input along a route of assets after the input asset,
+// stopping once the price of a fill exceeds the spill price, as pd's
+// `fill_route` does. At least one fill is always made.
+func (s *Snapshot) FillRoute(input *assetv1alpha1.Value, hops []*assetv1alpha1.AssetId, spill *num.U128x128) (*dexv1alpha1.SwapExecution, error) {
+	route := append([]*assetv1alpha1.AssetId{input.GetAssetId()}, hops...)
+	if len(route) < 2 {
+		return nil, fmt.Errorf("invalid route length %d (must be at least 2)", len(route))
+	}
+	f, err := s.loadFrontier(route)
+	if err != nil {
+		return nil, err
+	}
+	remaining := num.AmountFromProto(input.GetAmount())
+	filledOnce := false
+	for {
+		constraint, err := f.senseCapacityConstraint(remaining)
+		if err != nil {
+			return nil, err
+		}
+		var tx *frontierTx
+		if constraint >= 0 {
+			tx, err = f.fillConstrained(constraint)
+		} else {
+			tx, err = f.fillUnconstrained(remaining)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if spill != nil && filledOnce {
+			price, err := tx.actualPrice()
+			if err != nil || price.Cmp(*spill) > 0 {
+				break
+			}
+		}
+		in, _ := f.apply(tx)
+		filledOnce = true
+		remaining, _ = remaining.CheckedSub(in)
+		if !f.replaceEmptyPositions() || constraint < 0 {
+			break
+		}
+	}
+	f.commit()
+
+	exec := &dexv1alpha1.SwapExecution{Traces: f.traces}
+	var in, out num.Amount
+	for _, trace := range f.traces {
+		in, _ = in.CheckedAdd(num.AmountFromProto(trace.Value[0].GetAmount()))
+		out, _ = out.CheckedAdd(num.AmountFromProto(trace.Value[len(trace.Value)-1].GetAmount()))
+	}
+	exec.Input = value(route[0], in)
+	exec.Output = value(route[len(route)-1], out)
+	return exec, nil
+}
+
+// RouteAndFill routes and fills an input of src for dst over the snapshot's
+// positions, updating them, as pd's `route_and_fill` does for a batch swap.
+func (s *Snapshot) RouteAndFill(src, dst *assetv1alpha1.AssetId, input num.Amount, params RoutingParams) (*dexv1alpha1.SwapExecution, error) {
+	unfilled, output := input, num.Zero
+	var traces []*dexv1alpha1.SwapExecution_Trace
+	for {
+		hops, spill, err := s.PathSearch(src, dst, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(hops) == 0 {
+			break
+		}
+		delta := unfilled
+		if delta.Cmp(MaxReserveAmount) > 0 {
+			delta = MaxReserveAmount
+		}
+		exec, err := s.FillRoute(value(src, delta), hops, spill)
+		var overflow *overflowError
+		if errors.As(err, &overflow) {
+			// Route around the position, as pd does.
+			s.closePosition(overflow.id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error filling route: %w", err)
+		}
+		output, _ = output.CheckedAdd(num.AmountFromProto(exec.GetOutput().GetAmount()))
+		// pd keeps the whole unfilled input rather than the capped delta's
+		// when subtracting the fill, so an input over MaxReserveAmount
+		// counts its excess twice. Follow it, wrapping as its release
+		// build does, so that executions agree.
+		left, _ := unfilled.CheckedSub(num.AmountFromProto(exec.GetInput().GetAmount()))
+		unfilled, _ = unfilled.CheckedSub(delta)
+		unfilled, _ = unfilled.CheckedAdd(left)
+		traces = append(traces, exec.GetTraces()...)
+		if unfilled.IsZero() || len(exec.GetTraces()) == 0 {
+			break
+		}
+		last := exec.GetTraces()[len(exec.GetTraces())-1].GetValue()
+		maxPrice, err := num.Ratio(num.AmountFromProto(last[0].GetAmount()), num.AmountFromProto(last[len(last)-1].GetAmount()))
+		if err != nil {
+			return nil, err
+		}
+		if params.PriceLimit != nil && maxPrice.Cmp(*params.PriceLimit) >= 0 {
+			break
+		}
+	}
+	filled, _ := input.CheckedSub(unfilled)
+	return &dexv1alpha1.SwapExecution{
+		Traces: traces,
+		Input:  value(src, filled),
+		Output: value(dst, output),
+	}, nil
+}
+
+// SimulateTrade answers a SimulateTrade request against a copy of the
+// snapshot, as pd's SimulationService does against its latest state. The
+// cache resolves pd's fixed routing candidates.
+func (s *Snapshot) SimulateTrade(req *dexv1alpha1.SimulateTradeRequest, cache *asset.Cache) (*dexv1alpha1.SimulateTradeResponse, error) {
+	if req.GetInput() == nil {
+		return nil, errors.New("missing input parameter")
+	}
+	if req.GetOutput() == nil {
+		return nil, errors.New("missing output id parameter")
+	}
+	exec, err := s.Clone().RouteAndFill(req.GetInput().GetAssetId(), req.GetOutput(),
+		num.AmountFromProto(req.GetInput().GetAmount()), RoutingParamsFor(req.GetRouting(), cache))
+	if err != nil {
+		return nil, fmt.Errorf("error simulating trade: %w", err)
+	}
+	return &dexv1alpha1.SimulateTradeResponse{Output: exec}, nil
+}
+
+// Comparison is a local simulation checked against pd's.
+type Comparison struct {
+	Local, Remote *dexv1alpha1.SwapExecution
+	// Differences describes each mismatch, empty if the executions agree.
+	Differences []string
+}
+
+// Equal reports whether the executions agree.
+func (c *Comparison) Equal() bool {
+	return len(c.Differences) == 0
+}
+
+// CompareSimulation runs a trade simulation locally and on a fullnode and
+// compares the executions. The snapshot should be of the fullnode's latest
+// state for them to agree, and even then they can differ where pd's route
+// search is not deterministic, as described on PathSearch.
+func CompareSimulation(ctx context.Context, sim SimulationService, s *Snapshot, req *dexv1alpha1.SimulateTradeRequest, cache *asset.Cache) (*Comparison, error) {
+	local, err := s.SimulateTrade(req, cache)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := sim.SimulateTrade(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c := &Comparison{Local: local.GetOutput(), Remote: remote.GetOutput()}
+	c.Differences = compareExecutions(c.Local, c.Remote)
+	return c, nil
+}
+
+func compareExecutions(local, remote *dexv1alpha1.SwapExecution) []string {
+	var diffs []string
+	compareValue := func(what string, l, r *assetv1alpha1.Value) {
+		la, ra := num.AmountFromProto(l.GetAmount()), num.AmountFromProto(r.GetAmount())
+		if !asset.IdEqual(l.GetAssetId(), r.GetAssetId()) || la.Cmp(ra) != 0 {
+			diffs = append(diffs, fmt.Sprintf("%s: local %s %s, remote %s %s", what,
+				la, asset.FormatAssetId(l.GetAssetId()), ra, asset.FormatAssetId(r.GetAssetId())))
+		}
+	}
+	compareValue("input", local.GetInput(), remote.GetInput())
+	compareValue("output", local.GetOutput(), remote.GetOutput())
+	lt, rt := local.GetTraces(), remote.GetTraces()
+	if len(lt) != len(rt) {
+		diffs = append(diffs, fmt.Sprintf("traces: local %d, remote %d", len(lt), len(rt)))
+	}
+	for i := 0; i < len(lt) && i < len(rt); i++ {
+		lv, rv := lt[i].GetValue(), rt[i].GetValue()
+		if len(lv) != len(rv) {
+			diffs = append(diffs, fmt.Sprintf("trace %d: local %d hops, remote %d", i, len(lv)-1, len(rv)-1))
+			continue
+		}
+		for j := range lv {
+			compareValue(fmt.Sprintf("trace %d value %d", i, j), lv[j], rv[j])
+		}
+	}
+	return diffs
+}
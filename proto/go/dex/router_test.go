@@ -0,0 +1,297 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// limitBuy adds a position buying quantity units of start for price units
+// of end each, as `limit_buy` in pd's router tests.
+func (tp *testPositions) limitBuy(start, end *assetv1alpha1.AssetId, startUnit, endUnit num.Amount, quantity, price uint64) {
+	p, _ := num.NewAmount(price).CheckedMul(endUnit)
+	r2, _ := num.NewAmount(quantity).CheckedMul(p)
+	tp.add(start, end, p, startUnit, Reserves{R2: r2})
+}
+
+// limitSell adds a position selling quantity of start for price of end each.
+func (tp *testPositions) limitSell(start, end *assetv1alpha1.AssetId, quantity, price uint64) {
+	tp.add(start, end, num.NewAmount(price), num.NewAmount(1), Reserves{R1: num.NewAmount(quantity)})
+}
+
+func (tp *testPositions) snapshot() *Snapshot {
+	s, err := NewSnapshot(tp.positions)
+	if err != nil {
+		tp.t.Fatal(err)
+	}
+	return s
+}
+
+func amountOf(v *assetv1alpha1.Value) num.Amount {
+	return num.AmountFromProto(v.GetAmount())
+}
+
+// TestFillRouteStacked follows `fill_route_constraint_stacked` in pd's
+// router tests: a fill constrained on each hop in turn.
+func TestFillRouteStacked(t *testing.T) {
+	gm, gn, penumbra, pusd := testAssetId(1), testAssetId(2), testAssetId(3), testAssetId(4)
+	u6, _ := num.Pow10(6)
+	u18, _ := num.Pow10(18)
+	tp := &testPositions{t: t}
+	tp.limitBuy(gm, gn, u6, u6, 3, 2)
+	tp.limitBuy(gm, gn, u6, u6, 1, 1)
+	tp.limitBuy(gn, penumbra, u6, u6, 1, 2)
+	for i := 0; i < 3; i++ {
+		tp.limitBuy(gn, penumbra, u6, u6, 50, 1)
+	}
+	for _, price := range []uint64{2000, 2500, 3000, 3100, 10_000} {
+		quantity := uint64(1)
+		if price == 3000 {
+			quantity = 198
+		}
+		tp.limitBuy(penumbra, pusd, u6, u18, quantity, price)
+	}
+	s := tp.snapshot()
+
+	input, _ := num.NewAmount(4).CheckedMul(u6)
+	spill := num.U128x128FromAmount(num.NewAmount(1_000_000_000))
+	exec, err := s.FillRoute(value(gm, input), []*assetv1alpha1.AssetId{gn, penumbra, pusd}, &spill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := num.NewAmount(10_000 + 3100 + 6*3000).CheckedMul(u18)
+	if !asset.IdEqual(exec.GetInput().GetAssetId(), gm) || amountOf(exec.GetInput()) != input {
+		t.Errorf("input = %v, want all %s", exec.GetInput(), input)
+	}
+	if !asset.IdEqual(exec.GetOutput().GetAssetId(), pusd) || amountOf(exec.GetOutput()) != want {
+		t.Errorf("output = %s, want %s", amountOf(exec.GetOutput()), want)
+	}
+}
+
+// routeSnapshot has a direct route from a to c at 2a per c, and a cheaper
+// one through b at 1a per c with 50c of liquidity.
+func routeSnapshot(t *testing.T) (*Snapshot, *assetv1alpha1.AssetId, *assetv1alpha1.AssetId, *assetv1alpha1.AssetId) {
+	a, b, c := testAssetId(1), testAssetId(2), testAssetId(3)
+	tp := &testPositions{t: t}
+	tp.limitSell(c, a, 100, 2)
+	tp.limitSell(b, a, 300, 1)
+	tp.limitSell(c, b, 50, 1)
+	return tp.snapshot(), a, b, c
+}
+
+var testRouting = RoutingParams{MaxHops: DefaultMaxHops}
+
+func TestPathSearch(t *testing.T) {
+	s, a, b, c := routeSnapshot(t)
+	hops, spill, err := s.PathSearch(a, c, testRouting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hops) != 2 || !asset.IdEqual(hops[0], b) || !asset.IdEqual(hops[1], c) {
+		t.Errorf("PathSearch = %v, want the route through b", hops)
+	}
+	if two := num.U128x128FromAmount(num.NewAmount(2)); spill == nil || spill.Cmp(two) != 0 {
+		t.Errorf("spill price = %v, want the direct route's 2", spill)
+	}
+
+	hops, _, _ = s.PathSearch(a, c, RoutingParams{MaxHops: 1})
+	if len(hops) != 1 || !asset.IdEqual(hops[0], c) {
+		t.Errorf("single hop PathSearch = %v, want the direct route", hops)
+	}
+	limit := num.U128x128FromAmount(num.NewAmount(1))
+	if hops, _, _ = s.PathSearch(a, c, RoutingParams{MaxHops: DefaultMaxHops, PriceLimit: &limit}); hops != nil {
+		t.Errorf("PathSearch at the price limit = %v, want no route", hops)
+	}
+	if hops, _, _ = s.PathSearch(c, a, testRouting); hops != nil {
+		t.Errorf("PathSearch against the positions = %v, want no route", hops)
+	}
+}
+
+func TestRouteAndFill(t *testing.T) {
+	tests := []struct {
+		name                    string
+		input                   uint64
+		filled, output          uint64
+		traces                  [][]uint64
+		directLeft, throughLeft uint64
+	}{
+		// The route through b fills until its liquidity runs out, then the
+		// direct route fills the rest.
+		{"both routes", 150, 150, 100, [][]uint64{{50, 50, 50}, {100, 50}}, 50, 0},
+		{"cheap route only", 30, 30, 30, [][]uint64{{30, 30, 30}}, 100, 20},
+		{"unfilled", 300, 250, 150, [][]uint64{{50, 50, 50}, {200, 100}}, 0, 0},
+	}
+	for _, tt := range tests {
+		s, a, _, c := routeSnapshot(t)
+		exec, err := s.RouteAndFill(a, c, num.NewAmount(tt.input), testRouting)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !asset.IdEqual(exec.GetInput().GetAssetId(), a) || amountOf(exec.GetInput()) != num.NewAmount(tt.filled) {
+			t.Errorf("%s: input = %s, want %d", tt.name, amountOf(exec.GetInput()), tt.filled)
+		}
+		if !asset.IdEqual(exec.GetOutput().GetAssetId(), c) || amountOf(exec.GetOutput()) != num.NewAmount(tt.output) {
+			t.Errorf("%s: output = %s, want %d", tt.name, amountOf(exec.GetOutput()), tt.output)
+		}
+		var traces [][]uint64
+		for _, trace := range exec.GetTraces() {
+			var values []uint64
+			for _, v := range trace.GetValue() {
+				values = append(values, amountOf(v).Lo())
+			}
+			traces = append(traces, values)
+		}
+		if len(traces) != len(tt.traces) || !tracesEqual(traces, tt.traces) {
+			t.Errorf("%s: traces = %v, want %v", tt.name, traces, tt.traces)
+		}
+
+		// The fills update the snapshot's reserves.
+		for _, pos := range s.Positions() {
+			pair := pos.GetPhi().GetPair()
+			if !asset.IdEqual(pair.GetAsset_1(), c) && !asset.IdEqual(pair.GetAsset_2(), c) {
+				continue
+			}
+			left, _ := ReservesFor(pos, c)
+			want := tt.directLeft
+			if !asset.IdEqual(pair.GetAsset_1(), a) && !asset.IdEqual(pair.GetAsset_2(), a) {
+				want = tt.throughLeft
+			}
+			if left != num.NewAmount(want) {
+				t.Errorf("%s: position on %s-%s has %s left, want %d", tt.name,
+					asset.FormatAssetId(pair.GetAsset_1()), asset.FormatAssetId(pair.GetAsset_2()), left, want)
+			}
+		}
+	}
+}
+
+func tracesEqual(a, b [][]uint64) bool {
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TestRouteAndFillMaxReserve fills an input over MaxReserveAmount, which is
+// routed in deltas of at most MaxReserveAmount. pd subtracts each fill from
+// the whole unfilled input rather than from the delta, counting the excess
+// twice, and the execution follows it.
+func TestRouteAndFillMaxReserve(t *testing.T) {
+	a, c := testAssetId(1), testAssetId(3)
+	tp := &testPositions{t: t}
+	tp.add(c, a, num.NewAmount(1), num.NewAmount(1), Reserves{R1: MaxReserveAmount})
+	excess := num.NewAmount(1000)
+	input, _ := MaxReserveAmount.CheckedAdd(excess)
+
+	exec, err := tp.snapshot().RouteAndFill(a, c, input, testRouting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The delta fills entirely, leaving pd with 2*excess unfilled.
+	wantFilled, _ := MaxReserveAmount.CheckedSub(excess)
+	if got := amountOf(exec.GetInput()); got != wantFilled {
+		t.Errorf("input = %s, want %s", got, wantFilled)
+	}
+	if got := amountOf(exec.GetOutput()); got != MaxReserveAmount {
+		t.Errorf("output = %s, want %s", got, MaxReserveAmount)
+	}
+	if len(exec.GetTraces()) != 1 || amountOf(exec.GetTraces()[0].GetValue()[0]) != MaxReserveAmount {
+		t.Errorf("traces = %v, want one fill of the delta", exec.GetTraces())
+	}
+}
+
+// stubSimulation answers SimulateTrade with a fixed response.
+type stubSimulation struct {
+	rsp *dexv1alpha1.SimulateTradeResponse
+	err error
+	req *dexv1alpha1.SimulateTradeRequest
+}
+
+func (s *stubSimulation) SimulateTrade(_ context.Context, req *dexv1alpha1.SimulateTradeRequest) (*dexv1alpha1.SimulateTradeResponse, error) {
+	s.req = req
+	return s.rsp, s.err
+}
+
+func execution(src, dst *assetv1alpha1.AssetId, input, output num.Amount, traces ...[]*assetv1alpha1.Value) *dexv1alpha1.SwapExecution {
+	exec := &dexv1alpha1.SwapExecution{Input: value(src, input), Output: value(dst, output)}
+	for _, values := range traces {
+		exec.Traces = append(exec.Traces, &dexv1alpha1.SwapExecution_Trace{Value: values})
+	}
+	return exec
+}
+
+func TestCompareSimulation(t *testing.T) {
+	s, a, b, c := routeSnapshot(t)
+	n := num.NewAmount
+	req := &dexv1alpha1.SimulateTradeRequest{Input: value(a, n(150)), Output: c}
+	pd := execution(a, c, n(150), n(100),
+		[]*assetv1alpha1.Value{value(a, n(50)), value(b, n(50)), value(c, n(50))},
+		[]*assetv1alpha1.Value{value(a, n(100)), value(c, n(50))})
+
+	sim := &stubSimulation{rsp: &dexv1alpha1.SimulateTradeResponse{Output: pd}}
+	cmp, err := CompareSimulation(context.Background(), sim, s, req, asset.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal() || sim.req != req {
+		t.Errorf("CompareSimulation of matching executions: %v", cmp.Differences)
+	}
+	// The simulation leaves the snapshot unchanged.
+	if exec, _ := s.RouteAndFill(a, c, n(150), testRouting); amountOf(exec.GetOutput()) != n(100) {
+		t.Errorf("the simulation consumed the snapshot's liquidity")
+	}
+
+	// pd taking the direct route first.
+	s, _, _, _ = routeSnapshot(t)
+	sim.rsp = &dexv1alpha1.SimulateTradeResponse{Output: execution(a, c, n(150), n(75),
+		[]*assetv1alpha1.Value{value(a, n(150)), value(c, n(75))})}
+	cmp, err = CompareSimulation(context.Background(), sim, s, req, asset.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"output: local 100", "traces: local 2, remote 1", "trace 0: local 2 hops, remote 1"}
+	if len(cmp.Differences) != len(want) {
+		t.Fatalf("Differences = %q, want %d", cmp.Differences, len(want))
+	}
+	for i, d := range cmp.Differences {
+		if !strings.HasPrefix(d, want[i]) {
+			t.Errorf("difference %d = %q, want %q", i, d, want[i])
+		}
+	}
+
+	// An input over MaxReserveAmount agrees with pd's unfilled bookkeeping.
+	tp := &testPositions{t: t}
+	tp.add(c, a, n(1), n(1), Reserves{R1: MaxReserveAmount})
+	input, _ := MaxReserveAmount.CheckedAdd(n(1000))
+	filled, _ := MaxReserveAmount.CheckedSub(n(1000))
+	sim.rsp = &dexv1alpha1.SimulateTradeResponse{Output: execution(a, c, filled, MaxReserveAmount,
+		[]*assetv1alpha1.Value{value(a, MaxReserveAmount), value(c, MaxReserveAmount)})}
+	cmp, err = CompareSimulation(context.Background(), sim, tp.snapshot(),
+		&dexv1alpha1.SimulateTradeRequest{Input: value(a, input), Output: c}, asset.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal() {
+		t.Errorf("CompareSimulation over MaxReserveAmount: %v", cmp.Differences)
+	}
+
+	sim.err = errors.New("unavailable")
+	if _, err := CompareSimulation(context.Background(), sim, s, req, asset.NewCache()); err != sim.err {
+		t.Errorf("CompareSimulation with a failing fullnode = %v", err)
+	}
+	if _, err := CompareSimulation(context.Background(), sim, s, &dexv1alpha1.SimulateTradeRequest{Output: c}, asset.NewCache()); err == nil {
+		t.Errorf("CompareSimulation without an input succeeded")
+	}
+}
@@ -0,0 +1,98 @@
+package dex
+
+import (
+	"context"
+
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of the DEX component's QueryService.
+const QueryServiceName = "penumbra.core.component.dex.v1alpha1.QueryService"
+
+// QueryService is the DEX component's QueryService API.
+type QueryService interface {
+	BatchSwapOutputData(ctx context.Context, req *dexv1alpha1.BatchSwapOutputDataRequest) (*dexv1alpha1.BatchSwapOutputDataResponse, error)
+	SwapExecution(ctx context.Context, req *dexv1alpha1.SwapExecutionRequest) (*dexv1alpha1.SwapExecutionResponse, error)
+	ArbExecution(ctx context.Context, req *dexv1alpha1.ArbExecutionRequest) (*dexv1alpha1.ArbExecutionResponse, error)
+	SwapExecutions(ctx context.Context, req *dexv1alpha1.SwapExecutionsRequest) (grpcclient.Stream[dexv1alpha1.SwapExecutionsResponse], error)
+	ArbExecutions(ctx context.Context, req *dexv1alpha1.ArbExecutionsRequest) (grpcclient.Stream[dexv1alpha1.ArbExecutionsResponse], error)
+	LiquidityPositions(ctx context.Context, req *dexv1alpha1.LiquidityPositionsRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsResponse], error)
+	LiquidityPositionById(ctx context.Context, req *dexv1alpha1.LiquidityPositionByIdRequest) (*dexv1alpha1.LiquidityPositionByIdResponse, error)
+	LiquidityPositionsById(ctx context.Context, req *dexv1alpha1.LiquidityPositionsByIdRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsByIdResponse], error)
+	LiquidityPositionsByPrice(ctx context.Context, req *dexv1alpha1.LiquidityPositionsByPriceRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsByPriceResponse], error)
+	Spread(ctx context.Context, req *dexv1alpha1.SpreadRequest) (*dexv1alpha1.SpreadResponse, error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) BatchSwapOutputData(ctx context.Context, req *dexv1alpha1.BatchSwapOutputDataRequest) (*dexv1alpha1.BatchSwapOutputDataResponse, error) {
+	return grpcclient.Call[dexv1alpha1.BatchSwapOutputDataResponse](ctx, s.svc, "BatchSwapOutputData", req)
+}
+
+func (s *grpcQueryService) SwapExecution(ctx context.Context, req *dexv1alpha1.SwapExecutionRequest) (*dexv1alpha1.SwapExecutionResponse, error) {
+	return grpcclient.Call[dexv1alpha1.SwapExecutionResponse](ctx, s.svc, "SwapExecution", req)
+}
+
+func (s *grpcQueryService) ArbExecution(ctx context.Context, req *dexv1alpha1.ArbExecutionRequest) (*dexv1alpha1.ArbExecutionResponse, error) {
+	return grpcclient.Call[dexv1alpha1.ArbExecutionResponse](ctx, s.svc, "ArbExecution", req)
+}
+
+func (s *grpcQueryService) SwapExecutions(ctx context.Context, req *dexv1alpha1.SwapExecutionsRequest) (grpcclient.Stream[dexv1alpha1.SwapExecutionsResponse], error) {
+	return grpcclient.OpenStream[dexv1alpha1.SwapExecutionsResponse](ctx, s.svc, "SwapExecutions", req)
+}
+
+func (s *grpcQueryService) ArbExecutions(ctx context.Context, req *dexv1alpha1.ArbExecutionsRequest) (grpcclient.Stream[dexv1alpha1.ArbExecutionsResponse], error) {
+	return grpcclient.OpenStream[dexv1alpha1.ArbExecutionsResponse](ctx, s.svc, "ArbExecutions", req)
+}
+
+func (s *grpcQueryService) LiquidityPositions(ctx context.Context, req *dexv1alpha1.LiquidityPositionsRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsResponse], error) {
+	return grpcclient.OpenStream[dexv1alpha1.LiquidityPositionsResponse](ctx, s.svc, "LiquidityPositions", req)
+}
+
+func (s *grpcQueryService) LiquidityPositionById(ctx context.Context, req *dexv1alpha1.LiquidityPositionByIdRequest) (*dexv1alpha1.LiquidityPositionByIdResponse, error) {
+	return grpcclient.Call[dexv1alpha1.LiquidityPositionByIdResponse](ctx, s.svc, "LiquidityPositionById", req)
+}
+
+func (s *grpcQueryService) LiquidityPositionsById(ctx context.Context, req *dexv1alpha1.LiquidityPositionsByIdRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsByIdResponse], error) {
+	return grpcclient.OpenStream[dexv1alpha1.LiquidityPositionsByIdResponse](ctx, s.svc, "LiquidityPositionsById", req)
+}
+
+func (s *grpcQueryService) LiquidityPositionsByPrice(ctx context.Context, req *dexv1alpha1.LiquidityPositionsByPriceRequest) (grpcclient.Stream[dexv1alpha1.LiquidityPositionsByPriceResponse], error) {
+	return grpcclient.OpenStream[dexv1alpha1.LiquidityPositionsByPriceResponse](ctx, s.svc, "LiquidityPositionsByPrice", req)
+}
+
+func (s *grpcQueryService) Spread(ctx context.Context, req *dexv1alpha1.SpreadRequest) (*dexv1alpha1.SpreadResponse, error) {
+	return grpcclient.Call[dexv1alpha1.SpreadResponse](ctx, s.svc, "Spread", req)
+}
+
+// SimulationServiceName is the full name of the DEX component's
+// SimulationService.
+const SimulationServiceName = "penumbra.core.component.dex.v1alpha1.SimulationService"
+
+// SimulationService is the DEX component's SimulationService API.
+type SimulationService interface {
+	SimulateTrade(ctx context.Context, req *dexv1alpha1.SimulateTradeRequest) (*dexv1alpha1.SimulateTradeResponse, error)
+}
+type grpcSimulationService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCSimulationService returns a SimulationService that calls a
+// fullnode over a gRPC connection.
+func NewGRPCSimulationService(conn grpc.ClientConnInterface) SimulationService {
+	return &grpcSimulationService{svc: grpcclient.Service{Conn: conn, Name: SimulationServiceName}}
+}
+
+func (s *grpcSimulationService) SimulateTrade(ctx context.Context, req *dexv1alpha1.SimulateTradeRequest) (*dexv1alpha1.SimulateTradeResponse, error) {
+	return grpcclient.Call[dexv1alpha1.SimulateTradeResponse](ctx, s.svc, "SimulateTrade", req)
+}
@@ -0,0 +1,163 @@
+package dex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/poseidon377"
+	"github.com/penumbra-zone/penumbra/proto/go/shieldedpool"
+)
+
+// RseedLen is the length of a swap or note rseed.
+const RseedLen = 32
+
+var (
+	output1BlindingDomain = domainSeparator("penumbra.swapclaim.output1.blinding")
+	output2BlindingDomain = domainSeparator("penumbra.swapclaim.output2.blinding")
+)
+
+// domainSeparator hashes a label to an element of Fq, as the Rust crates'
+// `Fq::from_le_bytes_mod_order(blake2b(label))` does.
+func domainSeparator(label string) *big.Int {
+	sum := blake2b.Sum512("", []byte(label))
+	return decaf377.ReduceScalar(sum[:], decaf377.FqModulus)
+}
+
+// ProRataOutputs returns a swap's share of the outputs of its batch, of asset
+// 1 and asset 2, exactly as `BatchSwapOutputData::pro_rata_outputs` does:
+// each input's fraction of the batch input is paid out of what that input
+// was traded for plus what of it went unfilled, rounded down.
+func ProRataOutputs(bsod *dexv1alpha1.BatchSwapOutputData, delta1I, delta2I num.Amount) (num.Amount, num.Amount, error) {
+	delta1 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetDelta_1()))
+	delta2 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetDelta_2()))
+	lambda1 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetLambda_1()))
+	lambda2 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetLambda_2()))
+	unfilled1 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetUnfilled_1()))
+	unfilled2 := num.U128x128FromAmount(num.AmountFromProto(bsod.GetUnfilled_2()))
+
+	// As in pd, a failed operation counts as zero, so an empty batch input
+	// gives no share of it.
+	orZero := func(x num.U128x128, err error) num.U128x128 {
+		if err != nil {
+			return num.U128x128{}
+		}
+		return x
+	}
+	share1 := orZero(num.U128x128FromAmount(delta1I).Div(delta1))
+	share2 := orZero(num.U128x128FromAmount(delta2I).Div(delta2))
+
+	lambda1I := orZero(orZero(share1.Mul(unfilled1)).Add(orZero(share2.Mul(lambda1))))
+	lambda2I := orZero(orZero(share1.Mul(lambda2)).Add(orZero(share2.Mul(unfilled2))))
+
+	out1, err := lambda1I.RoundDown().Amount()
+	if err != nil {
+		return num.Zero, num.Zero, err
+	}
+	out2, err := lambda2I.RoundDown().Amount()
+	if err != nil {
+		return num.Zero, num.Zero, err
+	}
+	return out1, out2, nil
+}
+
+// OutputRseeds derives the rseeds of a swap's two output notes from the
+// swap's rseed, as `SwapPlaintext::output_rseeds` does: each is the Poseidon
+// hash of the rseed, as an element of Fq, under its output's domain
+// separator.
+func OutputRseeds(rseed []byte) ([]byte, []byte, error) {
+	if len(rseed) != RseedLen {
+		return nil, nil, fmt.Errorf("swap rseed has length %d, expected %d", len(rseed), RseedLen)
+	}
+	x := decaf377.ReduceScalar(rseed, decaf377.FqModulus)
+	rseed1 := decaf377.EncodeScalar(poseidon377.Hash(output1BlindingDomain, x))
+	rseed2 := decaf377.EncodeScalar(poseidon377.Hash(output2BlindingDomain, x))
+	return rseed1, rseed2, nil
+}
+
+// OutputNotes returns the notes of asset 1 and asset 2 that claiming a swap
+// creates, as `SwapPlaintext::output_notes` does. The batch output data must
+// be for the swap's trading pair.
+func OutputNotes(swap *dexv1alpha1.SwapPlaintext, bsod *dexv1alpha1.BatchSwapOutputData) (*shielded_poolv1alpha1.Note, *shielded_poolv1alpha1.Note, error) {
+	pair := swap.GetTradingPair()
+	if !asset.IdEqual(pair.GetAsset_1(), bsod.GetTradingPair().GetAsset_1()) ||
+		!asset.IdEqual(pair.GetAsset_2(), bsod.GetTradingPair().GetAsset_2()) {
+		return nil, nil, errors.New("batch output data is for a different trading pair than the swap")
+	}
+	if swap.GetClaimAddress() == nil {
+		return nil, nil, errors.New("swap has no claim address")
+	}
+	lambda1I, lambda2I, err := ProRataOutputs(bsod, num.AmountFromProto(swap.GetDelta_1I()), num.AmountFromProto(swap.GetDelta_2I()))
+	if err != nil {
+		return nil, nil, err
+	}
+	rseed1, rseed2, err := OutputRseeds(swap.GetRseed())
+	if err != nil {
+		return nil, nil, err
+	}
+	note1 := &shielded_poolv1alpha1.Note{
+		Value:   &assetv1alpha1.Value{Amount: lambda1I.Proto(), AssetId: pair.GetAsset_1()},
+		Rseed:   rseed1,
+		Address: swap.GetClaimAddress(),
+	}
+	note2 := &shielded_poolv1alpha1.Note{
+		Value:   &assetv1alpha1.Value{Amount: lambda2I.Proto(), AssetId: pair.GetAsset_2()},
+		Rseed:   rseed2,
+		Address: swap.GetClaimAddress(),
+	}
+	return note1, note2, nil
+}
+
+// ClaimOutputs are the expected results of claiming a swap.
+type ClaimOutputs struct {
+	Note1, Note2             *shielded_poolv1alpha1.Note
+	Commitment1, Commitment2 []byte
+}
+
+// PredictSwapClaim returns the notes, and their commitments, that claiming
+// a swap against its batch output data creates.
+func PredictSwapClaim(swap *dexv1alpha1.SwapPlaintext, bsod *dexv1alpha1.BatchSwapOutputData) (*ClaimOutputs, error) {
+	note1, note2, err := OutputNotes(swap, bsod)
+	if err != nil {
+		return nil, err
+	}
+	c := &ClaimOutputs{Note1: note1, Note2: note2}
+	cm1, err := shieldedpool.Commit(note1)
+	if err != nil {
+		return nil, err
+	}
+	cm2, err := shieldedpool.Commit(note2)
+	if err != nil {
+		return nil, err
+	}
+	c.Commitment1, c.Commitment2 = cm1.GetInner(), cm2.GetInner()
+	return c, nil
+}
+
+// VerifySwapClaim checks that a SwapClaim body's output commitments are
+// those of the notes claiming the swap against the body's output data
+// creates.
+func VerifySwapClaim(body *dexv1alpha1.SwapClaimBody, swap *dexv1alpha1.SwapPlaintext) error {
+	if body.GetOutputData() == nil {
+		return errors.New("swap claim has no output data")
+	}
+	c, err := PredictSwapClaim(swap, body.GetOutputData())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(c.Commitment1, body.GetOutput_1Commitment().GetInner()) {
+		return errors.New("swap claim output 1 commitment does not match the swap")
+	}
+	if !bytes.Equal(c.Commitment2, body.GetOutput_2Commitment().GetInner()) {
+		return errors.New("swap claim output 2 commitment does not match the swap")
+	}
+	return nil
+}
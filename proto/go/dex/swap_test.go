@@ -0,0 +1,124 @@
+package dex
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/shieldedpool"
+)
+
+func TestOutputBlindingDomains(t *testing.T) {
+	// Fq::from_le_bytes_mod_order(blake2b(label)), as in
+	// `penumbra_dex::swap::plaintext`.
+	for _, tt := range []struct {
+		got  *big.Int
+		want string
+	}{
+		{output1BlindingDomain, "70ab28c5a450c7da6d3184337e6765a418e1d3c0ded3c995ed58ccfe365620b"},
+		{output2BlindingDomain, "820376d95219908ea4cbd0acf7f042de723a08acdac5e94bd76ef2d43aa1ea2"},
+	} {
+		if got := tt.got.Text(16); got != tt.want {
+			t.Errorf("domain separator %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestOutputRseeds(t *testing.T) {
+	rseed1, rseed2, err := OutputRseeds(bytes.Repeat([]byte{3}, RseedLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rseed1) != RseedLen || len(rseed2) != RseedLen || bytes.Equal(rseed1, rseed2) {
+		t.Errorf("OutputRseeds = %x, %x", rseed1, rseed2)
+	}
+	// The rseed is reduced modulo Fq before hashing.
+	high := bytes.Repeat([]byte{0xff}, RseedLen)
+	reduced := decaf377.EncodeScalar(decaf377.ReduceScalar(high, decaf377.FqModulus))
+	a1, a2, _ := OutputRseeds(high)
+	b1, b2, _ := OutputRseeds(reduced)
+	if !bytes.Equal(a1, b1) || !bytes.Equal(a2, b2) {
+		t.Errorf("OutputRseeds does not reduce the rseed")
+	}
+	if _, _, err := OutputRseeds(high[:31]); err == nil {
+		t.Errorf("OutputRseeds accepted a short rseed")
+	}
+}
+
+func testSwap(t *testing.T) (*dexv1alpha1.SwapPlaintext, *dexv1alpha1.BatchSwapOutputData) {
+	t.Helper()
+	address, err := keys.ParseAddress("penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := NewTradingPair(testAssetId(1), testAssetId(2))
+	swap := &dexv1alpha1.SwapPlaintext{
+		TradingPair:  pair,
+		Delta_1I:     num.NewAmount(25).Proto(),
+		Delta_2I:     num.Zero.Proto(),
+		ClaimAddress: address,
+		Rseed:        bytes.Repeat([]byte{9}, RseedLen),
+	}
+	bsod := &dexv1alpha1.BatchSwapOutputData{
+		TradingPair: pair,
+		Delta_1:     num.NewAmount(100).Proto(),
+		Delta_2:     num.NewAmount(0).Proto(),
+		Lambda_1:    num.NewAmount(0).Proto(),
+		Lambda_2:    num.NewAmount(300).Proto(),
+		Unfilled_1:  num.NewAmount(10).Proto(),
+		Unfilled_2:  num.NewAmount(0).Proto(),
+	}
+	return swap, bsod
+}
+
+func TestPredictSwapClaim(t *testing.T) {
+	swap, bsod := testSwap(t)
+	c, err := PredictSwapClaim(swap, bsod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A quarter of the batch input gets a quarter of its output, and of
+	// what went unfilled, rounded down.
+	out1 := num.AmountFromProto(c.Note1.GetValue().GetAmount())
+	out2 := num.AmountFromProto(c.Note2.GetValue().GetAmount())
+	if out1 != num.NewAmount(2) || out2 != num.NewAmount(75) {
+		t.Errorf("outputs = %s, %s; want 2, 75", out1, out2)
+	}
+	rseed1, rseed2, _ := OutputRseeds(swap.GetRseed())
+	if !bytes.Equal(c.Note1.GetRseed(), rseed1) || !bytes.Equal(c.Note2.GetRseed(), rseed2) {
+		t.Errorf("output notes do not use the derived rseeds")
+	}
+	cm1, _ := shieldedpool.Commit(c.Note1)
+	if !bytes.Equal(c.Commitment1, cm1.GetInner()) {
+		t.Errorf("commitment 1 is not the note's")
+	}
+
+	body := &dexv1alpha1.SwapClaimBody{
+		OutputData:         bsod,
+		Output_1Commitment: &tctv1alpha1.StateCommitment{Inner: c.Commitment1},
+		Output_2Commitment: &tctv1alpha1.StateCommitment{Inner: c.Commitment2},
+	}
+	if err := VerifySwapClaim(body, swap); err != nil {
+		t.Errorf("VerifySwapClaim: %v", err)
+	}
+	body.Output_1Commitment, body.Output_2Commitment = body.Output_2Commitment, body.Output_1Commitment
+	if err := VerifySwapClaim(body, swap); err == nil {
+		t.Errorf("VerifySwapClaim accepted swapped commitments")
+	}
+
+	other, _ := testSwap(t)
+	other.TradingPair = NewTradingPair(testAssetId(1), testAssetId(3))
+	if _, err := PredictSwapClaim(other, bsod); err == nil {
+		t.Errorf("PredictSwapClaim accepted output data for another pair")
+	}
+	other, _ = testSwap(t)
+	other.Rseed = other.Rseed[:31]
+	if _, err := PredictSwapClaim(other, bsod); err == nil {
+		t.Errorf("PredictSwapClaim accepted a short rseed")
+	}
+}
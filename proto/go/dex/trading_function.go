@@ -0,0 +1,281 @@
+package dex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// FeeScale is the denominator of trading function fees, which are in basis
+// points.
+const FeeScale = 10_000
+
+// Reserves are the amounts of each asset of a trading pair held by a
+// position.
+type Reserves struct {
+	R1, R2 num.Amount
+}
+
+// ReservesFromProto decodes reserves.
+func ReservesFromProto(r *dexv1alpha1.Reserves) (Reserves, error) {
+	if r.GetR1() == nil || r.GetR2() == nil {
+		return Reserves{}, errors.New("reserves are missing an amount")
+	}
+	return Reserves{R1: num.AmountFromProto(r.GetR1()), R2: num.AmountFromProto(r.GetR2())}, nil
+}
+
+// Proto encodes the reserves.
+func (r Reserves) Proto() *dexv1alpha1.Reserves {
+	return &dexv1alpha1.Reserves{R1: r.R1.Proto(), R2: r.R2.Proto()}
+}
+
+// Flip returns the reserves with the assets swapped.
+func (r Reserves) Flip() Reserves {
+	return Reserves{R1: r.R2, R2: r.R1}
+}
+
+// BareTradingFunction is the constant-sum trading function p*R1 + q*R2 of a
+// position, with a fee in basis points charged on the input, mirroring
+// `penumbra_dex::lp::BareTradingFunction`. It trades asset 1 for asset 2;
+// Flip gives the other direction.
+type BareTradingFunction struct {
+	Fee  uint32
+	P, Q num.Amount
+}
+
+// BareTradingFunctionFromProto decodes a trading function.
+func BareTradingFunctionFromProto(phi *dexv1alpha1.BareTradingFunction) (BareTradingFunction, error) {
+	if phi.GetP() == nil || phi.GetQ() == nil {
+		return BareTradingFunction{}, errors.New("trading function is missing a coefficient")
+	}
+	return BareTradingFunction{Fee: phi.GetFee(), P: num.AmountFromProto(phi.GetP()), Q: num.AmountFromProto(phi.GetQ())}, nil
+}
+
+// Proto encodes the trading function.
+func (f BareTradingFunction) Proto() *dexv1alpha1.BareTradingFunction {
+	return &dexv1alpha1.BareTradingFunction{Fee: f.Fee, P: f.P.Proto(), Q: f.Q.Proto()}
+}
+
+// Flip returns the trading function in the other direction, trading asset 2
+// for asset 1.
+func (f BareTradingFunction) Flip() BareTradingFunction {
+	return BareTradingFunction{Fee: f.Fee, P: f.Q, Q: f.P}
+}
+
+// Gamma returns the fraction of the input kept after the fee, 1 - fee.
+func (f BareTradingFunction) Gamma() (num.U128x128, error) {
+	if f.Fee > FeeScale {
+		return num.U128x128{}, fmt.Errorf("fee of %d bps exceeds 100%%", f.Fee)
+	}
+	return num.Ratio(num.NewAmount(uint64(FeeScale-f.Fee)), num.NewAmount(FeeScale))
+}
+
+// EffectivePrice returns the price of asset 2 in asset 1 including the fee,
+// q / (p * gamma): the input needed per unit of output. Positions are
+// ordered by it in pd's price index.
+func (f BareTradingFunction) EffectivePrice() (num.U128x128, error) {
+	gamma, err := f.Gamma()
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	ratio, err := num.Ratio(f.Q, f.P)
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	return ratio.Div(gamma)
+}
+
+// EffectivePriceInv returns the output per unit of input including the fee,
+// p * gamma / q.
+func (f BareTradingFunction) EffectivePriceInv() (num.U128x128, error) {
+	gamma, err := f.Gamma()
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	ratio, err := num.Ratio(f.P, f.Q)
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	return ratio.Mul(gamma)
+}
+
+// EffectivePriceKey returns the big-endian encoding of the effective price,
+// which pd uses to index positions from the cheapest.
+func (f BareTradingFunction) EffectivePriceKey() ([]byte, error) {
+	price, err := f.EffectivePrice()
+	if err != nil {
+		return nil, err
+	}
+	return price.Bytes(), nil
+}
+
+// ConvertToLambda2 returns the output for an input of asset 1.
+func (f BareTradingFunction) ConvertToLambda2(delta1 num.U128x128) (num.U128x128, error) {
+	inv, err := f.EffectivePriceInv()
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	return inv.Mul(delta1)
+}
+
+// ConvertToDelta1 returns the input of asset 1 needed for an output.
+func (f BareTradingFunction) ConvertToDelta1(lambda2 num.U128x128) (num.U128x128, error) {
+	price, err := f.EffectivePrice()
+	if err != nil {
+		return num.U128x128{}, err
+	}
+	return price.Mul(lambda2)
+}
+
+// Fill trades an input of asset 1 against reserves, returning the unfilled
+// part of the input, the new reserves and the output of asset 2. The output
+// is rounded down; if the reserves run out, the input consumed is rounded
+// up, so the position never loses value to rounding.
+func (f BareTradingFunction) Fill(delta1 num.Amount, r Reserves) (num.Amount, Reserves, num.Amount, error) {
+	tentative, err := f.ConvertToLambda2(num.U128x128FromAmount(delta1))
+	if err != nil {
+		return num.Zero, Reserves{}, num.Zero, err
+	}
+	if tentative.Cmp(num.U128x128FromAmount(r.R2)) <= 0 {
+		lambda2, err := tentative.RoundDown().Amount()
+		if err != nil {
+			return num.Zero, Reserves{}, num.Zero, err
+		}
+		r1, ok := r.R1.CheckedAdd(delta1)
+		if !ok {
+			return num.Zero, Reserves{}, num.Zero, num.ErrOverflow
+		}
+		r2, _ := r.R2.CheckedSub(lambda2)
+		return num.Zero, Reserves{R1: r1, R2: r2}, lambda2, nil
+	}
+	fillable, err := f.MaxInput(r)
+	if err != nil {
+		return num.Zero, Reserves{}, num.Zero, err
+	}
+	unfilled, ok := delta1.CheckedSub(fillable)
+	if !ok {
+		return num.Zero, Reserves{}, num.Zero, num.ErrUnderflow
+	}
+	r1, ok := r.R1.CheckedAdd(fillable)
+	if !ok {
+		return num.Zero, Reserves{}, num.Zero, num.ErrOverflow
+	}
+	return unfilled, Reserves{R1: r1}, r.R2, nil
+}
+
+// FillOutput returns the new reserves and the input of asset 1, rounded up,
+// needed to get an output of asset 2. It is false if the reserves cannot
+// provide the output.
+func (f BareTradingFunction) FillOutput(r Reserves, lambda2 num.Amount) (Reserves, num.Amount, bool, error) {
+	if lambda2.Cmp(r.R2) > 0 {
+		return Reserves{}, num.Zero, false, nil
+	}
+	delta1, err := f.inputFor(lambda2)
+	if err != nil {
+		return Reserves{}, num.Zero, false, err
+	}
+	r1, ok := r.R1.CheckedAdd(delta1)
+	if !ok {
+		return Reserves{}, num.Zero, false, num.ErrOverflow
+	}
+	r2, _ := r.R2.CheckedSub(lambda2)
+	return Reserves{R1: r1, R2: r2}, delta1, true, nil
+}
+
+// Capacity returns the largest output of asset 2 the reserves can provide.
+func (f BareTradingFunction) Capacity(r Reserves) num.Amount {
+	return r.R2
+}
+
+// MaxInput returns the input of asset 1, rounded up, that exhausts the
+// reserves of asset 2.
+func (f BareTradingFunction) MaxInput(r Reserves) (num.Amount, error) {
+	return f.inputFor(r.R2)
+}
+
+func (f BareTradingFunction) inputFor(lambda2 num.Amount) (num.Amount, error) {
+	delta1, err := f.ConvertToDelta1(num.U128x128FromAmount(lambda2))
+	if err != nil {
+		return num.Zero, err
+	}
+	if delta1, err = delta1.RoundUp(); err != nil {
+		return num.Zero, err
+	}
+	return delta1.Amount()
+}
+
+// Orient returns the component of a trading function trading from an
+// asset, or false if the asset is not in its pair.
+func Orient(phi *dexv1alpha1.TradingFunction, start *assetv1alpha1.AssetId) (BareTradingFunction, bool, error) {
+	f, err := BareTradingFunctionFromProto(phi.GetComponent())
+	if err != nil {
+		return BareTradingFunction{}, false, err
+	}
+	switch {
+	case asset.IdEqual(start, phi.GetPair().GetAsset_1()):
+		return f, true, nil
+	case asset.IdEqual(start, phi.GetPair().GetAsset_2()):
+		return f.Flip(), true, nil
+	}
+	return BareTradingFunction{}, false, nil
+}
+
+// Fill trades an input of either asset of a trading function against the
+// reserves, returning the unfilled input, the new reserves and the output,
+// as `TradingFunction::fill` does.
+func Fill(phi *dexv1alpha1.TradingFunction, r Reserves, input *assetv1alpha1.Value) (*assetv1alpha1.Value, Reserves, *assetv1alpha1.Value, error) {
+	f, err := BareTradingFunctionFromProto(phi.GetComponent())
+	if err != nil {
+		return nil, Reserves{}, nil, err
+	}
+	pair := phi.GetPair()
+	amount := num.AmountFromProto(input.GetAmount())
+	switch {
+	case asset.IdEqual(input.GetAssetId(), pair.GetAsset_1()):
+		unfilled, reserves, output, err := f.Fill(amount, r)
+		if err != nil {
+			return nil, Reserves{}, nil, err
+		}
+		return &assetv1alpha1.Value{Amount: unfilled.Proto(), AssetId: pair.GetAsset_1()}, reserves,
+			&assetv1alpha1.Value{Amount: output.Proto(), AssetId: pair.GetAsset_2()}, nil
+	case asset.IdEqual(input.GetAssetId(), pair.GetAsset_2()):
+		unfilled, reserves, output, err := f.Flip().Fill(amount, r.Flip())
+		if err != nil {
+			return nil, Reserves{}, nil, err
+		}
+		return &assetv1alpha1.Value{Amount: unfilled.Proto(), AssetId: pair.GetAsset_2()}, reserves.Flip(),
+			&assetv1alpha1.Value{Amount: output.Proto(), AssetId: pair.GetAsset_1()}, nil
+	}
+	return nil, Reserves{}, nil, fmt.Errorf("input asset %s is not in the trading pair", asset.FormatAssetId(input.GetAssetId()))
+}
+
+// FillOutput returns the new reserves and the input, rounded up, needed to
+// get an output of either asset of a trading function, or false if the
+// reserves cannot provide it, as `TradingFunction::fill_output` does.
+func FillOutput(phi *dexv1alpha1.TradingFunction, r Reserves, output *assetv1alpha1.Value) (Reserves, *assetv1alpha1.Value, bool, error) {
+	f, err := BareTradingFunctionFromProto(phi.GetComponent())
+	if err != nil {
+		return Reserves{}, nil, false, err
+	}
+	pair := phi.GetPair()
+	amount := num.AmountFromProto(output.GetAmount())
+	switch {
+	case asset.IdEqual(output.GetAssetId(), pair.GetAsset_2()):
+		reserves, input, ok, err := f.FillOutput(r, amount)
+		if err != nil || !ok {
+			return Reserves{}, nil, false, err
+		}
+		return reserves, &assetv1alpha1.Value{Amount: input.Proto(), AssetId: pair.GetAsset_1()}, true, nil
+	case asset.IdEqual(output.GetAssetId(), pair.GetAsset_1()):
+		reserves, input, ok, err := f.Flip().FillOutput(r.Flip(), amount)
+		if err != nil || !ok {
+			return Reserves{}, nil, false, err
+		}
+		return reserves.Flip(), &assetv1alpha1.Value{Amount: input.Proto(), AssetId: pair.GetAsset_2()}, true, nil
+	}
+	return Reserves{}, nil, false, fmt.Errorf("output asset %s is not in the trading pair", asset.FormatAssetId(output.GetAssetId()))
+}
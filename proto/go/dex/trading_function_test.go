@@ -0,0 +1,215 @@
+package dex
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// testAssetId returns the asset ID encoding the field element n.
+func testAssetId(n byte) *assetv1alpha1.AssetId {
+	inner := make([]byte, 32)
+	inner[0] = n
+	return &assetv1alpha1.AssetId{Inner: inner}
+}
+
+func amounts(a ...uint64) []num.Amount {
+	var out []num.Amount
+	for _, v := range a {
+		out = append(out, num.NewAmount(v))
+	}
+	return out
+}
+
+// The tests below follow the tests of `penumbra_dex::lp::trading_function`.
+
+func TestEffectivePrice(t *testing.T) {
+	noFee := BareTradingFunction{Fee: 0, P: num.NewAmount(2_000_000), Q: num.NewAmount(1_000_000)}
+	withFee := BareTradingFunction{Fee: 100, P: num.NewAmount(2_000_000), Q: num.NewAmount(1_000_000)}
+
+	if gamma, _ := noFee.Gamma(); gamma.Cmp(num.U128x128FromAmount(num.NewAmount(1))) != 0 {
+		t.Errorf("gamma without a fee = %s, want 1", gamma)
+	}
+	gamma, _ := withFee.Gamma()
+	if want, _ := num.Ratio(num.NewAmount(99_000_000), num.NewAmount(100_000_000)); gamma.Cmp(want) != 0 {
+		t.Errorf("gamma with a 100bps fee = %s, want %s", gamma, want)
+	}
+
+	tests := []struct {
+		name     string
+		f        BareTradingFunction
+		price    string
+		priceInv string
+	}{
+		{"no fee", noFee,
+			"0000000000000000000000000000000080000000000000000000000000000000",
+			"0000000000000000000000000000000200000000000000000000000000000000"},
+		{"100bps fee", withFee,
+			"00000000000000000000000000000000814afd6a052bf5a814afd6a052bf5a81",
+			"00000000000000000000000000000001fae147ae147ae147ae147ae147ae147a"},
+	}
+	for _, tt := range tests {
+		key, err := tt.f.EffectivePriceKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(key); got != tt.price {
+			t.Errorf("%s: effective price %s, want %s", tt.name, got, tt.price)
+		}
+		inv, _ := tt.f.EffectivePriceInv()
+		if got := hex.EncodeToString(inv.Bytes()); got != tt.priceInv {
+			t.Errorf("%s: inverse effective price %s, want %s", tt.name, got, tt.priceInv)
+		}
+	}
+
+	// The fee makes the price worse, and the keys sort as the prices do.
+	key1, _ := noFee.EffectivePriceKey()
+	key2, _ := withFee.EffectivePriceKey()
+	if bytes.Compare(key1, key2) >= 0 {
+		t.Errorf("the key of the cheaper price does not sort first")
+	}
+
+	// The conversions agree with the effective prices.
+	f := BareTradingFunction{Fee: 150, P: num.NewAmount(12), Q: num.NewAmount(55)}
+	one := num.U128x128FromAmount(num.NewAmount(1))
+	price, _ := f.EffectivePrice()
+	if delta1, _ := f.ConvertToDelta1(one); delta1.Cmp(price) != 0 {
+		t.Errorf("ConvertToDelta1(1) = %s, want %s", delta1, price)
+	}
+	inv, _ := f.EffectivePriceInv()
+	if lambda2, _ := f.ConvertToLambda2(one); lambda2.Cmp(inv) != 0 {
+		t.Errorf("ConvertToLambda2(1) = %s, want %s", lambda2, inv)
+	}
+
+	if _, err := (BareTradingFunction{Fee: 10_001, P: num.NewAmount(1), Q: num.NewAmount(1)}).Gamma(); err == nil {
+		t.Errorf("Gamma accepted a fee above 100%%")
+	}
+}
+
+func TestBareFill(t *testing.T) {
+	tests := []struct {
+		name                        string
+		f                           BareTradingFunction
+		r1, r2, delta1              uint64
+		unfilled, newR1, newR2, out uint64
+	}{
+		// At a price of 1/3, 10,000,000 of asset 1 buys 3,333,333 of asset 2.
+		{"partial", BareTradingFunction{P: num.NewAmount(1), Q: num.NewAmount(3)},
+			1_000_000, 100_000_000, 10_000_000,
+			0, 11_000_000, 96_666_667, 3_333_333},
+		// Buying more than the reserves takes all of them for 300,000,000.
+		{"exhausted", BareTradingFunction{P: num.NewAmount(1), Q: num.NewAmount(3)},
+			1_000_000, 100_000_000, 600_000_000,
+			300_000_000, 301_000_000, 0, 100_000_000},
+		// The output is rounded down.
+		{"bad rounding", BareTradingFunction{P: num.NewAmount(12), Q: num.NewAmount(10)},
+			0, 120, 100,
+			0, 100, 1, 119},
+		// A 30bps fee is charged on the input.
+		{"fee", BareTradingFunction{Fee: 30, P: num.NewAmount(1), Q: num.NewAmount(1)},
+			0, 1_000, 100,
+			0, 100, 901, 99},
+	}
+	for _, tt := range tests {
+		unfilled, reserves, out, err := tt.f.Fill(num.NewAmount(tt.delta1), Reserves{R1: num.NewAmount(tt.r1), R2: num.NewAmount(tt.r2)})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := []num.Amount{unfilled, reserves.R1, reserves.R2, out}
+		want := amounts(tt.unfilled, tt.newR1, tt.newR2, tt.out)
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: Fill = %v, want %v", tt.name, got, want)
+				break
+			}
+		}
+		// Fill conserves value.
+		if in, _ := num.NewAmount(tt.r1).CheckedAdd(num.NewAmount(tt.delta1)); in != must(reserves.R1.CheckedAdd(unfilled)) {
+			t.Errorf("%s: asset 1 is not conserved", tt.name)
+		}
+		if num.NewAmount(tt.r2) != must(reserves.R2.CheckedAdd(out)) {
+			t.Errorf("%s: asset 2 is not conserved", tt.name)
+		}
+	}
+}
+
+func must(a num.Amount, ok bool) num.Amount {
+	if !ok {
+		panic("overflow")
+	}
+	return a
+}
+
+func TestBareFillOutput(t *testing.T) {
+	f := BareTradingFunction{P: num.NewAmount(12), Q: num.NewAmount(10)}
+	r := Reserves{R1: num.NewAmount(0), R2: num.NewAmount(120)}
+	// 119 of asset 2 costs 119 * 10/12 = 99.17 of asset 1, rounded up.
+	reserves, delta1, ok, err := f.FillOutput(r, num.NewAmount(119))
+	if err != nil || !ok {
+		t.Fatalf("FillOutput = %v, %v", ok, err)
+	}
+	if delta1 != num.NewAmount(100) || reserves.R1 != num.NewAmount(100) || reserves.R2 != num.NewAmount(1) {
+		t.Errorf("FillOutput = %v, %s", reserves, delta1)
+	}
+	if max, _ := f.MaxInput(r); max != num.NewAmount(100) {
+		t.Errorf("MaxInput = %s, want 100", max)
+	}
+	if _, _, ok, _ := f.FillOutput(r, num.NewAmount(121)); ok {
+		t.Errorf("FillOutput filled more than the reserves")
+	}
+}
+
+func TestFillTradingFunction(t *testing.T) {
+	a, b := testAssetId(0), testAssetId(1)
+	phi := &dexv1alpha1.TradingFunction{
+		Component: BareTradingFunction{P: num.NewAmount(1), Q: num.NewAmount(2)}.Proto(),
+		Pair:      NewTradingPair(a, b),
+	}
+
+	// From A to B, where id(A) < id(B).
+	unfilled, reserves, out, err := Fill(phi, Reserves{R2: num.NewAmount(100)}, &assetv1alpha1.Value{Amount: num.NewAmount(200).Proto(), AssetId: a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !num.AmountFromProto(unfilled.GetAmount()).IsZero() || unfilled.GetAssetId() != a {
+		t.Errorf("unfilled = %v, want 0 of A", unfilled)
+	}
+	if num.AmountFromProto(out.GetAmount()) != num.NewAmount(100) || out.GetAssetId() != b {
+		t.Errorf("output = %v, want 100 of B", out)
+	}
+	if reserves.R1 != num.NewAmount(200) || !reserves.R2.IsZero() {
+		t.Errorf("reserves = %v, want 200 and 0", reserves)
+	}
+
+	// From B to A.
+	unfilled, reserves, out, err = Fill(phi, Reserves{R1: num.NewAmount(100)}, &assetv1alpha1.Value{Amount: num.NewAmount(50).Proto(), AssetId: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !num.AmountFromProto(unfilled.GetAmount()).IsZero() || unfilled.GetAssetId() != b {
+		t.Errorf("unfilled = %v, want 0 of B", unfilled)
+	}
+	if num.AmountFromProto(out.GetAmount()) != num.NewAmount(100) || out.GetAssetId() != a {
+		t.Errorf("output = %v, want 100 of A", out)
+	}
+	if !reserves.R1.IsZero() || reserves.R2 != num.NewAmount(50) {
+		t.Errorf("reserves = %v, want 0 and 50", reserves)
+	}
+
+	// An output of A costs B at the flipped price.
+	reserves, in, ok, err := FillOutput(phi, Reserves{R1: num.NewAmount(100)}, &assetv1alpha1.Value{Amount: num.NewAmount(100).Proto(), AssetId: a})
+	if err != nil || !ok {
+		t.Fatalf("FillOutput = %v, %v", ok, err)
+	}
+	if num.AmountFromProto(in.GetAmount()) != num.NewAmount(50) || in.GetAssetId() != b || reserves.R2 != num.NewAmount(50) {
+		t.Errorf("FillOutput = %v, %v", reserves, in)
+	}
+
+	if _, _, _, err := Fill(phi, Reserves{}, &assetv1alpha1.Value{Amount: num.NewAmount(1).Proto(), AssetId: testAssetId(2)}); err == nil {
+		t.Errorf("Fill accepted an asset outside the pair")
+	}
+}
@@ -0,0 +1,158 @@
+// Package event decodes the typed events emitted by pd as ABCI events,
+// mirroring the `ProtoEvent` trait in `penumbra_proto`.
+//
+// pd records each event as an ABCI event whose type is the full protobuf name
+// of the message, with one attribute per field of its ProtoJSON encoding: the
+// attribute key is the JSON field name and the attribute value is the JSON
+// encoding of the field, with attributes sorted by key.
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	sctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/sct/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// ErrUnknownEvent is returned when decoding an event whose kind is not
+	// registered.
+	ErrUnknownEvent = errors.New("unknown event kind")
+	// ErrAmbiguousEvent is returned when the kind of an event recovered from
+	// transaction tags cannot be determined unambiguously.
+	ErrAmbiguousEvent = errors.New("ambiguous event kind")
+)
+
+// Attribute is a key-value attribute of an ABCI event.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Event is an ABCI event.
+type Event struct {
+	Kind       string
+	Attributes []Attribute
+}
+
+// Registry maps event kinds to the protobuf messages they decode into.
+type Registry struct {
+	mu    sync.RWMutex
+	kinds map[string]protoreflect.MessageType
+}
+
+// NewRegistry returns a registry containing the given event messages.
+func NewRegistry(msgs ...proto.Message) *Registry {
+	r := &Registry{kinds: make(map[string]protoreflect.MessageType)}
+	for _, msg := range msgs {
+		r.Register(msg)
+	}
+	return r
+}
+
+// DefaultRegistry contains the events emitted by the pd components.
+var DefaultRegistry = NewRegistry(
+	&dexv1alpha1.EventSwap{},
+	&dexv1alpha1.EventSwapClaim{},
+	&dexv1alpha1.EventPositionOpen{},
+	&dexv1alpha1.EventPositionClose{},
+	&dexv1alpha1.EventPositionWithdraw{},
+	&shielded_poolv1alpha1.EventSpend{},
+	&shielded_poolv1alpha1.EventOutput{},
+	&sctv1alpha1.EventCommitment{},
+	&sctv1alpha1.EventAnchor{},
+	&sctv1alpha1.EventEpochRoot{},
+	&sctv1alpha1.EventBlockRoot{},
+)
+
+// Register adds an event message to the default registry.
+func Register(msg proto.Message) {
+	DefaultRegistry.Register(msg)
+}
+
+// Register adds an event message to the registry, keyed by its full name.
+func (r *Registry) Register(msg proto.Message) {
+	mt := msg.ProtoReflect().Type()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds[string(mt.Descriptor().FullName())] = mt
+}
+
+// Kinds returns the registered event kinds, sorted.
+func (r *Registry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.kinds))
+	for kind := range r.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (r *Registry) lookup(kind string) (protoreflect.MessageType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mt, ok := r.kinds[kind]
+	return mt, ok
+}
+
+// Decode decodes an event into a new message of its registered kind.
+func (r *Registry) Decode(e Event) (proto.Message, error) {
+	mt, ok := r.lookup(e.Kind)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEvent, e.Kind)
+	}
+	fields := make(map[string]json.RawMessage, len(e.Attributes))
+	for _, a := range e.Attributes {
+		if !json.Valid([]byte(a.Value)) {
+			return nil, fmt.Errorf("could not parse JSON for attribute %q of %s", a.Key, e.Kind)
+		}
+		fields[a.Key] = json.RawMessage(a.Value)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	msg := mt.New().Interface()
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("could not deserialize %s: %w", e.Kind, err)
+	}
+	return msg, nil
+}
+
+// Decode decodes an event using the default registry.
+func Decode(e Event) (proto.Message, error) {
+	return DefaultRegistry.Decode(e)
+}
+
+// Encode encodes a message as an ABCI event, as pd does.
+func Encode(msg proto.Message) (Event, error) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return Event{}, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Event{}, err
+	}
+	e := Event{Kind: string(msg.ProtoReflect().Descriptor().FullName())}
+	for key, value := range fields {
+		// Re-encode each value compactly, as serde_json does.
+		compact, err := json.Marshal(value)
+		if err != nil {
+			return Event{}, err
+		}
+		e.Attributes = append(e.Attributes, Attribute{Key: key, Value: string(compact)})
+	}
+	sort.Slice(e.Attributes, func(i, j int) bool { return e.Attributes[i].Key < e.Attributes[j].Key })
+	return e, nil
+}
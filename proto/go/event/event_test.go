@@ -0,0 +1,221 @@
+package event
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	sctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/sct/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	spendKind  = "penumbra.core.component.shielded_pool.v1alpha1.EventSpend"
+	outputKind = "penumbra.core.component.shielded_pool.v1alpha1.EventOutput"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  proto.Message
+		err   bool
+	}{
+		{
+			name: "spend",
+			event: Event{Kind: spendKind, Attributes: []Attribute{
+				{Key: "nullifier", Value: `{"inner":"AQID"}`},
+			}},
+			want: &shielded_poolv1alpha1.EventSpend{Nullifier: &sctv1alpha1.Nullifier{Inner: []byte{1, 2, 3}}},
+		},
+		{
+			name: "swap amounts",
+			event: Event{Kind: "penumbra.core.component.dex.v1alpha1.EventSwap", Attributes: []Attribute{
+				{Key: "delta1I", Value: `{"lo":"5"}`},
+				{Key: "delta2I", Value: `{}`},
+			}},
+			want: &dexv1alpha1.EventSwap{Delta_1I: &numv1alpha1.Amount{Lo: 5}, Delta_2I: &numv1alpha1.Amount{}},
+		},
+		{
+			name:  "unknown kind",
+			event: Event{Kind: "transfer"},
+			err:   true,
+		},
+		{
+			name:  "invalid JSON",
+			event: Event{Kind: spendKind, Attributes: []Attribute{{Key: "nullifier", Value: `{inner`}}},
+			err:   true,
+		},
+		{
+			name:  "unknown field",
+			event: Event{Kind: spendKind, Attributes: []Attribute{{Key: "commitment", Value: `{}`}}},
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := Decode(tt.event)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: Decode succeeded", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tt.name, err)
+			continue
+		}
+		if !proto.Equal(got, tt.want) {
+			t.Errorf("%s: Decode = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := Decode(Event{Kind: "transfer"}); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Decode of an unknown kind = %v, want ErrUnknownEvent", err)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	msg := &dexv1alpha1.EventSwap{
+		TradingPair: &dexv1alpha1.TradingPair{},
+		Delta_1I:    &numv1alpha1.Amount{Lo: 1, Hi: 2},
+		Delta_2I:    &numv1alpha1.Amount{Lo: 3},
+	}
+	e, err := Encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Kind != "penumbra.core.component.dex.v1alpha1.EventSwap" {
+		t.Errorf("Encode kind = %s", e.Kind)
+	}
+	for i := 1; i < len(e.Attributes); i++ {
+		if e.Attributes[i-1].Key >= e.Attributes[i].Key {
+			t.Errorf("attributes are not sorted: %v", e.Attributes)
+		}
+	}
+	got, err := Decode(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, msg) {
+		t.Errorf("Decode(Encode(m)) = %v, want %v", got, msg)
+	}
+}
+
+func TestFromTags(t *testing.T) {
+	tag := func(k, v string) *tendermint_proxyv1alpha1.Tag {
+		return &tendermint_proxyv1alpha1.Tag{Key: []byte(k), Value: []byte(v)}
+	}
+	// A spend, two outputs, and a foreign event: a new event starts wherever
+	// the keys stop increasing.
+	tags := []*tendermint_proxyv1alpha1.Tag{
+		tag("nullifier", `{"inner":"AQ=="}`),
+		tag("noteCommitment", `{"inner":"Ag=="}`),
+		tag("noteCommitment", `{"inner":"Aw=="}`),
+		tag("action", "send"),
+	}
+	events, errs := DefaultRegistry.FromTags(tags)
+	wantKinds := []string{spendKind, outputKind, outputKind, ""}
+	if len(events) != len(wantKinds) {
+		t.Fatalf("FromTags returned %d events, want %d", len(events), len(wantKinds))
+	}
+	for i, want := range wantKinds {
+		if events[i].Kind != want || errs[i] != nil {
+			t.Errorf("event %d = %q (%v), want %q", i, events[i].Kind, errs[i], want)
+		}
+	}
+
+	// Without its trading fee, a position opening has the fields of a
+	// withdrawal, which has fewer.
+	events, errs = DefaultRegistry.FromTags([]*tendermint_proxyv1alpha1.Tag{
+		tag("positionId", `{}`),
+		tag("reserves1", `{}`),
+		tag("tradingPair", `{}`),
+		tag("positionId", `{}`),
+		tag("tradingFee", `30`),
+	})
+	wantKinds = []string{
+		"penumbra.core.component.dex.v1alpha1.EventPositionWithdraw",
+		"penumbra.core.component.dex.v1alpha1.EventPositionOpen",
+	}
+	if len(events) != len(wantKinds) {
+		t.Fatalf("FromTags returned %d events, want %d", len(events), len(wantKinds))
+	}
+	for i, want := range wantKinds {
+		if events[i].Kind != want || errs[i] != nil {
+			t.Errorf("event %d = %q (%v), want %q", i, events[i].Kind, errs[i], want)
+		}
+	}
+
+	// Nullifiers and state commitments both have a single "inner" field.
+	r := NewRegistry(&sctv1alpha1.Nullifier{}, &tctv1alpha1.StateCommitment{})
+	events, errs = r.FromTags([]*tendermint_proxyv1alpha1.Tag{tag("inner", `"AQ=="`)})
+	if len(events) != 1 || !errors.Is(errs[0], ErrAmbiguousEvent) {
+		t.Errorf("FromTags of ambiguous tags = %v, %v", events, errs)
+	}
+
+	stream := DefaultRegistry.TxStream(&tendermint_proxyv1alpha1.GetTxResponse{
+		Height:   7,
+		Index:    2,
+		TxResult: &tendermint_proxyv1alpha1.TxResult{Tags: tags},
+	})
+	decoded, err := stream.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("TxStream decoded %d events, want 3", len(decoded))
+	}
+	out := decoded[2].Message.(*shielded_poolv1alpha1.EventOutput)
+	if decoded[2].Height != 7 || decoded[2].TxIndex != 2 || !bytes.Equal(out.GetNoteCommitment().GetInner(), []byte{3}) {
+		t.Errorf("TxStream decoded %+v", decoded[2])
+	}
+}
+
+func TestBlockStream(t *testing.T) {
+	results, err := ParseBlockResults([]byte(`{"jsonrpc":"2.0","id":1,"result":{
+		"height": "12",
+		"txs_results": [{"code": 0, "log": "", "events": [
+			{"type": "tx", "attributes": [{"key": "fee", "value": "0"}]},
+			{"type": "` + spendKind + `", "attributes": [{"key": "nullifier", "value": "{\"inner\":\"AQ==\"}"}]}
+		]}],
+		"begin_block_events": null,
+		"end_block_events": [
+			{"type": "` + outputKind + `", "attributes": [{"key": "noteCommitment", "value": "{\"inner\":\"Ag==\"}"}]}
+		],
+		"finalize_block_events": [
+			{"type": "` + outputKind + `", "attributes": [{"key": "noteCommitment", "value": "{\"inner\":\"Aw==\"}"}]}
+		]
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DefaultRegistry.BlockStream(results).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind    string
+		txIndex int
+	}{
+		{spendKind, 0},
+		{outputKind, -1},
+		{outputKind, -1},
+	}
+	if len(decoded) != len(want) {
+		t.Fatalf("BlockStream decoded %d events, want %d", len(decoded), len(want))
+	}
+	for i, w := range want {
+		d := decoded[i]
+		if d.Height != 12 || d.Event.Kind != w.kind || d.TxIndex != w.txIndex {
+			t.Errorf("event %d = {%d %q %d}, want {12 %q %d}", i, d.Height, d.Event.Kind, d.TxIndex, w.kind, w.txIndex)
+		}
+	}
+
+	if _, err := ParseBlockResults([]byte(`{"height": "twelve"}`)); err == nil {
+		t.Errorf("ParseBlockResults accepted a non-numeric height")
+	}
+}
@@ -0,0 +1,188 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Decoded is an event decoded from a transaction or block result.
+type Decoded struct {
+	// Height is the height of the block containing the event.
+	Height uint64
+	// TxIndex is the index of the transaction within its block that emitted
+	// the event, or -1 for events emitted outside of a transaction.
+	TxIndex int
+	Event   Event
+	Message proto.Message
+}
+
+type pending struct {
+	txIndex int
+	event   Event
+	err     error
+}
+
+// Stream decodes the events of a transaction or block result one at a time.
+// Events whose kind is not registered, such as those emitted by CometBFT or
+// the IBC handler, are skipped.
+type Stream struct {
+	r       *Registry
+	height  uint64
+	pending []pending
+}
+
+// TxStream returns a stream over the events of a transaction, recovered from
+// its tags as described in FromTags.
+func (r *Registry) TxStream(rsp *tendermint_proxyv1alpha1.GetTxResponse) *Stream {
+	s := &Stream{r: r, height: rsp.GetHeight()}
+	events, errs := r.FromTags(rsp.GetTxResult().GetTags())
+	for i, e := range events {
+		s.pending = append(s.pending, pending{txIndex: int(rsp.GetIndex()), event: e, err: errs[i]})
+	}
+	return s
+}
+
+// BlockStream returns a stream over the events of a block, in the order in
+// which they were emitted: block-level events before the transactions, then
+// the events of each transaction, then the block-level events after them.
+func (r *Registry) BlockStream(results *BlockResults) *Stream {
+	s := &Stream{r: r, height: results.Height}
+	for _, e := range results.BeginBlockEvents {
+		s.pending = append(s.pending, pending{txIndex: -1, event: e})
+	}
+	for i, tx := range results.TxResults {
+		for _, e := range tx.Events {
+			s.pending = append(s.pending, pending{txIndex: i, event: e})
+		}
+	}
+	for _, e := range results.EndBlockEvents {
+		s.pending = append(s.pending, pending{txIndex: -1, event: e})
+	}
+	return s
+}
+
+// Next returns the next registered event in the stream, or io.EOF once the
+// stream is exhausted. A decoding error does not end the stream.
+func (s *Stream) Next() (*Decoded, error) {
+	for len(s.pending) > 0 {
+		p := s.pending[0]
+		s.pending = s.pending[1:]
+		if p.err != nil {
+			return nil, p.err
+		}
+		if _, ok := s.r.lookup(p.event.Kind); !ok {
+			continue
+		}
+		msg, err := s.r.Decode(p.event)
+		if err != nil {
+			return nil, err
+		}
+		return &Decoded{Height: s.height, TxIndex: p.txIndex, Event: p.event, Message: msg}, nil
+	}
+	return nil, io.EOF
+}
+
+// All drains the stream, returning every decoded event and the first error
+// encountered.
+func (s *Stream) All() ([]*Decoded, error) {
+	var (
+		decoded  []*Decoded
+		firstErr error
+	)
+	for {
+		d, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return decoded, firstErr
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		decoded = append(decoded, d)
+	}
+}
+
+// TxResult is the result of executing a transaction in a block.
+type TxResult struct {
+	Code   uint32
+	Log    string
+	Events []Event
+}
+
+// BlockResults are the results of executing a block, as returned by the
+// CometBFT `block_results` RPC.
+type BlockResults struct {
+	Height           uint64
+	BeginBlockEvents []Event
+	TxResults        []TxResult
+	EndBlockEvents   []Event
+}
+
+type rpcEvent struct {
+	Type       string `json:"type"`
+	Attributes []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"attributes"`
+}
+
+type rpcBlockResults struct {
+	Height     string `json:"height"`
+	TxsResults []struct {
+		Code   uint32     `json:"code"`
+		Log    string     `json:"log"`
+		Events []rpcEvent `json:"events"`
+	} `json:"txs_results"`
+	BeginBlockEvents    []rpcEvent `json:"begin_block_events"`
+	EndBlockEvents      []rpcEvent `json:"end_block_events"`
+	FinalizeBlockEvents []rpcEvent `json:"finalize_block_events"`
+}
+
+// ParseBlockResults parses the result of the CometBFT `block_results` RPC,
+// either bare or wrapped in a JSON-RPC response. Events reported as
+// `finalize_block_events` are treated as end-block events.
+func ParseBlockResults(data []byte) (*BlockResults, error) {
+	var envelope struct {
+		Result *json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Result != nil {
+		data = *envelope.Result
+	}
+	var raw rpcBlockResults
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("could not parse block results: %w", err)
+	}
+	height, err := strconv.ParseUint(raw.Height, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse block height %q: %w", raw.Height, err)
+	}
+	results := &BlockResults{
+		Height:           height,
+		BeginBlockEvents: convertEvents(raw.BeginBlockEvents),
+		EndBlockEvents:   append(convertEvents(raw.EndBlockEvents), convertEvents(raw.FinalizeBlockEvents)...),
+	}
+	for _, tx := range raw.TxsResults {
+		results.TxResults = append(results.TxResults, TxResult{Code: tx.Code, Log: tx.Log, Events: convertEvents(tx.Events)})
+	}
+	return results, nil
+}
+
+func convertEvents(raw []rpcEvent) []Event {
+	events := make([]Event, 0, len(raw))
+	for _, re := range raw {
+		e := Event{Kind: re.Type}
+		for _, a := range re.Attributes {
+			e.Attributes = append(e.Attributes, Attribute{Key: a.Key, Value: a.Value})
+		}
+		events = append(events, e)
+	}
+	return events
+}
@@ -0,0 +1,109 @@
+package event
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+)
+
+// FromTags recovers events from the tags of a transaction result.
+//
+// The tendermint proxy flattens the attributes of all the events of a
+// transaction into a single list of tags, dropping the event kinds. Since pd
+// sorts the attributes of each event by key, a new event starts wherever the
+// keys stop increasing; each run of attributes is then matched against the
+// JSON field names of the registered events. Runs that match no registered
+// event are returned with an empty Kind. Runs that match several events
+// equally well are reported with an error wrapping ErrAmbiguousEvent at the
+// same index. Since ProtoJSON omits default values, a run can also match the
+// wrong event: an `EventPositionOpen` with a zero trading fee has the fields
+// of an `EventPositionWithdraw`, and is reported as one.
+//
+// Because the recovery is heuristic, decoding block results, which retain the
+// event kinds, should be preferred where possible.
+func (r *Registry) FromTags(tags []*tendermint_proxyv1alpha1.Tag) ([]Event, []error) {
+	fieldSets := r.fieldSets()
+	var (
+		events []Event
+		errs   []error
+		run    []Attribute
+	)
+	flush := func() {
+		for len(run) > 0 {
+			n, kind, err := matchRun(fieldSets, run)
+			events = append(events, Event{Kind: kind, Attributes: run[:n]})
+			errs = append(errs, err)
+			run = run[n:]
+		}
+	}
+	for _, tag := range tags {
+		a := Attribute{Key: string(tag.GetKey()), Value: string(tag.GetValue())}
+		if len(run) > 0 && a.Key <= run[len(run)-1].Key {
+			flush()
+		}
+		run = append(run, a)
+	}
+	flush()
+	return events, errs
+}
+
+// fieldSets returns the JSON field names of each registered event.
+func (r *Registry) fieldSets() map[string]map[string]bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sets := make(map[string]map[string]bool, len(r.kinds))
+	for kind, mt := range r.kinds {
+		fields := mt.Descriptor().Fields()
+		set := make(map[string]bool, fields.Len())
+		for i := 0; i < fields.Len(); i++ {
+			set[fields.Get(i).JSONName()] = true
+		}
+		sets[kind] = set
+	}
+	return sets
+}
+
+// matchRun finds the longest prefix of the run whose keys are all fields of
+// some registered event, and returns its length and the best matching kind:
+// the one with the fewest fields, since ProtoJSON omits default values.
+func matchRun(fieldSets map[string]map[string]bool, run []Attribute) (int, string, error) {
+	for n := len(run); n > 0; n-- {
+		var candidates []string
+		best := -1
+		for kind, set := range fieldSets {
+			if !covers(set, run[:n]) {
+				continue
+			}
+			switch {
+			case best < 0 || len(set) < best:
+				best = len(set)
+				candidates = []string{kind}
+			case len(set) == best:
+				candidates = append(candidates, kind)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return n, candidates[0], nil
+		default:
+			sort.Strings(candidates)
+			return n, "", fmt.Errorf("%w: one of %s", ErrAmbiguousEvent, strings.Join(candidates, ", "))
+		}
+	}
+	// No registered event has this attribute: treat the whole run as a
+	// single foreign event.
+	return len(run), "", nil
+}
+
+func covers(set map[string]bool, attrs []Attribute) bool {
+	for _, a := range attrs {
+		if !set[a.Key] {
+			return false
+		}
+	}
+	return true
+}
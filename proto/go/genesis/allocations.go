@@ -0,0 +1,152 @@
+// Package genesis builds and validates the genesis of a Penumbra chain,
+// mirroring `pd testnet generate`.
+package genesis
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// Allocation is an initial allocation of tokens to an address. Its amount is
+// expressed in units of its denom, which may be a display unit such as
+// `penumbra` rather than the base `upenumbra`.
+type Allocation = shielded_poolv1alpha1.GenesisContent_Allocation
+
+// ReadAllocationsFile reads allocations from a CSV file, as described in
+// ParseAllocations.
+func ReadAllocationsFile(path string) ([]*Allocation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	allocations, err := ParseAllocations(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse allocations file %s: %w", path, err)
+	}
+	return allocations, nil
+}
+
+// ParseAllocations parses allocations in the CSV format used by the
+// `testnets/*/allocations.csv` files:
+//
+//	amount,denom,address
+//	1_000_000__000_000,upenumbra,penumbra1...
+//
+// The header row is required, but its columns may be in any order. Amounts
+// may use underscores as digit separators.
+func ParseAllocations(r io.Reader) ([]*Allocation, error) {
+	rdr := csv.NewReader(r)
+	rdr.TrimLeadingSpace = true
+	header, err := rdr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	cols := map[string]int{"amount": -1, "denom": -1, "address": -1}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := cols[name]; ok {
+			cols[name] = i
+		}
+	}
+	for name, i := range cols {
+		if i < 0 {
+			return nil, fmt.Errorf("header is missing column %q", name)
+		}
+	}
+
+	var allocations []*Allocation
+	for entry := 0; ; entry++ {
+		record, err := rdr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		a, err := parseAllocation(record[cols["amount"]], record[cols["denom"]], record[cols["address"]])
+		if err != nil {
+			return nil, fmt.Errorf("invalid allocation in entry %d of allocations file: %w", entry, err)
+		}
+		allocations = append(allocations, a)
+	}
+	if len(allocations) == 0 {
+		return nil, errors.New("parsed no entries from allocations input file; is the file valid CSV?")
+	}
+	return allocations, nil
+}
+
+func parseAllocation(amount, denom, address string) (*Allocation, error) {
+	a, err := num.ParseAmount(strings.TrimSpace(amount))
+	if err != nil {
+		return nil, err
+	}
+	addr, err := keys.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return nil, fmt.Errorf("invalid address format in genesis allocations: %w", err)
+	}
+	allocation := &Allocation{
+		Amount:  a.Proto(),
+		Denom:   strings.TrimSpace(denom),
+		Address: addr,
+	}
+	if err := ValidateAllocation(allocation); err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
+
+// denomPattern matches the denoms accepted in allocations: the asset
+// registry accepts any string as a base denom, so this only rules out
+// strings that cannot be intended as one.
+var denomPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_./:-]*$`)
+
+// delegationPrefixes are the units of delegation tokens, as in the asset
+// registry.
+var delegationPrefixes = []string{"udelegation_", "mdelegation_", "delegation_"}
+
+// DelegationDenom returns the base denom of the delegation token of a
+// validator, given its Bech32m identity key.
+func DelegationDenom(identityKey string) string {
+	return "udelegation_" + identityKey
+}
+
+// delegatedValidator returns the identity key of the validator whose
+// delegation token is denom, if it is one.
+func delegatedValidator(denom string) (string, bool) {
+	for _, p := range delegationPrefixes {
+		if ik, ok := strings.CutPrefix(denom, p); ok {
+			return ik, true
+		}
+	}
+	return "", false
+}
+
+// ValidateAllocation checks that an allocation has a non-zero amount, a
+// well-formed denom, and a valid address.
+func ValidateAllocation(a *Allocation) error {
+	if num.AmountFromProto(a.GetAmount()).IsZero() {
+		return errors.New("genesis allocations contain empty note")
+	}
+	if !denomPattern.MatchString(a.GetDenom()) {
+		return fmt.Errorf("invalid denom %q", a.GetDenom())
+	}
+	if ik, ok := delegatedValidator(a.GetDenom()); ok {
+		if _, err := keys.ParseIdentityKey(ik); err != nil {
+			return fmt.Errorf("invalid delegation denom %q: %w", a.GetDenom(), err)
+		}
+	}
+	if len(a.GetAddress().GetInner()) != keys.AddressLenBytes {
+		return fmt.Errorf("address has incorrect length %d", len(a.GetAddress().GetInner()))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package genesis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+const testAddress = "penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz"
+
+func TestParseAllocationsAmounts(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   string
+	}{
+		{"1_000_000__000_000", "1000000000000"},
+		{"20_000", "20000"},
+		{"10000", "10000"},
+		{"_1_", "1"},
+		{" 5", "5"},
+		{"340282366920938463463374607431768211455", "340282366920938463463374607431768211455"},
+	}
+	for _, tt := range tests {
+		csv := "amount,denom,address\n" + tt.amount + ",upenumbra," + testAddress + "\n"
+		allocations, err := ParseAllocations(strings.NewReader(csv))
+		if err != nil {
+			t.Errorf("amount %q: %v", tt.amount, err)
+			continue
+		}
+		if got := num.AmountFromProto(allocations[0].GetAmount()).String(); got != tt.want {
+			t.Errorf("amount %q parsed as %s, want %s", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestParseAllocationsColumns(t *testing.T) {
+	csv := "address, denom, amount\n" +
+		testAddress + ", gm, 20_000\n" +
+		testAddress + ", udelegation_penumbravalid1abc, 1\n"
+	// The second entry names an invalid identity key.
+	if _, err := ParseAllocations(strings.NewReader(csv)); err == nil || !strings.Contains(err.Error(), "entry 1") {
+		t.Errorf("ParseAllocations = %v, want an error in entry 1", err)
+	}
+
+	allocations, err := ParseAllocations(strings.NewReader("address, denom, amount\n" + testAddress + ", gm, 20_000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := allocations[0]
+	if a.GetDenom() != "gm" || num.AmountFromProto(a.GetAmount()).String() != "20000" || len(a.GetAddress().GetInner()) != 80 {
+		t.Errorf("ParseAllocations = %v", a)
+	}
+}
+
+func TestParseAllocationsInvalid(t *testing.T) {
+	tests := []struct {
+		name, csv string
+	}{
+		{"missing column", "amount,denom\n1,upenumbra\n"},
+		{"no entries", "amount,denom,address\n"},
+		{"empty amount", "amount,denom,address\n_," + "upenumbra," + testAddress + "\n"},
+		{"zero amount", "amount,denom,address\n0_000,upenumbra," + testAddress + "\n"},
+		{"negative amount", "amount,denom,address\n-1,upenumbra," + testAddress + "\n"},
+		{"hex amount", "amount,denom,address\n0x10,upenumbra," + testAddress + "\n"},
+		{"overflow", "amount,denom,address\n340_282_366_920_938_463_463_374_607_431_768_211_456,upenumbra," + testAddress + "\n"},
+		{"bad denom", "amount,denom,address\n1,1upenumbra," + testAddress + "\n"},
+		{"bad address", "amount,denom,address\n1,upenumbra,penumbra1abc\n"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseAllocations(strings.NewReader(tt.csv)); err == nil {
+			t.Errorf("%s: ParseAllocations succeeded", tt.name)
+		}
+	}
+}
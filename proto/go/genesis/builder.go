@@ -0,0 +1,360 @@
+package genesis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	daov1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dao/v1alpha1"
+	distributionsv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/distributions/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	ibcv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/ibc/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// MaxChainIdLen is the maximum length of a CometBFT chain ID.
+const MaxChainIdLen = 50
+
+// DefaultContent returns the genesis content of a chain with no validators
+// or allocations, and the default parameters of every component.
+func DefaultContent(chainId string) *appv1alpha1.GenesisContent {
+	return &appv1alpha1.GenesisContent{
+		StakeContent: &stakev1alpha1.GenesisContent{
+			StakeParams: &stakev1alpha1.StakeParameters{
+				UnbondingEpochs:      2,
+				ActiveValidatorLimit: 80,
+				// copied from cosmos hub
+				SignedBlocksWindowLen: 10000,
+				MissedBlocksMaximum:   9500,
+				// 1000 basis points = 10%
+				SlashingPenaltyMisbehavior: 1000_0000,
+				// 1 basis point = 0.01%
+				SlashingPenaltyDowntime: 1_0000,
+				// 3bps -> 11% return over 365 epochs
+				BaseRewardRate: 3_0000,
+			},
+		},
+		ShieldedPoolContent: &shielded_poolv1alpha1.GenesisContent{},
+		GovernanceContent: &governancev1alpha1.GenesisContent{
+			GovernanceParams: &governancev1alpha1.GovernanceParameters{
+				// 24 hours, at a 5 second block time
+				ProposalVotingBlocks: 17_280,
+				// 10,000,000 upenumbra = 10 penumbra
+				ProposalDepositAmount: num.NewAmount(10_000_000).Proto(),
+				// governance parameters copied from cosmos hub
+				ProposalValidQuorum:    "40/100",
+				ProposalPassThreshold:  "50/100",
+				ProposalSlashThreshold: "80/100",
+			},
+		},
+		IbcContent: &ibcv1alpha1.GenesisContent{
+			IbcParams: &ibcv1alpha1.IbcParameters{
+				IbcEnabled:                    true,
+				InboundIcs20TransfersEnabled:  true,
+				OutboundIcs20TransfersEnabled: true,
+			},
+		},
+		ChainContent: &chainv1alpha1.GenesisContent{
+			ChainParams: &chainv1alpha1.ChainParameters{
+				ChainId:       chainId,
+				EpochDuration: 719,
+			},
+		},
+		DaoContent: &daov1alpha1.GenesisContent{
+			DaoParams: &daov1alpha1.DaoParameters{
+				DaoSpendProposalsEnabled: true,
+			},
+		},
+		FeeContent: &feev1alpha1.GenesisContent{
+			FeeParams: &feev1alpha1.FeeParameters{},
+			GasPrices: &feev1alpha1.GasPrices{},
+		},
+		DistributionsContent: &distributionsv1alpha1.GenesisContent{
+			DistributionsParams: &distributionsv1alpha1.DistributionsParameters{
+				StakingIssuancePerBlock: 1,
+			},
+		},
+	}
+}
+
+// Builder assembles the genesis of a chain.
+type Builder struct {
+	// Content is the genesis content being built. Its parameters may be
+	// changed directly.
+	Content     *appv1alpha1.GenesisContent
+	GenesisTime time.Time
+}
+
+// NewBuilder returns a builder starting from the default content, with the
+// current time as genesis time.
+func NewBuilder(chainId string) *Builder {
+	return &Builder{
+		Content:     DefaultContent(chainId),
+		GenesisTime: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// AddAllocations adds initial allocations.
+func (b *Builder) AddAllocations(allocations ...*Allocation) {
+	pool := b.Content.GetShieldedPoolContent()
+	pool.Allocations = append(pool.Allocations, allocations...)
+}
+
+// AddValidators adds genesis validators, and the delegation allocations of
+// those with a delegation address.
+func (b *Builder) AddValidators(validators ...Validator) error {
+	stake := b.Content.GetStakeContent()
+	for i := range validators {
+		v, err := validators[i].Proto()
+		if err != nil {
+			return err
+		}
+		allocation, err := validators[i].delegationAllocation()
+		if err != nil {
+			return err
+		}
+		stake.Validators = append(stake.Validators, v)
+		if allocation != nil {
+			b.AddAllocations(allocation)
+		}
+	}
+	return nil
+}
+
+// Validate checks the genesis content, as described in Validate.
+func (b *Builder) Validate() error {
+	return Validate(b.Content)
+}
+
+// AppState returns the app state embedded in the genesis.
+func (b *Builder) AppState() *appv1alpha1.GenesisAppState {
+	return &appv1alpha1.GenesisAppState{
+		GenesisAppState: &appv1alpha1.GenesisAppState_GenesisContent{GenesisContent: b.Content},
+	}
+}
+
+// Genesis validates the content and returns the CometBFT genesis document
+// embedding it.
+func (b *Builder) Genesis() (*Genesis, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	appState, err := protojson.Marshal(b.AppState())
+	if err != nil {
+		return nil, err
+	}
+	return &Genesis{
+		GenesisTime:     b.GenesisTime,
+		ChainId:         b.Content.GetChainContent().GetChainParams().GetChainId(),
+		ConsensusParams: DefaultConsensusParams(),
+		Validators:      []json.RawMessage{},
+		AppState:        appState,
+	}, nil
+}
+
+// Validate checks the chain ID, the parameters of each component, the
+// genesis validators and the allocations of genesis content. Allocations of
+// delegation tokens must be to genesis validators, and at least one genesis
+// validator must be delegated to, so that the chain has voting power.
+func Validate(c *appv1alpha1.GenesisContent) error {
+	chain := c.GetChainContent().GetChainParams()
+	if chain.GetChainId() == "" {
+		return errors.New("chain ID is empty")
+	}
+	if len(chain.GetChainId()) > MaxChainIdLen {
+		return fmt.Errorf("chain ID is longer than %d characters", MaxChainIdLen)
+	}
+	if chain.GetEpochDuration() == 0 {
+		return errors.New("epoch duration must be positive")
+	}
+	if err := validateStakeParams(c.GetStakeContent().GetStakeParams()); err != nil {
+		return err
+	}
+	if err := validateGovernanceParams(c.GetGovernanceContent().GetGovernanceParams()); err != nil {
+		return err
+	}
+	for _, missing := range []struct {
+		name string
+		ok   bool
+	}{
+		{"shielded pool content", c.GetShieldedPoolContent() != nil},
+		{"ibc parameters", c.GetIbcContent().GetIbcParams() != nil},
+		{"dao parameters", c.GetDaoContent().GetDaoParams() != nil},
+		{"fee parameters", c.GetFeeContent().GetFeeParams() != nil},
+		{"gas prices", c.GetFeeContent().GetGasPrices() != nil},
+		{"distributions parameters", c.GetDistributionsContent().GetDistributionsParams() != nil},
+	} {
+		if !missing.ok {
+			return fmt.Errorf("genesis content is missing %s", missing.name)
+		}
+	}
+
+	validators := c.GetStakeContent().GetValidators()
+	if len(validators) == 0 {
+		return errors.New("genesis has no validators")
+	}
+	identityKeys := make(map[string]bool)
+	consensusKeys := make(map[string]bool)
+	for _, v := range validators {
+		if err := ValidateValidator(v); err != nil {
+			return err
+		}
+		ik := keys.FormatIdentityKey(v.GetIdentityKey())
+		if identityKeys[ik] {
+			return fmt.Errorf("duplicate genesis validator %s", ik)
+		}
+		identityKeys[ik] = true
+		if consensusKeys[string(v.GetConsensusKey())] {
+			return fmt.Errorf("genesis validator %s reuses a consensus key", ik)
+		}
+		consensusKeys[string(v.GetConsensusKey())] = true
+	}
+
+	delegated := false
+	for i, a := range c.GetShieldedPoolContent().GetAllocations() {
+		if err := ValidateAllocation(a); err != nil {
+			return fmt.Errorf("invalid allocation %d: %w", i, err)
+		}
+		if ik, ok := delegatedValidator(a.GetDenom()); ok {
+			if !identityKeys[ik] {
+				return fmt.Errorf("allocation %d is of delegation tokens of %s, which is not a genesis validator", i, ik)
+			}
+			delegated = true
+		}
+	}
+	if !delegated {
+		return errors.New("no genesis validator has delegations, so the chain would have no voting power")
+	}
+	return nil
+}
+
+func validateStakeParams(p *stakev1alpha1.StakeParameters) error {
+	switch {
+	case p == nil:
+		return errors.New("genesis content is missing stake parameters")
+	case p.GetActiveValidatorLimit() == 0:
+		return errors.New("active validator limit must be positive")
+	case p.GetUnbondingEpochs() == 0:
+		return errors.New("unbonding epochs must be positive")
+	case p.GetSignedBlocksWindowLen() == 0:
+		return errors.New("signed blocks window length must be positive")
+	case p.GetMissedBlocksMaximum() > p.GetSignedBlocksWindowLen():
+		return errors.New("missed blocks maximum exceeds the signed blocks window length")
+	case p.GetSlashingPenaltyMisbehavior() > 1_0000_0000:
+		return errors.New("misbehavior slashing penalty exceeds 100%")
+	case p.GetSlashingPenaltyDowntime() > 1_0000_0000:
+		return errors.New("downtime slashing penalty exceeds 100%")
+	}
+	return nil
+}
+
+func validateGovernanceParams(p *governancev1alpha1.GovernanceParameters) error {
+	if p == nil {
+		return errors.New("genesis content is missing governance parameters")
+	}
+	if p.GetProposalVotingBlocks() == 0 {
+		return errors.New("proposal voting blocks must be positive")
+	}
+	if p.GetProposalDepositAmount() == nil {
+		return errors.New("proposal deposit amount is missing")
+	}
+	for name, ratio := range map[string]string{
+		"proposal valid quorum":    p.GetProposalValidQuorum(),
+		"proposal pass threshold":  p.GetProposalPassThreshold(),
+		"proposal slash threshold": p.GetProposalSlashThreshold(),
+	} {
+		if err := validateRatio(ratio); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateRatio checks that a governance ratio, formatted as
+// `numerator/denominator`, is at most one.
+func validateRatio(s string) error {
+	n, d, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("ratio %q is not of the form n/d", s)
+	}
+	numerator, err := strconv.ParseUint(n, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid numerator in %q: %w", s, err)
+	}
+	denominator, err := strconv.ParseUint(d, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid denominator in %q: %w", s, err)
+	}
+	if denominator == 0 || numerator > denominator {
+		return fmt.Errorf("ratio %q is not between 0 and 1", s)
+	}
+	return nil
+}
+
+// Genesis is a CometBFT genesis document.
+type Genesis struct {
+	GenesisTime     time.Time       `json:"genesis_time"`
+	ChainId         string          `json:"chain_id"`
+	InitialHeight   int64           `json:"initial_height,string"`
+	ConsensusParams ConsensusParams `json:"consensus_params"`
+	// Validators is always empty, so that CometBFT uses the validators
+	// returned by pd from the app state.
+	Validators []json.RawMessage `json:"validators"`
+	AppHash    string            `json:"app_hash"`
+	AppState   json.RawMessage   `json:"app_state"`
+}
+
+// ConsensusParams are the consensus parameters of a CometBFT genesis.
+type ConsensusParams struct {
+	Block struct {
+		MaxBytes   int64 `json:"max_bytes,string"`
+		MaxGas     int64 `json:"max_gas,string"`
+		TimeIotaMs int64 `json:"time_iota_ms,string"`
+	} `json:"block"`
+	Evidence struct {
+		MaxAgeNumBlocks int64         `json:"max_age_num_blocks,string"`
+		MaxAgeDuration  time.Duration `json:"max_age_duration,string"`
+		MaxBytes        int64         `json:"max_bytes,string"`
+	} `json:"evidence"`
+	Validator struct {
+		PubKeyTypes []string `json:"pub_key_types"`
+	} `json:"validator"`
+	Version struct {
+		App uint64 `json:"app,string"`
+	} `json:"version"`
+}
+
+// DefaultConsensusParams returns the consensus parameters used by
+// `pd testnet generate`.
+func DefaultConsensusParams() ConsensusParams {
+	var p ConsensusParams
+	p.Block.MaxBytes = 22020096
+	p.Block.MaxGas = -1
+	// minimum time increment between consecutive blocks
+	p.Block.TimeIotaMs = 500
+	p.Evidence.MaxAgeNumBlocks = 100000
+	p.Evidence.MaxAgeDuration = 24 * time.Hour
+	p.Evidence.MaxBytes = 1048576
+	p.Validator.PubKeyTypes = []string{"ed25519"}
+	return p
+}
+
+// WriteFile writes the genesis document as indented JSON.
+func (g *Genesis) WriteFile(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
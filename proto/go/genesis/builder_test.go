@@ -0,0 +1,174 @@
+package genesis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ciBuilder returns a builder with the allocations of a testnet and the CI
+// validators, with generated keys.
+func ciBuilder(t *testing.T) *Builder {
+	b := NewBuilder("penumbra-testnet-ci")
+	allocations, err := ReadAllocationsFile("../../../testnets/064-titan/allocations.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddAllocations(allocations...)
+	validators, err := ReadValidatorsFile(ciValidatorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range validators {
+		if err := validators[i].SetKeys(testKeys(t, byte(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.AddValidators(validators...); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBuilderCI(t *testing.T) {
+	b := ciBuilder(t)
+	b.GenesisTime = time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	allocations, _ := ReadAllocationsFile("../../../testnets/064-titan/allocations.csv")
+
+	validators := b.Content.GetStakeContent().GetValidators()
+	if len(validators) != 2 {
+		t.Fatalf("%d genesis validators, want 2", len(validators))
+	}
+	got := b.Content.GetShieldedPoolContent().GetAllocations()
+	if len(got) != len(allocations)+2 {
+		t.Fatalf("%d allocations, want the file's %d and one delegation per validator", len(got), len(allocations))
+	}
+	for i, a := range got[len(allocations):] {
+		ik := keys.FormatIdentityKey(validators[i].GetIdentityKey())
+		if a.GetDenom() != DelegationDenom(ik) || num.AmountFromProto(a.GetAmount()) != GenesisDelegation {
+			t.Errorf("delegation %d = %v", i, a)
+		}
+	}
+
+	g, err := b.Genesis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := g.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		GenesisTime     string            `json:"genesis_time"`
+		ChainId         string            `json:"chain_id"`
+		InitialHeight   string            `json:"initial_height"`
+		ConsensusParams json.RawMessage   `json:"consensus_params"`
+		Validators      []json.RawMessage `json:"validators"`
+		AppState        json.RawMessage   `json:"app_state"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.GenesisTime != "2023-11-01T00:00:00Z" || doc.ChainId != "penumbra-testnet-ci" || doc.InitialHeight != "0" || doc.Validators == nil || len(doc.Validators) != 0 {
+		t.Errorf("genesis = %s", data[:200])
+	}
+	if !strings.Contains(string(doc.ConsensusParams), `"max_age_duration": "86400000000000"`) {
+		t.Errorf("consensus params = %s", doc.ConsensusParams)
+	}
+	var appState appv1alpha1.GenesisAppState
+	if err := protojson.Unmarshal(doc.AppState, &appState); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(appState.GetGenesisContent(), b.Content) {
+		t.Errorf("app state does not round trip")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := ciBuilder(t).Validate(); err != nil {
+		t.Fatalf("Validate of the CI genesis: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *appv1alpha1.GenesisContent)
+		want   string
+	}{
+		{"empty chain ID", func(c *appv1alpha1.GenesisContent) { c.ChainContent.ChainParams.ChainId = "" }, "chain ID is empty"},
+		{"long chain ID", func(c *appv1alpha1.GenesisContent) {
+			c.ChainContent.ChainParams.ChainId = strings.Repeat("a", MaxChainIdLen+1)
+		}, "longer than"},
+		{"zero epoch duration", func(c *appv1alpha1.GenesisContent) { c.ChainContent.ChainParams.EpochDuration = 0 }, "epoch duration"},
+		{"no stake parameters", func(c *appv1alpha1.GenesisContent) { c.StakeContent.StakeParams = nil }, "stake parameters"},
+		{"zero active validator limit", func(c *appv1alpha1.GenesisContent) { c.StakeContent.StakeParams.ActiveValidatorLimit = 0 }, "active validator limit"},
+		{"zero unbonding epochs", func(c *appv1alpha1.GenesisContent) { c.StakeContent.StakeParams.UnbondingEpochs = 0 }, "unbonding epochs"},
+		{"zero signed blocks window", func(c *appv1alpha1.GenesisContent) { c.StakeContent.StakeParams.SignedBlocksWindowLen = 0 }, "signed blocks window"},
+		{"missed blocks over window", func(c *appv1alpha1.GenesisContent) { c.StakeContent.StakeParams.MissedBlocksMaximum = 10001 }, "missed blocks maximum"},
+		{"misbehavior penalty over 100%", func(c *appv1alpha1.GenesisContent) {
+			c.StakeContent.StakeParams.SlashingPenaltyMisbehavior = 1_0000_0001
+		}, "misbehavior slashing penalty"},
+		{"downtime penalty over 100%", func(c *appv1alpha1.GenesisContent) {
+			c.StakeContent.StakeParams.SlashingPenaltyDowntime = 1_0000_0001
+		}, "downtime slashing penalty"},
+		{"no governance parameters", func(c *appv1alpha1.GenesisContent) { c.GovernanceContent.GovernanceParams = nil }, "governance parameters"},
+		{"zero voting blocks", func(c *appv1alpha1.GenesisContent) { c.GovernanceContent.GovernanceParams.ProposalVotingBlocks = 0 }, "voting blocks"},
+		{"no deposit amount", func(c *appv1alpha1.GenesisContent) { c.GovernanceContent.GovernanceParams.ProposalDepositAmount = nil }, "deposit amount"},
+		{"malformed quorum", func(c *appv1alpha1.GenesisContent) { c.GovernanceContent.GovernanceParams.ProposalValidQuorum = "40" }, "proposal valid quorum"},
+		{"threshold over one", func(c *appv1alpha1.GenesisContent) {
+			c.GovernanceContent.GovernanceParams.ProposalPassThreshold = "3/2"
+		}, "proposal pass threshold"},
+		{"zero denominator", func(c *appv1alpha1.GenesisContent) {
+			c.GovernanceContent.GovernanceParams.ProposalSlashThreshold = "0/0"
+		}, "proposal slash threshold"},
+		{"no shielded pool content", func(c *appv1alpha1.GenesisContent) { c.ShieldedPoolContent = nil }, "shielded pool content"},
+		{"no ibc parameters", func(c *appv1alpha1.GenesisContent) { c.IbcContent = nil }, "ibc parameters"},
+		{"no dao parameters", func(c *appv1alpha1.GenesisContent) { c.DaoContent.DaoParams = nil }, "dao parameters"},
+		{"no fee parameters", func(c *appv1alpha1.GenesisContent) { c.FeeContent.FeeParams = nil }, "fee parameters"},
+		{"no gas prices", func(c *appv1alpha1.GenesisContent) { c.FeeContent.GasPrices = nil }, "gas prices"},
+		{"no distributions parameters", func(c *appv1alpha1.GenesisContent) { c.DistributionsContent = nil }, "distributions parameters"},
+		{"no validators", func(c *appv1alpha1.GenesisContent) { c.StakeContent.Validators = nil }, "no validators"},
+		{"duplicate validator", func(c *appv1alpha1.GenesisContent) {
+			v := proto.Clone(c.StakeContent.Validators[0]).(*stakev1alpha1.Validator)
+			v.ConsensusKey = make([]byte, 32)
+			c.StakeContent.Validators = append(c.StakeContent.Validators, v)
+		}, "duplicate genesis validator"},
+		{"reused consensus key", func(c *appv1alpha1.GenesisContent) {
+			c.StakeContent.Validators[1].ConsensusKey = c.StakeContent.Validators[0].ConsensusKey
+		}, "reuses a consensus key"},
+		{"invalid validator", func(c *appv1alpha1.GenesisContent) { c.StakeContent.Validators[1].IdentityKey.Ik = nil }, "identity key of length 0"},
+		{"empty allocation", func(c *appv1alpha1.GenesisContent) {
+			c.ShieldedPoolContent.Allocations[0].Amount = num.NewAmount(0).Proto()
+		}, "invalid allocation 0"},
+		{"delegation to a non-genesis validator", func(c *appv1alpha1.GenesisContent) {
+			c.StakeContent.Validators = c.StakeContent.Validators[:1]
+		}, "which is not a genesis validator"},
+		{"no delegations", func(c *appv1alpha1.GenesisContent) {
+			allocations := c.ShieldedPoolContent.Allocations
+			c.ShieldedPoolContent.Allocations = allocations[:len(allocations)-2]
+		}, "no genesis validator has delegations"},
+	}
+	for _, tt := range tests {
+		b := ciBuilder(t)
+		tt.modify(b.Content)
+		if err := b.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+		if _, err := b.Genesis(); err == nil {
+			t.Errorf("%s: Genesis of invalid content succeeded", tt.name)
+		}
+	}
+}
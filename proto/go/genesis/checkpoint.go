@@ -0,0 +1,250 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/snapshot"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotCheckpoint is returned when a checkpoint is requested from a genesis
+// whose app state is a full configuration.
+var ErrNotCheckpoint = errors.New("genesis app state is not a checkpoint")
+
+// ReadFile reads a CometBFT genesis file.
+func ReadFile(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g := new(Genesis)
+	if err := json.Unmarshal(data, g); err != nil {
+		return nil, fmt.Errorf("could not parse genesis file %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// DecodeAppState decodes the app state embedded in the genesis.
+func (g *Genesis) DecodeAppState() (*appv1alpha1.GenesisAppState, error) {
+	appState := new(appv1alpha1.GenesisAppState)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(g.AppState, appState); err != nil {
+		return nil, fmt.Errorf("could not decode app state: %w", err)
+	}
+	return appState, nil
+}
+
+// Checkpoint describes a genesis that starts a chain from existing state,
+// as written by `pd migrate`: its app state only holds the app hash of the
+// migrated state, which pd returns to CometBFT instead of initializing its
+// state.
+type Checkpoint struct {
+	ChainId       string
+	InitialHeight int64
+	GenesisTime   time.Time
+	// AppHash is the root hash of the migrated state.
+	AppHash []byte
+}
+
+// Checkpoint returns the checkpoint embedded in the genesis. The app hash of
+// the checkpoint must be a 32-byte root hash, and match the genesis
+// `app_hash` if that is set.
+func (g *Genesis) Checkpoint() (*Checkpoint, error) {
+	appState, err := g.DecodeAppState()
+	if err != nil {
+		return nil, err
+	}
+	state, ok := appState.GetGenesisAppState().(*appv1alpha1.GenesisAppState_GenesisCheckpoint)
+	if !ok {
+		return nil, ErrNotCheckpoint
+	}
+	if len(state.GenesisCheckpoint) != 32 {
+		return nil, fmt.Errorf("checkpoint app hash has length %d, expected 32", len(state.GenesisCheckpoint))
+	}
+	if g.AppHash != "" {
+		appHash, err := hex.DecodeString(g.AppHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid genesis app hash: %w", err)
+		}
+		if !bytes.Equal(appHash, state.GenesisCheckpoint) {
+			return nil, fmt.Errorf("genesis app hash %X does not match checkpoint %X", appHash, state.GenesisCheckpoint)
+		}
+	}
+	return &Checkpoint{
+		ChainId:       g.ChainId,
+		InitialHeight: g.InitialHeight,
+		GenesisTime:   g.GenesisTime,
+		AppHash:       state.GenesisCheckpoint,
+	}, nil
+}
+
+// VerifyLive checks that a running chain started from the checkpoint: the
+// header of its first block must commit to the checkpoint's app hash.
+func (c *Checkpoint) VerifyLive(ctx context.Context, v *cnidarium.Verifier) error {
+	if c.InitialHeight < 1 {
+		return fmt.Errorf("checkpoint has invalid initial height %d", c.InitialHeight)
+	}
+	appHash, err := v.AppHash(ctx, uint64(c.InitialHeight-1))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(appHash, c.AppHash) {
+		return fmt.Errorf("chain started from app hash %X, not checkpoint %X", appHash, c.AppHash)
+	}
+	return nil
+}
+
+// Comparison is the result of comparing a checkpoint to a state export.
+type Comparison struct {
+	// Matched holds the sections exported at the initial height, whose
+	// recorded app hash is the checkpoint.
+	Matched []snapshot.Section
+	// Mismatched holds the sections exported at the initial height, whose
+	// recorded app hash is not the checkpoint.
+	Mismatched []snapshot.Section
+	// Unverifiable holds the sections exported at other heights, or without
+	// a recorded app hash, which cannot be compared to the checkpoint.
+	Unverifiable []snapshot.Section
+	// Problems lists inconsistencies between the exported state and the
+	// genesis.
+	Problems []string
+}
+
+// OK reports whether the export confirms the checkpoint: some section was
+// exported from the checkpoint state, and nothing contradicts it.
+func (c *Comparison) OK() bool {
+	return len(c.Matched) > 0 && len(c.Mismatched) == 0 && len(c.Problems) == 0
+}
+
+// CompareSnapshot compares a checkpoint to a state export of the migrated
+// chain. The header of the block at the initial height commits to the
+// checkpoint, so sections exported at that height must have recorded it as
+// their app hash. The exported chain parameters must also name the
+// genesis chain.
+func (c *Checkpoint) CompareSnapshot(s *snapshot.Snapshot) *Comparison {
+	cmp := new(Comparison)
+	for _, sec := range s.Sections {
+		switch {
+		case sec.AppHash == nil || int64(sec.Height) != c.InitialHeight:
+			cmp.Unverifiable = append(cmp.Unverifiable, sec)
+		case bytes.Equal(sec.AppHash, c.AppHash):
+			cmp.Matched = append(cmp.Matched, sec)
+		default:
+			cmp.Mismatched = append(cmp.Mismatched, sec)
+		}
+	}
+	if s.ChainId != "" && s.ChainId != c.ChainId {
+		cmp.Problems = append(cmp.Problems, fmt.Sprintf("state was exported from chain %q, not %q", s.ChainId, c.ChainId))
+	}
+	if value := s.Get("chain/params"); value != nil {
+		params := new(chainv1alpha1.ChainParameters)
+		if err := proto.Unmarshal(value, params); err != nil {
+			cmp.Problems = append(cmp.Problems, fmt.Sprintf("could not decode exported chain parameters: %v", err))
+		} else if params.GetChainId() != c.ChainId {
+			cmp.Problems = append(cmp.Problems, fmt.Sprintf("exported chain parameters name chain %q, not %q", params.GetChainId(), c.ChainId))
+		}
+	}
+	return cmp
+}
+
+// Summary holds statistics about a genesis.
+type Summary struct {
+	ChainId       string
+	InitialHeight int64
+	GenesisTime   time.Time
+	// Checkpoint is set if the genesis is a checkpoint, in which case the
+	// content statistics below are empty.
+	Checkpoint *Checkpoint
+	Params     map[string]proto.Message
+	Validators int
+	// Allocations counts the initial allocations, and Totals sums them by
+	// denom.
+	Allocations int
+	Totals      map[string]num.Amount
+}
+
+// Summarize returns statistics about a genesis.
+func (g *Genesis) Summarize() (*Summary, error) {
+	s := &Summary{
+		ChainId:       g.ChainId,
+		InitialHeight: g.InitialHeight,
+		GenesisTime:   g.GenesisTime,
+	}
+	appState, err := g.DecodeAppState()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := appState.GetGenesisAppState().(*appv1alpha1.GenesisAppState_GenesisCheckpoint); ok {
+		if s.Checkpoint, err = g.Checkpoint(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	content := appState.GetGenesisContent()
+	s.Params = map[string]proto.Message{
+		"chain":         content.GetChainContent().GetChainParams(),
+		"stake":         content.GetStakeContent().GetStakeParams(),
+		"governance":    content.GetGovernanceContent().GetGovernanceParams(),
+		"ibc":           content.GetIbcContent().GetIbcParams(),
+		"dao":           content.GetDaoContent().GetDaoParams(),
+		"fee":           content.GetFeeContent().GetFeeParams(),
+		"gas_prices":    content.GetFeeContent().GetGasPrices(),
+		"distributions": content.GetDistributionsContent().GetDistributionsParams(),
+	}
+	s.Validators = len(content.GetStakeContent().GetValidators())
+	s.Totals = make(map[string]num.Amount)
+	for _, a := range content.GetShieldedPoolContent().GetAllocations() {
+		s.Allocations++
+		total, ok := s.Totals[a.GetDenom()].CheckedAdd(num.AmountFromProto(a.GetAmount()))
+		if !ok {
+			return nil, fmt.Errorf("total allocation of %s overflows", a.GetDenom())
+		}
+		s.Totals[a.GetDenom()] = total
+	}
+	return s, nil
+}
+
+// String formats the summary for display.
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chain id:       %s\n", s.ChainId)
+	fmt.Fprintf(&b, "initial height: %d\n", s.InitialHeight)
+	fmt.Fprintf(&b, "genesis time:   %s\n", s.GenesisTime.Format(time.RFC3339))
+	if s.Checkpoint != nil {
+		fmt.Fprintf(&b, "checkpoint:     %X\n", s.Checkpoint.AppHash)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "validators:     %d\n", s.Validators)
+	fmt.Fprintf(&b, "allocations:    %d\n", s.Allocations)
+	denoms := make([]string, 0, len(s.Totals))
+	for denom := range s.Totals {
+		denoms = append(denoms, denom)
+	}
+	sort.Strings(denoms)
+	for _, denom := range denoms {
+		fmt.Fprintf(&b, "  %s %s\n", s.Totals[denom], denom)
+	}
+	components := make([]string, 0, len(s.Params))
+	for name := range s.Params {
+		components = append(components, name)
+	}
+	sort.Strings(components)
+	for _, name := range components {
+		params, _ := protojson.Marshal(s.Params[name])
+		fmt.Fprintf(&b, "%s: %s\n", name, params)
+	}
+	return b.String()
+}
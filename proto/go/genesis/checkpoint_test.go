@@ -0,0 +1,195 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/gen/tendermint/types"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+	"github.com/penumbra-zone/penumbra/proto/go/snapshot"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var testCheckpointHash = bytes.Repeat([]byte{0xab}, 32)
+
+// checkpointGenesis returns a genesis written by `pd migrate` with the given
+// checkpoint, starting at height 100.
+func checkpointGenesis(t *testing.T, checkpoint []byte) *Genesis {
+	appState, err := protojson.Marshal(&appv1alpha1.GenesisAppState{
+		GenesisAppState: &appv1alpha1.GenesisAppState_GenesisCheckpoint{GenesisCheckpoint: checkpoint},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Genesis{ChainId: "penumbra-testnet-migrated", InitialHeight: 100, AppState: appState}
+}
+
+func TestCheckpoint(t *testing.T) {
+	g := checkpointGenesis(t, testCheckpointHash)
+	c, err := g.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ChainId != g.ChainId || c.InitialHeight != 100 || !bytes.Equal(c.AppHash, testCheckpointHash) {
+		t.Errorf("Checkpoint = %+v", c)
+	}
+	g.AppHash = strings.ToUpper(hex.EncodeToString(testCheckpointHash))
+	if _, err := g.Checkpoint(); err != nil {
+		t.Errorf("Checkpoint with a matching app_hash: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		checkpoint []byte
+		appHash    string
+	}{
+		{"short checkpoint", testCheckpointHash[:31], ""},
+		{"long checkpoint", append(bytes.Clone(testCheckpointHash), 0), ""},
+		{"app_hash mismatch", testCheckpointHash, hex.EncodeToString(bytes.Repeat([]byte{0xcd}, 32))},
+		{"invalid app_hash", testCheckpointHash, "not hex"},
+	} {
+		g := checkpointGenesis(t, tt.checkpoint)
+		g.AppHash = tt.appHash
+		if c, err := g.Checkpoint(); err == nil {
+			t.Errorf("%s: Checkpoint = %+v", tt.name, c)
+		}
+	}
+
+	g, err = ciBuilder(t).Genesis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Checkpoint(); !errors.Is(err, ErrNotCheckpoint) {
+		t.Errorf("Checkpoint of a full genesis = %v, want ErrNotCheckpoint", err)
+	}
+}
+
+// headerProxy serves block headers committing to an app hash.
+type headerProxy struct {
+	proxy.Service
+	appHash []byte
+	heights []int64
+}
+
+func (p *headerProxy) GetBlockByHeight(_ context.Context, req *tendermint_proxyv1alpha1.GetBlockByHeightRequest) (*tendermint_proxyv1alpha1.GetBlockByHeightResponse, error) {
+	p.heights = append(p.heights, req.GetHeight())
+	return &tendermint_proxyv1alpha1.GetBlockByHeightResponse{Block: &types.Block{Header: &types.Header{AppHash: p.appHash}}}, nil
+}
+
+func TestVerifyLive(t *testing.T) {
+	ctx := context.Background()
+	c := &Checkpoint{ChainId: "penumbra-testnet-migrated", InitialHeight: 100, AppHash: testCheckpointHash}
+	p := &headerProxy{appHash: testCheckpointHash}
+	v := cnidarium.NewVerifier(p)
+	if err := c.VerifyLive(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	// The app hash of the state before the initial height is committed to by
+	// the header of the first block.
+	if len(p.heights) != 1 || p.heights[0] != 100 {
+		t.Errorf("VerifyLive read headers %v, want the one at the initial height", p.heights)
+	}
+
+	p.appHash = bytes.Repeat([]byte{0xcd}, 32)
+	if err := c.VerifyLive(ctx, v); err == nil {
+		t.Errorf("VerifyLive accepted a chain started from another app hash")
+	}
+	c.InitialHeight = 0
+	if err := c.VerifyLive(ctx, v); err == nil {
+		t.Errorf("VerifyLive accepted an initial height of 0")
+	}
+}
+
+func TestCompareSnapshot(t *testing.T) {
+	c := &Checkpoint{ChainId: "penumbra-testnet-migrated", InitialHeight: 100, AppHash: testCheckpointHash}
+	other := bytes.Repeat([]byte{0xcd}, 32)
+	params, err := proto.Marshal(&chainv1alpha1.ChainParameters{ChainId: c.ChainId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &snapshot.Snapshot{
+		ChainId: c.ChainId,
+		Sections: []snapshot.Section{
+			{Prefix: "chain/", Height: 100, AppHash: testCheckpointHash},
+			{Prefix: "stake/", Height: 100, AppHash: other},
+			{Prefix: "dex/", Height: 99, AppHash: testCheckpointHash},
+			{Prefix: "sct/", Height: 101, AppHash: testCheckpointHash},
+			{Prefix: "dao/"},
+		},
+		Entries: []snapshot.Entry{{Key: "chain/params", Value: params}},
+	}
+	cmp := c.CompareSnapshot(s)
+	prefixes := func(secs []snapshot.Section) string {
+		var ps []string
+		for _, sec := range secs {
+			ps = append(ps, sec.Prefix)
+		}
+		return strings.Join(ps, " ")
+	}
+	if got := prefixes(cmp.Matched); got != "chain/" {
+		t.Errorf("Matched = %s", got)
+	}
+	if got := prefixes(cmp.Mismatched); got != "stake/" {
+		t.Errorf("Mismatched = %s", got)
+	}
+	if got := prefixes(cmp.Unverifiable); got != "dex/ sct/ dao/" {
+		t.Errorf("Unverifiable = %s", got)
+	}
+	if len(cmp.Problems) != 0 || cmp.OK() {
+		t.Errorf("Comparison with a mismatch: problems %v, OK %v", cmp.Problems, cmp.OK())
+	}
+
+	s.Sections = append(s.Sections[:1], s.Sections[2:]...)
+	if cmp := c.CompareSnapshot(s); !cmp.OK() {
+		t.Errorf("Comparison = %+v, want OK", cmp)
+	}
+
+	s.ChainId = "penumbra-testnet"
+	if cmp := c.CompareSnapshot(s); len(cmp.Problems) != 1 || cmp.OK() {
+		t.Errorf("Problems from another chain = %v", cmp.Problems)
+	}
+	s.ChainId = ""
+	s.Entries[0].Value, _ = proto.Marshal(&chainv1alpha1.ChainParameters{ChainId: "penumbra-testnet"})
+	if cmp := c.CompareSnapshot(s); len(cmp.Problems) != 1 || cmp.OK() {
+		t.Errorf("Problems from other chain parameters = %v", cmp.Problems)
+	}
+	s.Entries[0].Value = []byte{0xff}
+	if cmp := c.CompareSnapshot(s); len(cmp.Problems) != 1 || cmp.OK() {
+		t.Errorf("Problems from undecodable chain parameters = %v", cmp.Problems)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s, err := checkpointGenesis(t, testCheckpointHash).Summarize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Checkpoint == nil || s.Params != nil || !strings.Contains(s.String(), "checkpoint:     ABABAB") {
+		t.Errorf("Summarize of a checkpoint = %s", s)
+	}
+
+	b := ciBuilder(t)
+	g, err := b.Genesis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = g.Summarize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Checkpoint != nil || s.Validators != 2 || s.Allocations != len(b.Content.GetShieldedPoolContent().GetAllocations()) || len(s.Params) != 8 {
+		t.Errorf("Summarize = %+v", s)
+	}
+	if !strings.Contains(s.String(), "validators:     2\n") {
+		t.Errorf("Summary = %s", s)
+	}
+}
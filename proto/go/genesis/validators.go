@@ -0,0 +1,321 @@
+package genesis
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// MaxFundingRateBps is the maximum total rate of a validator's funding
+// streams: 100%, in basis points.
+const MaxFundingRateBps = 10_000
+
+// GenesisDelegation is the amount of delegation tokens allocated to each
+// validator with a delegation address by `pd testnet generate`: 25,000
+// delegation tokens, in base units.
+var GenesisDelegation = num.NewAmount(25_000 * 1_000_000)
+
+// FundingStream is a funding stream in a validators file. It is encoded
+// either as an object with `rate_bps` and `address` fields, or as a
+// `[rate_bps, address]` pair, as in `testnets/validators-ci.json`.
+type FundingStream struct {
+	RateBps uint16 `json:"rate_bps"`
+	Address string `json:"address"`
+}
+
+func (fs *FundingStream) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var pair []json.RawMessage
+		if err := json.Unmarshal(data, &pair); err != nil {
+			return err
+		}
+		if len(pair) != 2 {
+			return fmt.Errorf("funding stream has %d elements, expected [rate_bps, address]", len(pair))
+		}
+		if err := json.Unmarshal(pair[0], &fs.RateBps); err != nil {
+			return err
+		}
+		return json.Unmarshal(pair[1], &fs.Address)
+	}
+	type plain FundingStream
+	return json.Unmarshal(data, (*plain)(fs))
+}
+
+// ConsensusKey is a validator's Ed25519 consensus public key. It is encoded
+// either as a base64 string, or as the `pub_key` object of a CometBFT
+// `priv_validator_key.json` file.
+type ConsensusKey []byte
+
+func (k *ConsensusKey) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var pk struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(data, &pk); err != nil {
+			return err
+		}
+		if pk.Type != "tendermint/PubKeyEd25519" {
+			return fmt.Errorf("unsupported consensus key type %q", pk.Type)
+		}
+		encoded = pk.Value
+	} else if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid consensus key: %w", err)
+	}
+	*k = b
+	return nil
+}
+
+func (k ConsensusKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString(k))
+}
+
+// Validator is a genesis validator in a validators file, in the format of
+// `testnets/validators-ci.json`.
+//
+// A validator in pd's format has no keys: as pd does, give it generated
+// keys with SetKeys before converting it. Otherwise both IdentityKey and
+// ConsensusKey are required, and GovernanceKey defaults to the identity key.
+// If DelegationAddress is set, it receives an allocation of
+// GenesisDelegation delegation tokens, as pd allocates to the validator's
+// own address.
+type Validator struct {
+	Name              string          `json:"name"`
+	Website           string          `json:"website"`
+	Description       string          `json:"description"`
+	FundingStreams    []FundingStream `json:"funding_streams"`
+	SequenceNumber    uint32          `json:"sequence_number"`
+	IdentityKey       string          `json:"identity_key"`
+	GovernanceKey     string          `json:"governance_key,omitempty"`
+	ConsensusKey      ConsensusKey    `json:"consensus_key"`
+	DelegationAddress string          `json:"delegation_address,omitempty"`
+}
+
+// ReadValidatorsFile reads validators from a JSON file.
+func ReadValidatorsFile(path string) ([]Validator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	validators, err := ParseValidators(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse validators file %s: %w", path, err)
+	}
+	return validators, nil
+}
+
+// ParseValidators parses a JSON array of validators.
+func ParseValidators(r io.Reader) ([]Validator, error) {
+	var validators []Validator
+	if err := json.NewDecoder(r).Decode(&validators); err != nil {
+		return nil, err
+	}
+	return validators, nil
+}
+
+// HasKeys reports whether the validator's identity or consensus key is
+// given. A validator without either needs generated keys.
+func (v *Validator) HasKeys() bool {
+	return v.IdentityKey != "" || len(v.ConsensusKey) != 0
+}
+
+// ValidatorKeys are the private keys of a genesis validator: the spend key
+// of its wallet, whose spend authorization key is its identity key, and its
+// CometBFT consensus key.
+type ValidatorKeys struct {
+	SpendKey     []byte
+	ConsensusKey ed25519.PrivateKey
+}
+
+// GenerateValidatorKeys generates the keys of a genesis validator, as
+// `ValidatorKeys::generate` does in pd.
+func GenerateValidatorKeys(rand io.Reader) (*ValidatorKeys, error) {
+	spendKey := make([]byte, keys.SpendKeyLenBytes)
+	if _, err := io.ReadFull(rand, spendKey); err != nil {
+		return nil, err
+	}
+	_, consensusKey, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidatorKeys{SpendKey: spendKey, ConsensusKey: consensusKey}, nil
+}
+
+// SetKeys sets the validator's public keys to those of k. As in pd, the
+// governance key is the identity key, and unless the validator has a
+// delegation address, its delegation is allocated to the first address of
+// its wallet.
+func (v *Validator) SetKeys(k *ValidatorKeys) error {
+	ask, err := keys.SpendAuthKey(k.SpendKey)
+	if err != nil {
+		return err
+	}
+	v.IdentityKey = keys.FormatIdentityKey(&keysv1alpha1.IdentityKey{Ik: ask.VerificationKey()})
+	v.GovernanceKey = ""
+	v.ConsensusKey = ConsensusKey(k.ConsensusKey.Public().(ed25519.PublicKey))
+	if v.DelegationAddress == "" {
+		addr, err := keys.PaymentAddress(k.SpendKey, 0)
+		if err != nil {
+			return err
+		}
+		v.DelegationAddress = keys.FormatAddress(addr)
+	}
+	return nil
+}
+
+// WriteFiles writes the keys to the CometBFT config directory of a node, as
+// `pd testnet generate` does: the consensus key to
+// `priv_validator_key.json`, and the spend key to the custody file
+// `validator_custody.json`.
+func (k *ValidatorKeys) WriteFiles(configDir string) error {
+	if err := os.MkdirAll(configDir, 0o700); err != nil {
+		return err
+	}
+	pub := k.ConsensusKey.Public().(ed25519.PublicKey)
+	address := sha256.Sum256(pub)
+	type key struct {
+		Type  string `json:"type"`
+		Value []byte `json:"value"`
+	}
+	privValidatorKey, err := json.MarshalIndent(struct {
+		Address string `json:"address"`
+		PubKey  key    `json:"pub_key"`
+		PrivKey key    `json:"priv_key"`
+	}{
+		Address: strings.ToUpper(hex.EncodeToString(address[:20])),
+		PubKey:  key{"tendermint/PubKeyEd25519", pub},
+		PrivKey: key{"tendermint/PrivKeyEd25519", k.ConsensusKey},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "priv_validator_key.json"), append(privValidatorKey, '\n'), 0o600); err != nil {
+		return err
+	}
+	custody := fmt.Sprintf("spend_key = %q\n", keys.FormatSpendKey(k.SpendKey))
+	return os.WriteFile(filepath.Join(configDir, "validator_custody.json"), []byte(custody), 0o600)
+}
+
+// Proto converts the validator to its genesis definition, checking its keys
+// and funding streams.
+func (v *Validator) Proto() (*stakev1alpha1.Validator, error) {
+	if !v.HasKeys() {
+		return nil, fmt.Errorf("validator %q has no keys: generate them with SetKeys", v.Name)
+	}
+	if v.IdentityKey == "" {
+		return nil, fmt.Errorf("validator %q has a consensus key but no identity key", v.Name)
+	}
+	if len(v.ConsensusKey) == 0 {
+		return nil, fmt.Errorf("validator %q has an identity key but no consensus key", v.Name)
+	}
+	ik, err := keys.ParseIdentityKey(v.IdentityKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key for validator %q: %w", v.Name, err)
+	}
+	gk := &keysv1alpha1.GovernanceKey{Gk: ik.GetIk()}
+	if v.GovernanceKey != "" {
+		if gk, err = keys.ParseGovernanceKey(v.GovernanceKey); err != nil {
+			return nil, fmt.Errorf("invalid governance key for validator %q: %w", v.Name, err)
+		}
+	}
+	validator := &stakev1alpha1.Validator{
+		IdentityKey:    ik,
+		ConsensusKey:   v.ConsensusKey,
+		Name:           v.Name,
+		Website:        v.Website,
+		Description:    v.Description,
+		Enabled:        true,
+		SequenceNumber: v.SequenceNumber,
+		GovernanceKey:  gk,
+	}
+	for _, fs := range v.FundingStreams {
+		validator.FundingStreams = append(validator.FundingStreams, &stakev1alpha1.FundingStream{
+			Recipient: &stakev1alpha1.FundingStream_ToAddress_{
+				ToAddress: &stakev1alpha1.FundingStream_ToAddress{
+					Address: fs.Address,
+					RateBps: uint32(fs.RateBps),
+				},
+			},
+		})
+	}
+	if err := ValidateValidator(validator); err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+// delegationAllocation returns the initial delegation allocated to the
+// validator, if it has a delegation address.
+func (v *Validator) delegationAllocation() (*Allocation, error) {
+	if v.DelegationAddress == "" {
+		return nil, nil
+	}
+	addr, err := keys.ParseAddress(v.DelegationAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegation address for validator %q: %w", v.Name, err)
+	}
+	return &Allocation{
+		Amount:  GenesisDelegation.Proto(),
+		Denom:   DelegationDenom(v.IdentityKey),
+		Address: addr,
+	}, nil
+}
+
+// ValidateValidator checks the keys and funding streams of a genesis
+// validator.
+func ValidateValidator(v *stakev1alpha1.Validator) error {
+	name := v.GetName()
+	if len(v.GetIdentityKey().GetIk()) != 32 {
+		return fmt.Errorf("validator %q has an identity key of length %d", name, len(v.GetIdentityKey().GetIk()))
+	}
+	if len(v.GetGovernanceKey().GetGk()) != 32 {
+		return fmt.Errorf("validator %q has a governance key of length %d", name, len(v.GetGovernanceKey().GetGk()))
+	}
+	if len(v.GetConsensusKey()) != 32 {
+		return fmt.Errorf("validator %q has an Ed25519 consensus key of length %d", name, len(v.GetConsensusKey()))
+	}
+	return ValidateFundingStreams(v.GetFundingStreams())
+}
+
+// ValidateFundingStreams checks that the rates of funding streams sum to at
+// most MaxFundingRateBps, and that their recipients are valid.
+func ValidateFundingStreams(streams []*stakev1alpha1.FundingStream) error {
+	var total uint64
+	for _, fs := range streams {
+		switch r := fs.GetRecipient().(type) {
+		case *stakev1alpha1.FundingStream_ToAddress_:
+			if _, err := keys.ParseAddress(r.ToAddress.GetAddress()); err != nil {
+				return fmt.Errorf("invalid funding stream address: %w", err)
+			}
+			total += uint64(r.ToAddress.GetRateBps())
+		case *stakev1alpha1.FundingStream_ToDao_:
+			total += uint64(r.ToDao.GetRateBps())
+		default:
+			return errors.New("missing funding stream recipient")
+		}
+	}
+	if total > MaxFundingRateBps {
+		return fmt.Errorf("sum of funding rates exceeds 100%% (10,000bps): %dbps", total)
+	}
+	return nil
+}
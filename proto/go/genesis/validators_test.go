@@ -0,0 +1,216 @@
+package genesis
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+)
+
+// ciValidatorsFile is pd's validators file for CI testnets, whose validators
+// have no keys.
+const ciValidatorsFile = "../../../testnets/validators-ci.json"
+
+// testKeys returns deterministic validator keys, distinct for each n.
+func testKeys(t *testing.T, n byte) *ValidatorKeys {
+	k, err := GenerateValidatorKeys(bytes.NewReader(bytes.Repeat([]byte{n}, 64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestReadValidatorsFile(t *testing.T) {
+	validators, err := ReadValidatorsFile(ciValidatorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 2 {
+		t.Fatalf("read %d validators, want 2", len(validators))
+	}
+	for i, v := range validators {
+		if want := "Penumbra Labs CI " + string(rune('1'+i)); v.Name != want {
+			t.Errorf("validator %d is named %q, want %q", i, v.Name, want)
+		}
+		if v.HasKeys() {
+			t.Errorf("%s: has keys", v.Name)
+		}
+		if len(v.FundingStreams) != 6 {
+			t.Errorf("%s: %d funding streams, want 6", v.Name, len(v.FundingStreams))
+		}
+		for _, fs := range v.FundingStreams {
+			if fs.RateBps != 50 || !strings.HasPrefix(fs.Address, "penumbra1") {
+				t.Errorf("%s: funding stream %+v", v.Name, fs)
+			}
+		}
+		if _, err := v.Proto(); err == nil || !strings.Contains(err.Error(), "no keys") {
+			t.Errorf("%s: Proto without keys = %v, want an error saying keys are required", v.Name, err)
+		}
+	}
+}
+
+func TestSetKeys(t *testing.T) {
+	validators, err := ReadValidatorsFile(ciValidatorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := validators[0]
+	k := testKeys(t, 1)
+	if err := v.SetKeys(k); err != nil {
+		t.Fatal(err)
+	}
+
+	ask, _ := keys.SpendAuthKey(k.SpendKey)
+	wantIk := &keysv1alpha1.IdentityKey{Ik: ask.VerificationKey()}
+	addr, _ := keys.PaymentAddress(k.SpendKey, 0)
+	if v.IdentityKey != keys.FormatIdentityKey(wantIk) || v.DelegationAddress != keys.FormatAddress(addr) {
+		t.Errorf("SetKeys set identity key %s and delegation address %s", v.IdentityKey, v.DelegationAddress)
+	}
+	pv, err := v.Proto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pv.GetGovernanceKey().GetGk(), wantIk.GetIk()) {
+		t.Errorf("governance key %x, want the identity key", pv.GetGovernanceKey().GetGk())
+	}
+	if !bytes.Equal(pv.GetConsensusKey(), k.ConsensusKey.Public().(ed25519.PublicKey)) {
+		t.Errorf("consensus key %x is not the generated key's", pv.GetConsensusKey())
+	}
+	if len(pv.GetFundingStreams()) != 6 || !pv.GetEnabled() {
+		t.Errorf("Proto = %v", pv)
+	}
+	a, err := v.delegationAllocation()
+	if err != nil || a.GetDenom() != DelegationDenom(v.IdentityKey) || !keys.AddressEqual(a.GetAddress(), addr) {
+		t.Errorf("delegationAllocation = %v, %v", a, err)
+	}
+
+	// A given delegation address is kept.
+	v = validators[1]
+	v.DelegationAddress = testAddress
+	if err := v.SetKeys(testKeys(t, 2)); err != nil || v.DelegationAddress != testAddress {
+		t.Errorf("SetKeys replaced the delegation address with %s, %v", v.DelegationAddress, err)
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	k := testKeys(t, 1)
+	dir := filepath.Join(t.TempDir(), "node0", "cometbft", "config")
+	if err := k.WriteFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "priv_validator_key.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pvk struct {
+		Address string `json:"address"`
+		PubKey  struct {
+			Type  string `json:"type"`
+			Value []byte `json:"value"`
+		} `json:"pub_key"`
+		PrivKey struct {
+			Type  string `json:"type"`
+			Value []byte `json:"value"`
+		} `json:"priv_key"`
+	}
+	if err := json.Unmarshal(data, &pvk); err != nil {
+		t.Fatal(err)
+	}
+	if len(pvk.Address) != 40 || pvk.PubKey.Type != "tendermint/PubKeyEd25519" || !bytes.Equal(pvk.PrivKey.Value, k.ConsensusKey) {
+		t.Errorf("priv_validator_key.json = %s", data)
+	}
+	// The public key parses as a consensus key of a validators file.
+	var ck ConsensusKey
+	pub, _ := json.Marshal(pvk.PubKey)
+	if err := json.Unmarshal(pub, &ck); err != nil || !bytes.Equal(ck, k.ConsensusKey.Public().(ed25519.PublicKey)) {
+		t.Errorf("pub_key = %s, %v", pub, err)
+	}
+
+	custody, err := os.ReadFile(filepath.Join(dir, "validator_custody.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "spend_key = \"" + keys.FormatSpendKey(k.SpendKey) + "\"\n"; string(custody) != want {
+		t.Errorf("validator_custody.json = %q, want %q", custody, want)
+	}
+}
+
+func TestParseValidatorsEncodings(t *testing.T) {
+	pub := bytes.Repeat([]byte{7}, 32)
+	b64 := base64.StdEncoding.EncodeToString(pub)
+	validators, err := ParseValidators(strings.NewReader(`[
+		{"name": "a", "consensus_key": "` + b64 + `", "funding_streams": [[100, "` + testAddress + `"]]},
+		{"name": "b", "consensus_key": {"type": "tendermint/PubKeyEd25519", "value": "` + b64 + `"}, "funding_streams": [{"rate_bps": 200, "address": "` + testAddress + `"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint16{100, 200} {
+		v := validators[i]
+		if !bytes.Equal(v.ConsensusKey, pub) || len(v.FundingStreams) != 1 || v.FundingStreams[0].RateBps != want || v.FundingStreams[0].Address != testAddress {
+			t.Errorf("validator %s = %+v", v.Name, v)
+		}
+	}
+
+	for _, bad := range []string{
+		`[{"consensus_key": {"type": "tendermint/PubKeySecp256k1", "value": "` + b64 + `"}}]`,
+		`[{"consensus_key": "not base64"}]`,
+		`[{"funding_streams": [[100]]}]`,
+	} {
+		if _, err := ParseValidators(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseValidators(%s) accepted the file", bad)
+		}
+	}
+}
+
+func TestValidatorProtoErrors(t *testing.T) {
+	keyed := Validator{Name: "v"}
+	if err := keyed.SetKeys(testKeys(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		modify func(v *Validator)
+		want   string
+	}{
+		{"no identity key", func(v *Validator) { v.IdentityKey = "" }, "no identity key"},
+		{"no consensus key", func(v *Validator) { v.ConsensusKey = nil }, "no consensus key"},
+		{"short consensus key", func(v *Validator) { v.ConsensusKey = v.ConsensusKey[:31] }, "consensus key of length 31"},
+		{"invalid identity key", func(v *Validator) { v.IdentityKey = "penumbravalid1abc" }, "invalid identity key"},
+		{"invalid governance key", func(v *Validator) { v.GovernanceKey = "penumbragovern1abc" }, "invalid governance key"},
+		{"funding rates over 100%", func(v *Validator) {
+			v.FundingStreams = []FundingStream{{RateBps: 6000, Address: testAddress}, {RateBps: 5000, Address: testAddress}}
+		}, "exceeds 100%"},
+		{"invalid funding stream address", func(v *Validator) {
+			v.FundingStreams = []FundingStream{{RateBps: 1, Address: "penumbra1abc"}}
+		}, "invalid funding stream address"},
+	}
+	for _, tt := range tests {
+		v := keyed
+		tt.modify(&v)
+		if _, err := v.Proto(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Proto = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	// The maximum funding rate is allowed, as are funding streams to the DAO.
+	streams := []*stakev1alpha1.FundingStream{
+		{Recipient: &stakev1alpha1.FundingStream_ToDao_{ToDao: &stakev1alpha1.FundingStream_ToDao{RateBps: 9000}}},
+		{Recipient: &stakev1alpha1.FundingStream_ToAddress_{ToAddress: &stakev1alpha1.FundingStream_ToAddress{Address: testAddress, RateBps: 1000}}},
+	}
+	if err := ValidateFundingStreams(streams); err != nil {
+		t.Errorf("ValidateFundingStreams of 100%%: %v", err)
+	}
+	if err := ValidateFundingStreams([]*stakev1alpha1.FundingStream{{}}); err == nil {
+		t.Errorf("ValidateFundingStreams accepted a stream without recipient")
+	}
+}
@@ -0,0 +1,192 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/stake"
+	"github.com/penumbra-zone/penumbra/proto/go/transaction"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+)
+
+// ErrNoVotingNotes is returned when a wallet held no delegation notes to a
+// validator that was active when voting on a proposal started.
+var ErrNoVotingNotes = errors.New("no delegation notes were staked to an active validator when voting started")
+
+// Voter plans delegator votes for a wallet, mirroring the voting logic of
+// the Rust view planner.
+type Voter struct {
+	Governance QueryService
+	View       view.Service
+	// ChainId, if set, is sent with the governance queries, which the
+	// fullnode rejects if it serves another chain.
+	ChainId  string
+	WalletId *keysv1alpha1.WalletId
+	// Source, if set, restricts voting to the notes of one account.
+	Source *keysv1alpha1.AddressIndex
+	// Rand is the source of randomizers and blinding factors, crypto/rand
+	// if nil.
+	Rand io.Reader
+}
+
+// NoteVote is the vote cast with a single delegation note.
+type NoteVote struct {
+	Record      *viewv1alpha1.SpendableNoteRecord
+	IdentityKey *keysv1alpha1.IdentityKey
+	Plan        *governancev1alpha1.DelegatorVotePlan
+}
+
+// DelegatorVotes are the actions voting on a proposal with all of a
+// wallet's eligible notes.
+type DelegatorVotes struct {
+	ProposalId       uint64
+	StartBlockHeight uint64
+	StartPosition    uint64
+	Votes            []NoteVote
+	// Skipped holds the notes staked to validators that were not active
+	// when voting started, which pd would reject.
+	Skipped []*viewv1alpha1.SpendableNoteRecord
+}
+
+// ActionPlans returns the DelegatorVote action plans, in note order.
+func (d *DelegatorVotes) ActionPlans() []*transactionv1alpha1.ActionPlan {
+	plans := make([]*transactionv1alpha1.ActionPlan, len(d.Votes))
+	for i, v := range d.Votes {
+		plans[i] = &transactionv1alpha1.ActionPlan{
+			Action: &transactionv1alpha1.ActionPlan_DelegatorVote{DelegatorVote: v.Plan},
+		}
+	}
+	return plans
+}
+
+// Unspent returns the voting notes that have not been spent. The Rust
+// planner spends them in the voting transaction, sending the delegation
+// tokens back as change, so that votes on later proposals cannot be linked
+// by their nullifiers.
+func (d *DelegatorVotes) Unspent() []*viewv1alpha1.SpendableNoteRecord {
+	var unspent []*viewv1alpha1.SpendableNoteRecord
+	for _, v := range d.Votes {
+		if v.Record.GetHeightSpent() == 0 {
+			unspent = append(unspent, v.Record)
+		}
+	}
+	return unspent
+}
+
+// AuthSlots returns the layout of AuthorizationData.DelegatorVoteAuths for a
+// plan holding the votes' actions in order, and no other delegator votes.
+func (d *DelegatorVotes) AuthSlots() []transaction.AuthSlot {
+	return transaction.DelegatorVoteAuthSlots(&transactionv1alpha1.TransactionPlan{Actions: d.ActionPlans()})
+}
+
+// Vote plans a delegator vote on a proposal with every note the wallet held
+// when voting started, weighting each by the unbonded value of its
+// delegation tokens at the validator's exchange rate when voting started.
+func (v *Voter) Vote(ctx context.Context, proposalId uint64, vote governancev1alpha1.Vote_Vote) (*DelegatorVotes, error) {
+	info, err := v.Governance.ProposalInfo(ctx, &governancev1alpha1.ProposalInfoRequest{ChainId: v.ChainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, fmt.Errorf("could not get proposal %d: %w", proposalId, err)
+	}
+	rates, err := v.rateData(ctx, proposalId)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := v.View.NotesForVoting(ctx, &viewv1alpha1.NotesForVotingRequest{
+		VotableAtHeight: info.GetStartBlockHeight(),
+		AddressIndex:    v.Source,
+		WalletId:        v.WalletId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	votes := &DelegatorVotes{
+		ProposalId:       proposalId,
+		StartBlockHeight: info.GetStartBlockHeight(),
+		StartPosition:    info.GetStartPosition(),
+	}
+	for {
+		rsp, err := notes.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := rsp.GetNoteRecord()
+		rate, ok := rates[keys.FormatIdentityKey(rsp.GetIdentityKey())]
+		if !ok {
+			votes.Skipped = append(votes.Skipped, record)
+			continue
+		}
+		unbonded, err := stake.UnbondedAmount(rate, num.AmountFromProto(record.GetNote().GetValue().GetAmount()))
+		if err != nil {
+			return nil, err
+		}
+		plan, err := v.plan(proposalId, info.GetStartPosition(), vote, record, unbonded)
+		if err != nil {
+			return nil, err
+		}
+		votes.Votes = append(votes.Votes, NoteVote{Record: record, IdentityKey: rsp.GetIdentityKey(), Plan: plan})
+	}
+	if len(votes.Votes) == 0 {
+		return nil, fmt.Errorf("can't vote on proposal %d: %w", proposalId, ErrNoVotingNotes)
+	}
+	return votes, nil
+}
+
+// rateData returns the rate data of each validator active when voting on
+// the proposal started, by identity key.
+func (v *Voter) rateData(ctx context.Context, proposalId uint64) (map[string]*stakev1alpha1.RateData, error) {
+	stream, err := v.Governance.ProposalRateData(ctx, &governancev1alpha1.ProposalRateDataRequest{ChainId: v.ChainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]*stakev1alpha1.RateData)
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rates[keys.FormatIdentityKey(rsp.GetRateData().GetIdentityKey())] = rsp.GetRateData()
+	}
+}
+
+func (v *Voter) plan(proposalId, startPosition uint64, vote governancev1alpha1.Vote_Vote, record *viewv1alpha1.SpendableNoteRecord, unbonded num.Amount) (*governancev1alpha1.DelegatorVotePlan, error) {
+	randomizer, err := decaf377.RandomFr(v.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingR, err := decaf377.RandomFq(v.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingS, err := decaf377.RandomFq(v.Rand)
+	if err != nil {
+		return nil, err
+	}
+	return &governancev1alpha1.DelegatorVotePlan{
+		Proposal:           proposalId,
+		StartPosition:      startPosition,
+		Vote:               &governancev1alpha1.Vote{Vote: vote},
+		StakedNote:         record.GetNote(),
+		StakedNotePosition: record.GetPosition(),
+		UnbondedAmount:     unbonded.Proto(),
+		Randomizer:         randomizer,
+		ProofBlindingR:     blindingR,
+		ProofBlindingS:     blindingS,
+	}, nil
+}
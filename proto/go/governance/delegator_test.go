@@ -0,0 +1,166 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/stake"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+)
+
+type sliceStream[T any] struct {
+	items []*T
+}
+
+func (s *sliceStream[T]) Recv() (*T, error) {
+	if len(s.items) == 0 {
+		return nil, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, nil
+}
+
+var (
+	activeValidator   = &keysv1alpha1.IdentityKey{Ik: bytes.Repeat([]byte{1}, 32)}
+	inactiveValidator = &keysv1alpha1.IdentityKey{Ik: bytes.Repeat([]byte{2}, 32)}
+)
+
+// fakeVoting serves a proposal that started voting at height 100, when only
+// activeValidator was active, at an exchange rate of 2.
+type fakeVoting struct {
+	QueryService
+	chainIds []string
+}
+
+func (f *fakeVoting) ProposalInfo(_ context.Context, req *governancev1alpha1.ProposalInfoRequest) (*governancev1alpha1.ProposalInfoResponse, error) {
+	f.chainIds = append(f.chainIds, req.GetChainId())
+	return &governancev1alpha1.ProposalInfoResponse{StartBlockHeight: 100, StartPosition: 4096}, nil
+}
+
+func (f *fakeVoting) ProposalRateData(_ context.Context, req *governancev1alpha1.ProposalRateDataRequest) (grpcclient.Stream[governancev1alpha1.ProposalRateDataResponse], error) {
+	f.chainIds = append(f.chainIds, req.GetChainId())
+	return &sliceStream[governancev1alpha1.ProposalRateDataResponse]{items: []*governancev1alpha1.ProposalRateDataResponse{
+		{RateData: &stakev1alpha1.RateData{IdentityKey: activeValidator, ValidatorExchangeRate: 2 * stake.RateScale}},
+	}}, nil
+}
+
+// fakeVotingView serves the wallet's delegation notes.
+type fakeVotingView struct {
+	view.Service
+	notes []*viewv1alpha1.NotesForVotingResponse
+	req   *viewv1alpha1.NotesForVotingRequest
+}
+
+func (f *fakeVotingView) NotesForVoting(_ context.Context, req *viewv1alpha1.NotesForVotingRequest) (grpcclient.Stream[viewv1alpha1.NotesForVotingResponse], error) {
+	f.req = req
+	return &sliceStream[viewv1alpha1.NotesForVotingResponse]{items: f.notes}, nil
+}
+
+func votingNote(ik *keysv1alpha1.IdentityKey, amount, position, heightSpent uint64) *viewv1alpha1.NotesForVotingResponse {
+	return &viewv1alpha1.NotesForVotingResponse{
+		NoteRecord: &viewv1alpha1.SpendableNoteRecord{
+			Note: &shielded_poolv1alpha1.Note{Value: &assetv1alpha1.Value{
+				Amount: num.NewAmount(amount).Proto(),
+			}},
+			Position:    position,
+			HeightSpent: heightSpent,
+		},
+		IdentityKey: ik,
+	}
+}
+
+func TestVote(t *testing.T) {
+	ctx := context.Background()
+	gov := &fakeVoting{}
+	wallet := &fakeVotingView{notes: []*viewv1alpha1.NotesForVotingResponse{
+		votingNote(activeValidator, 3_000_000, 5, 0),
+		votingNote(inactiveValidator, 1_000_000, 6, 0),
+		votingNote(activeValidator, 1_000_001, 7, 150),
+	}}
+	source := &keysv1alpha1.AddressIndex{Account: 1}
+	v := &Voter{Governance: gov, View: wallet, ChainId: "penumbra-testnet", Source: source}
+	votes, err := v.Vote(ctx, 3, governancev1alpha1.Vote_VOTE_YES)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wallet.req.GetVotableAtHeight() != 100 || wallet.req.GetAddressIndex() != source {
+		t.Errorf("NotesForVoting request = %v", wallet.req)
+	}
+	if len(gov.chainIds) != 2 || gov.chainIds[0] != "penumbra-testnet" || gov.chainIds[1] != "penumbra-testnet" {
+		t.Errorf("queries sent chain IDs %v", gov.chainIds)
+	}
+	if votes.ProposalId != 3 || votes.StartBlockHeight != 100 || votes.StartPosition != 4096 {
+		t.Errorf("votes = %+v", votes)
+	}
+	if len(votes.Skipped) != 1 || votes.Skipped[0].GetPosition() != 6 {
+		t.Errorf("Skipped = %v", votes.Skipped)
+	}
+
+	// Each vote is weighted by the unbonded value of its note, rounded down.
+	wantUnbonded := []uint64{6_000_000, 2_000_002}
+	if len(votes.Votes) != len(wantUnbonded) {
+		t.Fatalf("Votes = %v", votes.Votes)
+	}
+	for i, vote := range votes.Votes {
+		plan := vote.Plan
+		if plan.GetProposal() != 3 || plan.GetStartPosition() != 4096 || plan.GetVote().GetVote() != governancev1alpha1.Vote_VOTE_YES {
+			t.Errorf("vote %d plan = %v", i, plan)
+		}
+		if plan.GetStakedNotePosition() != vote.Record.GetPosition() || plan.GetStakedNote() != vote.Record.GetNote() {
+			t.Errorf("vote %d plan does not stake its note", i)
+		}
+		if got := num.AmountFromProto(plan.GetUnbondedAmount()); got != num.NewAmount(wantUnbonded[i]) {
+			t.Errorf("vote %d unbonded amount = %s, want %d", i, got, wantUnbonded[i])
+		}
+		if len(plan.GetRandomizer()) != 32 || len(plan.GetProofBlindingR()) != 32 || len(plan.GetProofBlindingS()) != 32 {
+			t.Errorf("vote %d plan has malformed randomness", i)
+		}
+	}
+
+	// The spent note still votes, since it was held when voting started.
+	if unspent := votes.Unspent(); len(unspent) != 1 || unspent[0].GetPosition() != 5 {
+		t.Errorf("Unspent = %v", unspent)
+	}
+	plans := votes.ActionPlans()
+	slots := votes.AuthSlots()
+	if len(plans) != 2 || len(slots) != 2 {
+		t.Fatalf("%d action plans and %d auth slots", len(plans), len(slots))
+	}
+	for i, slot := range slots {
+		if plans[i].GetDelegatorVote() != votes.Votes[i].Plan || slot.Index != i || slot.ActionIndex != i || !bytes.Equal(slot.Randomizer, votes.Votes[i].Plan.GetRandomizer()) {
+			t.Errorf("auth slot %d = %+v", i, slot)
+		}
+	}
+}
+
+func TestVoteErrors(t *testing.T) {
+	ctx := context.Background()
+	v := &Voter{Governance: &fakeVoting{}, View: &fakeVotingView{notes: []*viewv1alpha1.NotesForVotingResponse{
+		votingNote(inactiveValidator, 1_000_000, 6, 0),
+	}}}
+	if _, err := v.Vote(ctx, 3, governancev1alpha1.Vote_VOTE_NO); !errors.Is(err, ErrNoVotingNotes) {
+		t.Errorf("Vote with only inactive delegations = %v, want ErrNoVotingNotes", err)
+	}
+
+	v = &Voter{
+		Governance: &fakeVoting{},
+		View:       &fakeVotingView{notes: []*viewv1alpha1.NotesForVotingResponse{votingNote(activeValidator, 1_000_000, 5, 0)}},
+		Rand:       bytes.NewReader(make([]byte, 64)),
+	}
+	if _, err := v.Vote(ctx, 3, governancev1alpha1.Vote_VOTE_NO); err == nil {
+		t.Errorf("Vote succeeded with too little randomness")
+	}
+}
@@ -0,0 +1,163 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"google.golang.org/protobuf/proto"
+)
+
+// Planner builds the action plans of governance transactions, filling in
+// the values that pd checks against the chain state.
+type Planner struct {
+	Governance QueryService
+	App        params.QueryService
+	// ChainId, if set, guards the proposal and parameter queries: the
+	// fullnode refuses them if it serves another chain.
+	ChainId string
+}
+
+// NextProposalId returns the ID that the next submitted proposal must have.
+func (p *Planner) NextProposalId(ctx context.Context) (uint64, error) {
+	rsp, err := p.Governance.NextProposalId(ctx, &governancev1alpha1.NextProposalIdRequest{ChainId: p.ChainId})
+	if err != nil {
+		return 0, err
+	}
+	return rsp.GetNextProposalId(), nil
+}
+
+// DepositAmount returns the deposit that must accompany a new proposal.
+func (p *Planner) DepositAmount(ctx context.Context) (num.Amount, error) {
+	appParams, err := params.Fetch(ctx, p.App, p.ChainId)
+	if err != nil {
+		return num.Amount{}, err
+	}
+	if appParams.GetGovernanceParams() == nil {
+		return num.Amount{}, errors.New("app parameters are missing governance parameters")
+	}
+	return num.AmountFromProto(appParams.GetGovernanceParams().GetProposalDepositAmount()), nil
+}
+
+// Submit returns the action plan submitting a proposal, with its ID set to
+// the next proposal ID and the current deposit amount. The proposal is not
+// modified.
+func (p *Planner) Submit(ctx context.Context, proposal *governancev1alpha1.Proposal) (*transactionv1alpha1.ActionPlan, error) {
+	if err := Validate(proposal); err != nil {
+		return nil, err
+	}
+	id, err := p.NextProposalId(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get next proposal ID: %w", err)
+	}
+	deposit, err := p.DepositAmount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get proposal deposit amount: %w", err)
+	}
+	proposal = proto.Clone(proposal).(*governancev1alpha1.Proposal)
+	proposal.Id = id
+	return SubmitPlan(proposal, deposit), nil
+}
+
+// Withdraw returns the action plan withdrawing a proposal, after checking
+// that it is still being voted on.
+func (p *Planner) Withdraw(ctx context.Context, proposalId uint64, reason string) (*transactionv1alpha1.ActionPlan, error) {
+	data, err := p.Governance.ProposalData(ctx, &governancev1alpha1.ProposalDataRequest{ChainId: p.ChainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, err
+	}
+	switch data.GetState().GetState().(type) {
+	case *governancev1alpha1.ProposalState_Voting_:
+	case *governancev1alpha1.ProposalState_Withdrawn_:
+		return nil, fmt.Errorf("proposal %d has already been withdrawn", proposalId)
+	default:
+		return nil, fmt.Errorf("voting on proposal %d has already concluded", proposalId)
+	}
+	return WithdrawPlan(proposalId, reason), nil
+}
+
+// DepositClaim returns the action plan claiming the deposit of a proposal,
+// after checking that voting on it has finished.
+func (p *Planner) DepositClaim(ctx context.Context, proposalId uint64) (*transactionv1alpha1.ActionPlan, error) {
+	data, err := p.Governance.ProposalData(ctx, &governancev1alpha1.ProposalDataRequest{ChainId: p.ChainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, err
+	}
+	return DepositClaimPlan(proposalId, num.AmountFromProto(data.GetProposalDepositAmount()), data.GetState())
+}
+
+// SubmitPlan returns the action plan submitting a proposal whose ID is
+// already set.
+func SubmitPlan(proposal *governancev1alpha1.Proposal, deposit num.Amount) *transactionv1alpha1.ActionPlan {
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_ProposalSubmit{
+			ProposalSubmit: &governancev1alpha1.ProposalSubmit{
+				Proposal:      proposal,
+				DepositAmount: deposit.Proto(),
+			},
+		},
+	}
+}
+
+// WithdrawPlan returns the action plan withdrawing a proposal.
+func WithdrawPlan(proposalId uint64, reason string) *transactionv1alpha1.ActionPlan {
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_ProposalWithdraw{
+			ProposalWithdraw: &governancev1alpha1.ProposalWithdraw{
+				Proposal: proposalId,
+				Reason:   reason,
+			},
+		},
+	}
+}
+
+// DepositClaimPlan returns the action plan claiming the deposit of a
+// proposal in the finished state.
+func DepositClaimPlan(proposalId uint64, deposit num.Amount, state *governancev1alpha1.ProposalState) (*transactionv1alpha1.ActionPlan, error) {
+	var outcome *governancev1alpha1.ProposalOutcome
+	switch s := state.GetState().(type) {
+	case *governancev1alpha1.ProposalState_Finished_:
+		outcome = s.Finished.GetOutcome()
+	case *governancev1alpha1.ProposalState_Voting_:
+		return nil, fmt.Errorf("proposal %d is still voting", proposalId)
+	case *governancev1alpha1.ProposalState_Withdrawn_:
+		return nil, fmt.Errorf("proposal %d has been withdrawn but voting has not concluded", proposalId)
+	case *governancev1alpha1.ProposalState_Claimed_:
+		return nil, fmt.Errorf("the deposit for proposal %d has already been claimed", proposalId)
+	default:
+		return nil, fmt.Errorf("proposal %d has unknown state", proposalId)
+	}
+	if outcome.GetOutcome() == nil {
+		return nil, fmt.Errorf("proposal %d is missing its outcome", proposalId)
+	}
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_ProposalDepositClaim{
+			ProposalDepositClaim: &governancev1alpha1.ProposalDepositClaim{
+				Proposal:      proposalId,
+				DepositAmount: deposit.Proto(),
+				Outcome:       claimOutcome(outcome),
+			},
+		},
+	}, nil
+}
+
+// claimOutcome strips the withdrawal reason from an outcome, since a deposit
+// claim only records whether the proposal was withdrawn.
+func claimOutcome(outcome *governancev1alpha1.ProposalOutcome) *governancev1alpha1.ProposalOutcome {
+	outcome = proto.Clone(outcome).(*governancev1alpha1.ProposalOutcome)
+	switch o := outcome.GetOutcome().(type) {
+	case *governancev1alpha1.ProposalOutcome_Failed_:
+		if w := o.Failed.GetWithdrawn(); w != nil {
+			w.Reason = ""
+		}
+	case *governancev1alpha1.ProposalOutcome_Slashed_:
+		if w := o.Slashed.GetWithdrawn(); w != nil {
+			w.Reason = ""
+		}
+	}
+	return outcome
+}
@@ -0,0 +1,167 @@
+package governance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+)
+
+// fakeGovernance serves proposal data from a map.
+type fakeGovernance struct {
+	QueryService
+	next     uint64
+	data     map[uint64]*governancev1alpha1.ProposalDataResponse
+	chainIds []string
+}
+
+func (f *fakeGovernance) NextProposalId(_ context.Context, req *governancev1alpha1.NextProposalIdRequest) (*governancev1alpha1.NextProposalIdResponse, error) {
+	f.chainIds = append(f.chainIds, req.GetChainId())
+	return &governancev1alpha1.NextProposalIdResponse{NextProposalId: f.next}, nil
+}
+
+func (f *fakeGovernance) ProposalData(_ context.Context, req *governancev1alpha1.ProposalDataRequest) (*governancev1alpha1.ProposalDataResponse, error) {
+	f.chainIds = append(f.chainIds, req.GetChainId())
+	data, ok := f.data[req.GetProposalId()]
+	if !ok {
+		return nil, fmt.Errorf("proposal %d not found", req.GetProposalId())
+	}
+	return data, nil
+}
+
+type fakeApp struct {
+	params.QueryService
+	params *appv1alpha1.AppParameters
+}
+
+func (f *fakeApp) AppParameters(context.Context, *appv1alpha1.AppParametersRequest) (*appv1alpha1.AppParametersResponse, error) {
+	return &appv1alpha1.AppParametersResponse{AppParameters: f.params}, nil
+}
+
+func votingState() *governancev1alpha1.ProposalState {
+	return &governancev1alpha1.ProposalState{State: &governancev1alpha1.ProposalState_Voting_{Voting: &governancev1alpha1.ProposalState_Voting{}}}
+}
+
+func withdrawnState() *governancev1alpha1.ProposalState {
+	return &governancev1alpha1.ProposalState{State: &governancev1alpha1.ProposalState_Withdrawn_{Withdrawn: &governancev1alpha1.ProposalState_Withdrawn{Reason: "typo"}}}
+}
+
+// withdrawnOutcome is the outcome of a proposal withdrawn with a reason.
+func withdrawnOutcome() *governancev1alpha1.ProposalOutcome {
+	return &governancev1alpha1.ProposalOutcome{Outcome: &governancev1alpha1.ProposalOutcome_Failed_{Failed: &governancev1alpha1.ProposalOutcome_Failed{
+		Withdrawn: &governancev1alpha1.ProposalOutcome_Withdrawn{Reason: "typo"},
+	}}}
+}
+
+func passedOutcome() *governancev1alpha1.ProposalOutcome {
+	return &governancev1alpha1.ProposalOutcome{Outcome: &governancev1alpha1.ProposalOutcome_Passed_{Passed: &governancev1alpha1.ProposalOutcome_Passed{}}}
+}
+
+func finishedState(outcome *governancev1alpha1.ProposalOutcome) *governancev1alpha1.ProposalState {
+	return &governancev1alpha1.ProposalState{State: &governancev1alpha1.ProposalState_Finished_{Finished: &governancev1alpha1.ProposalState_Finished{Outcome: outcome}}}
+}
+
+func claimedState(outcome *governancev1alpha1.ProposalOutcome) *governancev1alpha1.ProposalState {
+	return &governancev1alpha1.ProposalState{State: &governancev1alpha1.ProposalState_Claimed_{Claimed: &governancev1alpha1.ProposalState_Claimed{Outcome: outcome}}}
+}
+
+func proposalData(state *governancev1alpha1.ProposalState) *governancev1alpha1.ProposalDataResponse {
+	return &governancev1alpha1.ProposalDataResponse{State: state, EndBlockHeight: 500, ProposalDepositAmount: num.NewAmount(10_000_000).Proto()}
+}
+
+func TestPlannerSubmit(t *testing.T) {
+	ctx := context.Background()
+	gov := &fakeGovernance{next: 7}
+	p := &Planner{Governance: gov, App: &fakeApp{params: testAppParams()}, ChainId: "penumbra-testnet"}
+	proposal, err := NewSignaling("Signal", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := p.Submit(ctx, proposal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submit := plan.GetProposalSubmit()
+	if submit.GetProposal().GetId() != 7 || num.AmountFromProto(submit.GetDepositAmount()) != num.NewAmount(10_000_000) {
+		t.Errorf("Submit = %v", submit)
+	}
+	if proposal.Id != 0 {
+		t.Errorf("Submit modified the proposal")
+	}
+	if len(gov.chainIds) != 1 || gov.chainIds[0] != "penumbra-testnet" {
+		t.Errorf("queries sent chain IDs %v", gov.chainIds)
+	}
+
+	if _, err := p.Submit(ctx, &governancev1alpha1.Proposal{Title: "Nothing"}); err == nil {
+		t.Errorf("Submit accepted an invalid proposal")
+	}
+	p.App = &fakeApp{params: &appv1alpha1.AppParameters{}}
+	if _, err := p.Submit(ctx, proposal); err == nil || !strings.Contains(err.Error(), "governance parameters") {
+		t.Errorf("Submit without governance parameters = %v", err)
+	}
+}
+
+func TestPlannerWithdraw(t *testing.T) {
+	ctx := context.Background()
+	p := &Planner{Governance: &fakeGovernance{data: map[uint64]*governancev1alpha1.ProposalDataResponse{
+		1: proposalData(votingState()),
+		2: proposalData(withdrawnState()),
+		3: proposalData(finishedState(passedOutcome())),
+	}}}
+	plan, err := p.Withdraw(ctx, 1, "typo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := plan.GetProposalWithdraw(); w.GetProposal() != 1 || w.GetReason() != "typo" {
+		t.Errorf("Withdraw = %v", w)
+	}
+	for _, id := range []uint64{2, 3, 4} {
+		if _, err := p.Withdraw(ctx, id, ""); err == nil {
+			t.Errorf("Withdraw(%d) succeeded", id)
+		}
+	}
+}
+
+func TestDepositClaimPlan(t *testing.T) {
+	deposit := num.NewAmount(10_000_000)
+	plan, err := DepositClaimPlan(1, deposit, finishedState(withdrawnOutcome()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := plan.GetProposalDepositClaim()
+	if claim.GetProposal() != 1 || num.AmountFromProto(claim.GetDepositAmount()) != deposit {
+		t.Errorf("DepositClaimPlan = %v", claim)
+	}
+	// The claim only records that the proposal was withdrawn.
+	if w := claim.GetOutcome().GetFailed().GetWithdrawn(); w == nil || w.GetReason() != "" {
+		t.Errorf("claim outcome = %v", claim.GetOutcome())
+	}
+
+	for _, tt := range []struct {
+		state *governancev1alpha1.ProposalState
+		want  string
+	}{
+		{votingState(), "still voting"},
+		{withdrawnState(), "voting has not concluded"},
+		{claimedState(passedOutcome()), "already been claimed"},
+		{&governancev1alpha1.ProposalState{}, "unknown state"},
+		{finishedState(&governancev1alpha1.ProposalOutcome{}), "missing its outcome"},
+	} {
+		if _, err := DepositClaimPlan(1, deposit, tt.state); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DepositClaimPlan in %s = %v, want %q", StateOf(tt.state), err, tt.want)
+		}
+	}
+
+	p := &Planner{Governance: &fakeGovernance{data: map[uint64]*governancev1alpha1.ProposalDataResponse{
+		1: proposalData(finishedState(passedOutcome())),
+	}}}
+	plan, err = p.DepositClaim(context.Background(), 1)
+	if err != nil || plan.GetProposalDepositClaim().GetOutcome().GetPassed() == nil {
+		t.Errorf("DepositClaim = %v, %v", plan, err)
+	}
+}
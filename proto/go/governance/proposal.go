@@ -0,0 +1,220 @@
+// Package governance builds and tracks Penumbra governance proposals,
+// mirroring `penumbra_governance`.
+package governance
+
+import (
+	"errors"
+	"fmt"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Length limits on proposals, enforced by consensus.
+const (
+	ProposalTitleLimit       = 80
+	ProposalDescriptionLimit = 10_000
+)
+
+// TransactionPlanTypeURL is the type URL of the transaction plan embedded in
+// a DAO spend proposal.
+const TransactionPlanTypeURL = "/penumbra.core.transaction.v1alpha1.TransactionPlan"
+
+// ErrInvalidProposal is returned for proposals that the chain would reject.
+var ErrInvalidProposal = errors.New("invalid proposal")
+
+// Kind is the kind of a proposal, determined by its payload.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindSignaling
+	KindEmergency
+	KindParameterChange
+	KindDaoSpend
+	KindUpgradePlan
+)
+
+var kindNames = map[Kind]string{
+	KindUnknown:         "unknown",
+	KindSignaling:       "signaling",
+	KindEmergency:       "emergency",
+	KindParameterChange: "parameter_change",
+	KindDaoSpend:        "dao_spend",
+	KindUpgradePlan:     "upgrade_plan",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// KindOf returns the kind of a proposal. As in pd, the first payload set
+// in field order determines the kind.
+func KindOf(p *governancev1alpha1.Proposal) Kind {
+	switch {
+	case p.GetSignaling() != nil:
+		return KindSignaling
+	case p.GetEmergency() != nil:
+		return KindEmergency
+	case p.GetParameterChange() != nil:
+		return KindParameterChange
+	case p.GetDaoSpend() != nil:
+		return KindDaoSpend
+	case p.GetUpgradePlan() != nil:
+		return KindUpgradePlan
+	default:
+		return KindUnknown
+	}
+}
+
+// NewSignaling returns a signaling proposal, optionally referring to a
+// commit of the code it signals for.
+func NewSignaling(title, description, commit string) (*governancev1alpha1.Proposal, error) {
+	return validated(&governancev1alpha1.Proposal{
+		Title:       title,
+		Description: description,
+		Signaling:   &governancev1alpha1.Proposal_Signaling{Commit: commit},
+	})
+}
+
+// NewEmergency returns an emergency proposal, which halts the chain if
+// haltChain is set and the proposal passes.
+func NewEmergency(title, description string, haltChain bool) (*governancev1alpha1.Proposal, error) {
+	return validated(&governancev1alpha1.Proposal{
+		Title:       title,
+		Description: description,
+		Emergency:   &governancev1alpha1.Proposal_Emergency{HaltChain: haltChain},
+	})
+}
+
+// NewParameterChange returns a parameter change proposal, such as one built
+// by params.NewParameterChange.
+func NewParameterChange(title, description string, change *governancev1alpha1.Proposal_ParameterChange) (*governancev1alpha1.Proposal, error) {
+	return validated(&governancev1alpha1.Proposal{
+		Title:           title,
+		Description:     description,
+		ParameterChange: change,
+	})
+}
+
+// NewDaoSpend returns a DAO spend proposal, which executes the transaction
+// plan with the DAO's funds if it passes.
+func NewDaoSpend(title, description string, plan *transactionv1alpha1.TransactionPlan) (*governancev1alpha1.Proposal, error) {
+	value, err := proto.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
+	return validated(&governancev1alpha1.Proposal{
+		Title:       title,
+		Description: description,
+		DaoSpend: &governancev1alpha1.Proposal_DaoSpend{
+			TransactionPlan: &anypb.Any{TypeUrl: TransactionPlanTypeURL, Value: value},
+		},
+	})
+}
+
+// NewUpgradePlan returns a proposal to halt the chain for an upgrade at the
+// given height.
+func NewUpgradePlan(title, description string, height uint64) (*governancev1alpha1.Proposal, error) {
+	return validated(&governancev1alpha1.Proposal{
+		Title:       title,
+		Description: description,
+		UpgradePlan: &governancev1alpha1.Proposal_UpgradePlan{Height: height},
+	})
+}
+
+func validated(p *governancev1alpha1.Proposal) (*governancev1alpha1.Proposal, error) {
+	if err := Validate(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks a proposal as pd does before any state is consulted. The
+// proposal ID and deposit are checked against the chain state when the
+// proposal is submitted.
+func Validate(p *governancev1alpha1.Proposal) error {
+	if len(p.GetTitle()) > ProposalTitleLimit {
+		return fmt.Errorf("%w: proposal title must fit within %d characters", ErrInvalidProposal, ProposalTitleLimit)
+	}
+	if len(p.GetDescription()) > ProposalDescriptionLimit {
+		return fmt.Errorf("%w: proposal description must fit within %d characters", ErrInvalidProposal, ProposalDescriptionLimit)
+	}
+	switch KindOf(p) {
+	case KindSignaling, KindEmergency, KindUpgradePlan:
+		return nil
+	case KindParameterChange:
+		change := p.GetParameterChange()
+		if change.GetOldParameters() == nil {
+			return fmt.Errorf("%w: missing old parameters", ErrInvalidProposal)
+		}
+		if change.GetNewParameters() == nil {
+			return fmt.Errorf("%w: missing new parameters", ErrInvalidProposal)
+		}
+		old, err := params.FromChanged(change.GetOldParameters(), nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidProposal, err)
+		}
+		updated, err := params.FromChanged(change.GetNewParameters(), old)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidProposal, err)
+		}
+		if err := params.CheckValidUpdate(old, updated); err != nil {
+			return fmt.Errorf("%w: invalid change to app parameters: %v", ErrInvalidProposal, err)
+		}
+		return nil
+	case KindDaoSpend:
+		plan, err := DaoSpendPlan(p)
+		if err != nil {
+			return err
+		}
+		return validateDaoSpendPlan(plan)
+	default:
+		return fmt.Errorf("%w: missing proposal payload or unknown proposal type", ErrInvalidProposal)
+	}
+}
+
+// DaoSpendPlan decodes the transaction plan of a DAO spend proposal.
+func DaoSpendPlan(p *governancev1alpha1.Proposal) (*transactionv1alpha1.TransactionPlan, error) {
+	planAny := p.GetDaoSpend().GetTransactionPlan()
+	if planAny == nil {
+		return nil, fmt.Errorf("%w: missing transaction plan", ErrInvalidProposal)
+	}
+	if planAny.GetTypeUrl() != TransactionPlanTypeURL {
+		return nil, fmt.Errorf("%w: unknown transaction plan type url: %s", ErrInvalidProposal, planAny.GetTypeUrl())
+	}
+	plan := new(transactionv1alpha1.TransactionPlan)
+	if err := proto.Unmarshal(planAny.GetValue(), plan); err != nil {
+		return nil, fmt.Errorf("%w: transaction plan was malformed: %v", ErrInvalidProposal, err)
+	}
+	return plan, nil
+}
+
+// validateDaoSpendPlan rejects the actions that pd does not allow in a DAO
+// spend, since they require proving or manipulate proposals.
+func validateDaoSpendPlan(plan *transactionv1alpha1.TransactionPlan) error {
+	for _, action := range plan.GetActions() {
+		switch action.GetAction().(type) {
+		case *transactionv1alpha1.ActionPlan_Spend,
+			*transactionv1alpha1.ActionPlan_Output,
+			*transactionv1alpha1.ActionPlan_Swap,
+			*transactionv1alpha1.ActionPlan_SwapClaim,
+			*transactionv1alpha1.ActionPlan_DelegatorVote,
+			*transactionv1alpha1.ActionPlan_UndelegateClaim:
+			return fmt.Errorf("%w: invalid action in DAO spend proposal (would require proving)", ErrInvalidProposal)
+		case *transactionv1alpha1.ActionPlan_Delegate,
+			*transactionv1alpha1.ActionPlan_Undelegate:
+			return fmt.Errorf("%w: invalid action in DAO spend proposal (can't claim outputs of undelegation)", ErrInvalidProposal)
+		case *transactionv1alpha1.ActionPlan_ProposalSubmit,
+			*transactionv1alpha1.ActionPlan_ProposalWithdraw,
+			*transactionv1alpha1.ActionPlan_ProposalDepositClaim:
+			return fmt.Errorf("%w: invalid action in DAO spend proposal (not allowed to manipulate proposals from within proposals)", ErrInvalidProposal)
+		case nil:
+			return fmt.Errorf("%w: empty action in DAO spend proposal", ErrInvalidProposal)
+		}
+	}
+	return nil
+}
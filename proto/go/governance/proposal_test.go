@@ -0,0 +1,148 @@
+package governance
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	daov1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dao/v1alpha1"
+	distributionsv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/distributions/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	ibcv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/ibc/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// testAppParams returns pd's default app parameters.
+func testAppParams() *appv1alpha1.AppParameters {
+	return &appv1alpha1.AppParameters{
+		ChainParams: &chainv1alpha1.ChainParameters{ChainId: "penumbra-testnet", EpochDuration: 719},
+		DaoParams:   &daov1alpha1.DaoParameters{DaoSpendProposalsEnabled: true},
+		GovernanceParams: &governancev1alpha1.GovernanceParameters{
+			ProposalVotingBlocks:   17_280,
+			ProposalDepositAmount:  num.NewAmount(10_000_000).Proto(),
+			ProposalValidQuorum:    "40/100",
+			ProposalPassThreshold:  "50/100",
+			ProposalSlashThreshold: "80/100",
+		},
+		IbcParams: &ibcv1alpha1.IbcParameters{IbcEnabled: true, InboundIcs20TransfersEnabled: true, OutboundIcs20TransfersEnabled: true},
+		StakeParams: &stakev1alpha1.StakeParameters{
+			UnbondingEpochs:            2,
+			ActiveValidatorLimit:       80,
+			BaseRewardRate:             30_000,
+			SlashingPenaltyMisbehavior: 10_000_000,
+			SlashingPenaltyDowntime:    10_000,
+			SignedBlocksWindowLen:      10_000,
+			MissedBlocksMaximum:        9_500,
+		},
+		FeeParams:           &feev1alpha1.FeeParameters{},
+		DistributionsParams: &distributionsv1alpha1.DistributionsParameters{StakingIssuancePerBlock: 1_000_000},
+	}
+}
+
+func TestNewProposals(t *testing.T) {
+	change, err := params.NewParameterChange(testAppParams(), "stake_params.unbonding_epochs=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &transactionv1alpha1.TransactionPlan{Actions: []*transactionv1alpha1.ActionPlan{
+		{Action: &transactionv1alpha1.ActionPlan_DaoSpend{DaoSpend: &governancev1alpha1.DaoSpend{}}},
+		{Action: &transactionv1alpha1.ActionPlan_DaoOutput{DaoOutput: &governancev1alpha1.DaoOutput{}}},
+	}}
+
+	tests := []struct {
+		kind Kind
+		new  func() (*governancev1alpha1.Proposal, error)
+	}{
+		{KindSignaling, func() (*governancev1alpha1.Proposal, error) { return NewSignaling("Signal", "", "abc123") }},
+		{KindEmergency, func() (*governancev1alpha1.Proposal, error) { return NewEmergency("Halt", "", true) }},
+		{KindParameterChange, func() (*governancev1alpha1.Proposal, error) { return NewParameterChange("Unbond faster", "", change) }},
+		{KindDaoSpend, func() (*governancev1alpha1.Proposal, error) { return NewDaoSpend("Grant", "", plan) }},
+		{KindUpgradePlan, func() (*governancev1alpha1.Proposal, error) { return NewUpgradePlan("Upgrade", "", 1000) }},
+	}
+	for _, tt := range tests {
+		p, err := tt.new()
+		if err != nil {
+			t.Errorf("%s: %v", tt.kind, err)
+			continue
+		}
+		if KindOf(p) != tt.kind {
+			t.Errorf("%s: KindOf = %s", tt.kind, KindOf(p))
+		}
+	}
+
+	p, _ := NewDaoSpend("Grant", "", plan)
+	decoded, err := DaoSpendPlan(p)
+	if err != nil || len(decoded.GetActions()) != 2 || decoded.GetActions()[1].GetDaoOutput() == nil {
+		t.Errorf("DaoSpendPlan = %v, %v", decoded, err)
+	}
+	if KindOf(&governancev1alpha1.Proposal{}) != KindUnknown || KindUnknown.String() != "unknown" || KindDaoSpend.String() != "dao_spend" {
+		t.Errorf("unknown kind is %s", KindOf(&governancev1alpha1.Proposal{}))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	current := testAppParams()
+	invalid := params.AsChanged(current)
+	invalid.StakeParams.ActiveValidatorLimit = 81
+
+	tests := []struct {
+		name     string
+		proposal *governancev1alpha1.Proposal
+		want     string
+	}{
+		{"long title", &governancev1alpha1.Proposal{Title: strings.Repeat("a", ProposalTitleLimit+1), Signaling: &governancev1alpha1.Proposal_Signaling{}}, "title"},
+		{"long description", &governancev1alpha1.Proposal{Description: strings.Repeat("a", ProposalDescriptionLimit+1), Signaling: &governancev1alpha1.Proposal_Signaling{}}, "description"},
+		{"no payload", &governancev1alpha1.Proposal{Title: "Nothing"}, "missing proposal payload"},
+		{"missing old parameters", &governancev1alpha1.Proposal{ParameterChange: &governancev1alpha1.Proposal_ParameterChange{NewParameters: params.AsChanged(current)}}, "missing old parameters"},
+		{"missing new parameters", &governancev1alpha1.Proposal{ParameterChange: &governancev1alpha1.Proposal_ParameterChange{OldParameters: params.AsChanged(current)}}, "missing new parameters"},
+		{"incomplete old parameters", &governancev1alpha1.Proposal{ParameterChange: &governancev1alpha1.Proposal_ParameterChange{
+			OldParameters: &governancev1alpha1.ChangedAppParameters{StakeParams: current.StakeParams},
+			NewParameters: params.AsChanged(current),
+		}}, "all parameters must be specified"},
+		{"forbidden parameter change", &governancev1alpha1.Proposal{ParameterChange: &governancev1alpha1.Proposal_ParameterChange{
+			OldParameters: params.AsChanged(current),
+			NewParameters: invalid,
+		}}, "active validator limit can't be changed"},
+		{"missing plan", &governancev1alpha1.Proposal{DaoSpend: &governancev1alpha1.Proposal_DaoSpend{}}, "missing transaction plan"},
+		{"wrong plan type", &governancev1alpha1.Proposal{DaoSpend: &governancev1alpha1.Proposal_DaoSpend{TransactionPlan: &anypb.Any{TypeUrl: "/penumbra.core.transaction.v1alpha1.Transaction"}}}, "unknown transaction plan type url"},
+		{"malformed plan", &governancev1alpha1.Proposal{DaoSpend: &governancev1alpha1.Proposal_DaoSpend{TransactionPlan: &anypb.Any{TypeUrl: TransactionPlanTypeURL, Value: []byte{0xff}}}}, "malformed"},
+		{"spend in plan", daoSpendProposal(t, &transactionv1alpha1.ActionPlan{Action: &transactionv1alpha1.ActionPlan_Spend{}}), "would require proving"},
+		{"delegation in plan", daoSpendProposal(t, &transactionv1alpha1.ActionPlan{Action: &transactionv1alpha1.ActionPlan_Delegate{}}), "can't claim outputs of undelegation"},
+		{"proposal in plan", daoSpendProposal(t, &transactionv1alpha1.ActionPlan{Action: &transactionv1alpha1.ActionPlan_ProposalWithdraw{}}), "not allowed to manipulate proposals"},
+		{"empty action in plan", daoSpendProposal(t, &transactionv1alpha1.ActionPlan{}), "empty action"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.proposal)
+		if !errors.Is(err, ErrInvalidProposal) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := NewSignaling(strings.Repeat("a", ProposalTitleLimit+1), "", ""); err == nil {
+		t.Errorf("NewSignaling accepted a long title")
+	}
+	if _, err := NewSignaling(strings.Repeat("a", ProposalTitleLimit), strings.Repeat("a", ProposalDescriptionLimit), ""); err != nil {
+		t.Errorf("NewSignaling at the limits: %v", err)
+	}
+}
+
+// daoSpendProposal returns a DAO spend proposal of a plan with the given
+// actions, without validating it.
+func daoSpendProposal(t *testing.T, actions ...*transactionv1alpha1.ActionPlan) *governancev1alpha1.Proposal {
+	value, err := proto.Marshal(&transactionv1alpha1.TransactionPlan{Actions: actions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &governancev1alpha1.Proposal{
+		Title:    "Grant",
+		DaoSpend: &governancev1alpha1.Proposal_DaoSpend{TransactionPlan: &anypb.Any{TypeUrl: TransactionPlanTypeURL, Value: value}},
+	}
+}
@@ -0,0 +1,67 @@
+package governance
+
+import (
+	"context"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of the governance component's
+// QueryService.
+const QueryServiceName = "penumbra.core.component.governance.v1alpha1.QueryService"
+
+// QueryService is the governance component's QueryService API.
+type QueryService interface {
+	ProposalInfo(ctx context.Context, req *governancev1alpha1.ProposalInfoRequest) (*governancev1alpha1.ProposalInfoResponse, error)
+	ProposalList(ctx context.Context, req *governancev1alpha1.ProposalListRequest) (grpcclient.Stream[governancev1alpha1.ProposalListResponse], error)
+	ProposalData(ctx context.Context, req *governancev1alpha1.ProposalDataRequest) (*governancev1alpha1.ProposalDataResponse, error)
+	NextProposalId(ctx context.Context, req *governancev1alpha1.NextProposalIdRequest) (*governancev1alpha1.NextProposalIdResponse, error)
+	ValidatorVotes(ctx context.Context, req *governancev1alpha1.ValidatorVotesRequest) (grpcclient.Stream[governancev1alpha1.ValidatorVotesResponse], error)
+	VotingPowerAtProposalStart(ctx context.Context, req *governancev1alpha1.VotingPowerAtProposalStartRequest) (*governancev1alpha1.VotingPowerAtProposalStartResponse, error)
+	AllTalliedDelegatorVotesForProposal(ctx context.Context, req *governancev1alpha1.AllTalliedDelegatorVotesForProposalRequest) (grpcclient.Stream[governancev1alpha1.AllTalliedDelegatorVotesForProposalResponse], error)
+	ProposalRateData(ctx context.Context, req *governancev1alpha1.ProposalRateDataRequest) (grpcclient.Stream[governancev1alpha1.ProposalRateDataResponse], error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) ProposalInfo(ctx context.Context, req *governancev1alpha1.ProposalInfoRequest) (*governancev1alpha1.ProposalInfoResponse, error) {
+	return grpcclient.Call[governancev1alpha1.ProposalInfoResponse](ctx, s.svc, "ProposalInfo", req)
+}
+
+func (s *grpcQueryService) ProposalList(ctx context.Context, req *governancev1alpha1.ProposalListRequest) (grpcclient.Stream[governancev1alpha1.ProposalListResponse], error) {
+	return grpcclient.OpenStream[governancev1alpha1.ProposalListResponse](ctx, s.svc, "ProposalList", req)
+}
+
+func (s *grpcQueryService) ProposalData(ctx context.Context, req *governancev1alpha1.ProposalDataRequest) (*governancev1alpha1.ProposalDataResponse, error) {
+	return grpcclient.Call[governancev1alpha1.ProposalDataResponse](ctx, s.svc, "ProposalData", req)
+}
+
+func (s *grpcQueryService) NextProposalId(ctx context.Context, req *governancev1alpha1.NextProposalIdRequest) (*governancev1alpha1.NextProposalIdResponse, error) {
+	return grpcclient.Call[governancev1alpha1.NextProposalIdResponse](ctx, s.svc, "NextProposalId", req)
+}
+
+func (s *grpcQueryService) ValidatorVotes(ctx context.Context, req *governancev1alpha1.ValidatorVotesRequest) (grpcclient.Stream[governancev1alpha1.ValidatorVotesResponse], error) {
+	return grpcclient.OpenStream[governancev1alpha1.ValidatorVotesResponse](ctx, s.svc, "ValidatorVotes", req)
+}
+
+func (s *grpcQueryService) VotingPowerAtProposalStart(ctx context.Context, req *governancev1alpha1.VotingPowerAtProposalStartRequest) (*governancev1alpha1.VotingPowerAtProposalStartResponse, error) {
+	return grpcclient.Call[governancev1alpha1.VotingPowerAtProposalStartResponse](ctx, s.svc, "VotingPowerAtProposalStart", req)
+}
+
+func (s *grpcQueryService) AllTalliedDelegatorVotesForProposal(ctx context.Context, req *governancev1alpha1.AllTalliedDelegatorVotesForProposalRequest) (grpcclient.Stream[governancev1alpha1.AllTalliedDelegatorVotesForProposalResponse], error) {
+	return grpcclient.OpenStream[governancev1alpha1.AllTalliedDelegatorVotesForProposalResponse](ctx, s.svc, "AllTalliedDelegatorVotesForProposal", req)
+}
+
+func (s *grpcQueryService) ProposalRateData(ctx context.Context, req *governancev1alpha1.ProposalRateDataRequest) (grpcclient.Stream[governancev1alpha1.ProposalRateDataResponse], error) {
+	return grpcclient.OpenStream[governancev1alpha1.ProposalRateDataResponse](ctx, s.svc, "ProposalRateData", req)
+}
@@ -0,0 +1,146 @@
+package governance
+
+import (
+	"context"
+	"sort"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// State is the state of a proposal, mirroring `proposal_state::State`.
+type State int
+
+const (
+	StateUnknown State = iota
+	// StateVoting is a proposal that is being voted on.
+	StateVoting
+	// StateWithdrawn is a proposal withdrawn by its submitter, which is
+	// still in its voting period.
+	StateWithdrawn
+	// StateFinished is a proposal whose voting period has ended, and whose
+	// deposit can be claimed.
+	StateFinished
+	// StateClaimed is a proposal whose deposit has been claimed.
+	StateClaimed
+)
+
+var stateNames = map[State]string{
+	StateUnknown:   "unknown",
+	StateVoting:    "voting",
+	StateWithdrawn: "withdrawn",
+	StateFinished:  "finished",
+	StateClaimed:   "claimed",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// StateOf returns the state of a proposal.
+func StateOf(s *governancev1alpha1.ProposalState) State {
+	switch s.GetState().(type) {
+	case *governancev1alpha1.ProposalState_Voting_:
+		return StateVoting
+	case *governancev1alpha1.ProposalState_Withdrawn_:
+		return StateWithdrawn
+	case *governancev1alpha1.ProposalState_Finished_:
+		return StateFinished
+	case *governancev1alpha1.ProposalState_Claimed_:
+		return StateClaimed
+	default:
+		return StateUnknown
+	}
+}
+
+// Tracked is a proposal followed by a Tracker.
+type Tracked struct {
+	Id             uint64
+	State          State
+	EndBlockHeight uint64
+	Deposit        num.Amount
+	// Outcome is set once voting has finished.
+	Outcome *governancev1alpha1.ProposalOutcome
+}
+
+// Transition is a change in the state of a tracked proposal.
+type Transition struct {
+	Id       uint64
+	From, To State
+}
+
+// Tracker follows proposals, typically those submitted by a wallet, through
+// their states, and plans the claim of their deposits once voting ends.
+type Tracker struct {
+	Query QueryService
+	// ChainId, if set, is sent with each ProposalData request, which the
+	// fullnode rejects if it serves another chain.
+	ChainId string
+	// OnTransition, if set, is called for every state change observed.
+	OnTransition func(Transition)
+
+	proposals map[uint64]*Tracked
+}
+
+// Track starts following a proposal.
+func (t *Tracker) Track(id uint64) {
+	if t.proposals == nil {
+		t.proposals = make(map[uint64]*Tracked)
+	}
+	if _, ok := t.proposals[id]; !ok {
+		t.proposals[id] = &Tracked{Id: id}
+	}
+}
+
+// Untrack stops following a proposal.
+func (t *Tracker) Untrack(id uint64) {
+	delete(t.proposals, id)
+}
+
+// Proposals returns the tracked proposals, ordered by ID.
+func (t *Tracker) Proposals() []Tracked {
+	proposals := make([]Tracked, 0, len(t.proposals))
+	for _, p := range t.proposals {
+		proposals = append(proposals, *p)
+	}
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].Id < proposals[j].Id })
+	return proposals
+}
+
+// Poll refreshes the state of every tracked proposal, and returns the
+// deposit claims of those whose voting has finished. Claims are returned on
+// every poll until the chain records them, so a claim whose transaction
+// failed is retried; proposals whose deposits are claimed stop being
+// tracked.
+func (t *Tracker) Poll(ctx context.Context) ([]*transactionv1alpha1.ActionPlan, error) {
+	var claims []*transactionv1alpha1.ActionPlan
+	for _, p := range t.Proposals() {
+		data, err := t.Query.ProposalData(ctx, &governancev1alpha1.ProposalDataRequest{ChainId: t.ChainId, ProposalId: p.Id})
+		if err != nil {
+			return nil, err
+		}
+		tracked := t.proposals[p.Id]
+		state := StateOf(data.GetState())
+		if state != tracked.State && t.OnTransition != nil {
+			t.OnTransition(Transition{Id: p.Id, From: tracked.State, To: state})
+		}
+		tracked.State = state
+		tracked.EndBlockHeight = data.GetEndBlockHeight()
+		tracked.Deposit = num.AmountFromProto(data.GetProposalDepositAmount())
+
+		switch state {
+		case StateFinished:
+			tracked.Outcome = data.GetState().GetFinished().GetOutcome()
+			claim, err := DepositClaimPlan(p.Id, tracked.Deposit, data.GetState())
+			if err != nil {
+				return nil, err
+			}
+			claims = append(claims, claim)
+		case StateClaimed:
+			tracked.Outcome = data.GetState().GetClaimed().GetOutcome()
+			t.Untrack(p.Id)
+		}
+	}
+	return claims, nil
+}
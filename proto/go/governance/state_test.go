@@ -0,0 +1,67 @@
+package governance
+
+import (
+	"context"
+	"testing"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+)
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	gov := &fakeGovernance{data: map[uint64]*governancev1alpha1.ProposalDataResponse{
+		1: proposalData(votingState()),
+		2: proposalData(votingState()),
+	}}
+	var transitions []Transition
+	tr := &Tracker{Query: gov, OnTransition: func(tt Transition) { transitions = append(transitions, tt) }}
+	tr.Track(2)
+	tr.Track(1)
+	tr.Track(1)
+
+	claims, err := tr.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposals := tr.Proposals()
+	if len(claims) != 0 || len(proposals) != 2 || proposals[0].Id != 1 || proposals[0].State != StateVoting || proposals[0].EndBlockHeight != 500 {
+		t.Errorf("after the first poll: claims %v, proposals %+v", claims, proposals)
+	}
+
+	// Proposal 1 is withdrawn, and voting on 2 ends.
+	gov.data[1] = proposalData(withdrawnState())
+	gov.data[2] = proposalData(finishedState(passedOutcome()))
+	transitions = nil
+	claims, err = tr.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims) != 1 || claims[0].GetProposalDepositClaim().GetProposal() != 2 {
+		t.Errorf("claims = %v", claims)
+	}
+	want := []Transition{{1, StateVoting, StateWithdrawn}, {2, StateVoting, StateFinished}}
+	if len(transitions) != 2 || transitions[0] != want[0] || transitions[1] != want[1] {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+
+	// The claim is planned again until the chain records it.
+	if claims, _ := tr.Poll(ctx); len(claims) != 1 {
+		t.Errorf("claims on the next poll = %v", claims)
+	}
+	gov.data[2] = proposalData(claimedState(passedOutcome()))
+	if claims, _ := tr.Poll(ctx); len(claims) != 0 {
+		t.Errorf("claims after the deposit was claimed = %v", claims)
+	}
+	if proposals := tr.Proposals(); len(proposals) != 1 || proposals[0].Id != 1 {
+		t.Errorf("proposals after the claim = %+v", proposals)
+	}
+
+	tr.Untrack(1)
+	if len(tr.Proposals()) != 0 {
+		t.Errorf("Untrack left %+v", tr.Proposals())
+	}
+	tr.Track(3)
+	if _, err := tr.Poll(ctx); err == nil {
+		t.Errorf("Poll of an unknown proposal succeeded")
+	}
+}
@@ -0,0 +1,325 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Tally counts voting power by vote, mirroring `tally::Tally`.
+type Tally struct {
+	Yes, No, Abstain uint64
+}
+
+// TallyFromProto converts a tally from its protobuf form.
+func TallyFromProto(t *governancev1alpha1.Tally) Tally {
+	return Tally{Yes: t.GetYes(), No: t.GetNo(), Abstain: t.GetAbstain()}
+}
+
+// VoteTally returns a tally of power for a single vote.
+func VoteTally(vote governancev1alpha1.Vote_Vote, power uint64) Tally {
+	switch vote {
+	case governancev1alpha1.Vote_VOTE_YES:
+		return Tally{Yes: power}
+	case governancev1alpha1.Vote_VOTE_NO:
+		return Tally{No: power}
+	case governancev1alpha1.Vote_VOTE_ABSTAIN:
+		return Tally{Abstain: power}
+	default:
+		return Tally{}
+	}
+}
+
+// Proto converts the tally to its protobuf form.
+func (t Tally) Proto() *governancev1alpha1.Tally {
+	return &governancev1alpha1.Tally{Yes: t.Yes, No: t.No, Abstain: t.Abstain}
+}
+
+// Total returns the power that voted.
+func (t Tally) Total() uint64 {
+	return t.Yes + t.No + t.Abstain
+}
+
+// Add returns the sum of two tallies.
+func (t Tally) Add(o Tally) Tally {
+	return Tally{Yes: t.Yes + o.Yes, No: t.No + o.No, Abstain: t.Abstain + o.Abstain}
+}
+
+// Outcome is the result of tallying a proposal, mirroring `tally::Outcome`.
+type Outcome int
+
+const (
+	OutcomeFail Outcome = iota
+	OutcomePass
+	OutcomeSlash
+)
+
+var outcomeNames = map[Outcome]string{
+	OutcomeFail:  "fail",
+	OutcomePass:  "pass",
+	OutcomeSlash: "slash",
+}
+
+func (o Outcome) String() string {
+	return outcomeNames[o]
+}
+
+// Proto returns the outcome recorded for a proposal that was not withdrawn.
+func (o Outcome) Proto() *governancev1alpha1.ProposalOutcome {
+	switch o {
+	case OutcomePass:
+		return &governancev1alpha1.ProposalOutcome{Outcome: &governancev1alpha1.ProposalOutcome_Passed_{
+			Passed: &governancev1alpha1.ProposalOutcome_Passed{},
+		}}
+	case OutcomeSlash:
+		return &governancev1alpha1.ProposalOutcome{Outcome: &governancev1alpha1.ProposalOutcome_Slashed_{
+			Slashed: &governancev1alpha1.ProposalOutcome_Slashed{},
+		}}
+	default:
+		return &governancev1alpha1.ProposalOutcome{Outcome: &governancev1alpha1.ProposalOutcome_Failed_{
+			Failed: &governancev1alpha1.ProposalOutcome_Failed{},
+		}}
+	}
+}
+
+// Thresholds are the governance parameters that decide a tally.
+type Thresholds struct {
+	ValidQuorum    params.Ratio
+	PassThreshold  params.Ratio
+	SlashThreshold params.Ratio
+}
+
+// ThresholdsFromParams parses the ratios of the governance parameters.
+func ThresholdsFromParams(p *governancev1alpha1.GovernanceParameters) (Thresholds, error) {
+	var t Thresholds
+	var err error
+	if t.ValidQuorum, err = params.ParseRatio(p.GetProposalValidQuorum()); err != nil {
+		return t, fmt.Errorf("invalid proposal valid quorum: %w", err)
+	}
+	if t.PassThreshold, err = params.ParseRatio(p.GetProposalPassThreshold()); err != nil {
+		return t, fmt.Errorf("invalid proposal pass threshold: %w", err)
+	}
+	if t.SlashThreshold, err = params.ParseRatio(p.GetProposalSlashThreshold()); err != nil {
+		return t, fmt.Errorf("invalid proposal slash threshold: %w", err)
+	}
+	return t, nil
+}
+
+// MeetsQuorum reports whether enough of the total voting power voted.
+func (t Tally) MeetsQuorum(totalPower uint64, th Thresholds) bool {
+	return params.NewRatio(t.Total(), totalPower).Cmp(th.ValidQuorum) >= 0
+}
+
+// Slashed reports whether enough of the voting power voted no to slash the
+// proposal's deposit.
+func (t Tally) Slashed(th Thresholds) bool {
+	return params.NewRatio(t.No, t.Total()).Cmp(th.SlashThreshold) > 0
+}
+
+// yesRatio mirrors pd, whose denominator is min(yes + no, 1) rather than
+// yes + no. Outcomes must be computed identically to the chain, so the quirk
+// is kept.
+func (t Tally) yesRatio() params.Ratio {
+	return params.NewRatio(t.Yes, min(t.Yes+t.No, 1))
+}
+
+// Outcome decides a proposal from its final tally, as pd does when voting
+// ends.
+func (t Tally) Outcome(totalPower uint64, th Thresholds) Outcome {
+	if !t.MeetsQuorum(totalPower, th) {
+		return OutcomeFail
+	}
+	if t.Slashed(th) {
+		return OutcomeSlash
+	}
+	if t.yesRatio().Cmp(th.PassThreshold) > 0 {
+		return OutcomePass
+	}
+	return OutcomeFail
+}
+
+// EmergencyPass reports whether an emergency proposal passes immediately,
+// which pd checks after every validator vote: more than 2/3 of the total
+// voting power must have voted yes.
+func (t Tally) EmergencyPass(totalPower uint64, th Thresholds) bool {
+	if !t.MeetsQuorum(totalPower, th) || t.Slashed(th) {
+		return false
+	}
+	return params.NewRatio(t.Yes, totalPower).Cmp(params.NewRatio(2, 3)) > 0
+}
+
+// Votes holds the votes on a proposal, keyed by validator identity key.
+type Votes struct {
+	ProposalId uint64
+	// Powers is the voting power of each validator active when voting
+	// started.
+	Powers map[string]uint64
+	// ValidatorVotes holds the votes cast by validators.
+	ValidatorVotes map[string]governancev1alpha1.Vote_Vote
+	// DelegatorTallies holds the delegator votes tallied so far, by the
+	// validator delegated to. Delegator votes are tallied at the end of each
+	// epoch, and when voting ends.
+	DelegatorTallies map[string]Tally
+	// PendingDelegatorTallies holds the delegator votes cast but not yet
+	// tallied, which count once they are.
+	PendingDelegatorTallies map[string]Tally
+}
+
+// TotalPower returns the voting power of all validators active when voting
+// started.
+func (v *Votes) TotalPower() uint64 {
+	var total uint64
+	for _, power := range v.Powers {
+		total += power
+	}
+	return total
+}
+
+// Tally combines the votes as pd does: a validator's vote counts with its
+// power less the power of its delegators that voted, and the delegators'
+// votes count regardless of whether the validator voted. If pending is set,
+// untallied delegator votes are included, as they will be when voting ends.
+func (v *Votes) Tally(pending bool) (Tally, error) {
+	delegatorTallies := make(map[string]Tally, len(v.DelegatorTallies))
+	for ik, t := range v.DelegatorTallies {
+		delegatorTallies[ik] = t
+	}
+	if pending {
+		for ik, t := range v.PendingDelegatorTallies {
+			delegatorTallies[ik] = delegatorTallies[ik].Add(t)
+		}
+	}
+
+	validators := make([]string, 0, len(v.Powers))
+	for ik := range v.Powers {
+		validators = append(validators, ik)
+	}
+	sort.Strings(validators)
+
+	var tally Tally
+	for _, ik := range validators {
+		power := v.Powers[ik]
+		delegatorTally := delegatorTallies[ik]
+		delete(delegatorTallies, ik)
+		if vote, ok := v.ValidatorVotes[ik]; ok {
+			if delegatorTally.Total() > power {
+				return Tally{}, fmt.Errorf("delegators to %s voted with %d, more than its power %d", ik, delegatorTally.Total(), power)
+			}
+			tally = tally.Add(VoteTally(vote, power-delegatorTally.Total()))
+		}
+		tally = tally.Add(delegatorTally)
+	}
+	for ik := range v.ValidatorVotes {
+		if _, ok := v.Powers[ik]; !ok {
+			return Tally{}, fmt.Errorf("validator %s voted but was not active when voting started", ik)
+		}
+	}
+	if len(delegatorTallies) > 0 {
+		return Tally{}, errors.New("delegators voted for a validator that was not active when voting started")
+	}
+	return tally, nil
+}
+
+// Prediction is the outcome a proposal would have if voting ended now.
+type Prediction struct {
+	Tally      Tally
+	TotalPower uint64
+	Outcome    Outcome
+	// EmergencyPass is set if an emergency proposal would pass immediately.
+	EmergencyPass bool
+}
+
+// Predict tallies the votes, including pending delegator votes, and decides
+// the outcome.
+func (v *Votes) Predict(th Thresholds) (*Prediction, error) {
+	tally, err := v.Tally(true)
+	if err != nil {
+		return nil, err
+	}
+	total := v.TotalPower()
+	return &Prediction{
+		Tally:         tally,
+		TotalPower:    total,
+		Outcome:       tally.Outcome(total, th),
+		EmergencyPass: tally.EmergencyPass(total, th),
+	}, nil
+}
+
+// proposalKey formats a proposal ID as pd does in state keys, zero-padded
+// so that the keys sort by ID.
+func proposalKey(id uint64) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+// FetchVotes collects the votes on a proposal. Validator votes and tallied
+// delegator votes are queried from the governance QueryService; the voting
+// power of every validator at the start of the proposal and the untallied
+// delegator votes are only exposed in the state, so they are read through
+// cnidarium.
+func FetchVotes(ctx context.Context, q QueryService, state cnidarium.QueryService, chainId string, proposalId uint64) (*Votes, error) {
+	v := &Votes{
+		ProposalId:              proposalId,
+		Powers:                  make(map[string]uint64),
+		ValidatorVotes:          make(map[string]governancev1alpha1.Vote_Vote),
+		DelegatorTallies:        make(map[string]Tally),
+		PendingDelegatorTallies: make(map[string]Tally),
+	}
+
+	powerPrefix := cnidarium.DefaultSchema.KeyPrefix("governance/proposal/{proposal_id}/voting_power_at_start/{identity_key}", proposalKey(proposalId))
+	if err := cnidarium.Scan(ctx, state, powerPrefix, func(key string, power *wrapperspb.UInt64Value) error {
+		v.Powers[strings.TrimPrefix(key, powerPrefix)] = power.GetValue()
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not read voting power at proposal start: %w", err)
+	}
+
+	pendingPrefix := cnidarium.DefaultSchema.KeyPrefix("governance/untallied_delegator_vote/{proposal_id}/{identity_key}/{nullifier}", proposalKey(proposalId))
+	if err := cnidarium.Scan(ctx, state, pendingPrefix, func(key string, t *governancev1alpha1.Tally) error {
+		ik, _, _ := strings.Cut(strings.TrimPrefix(key, pendingPrefix), "/")
+		v.PendingDelegatorTallies[ik] = v.PendingDelegatorTallies[ik].Add(TallyFromProto(t))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not read untallied delegator votes: %w", err)
+	}
+
+	votes, err := q.ValidatorVotes(ctx, &governancev1alpha1.ValidatorVotesRequest{ChainId: chainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rsp, err := votes.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.ValidatorVotes[keys.FormatIdentityKey(rsp.GetIdentityKey())] = rsp.GetVote().GetVote()
+	}
+
+	tallies, err := q.AllTalliedDelegatorVotesForProposal(ctx, &governancev1alpha1.AllTalliedDelegatorVotesForProposalRequest{ChainId: chainId, ProposalId: proposalId})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rsp, err := tallies.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ik := keys.FormatIdentityKey(rsp.GetIdentityKey())
+		v.DelegatorTallies[ik] = v.DelegatorTallies[ik].Add(TallyFromProto(rsp.GetTally()))
+	}
+	return v, nil
+}
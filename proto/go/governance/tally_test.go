@@ -0,0 +1,172 @@
+package governance
+
+import (
+	"testing"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+)
+
+// defaultThresholds are the defaults of `GovernanceParameters`.
+func defaultThresholds(t *testing.T) Thresholds {
+	t.Helper()
+	th, err := ThresholdsFromParams(&governancev1alpha1.GovernanceParameters{
+		ProposalValidQuorum:    "40/100",
+		ProposalPassThreshold:  "50/100",
+		ProposalSlashThreshold: "80/100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return th
+}
+
+func TestTallyOutcome(t *testing.T) {
+	th := defaultThresholds(t)
+	tests := []struct {
+		name       string
+		tally      Tally
+		totalPower uint64
+		want       Outcome
+		emergency  bool
+	}{
+		{"no votes", Tally{}, 100, OutcomeFail, false},
+		{"below quorum", Tally{Yes: 39}, 100, OutcomeFail, false},
+		{"exactly quorum", Tally{Yes: 40}, 100, OutcomePass, false},
+		{"abstain counts toward quorum", Tally{Yes: 1, Abstain: 39}, 100, OutcomePass, false},
+		{"only abstain", Tally{Abstain: 100}, 100, OutcomeFail, false},
+		{"only no", Tally{No: 50}, 100, OutcomeSlash, false},
+		// The yes ratio's denominator is min(yes + no, 1), so any yes vote
+		// passes a proposal that meets quorum and is not slashed.
+		{"exactly slash threshold", Tally{Yes: 20, No: 80}, 100, OutcomePass, false},
+		{"above slash threshold", Tally{Yes: 19, No: 81}, 100, OutcomeSlash, false},
+		{"abstain dilutes no", Tally{No: 80, Abstain: 20}, 100, OutcomeFail, false},
+		{"minority yes", Tally{Yes: 30, No: 60}, 100, OutcomePass, false},
+		{"majority yes", Tally{Yes: 60, No: 10}, 100, OutcomePass, false},
+		{"emergency below two thirds", Tally{Yes: 65}, 99, OutcomePass, false},
+		{"emergency at two thirds", Tally{Yes: 66, No: 33}, 99, OutcomePass, false},
+		{"emergency above two thirds", Tally{Yes: 67}, 99, OutcomePass, true},
+		{"emergency slashed", Tally{Yes: 67, No: 330}, 400, OutcomeSlash, false},
+	}
+	for _, tt := range tests {
+		if got := tt.tally.Outcome(tt.totalPower, th); got != tt.want {
+			t.Errorf("%s: Outcome = %s, want %s", tt.name, got, tt.want)
+		}
+		if got := tt.tally.EmergencyPass(tt.totalPower, th); got != tt.emergency {
+			t.Errorf("%s: EmergencyPass = %v, want %v", tt.name, got, tt.emergency)
+		}
+	}
+}
+
+func TestVotesTally(t *testing.T) {
+	yes, no, abstain := governancev1alpha1.Vote_VOTE_YES, governancev1alpha1.Vote_VOTE_NO, governancev1alpha1.Vote_VOTE_ABSTAIN
+	tests := []struct {
+		name    string
+		votes   Votes
+		pending bool
+		want    Tally
+		err     bool
+	}{
+		{
+			name: "validator votes only",
+			votes: Votes{
+				Powers:         map[string]uint64{"a": 60, "b": 30, "c": 10},
+				ValidatorVotes: map[string]governancev1alpha1.Vote_Vote{"a": yes, "b": no},
+			},
+			want: Tally{Yes: 60, No: 30},
+		},
+		{
+			name: "delegators override their validator",
+			votes: Votes{
+				Powers:           map[string]uint64{"a": 60},
+				ValidatorVotes:   map[string]governancev1alpha1.Vote_Vote{"a": yes},
+				DelegatorTallies: map[string]Tally{"a": {No: 15, Abstain: 5}},
+			},
+			want: Tally{Yes: 40, No: 15, Abstain: 5},
+		},
+		{
+			name: "delegators of a validator that did not vote",
+			votes: Votes{
+				Powers:           map[string]uint64{"a": 60, "b": 40},
+				ValidatorVotes:   map[string]governancev1alpha1.Vote_Vote{"b": abstain},
+				DelegatorTallies: map[string]Tally{"a": {Yes: 10}},
+			},
+			want: Tally{Yes: 10, Abstain: 40},
+		},
+		{
+			name: "pending delegator votes excluded",
+			votes: Votes{
+				Powers:                  map[string]uint64{"a": 60},
+				ValidatorVotes:          map[string]governancev1alpha1.Vote_Vote{"a": yes},
+				PendingDelegatorTallies: map[string]Tally{"a": {No: 20}},
+			},
+			want: Tally{Yes: 60},
+		},
+		{
+			name: "pending delegator votes included",
+			votes: Votes{
+				Powers:                  map[string]uint64{"a": 60},
+				ValidatorVotes:          map[string]governancev1alpha1.Vote_Vote{"a": yes},
+				DelegatorTallies:        map[string]Tally{"a": {No: 5}},
+				PendingDelegatorTallies: map[string]Tally{"a": {No: 20}},
+			},
+			pending: true,
+			want:    Tally{Yes: 35, No: 25},
+		},
+		{
+			name: "inactive validator voted",
+			votes: Votes{
+				Powers:         map[string]uint64{"a": 60},
+				ValidatorVotes: map[string]governancev1alpha1.Vote_Vote{"b": yes},
+			},
+			err: true,
+		},
+		{
+			name: "delegators of an inactive validator voted",
+			votes: Votes{
+				Powers:           map[string]uint64{"a": 60},
+				DelegatorTallies: map[string]Tally{"b": {Yes: 1}},
+			},
+			err: true,
+		},
+		{
+			name: "delegators voted more than their validator's power",
+			votes: Votes{
+				Powers:           map[string]uint64{"a": 10},
+				ValidatorVotes:   map[string]governancev1alpha1.Vote_Vote{"a": yes},
+				DelegatorTallies: map[string]Tally{"a": {No: 11}},
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.votes.Tally(tt.pending)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: Tally succeeded", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Tally: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Tally = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVotesPredict(t *testing.T) {
+	v := &Votes{
+		Powers:                  map[string]uint64{"a": 70, "b": 30},
+		ValidatorVotes:          map[string]governancev1alpha1.Vote_Vote{"a": governancev1alpha1.Vote_VOTE_YES},
+		PendingDelegatorTallies: map[string]Tally{"a": {No: 3}},
+	}
+	p, err := v.Predict(defaultThresholds(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TotalPower != 100 || p.Tally != (Tally{Yes: 67, No: 3}) || p.Outcome != OutcomePass || !p.EmergencyPass {
+		t.Errorf("Predict = %+v", p)
+	}
+}
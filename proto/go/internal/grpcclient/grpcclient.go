@@ -0,0 +1,68 @@
+// Package grpcclient calls the methods of Penumbra's gRPC services by name.
+//
+// Only message types are generated for Go, so the client packages have no
+// service stubs: each of their methods names the gRPC method it calls, and
+// this package invokes it over a connection.
+package grpcclient
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Service is a gRPC service reached over a connection, such as one returned
+// by grpc.NewClient.
+type Service struct {
+	Conn grpc.ClientConnInterface
+	// Name is the full name of the service.
+	Name string
+}
+
+func (s Service) path(method string) string {
+	return "/" + s.Name + "/" + method
+}
+
+// Call invokes a unary method of the service, returning its response.
+func Call[T any](ctx context.Context, s Service, method string, req any) (*T, error) {
+	rsp := new(T)
+	if err := s.Conn.Invoke(ctx, s.path(method), req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// Stream is the stream of responses to a server-streaming request. Recv
+// returns io.EOF once all responses have been received.
+type Stream[T any] interface {
+	Recv() (*T, error)
+}
+
+// OpenStream sends a request to a server-streaming method of the service,
+// returning the stream of its responses.
+func OpenStream[T any](ctx context.Context, s Service, method string, req any) (Stream[T], error) {
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := s.Conn.NewStream(ctx, desc, s.path(method))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &clientStream[T]{stream}, nil
+}
+
+type clientStream[T any] struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream[T]) Recv() (*T, error) {
+	rsp := new(T)
+	if err := s.ClientStream.RecvMsg(rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
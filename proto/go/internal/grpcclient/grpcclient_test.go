@@ -0,0 +1,31 @@
+package grpcclient
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeConn answers every unary call with its method path.
+type fakeConn struct {
+	grpc.ClientConnInterface
+}
+
+func (fakeConn) Invoke(_ context.Context, method string, _, rsp any, _ ...grpc.CallOption) error {
+	proto.Merge(rsp.(proto.Message), wrapperspb.String(method))
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	s := Service{Conn: fakeConn{}, Name: "penumbra.test.v1.QueryService"}
+	rsp, err := Call[wrapperspb.StringValue](context.Background(), s, "Method", wrapperspb.String("req"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/penumbra.test.v1.QueryService/Method"; rsp.GetValue() != want {
+		t.Errorf("Call invoked %q, want %q", rsp.GetValue(), want)
+	}
+}
@@ -0,0 +1,101 @@
+// Package keys provides string encodings for Penumbra addresses and keys,
+// mirroring the `Display` and `FromStr` implementations in `penumbra_keys`
+// and `penumbra_stake`.
+package keys
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+)
+
+// AddressLenBytes is the length of an encoded address.
+const AddressLenBytes = 80
+
+// AddressNumCharsShortForm is the number of characters of the Bech32m data
+// shown in the short form of an address.
+const AddressNumCharsShortForm = 24
+
+// FormatAddress returns the Bech32m encoding of an address.
+func FormatAddress(a *keysv1alpha1.Address) string {
+	if len(a.GetInner()) == 0 && a.GetAltBech32M() != "" {
+		return a.GetAltBech32M()
+	}
+	return bech32str.Encode(a.GetInner(), bech32str.AddressPrefix, bech32str.Bech32m)
+}
+
+// ShortForm returns an abbreviated form of an address, for display.
+func ShortForm(a *keysv1alpha1.Address) string {
+	full := FormatAddress(a)
+	n := len(bech32str.AddressPrefix) + 1 + AddressNumCharsShortForm
+	if len(full) <= n {
+		return full
+	}
+	return full[:n] + "…"
+}
+
+// ParseAddress decodes a Bech32m address.
+func ParseAddress(s string) (*keysv1alpha1.Address, error) {
+	inner, err := bech32str.Decode(s, bech32str.AddressPrefix, bech32str.Bech32m)
+	if err != nil {
+		return nil, err
+	}
+	if len(inner) != AddressLenBytes {
+		return nil, fmt.Errorf("address has incorrect length %d", len(inner))
+	}
+	return &keysv1alpha1.Address{Inner: inner}, nil
+}
+
+// AddressEqual reports whether two addresses have the same encoding.
+func AddressEqual(a, b *keysv1alpha1.Address) bool {
+	return bytes.Equal(a.GetInner(), b.GetInner())
+}
+
+// AddressOf returns the address underlying an address view.
+func AddressOf(av *keysv1alpha1.AddressView) *keysv1alpha1.Address {
+	switch v := av.GetAddressView().(type) {
+	case *keysv1alpha1.AddressView_Visible_:
+		return v.Visible.GetAddress()
+	case *keysv1alpha1.AddressView_Opaque_:
+		return v.Opaque.GetAddress()
+	}
+	return nil
+}
+
+// AddressIndexOf returns the address index of a visible address view.
+func AddressIndexOf(av *keysv1alpha1.AddressView) (*keysv1alpha1.AddressIndex, bool) {
+	if v, ok := av.GetAddressView().(*keysv1alpha1.AddressView_Visible_); ok {
+		return v.Visible.GetIndex(), true
+	}
+	return nil, false
+}
+
+// FormatIdentityKey returns the Bech32m encoding of a validator identity key.
+func FormatIdentityKey(ik *keysv1alpha1.IdentityKey) string {
+	return bech32str.Encode(ik.GetIk(), bech32str.ValidatorIdentityKeyPrefix, bech32str.Bech32m)
+}
+
+// ParseIdentityKey decodes a Bech32m validator identity key.
+func ParseIdentityKey(s string) (*keysv1alpha1.IdentityKey, error) {
+	ik, err := bech32str.Decode(s, bech32str.ValidatorIdentityKeyPrefix, bech32str.Bech32m)
+	if err != nil {
+		return nil, err
+	}
+	return &keysv1alpha1.IdentityKey{Ik: ik}, nil
+}
+
+// FormatGovernanceKey returns the Bech32m encoding of a governance key.
+func FormatGovernanceKey(gk *keysv1alpha1.GovernanceKey) string {
+	return bech32str.Encode(gk.GetGk(), bech32str.ValidatorGovernanceKeyPrefix, bech32str.Bech32m)
+}
+
+// ParseGovernanceKey decodes a Bech32m governance key.
+func ParseGovernanceKey(s string) (*keysv1alpha1.GovernanceKey, error) {
+	gk, err := bech32str.Decode(s, bech32str.ValidatorGovernanceKeyPrefix, bech32str.Bech32m)
+	if err != nil {
+		return nil, err
+	}
+	return &keysv1alpha1.GovernanceKey{Gk: gk}, nil
+}
@@ -0,0 +1,67 @@
+package keys
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+)
+
+// Lengths of the components of an address.
+const (
+	DiversifierLenBytes     = 16
+	TransmissionKeyLenBytes = 32
+	ClueKeyLenBytes         = 32
+)
+
+// AddressComponents are the parts of an address: the diversifier selecting
+// its diversified basepoint, the transmission key notes are encrypted to,
+// and the clue key for fuzzy message detection.
+type AddressComponents struct {
+	Diversifier     []byte
+	TransmissionKey []byte
+	ClueKey         []byte
+}
+
+// Components unjumbles an address into its components. As in Rust, the
+// transmission key must be a canonical encoding of a field element.
+func Components(a *keysv1alpha1.Address) (*AddressComponents, error) {
+	if len(a.GetInner()) != AddressLenBytes {
+		return nil, errors.New("address malformed")
+	}
+	b, err := F4JumbleInv(a.GetInner())
+	if err != nil {
+		return nil, err
+	}
+	c := &AddressComponents{
+		Diversifier:     b[:DiversifierLenBytes],
+		TransmissionKey: b[DiversifierLenBytes : DiversifierLenBytes+TransmissionKeyLenBytes],
+		ClueKey:         b[DiversifierLenBytes+TransmissionKeyLenBytes:],
+	}
+	if _, ok := decaf377.DecodeScalar(c.TransmissionKey, decaf377.FqModulus); !ok {
+		return nil, errors.New("address has an invalid transmission key")
+	}
+	return c, nil
+}
+
+// DiversifiedGenerator returns the basepoint of the addresses with the given
+// diversifier, the hash of the diversifier to the group.
+func DiversifiedGenerator(diversifier []byte) *decaf377.Element {
+	sum := blake2b.Sum512("Penumbra_Divrsfy", diversifier)
+	return decaf377.EncodeToCurve(decaf377.ReduceScalar(sum[:], decaf377.FqModulus))
+}
+
+// Expand is the PRF of `penumbra_keys::prf::expand`: BLAKE2b-512 of input,
+// keyed with key and personalized with a 16-byte label.
+func Expand(label string, key, input []byte) [blake2b.Size]byte {
+	return blake2b.SumKeyed512(label, key, input)
+}
+
+// ExpandField reduces the output of Expand modulo a field order, as
+// `prf::expand_ff` does.
+func ExpandField(label string, key, input []byte, modulus *big.Int) *big.Int {
+	sum := Expand(label, key, input)
+	return decaf377.ReduceScalar(sum[:], modulus)
+}
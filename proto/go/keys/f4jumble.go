@@ -0,0 +1,72 @@
+package keys
+
+import (
+	"errors"
+
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+)
+
+// F4Jumble applies the F4Jumble permutation of ZIP 316 to a message of 48
+// to 4194368 bytes, as the `f4jumble` crate does. Addresses are encoded
+// jumbled, so that changing any part of an address changes all of its
+// characters.
+func F4Jumble(m []byte) ([]byte, error) {
+	a, b, err := split(m)
+	if err != nil {
+		return nil, err
+	}
+	x := xor(b, g(0, a, len(b)))
+	y := xor(a, h(0, x, len(a)))
+	d := xor(x, g(1, y, len(b)))
+	c := xor(y, h(1, d, len(a)))
+	return append(c, d...), nil
+}
+
+// F4JumbleInv inverts F4Jumble.
+func F4JumbleInv(m []byte) ([]byte, error) {
+	c, d, err := split(m)
+	if err != nil {
+		return nil, err
+	}
+	y := xor(c, h(1, d, len(c)))
+	x := xor(d, g(1, y, len(d)))
+	a := xor(y, h(0, x, len(c)))
+	b := xor(x, g(0, a, len(d)))
+	return append(a, b...), nil
+}
+
+func split(m []byte) ([]byte, []byte, error) {
+	if len(m) < 48 || len(m) > 4194368 {
+		return nil, nil, errors.New("f4jumble: invalid message length")
+	}
+	l := min(blake2b.Size, len(m)/2)
+	return m[:l], m[l:], nil
+}
+
+// h is the hash H_i of ZIP 316, with an n-byte output.
+func h(i byte, u []byte, n int) []byte {
+	hash, err := blake2b.New(n, "UA_F4Jumble_H"+string([]byte{i, 0, 0}))
+	if err != nil {
+		panic(err)
+	}
+	hash.Write(u)
+	return hash.Sum(nil)
+}
+
+// g is the hash G_i of ZIP 316, which expands its input to n bytes.
+func g(i byte, u []byte, n int) []byte {
+	out := make([]byte, 0, n+blake2b.Size)
+	for j := 0; len(out) < n; j++ {
+		sum := blake2b.Sum512("UA_F4Jumble_G"+string([]byte{i, byte(j), byte(j >> 8)}), u)
+		out = append(out, sum[:]...)
+	}
+	return out[:n]
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package keys
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+)
+
+// Addresses of the test wallet of `penumbra_keys::test_keys`.
+var testAddresses = []string{
+	"penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz",
+	"penumbra1vmmz304hjlkjq6xv4al5dqumvgk3ek82rneagj07vdqkudjvl6y7zxzr5k6qq24yc7yyyekpu9qm7ef3acg2u8p950hs6hu3e73guq5pfmmvm63qudfx4qmg8h7fdweyw3ektn",
+}
+
+func TestComponents(t *testing.T) {
+	for _, s := range testAddresses {
+		a, err := ParseAddress(s)
+		if err != nil {
+			t.Fatalf("ParseAddress(%s): %v", s, err)
+		}
+		c, err := Components(a)
+		if err != nil {
+			t.Fatalf("Components(%s): %v", s, err)
+		}
+		// Both keys of a real address are encodings of group elements.
+		if _, err := decaf377.Decode(c.TransmissionKey); err != nil {
+			t.Errorf("%s: transmission key: %v", s, err)
+		}
+		if _, err := decaf377.Decode(c.ClueKey); err != nil {
+			t.Errorf("%s: clue key: %v", s, err)
+		}
+		b := append(append(append([]byte{}, c.Diversifier...), c.TransmissionKey...), c.ClueKey...)
+		jumbled, err := F4Jumble(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(jumbled, a.GetInner()) {
+			t.Errorf("%s: F4Jumble does not invert F4JumbleInv", s)
+		}
+	}
+}
+
+func TestF4JumbleRoundTrip(t *testing.T) {
+	for _, n := range []int{48, 80, 129, 1000} {
+		m := make([]byte, n)
+		for i := range m {
+			m[i] = byte(i * 7)
+		}
+		jumbled, err := F4Jumble(m)
+		if err != nil {
+			t.Fatalf("F4Jumble(%d bytes): %v", n, err)
+		}
+		if bytes.Equal(jumbled, m) {
+			t.Errorf("F4Jumble(%d bytes) is the identity", n)
+		}
+		got, err := F4JumbleInv(jumbled)
+		if err != nil {
+			t.Fatalf("F4JumbleInv(%d bytes): %v", n, err)
+		}
+		if !bytes.Equal(got, m) {
+			t.Errorf("F4JumbleInv(F4Jumble(m)) != m for %d bytes", n)
+		}
+	}
+	if _, err := F4Jumble(make([]byte, 47)); err == nil {
+		t.Errorf("F4Jumble accepted a 47-byte message")
+	}
+}
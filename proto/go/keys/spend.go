@@ -0,0 +1,90 @@
+package keys
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377rdsa"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/poseidon377"
+)
+
+// SpendKeyLenBytes is the length of a spend key.
+const SpendKeyLenBytes = 32
+
+// ParseSpendKey decodes a Bech32m spend key, as found in pcli's custody
+// file.
+func ParseSpendKey(s string) ([]byte, error) {
+	sk, err := bech32str.Decode(s, bech32str.SpendKeyPrefix, bech32str.Bech32m)
+	if err != nil {
+		return nil, err
+	}
+	if len(sk) != SpendKeyLenBytes {
+		return nil, fmt.Errorf("spend key has incorrect length %d", len(sk))
+	}
+	return sk, nil
+}
+
+// SpendAuthKey derives the spend authorization key of a spend key, as
+// `SpendKey::from` does. Its verification key is the first half of the full
+// viewing key, and the identity key of a validator run from the wallet.
+func SpendAuthKey(spendKey []byte) (*decaf377rdsa.SigningKey, error) {
+	if len(spendKey) != SpendKeyLenBytes {
+		return nil, fmt.Errorf("spend key has incorrect length %d", len(spendKey))
+	}
+	return decaf377rdsa.NewSigningKey(ExpandField("Penumbra_ExpndSd", spendKey, []byte{0}, decaf377.FrModulus))
+}
+
+// FormatSpendKey returns the Bech32m encoding of a spend key, as written to
+// pcli's custody file.
+func FormatSpendKey(spendKey []byte) string {
+	return bech32str.Encode(spendKey, bech32str.SpendKeyPrefix, bech32str.Bech32m)
+}
+
+// ivkDomainSep is the domain separator of the incoming viewing key hash.
+var ivkDomainSep = decaf377.ReduceScalar([]byte("penumbra.derive.ivk"), decaf377.FqModulus)
+
+// PaymentAddress derives the address of an account of a spend key, as
+// `IncomingViewingKey::payment_address` does for a non-ephemeral address
+// index.
+func PaymentAddress(spendKey []byte, account uint32) (*keysv1alpha1.Address, error) {
+	ask, err := SpendAuthKey(spendKey)
+	if err != nil {
+		return nil, err
+	}
+	ak := ask.VerificationKey()
+	akS, ok := decaf377.DecodeScalar(ak, decaf377.FqModulus)
+	if !ok {
+		return nil, errors.New("spend verification key is not a field element")
+	}
+	nk := ExpandField("Penumbra_ExpndSd", spendKey, []byte{1}, decaf377.FqModulus)
+	nkBytes := decaf377.EncodeScalar(nk)
+	dk := Expand("Penumbra_DerivDK", nkBytes, ak)
+	ivk := new(big.Int).Mod(poseidon377.Hash(ivkDomainSep, nk, akS), decaf377.FrModulus)
+
+	// The diversifier is the address index, encrypted under the diversifier
+	// key.
+	block, err := aes.NewCipher(dk[:16])
+	if err != nil {
+		return nil, err
+	}
+	index := make([]byte, DiversifierLenBytes)
+	binary.LittleEndian.PutUint32(index, account)
+	d := make([]byte, DiversifierLenBytes)
+	block.Encrypt(d, index)
+
+	pkD := DiversifiedGenerator(d).ScalarMul(ivk).Encode()
+	dtk := ExpandField("PenumbraExpndFMD", decaf377.EncodeScalar(ivk), d, decaf377.FrModulus)
+	ckD := decaf377.Basepoint().ScalarMul(dtk).Encode()
+
+	inner, err := F4Jumble(append(append(d, pkD...), ckD...))
+	if err != nil {
+		return nil, err
+	}
+	return &keysv1alpha1.Address{Inner: inner}, nil
+}
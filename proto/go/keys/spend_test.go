@@ -0,0 +1,96 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// A spend key and its full viewing key, from the pclientd configuration
+// guide.
+const (
+	testSpendKey       = "penumbraspendkey1e9gf5g8jfraap4jqul7e80vv0zrnwpsm4ke0df38ejrfh430nu4s9gc22d"
+	testFullViewingKey = "penumbrafullviewingkey1f33fr3zrquh869s3h8d0pjx4fpa9fyut2utw7x5y7xdcxz6z7c8sgf5hslrkpf3mh8d26vufsq8y666chx0x0su06ay3rkwu74zuwqq9w8aza"
+)
+
+func TestSpendAuthKey(t *testing.T) {
+	sk, err := ParseSpendKey(testSpendKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ask, err := SpendAuthKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fvk, err := bech32str.Decode(testFullViewingKey, bech32str.FullViewingKeyPrefix, bech32str.Bech32m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The full viewing key is ak || nk.
+	if !bytes.Equal(ask.VerificationKey(), fvk[:32]) {
+		t.Errorf("spend verification key %x, want %x", ask.VerificationKey(), fvk[:32])
+	}
+
+	if _, err := ParseSpendKey(testFullViewingKey); err == nil {
+		t.Errorf("ParseSpendKey accepted a full viewing key")
+	}
+	if _, err := SpendAuthKey(sk[:31]); err == nil {
+		t.Errorf("SpendAuthKey accepted a short spend key")
+	}
+}
+
+// The test wallet of `penumbra_keys::test_keys`, and the addresses of its
+// first two accounts.
+const (
+	testSeedPhrase = "comfort ten front cycle churn burger oak absent rice ice urge result art couple benefit cabbage frequent obscure hurry trick segment cool job debate"
+	testAddress0   = "penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz"
+	testAddress1   = "penumbra1vmmz304hjlkjq6xv4al5dqumvgk3ek82rneagj07vdqkudjvl6y7zxzr5k6qq24yc7yyyekpu9qm7ef3acg2u8p950hs6hu3e73guq5pfmmvm63qudfx4qmg8h7fdweyw3ektn"
+)
+
+// secp256k1Order is the order of the curve of BIP-32 keys.
+var secp256k1Order, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// bip44SpendKey derives the spend key of the BIP-44 path m/44'/6532'/0', as
+// `SpendKey::from_seed_phrase_bip44` does. Every step of the path is
+// hardened, so the derivation needs no curve arithmetic.
+func bip44SpendKey(seedPhrase string) []byte {
+	seed := pbkdf2.Key([]byte(seedPhrase), []byte("mnemonic"), 2048, 64, sha512.New)
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+	key, chainCode := new(big.Int).SetBytes(i[:32]), i[32:]
+	for _, index := range []uint32{44, 6532, 0} {
+		data := append([]byte{0}, key.FillBytes(make([]byte, 32))...)
+		data = binary.BigEndian.AppendUint32(data, index|1<<31)
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		i := mac.Sum(nil)
+		key.Add(key, new(big.Int).SetBytes(i[:32])).Mod(key, secp256k1Order)
+		chainCode = i[32:]
+	}
+	return key.FillBytes(make([]byte, 32))
+}
+
+func TestPaymentAddress(t *testing.T) {
+	sk := bip44SpendKey(testSeedPhrase)
+	for account, want := range []string{testAddress0, testAddress1} {
+		addr, err := PaymentAddress(sk, uint32(account))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatAddress(addr); got != want {
+			t.Errorf("account %d: address %s, want %s", account, got, want)
+		}
+	}
+
+	parsed, err := ParseSpendKey(FormatSpendKey(sk))
+	if err != nil || !bytes.Equal(parsed, sk) {
+		t.Errorf("spend key did not round trip: %x, %v", parsed, err)
+	}
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the Prometheus metrics of a monitor, labeled by validator
+// identity key.
+type Metrics struct {
+	MissedBlocks      *prometheus.GaugeVec
+	BlocksUntilJailed *prometheus.GaugeVec
+	VotingPower       *prometheus.GaugeVec
+	// State is 1 for the validator's current state and 0 for the others.
+	State *prometheus.GaugeVec
+	// Bonding is 1 for the validator's current bonding state and 0 for the
+	// others.
+	Bonding *prometheus.GaugeVec
+	Errors  prometheus.Counter
+}
+
+// NewMetrics creates the metrics and registers them with reg, if set.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		MissedBlocks: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator",
+			Name:      "missed_blocks",
+			Help:      "Blocks missed in the uptime window.",
+		}, []string{"identity_key"}),
+		BlocksUntilJailed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator",
+			Name:      "blocks_until_jailed",
+			Help:      "Consecutive blocks the validator can miss before being jailed.",
+		}, []string{"identity_key"}),
+		VotingPower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator",
+			Name:      "voting_power",
+			Help:      "Voting power of the validator.",
+		}, []string{"identity_key"}),
+		State: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator",
+			Name:      "state",
+			Help:      "State of the validator.",
+		}, []string{"identity_key", "state"}),
+		Bonding: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator",
+			Name:      "bonding_state",
+			Help:      "Bonding state of the validator.",
+		}, []string{"identity_key", "state"}),
+		Errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "penumbra",
+			Subsystem: "validator_monitor",
+			Name:      "errors_total",
+			Help:      "Polls that failed.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(m.MissedBlocks, m.BlocksUntilJailed, m.VotingPower, m.State, m.Bonding, m.Errors)
+	}
+	return m
+}
+
+func (m *Metrics) observe(s *Status) {
+	m.VotingPower.WithLabelValues(s.IdentityKey).Set(float64(s.VotingPower))
+	for v, name := range stakev1alpha1.ValidatorState_ValidatorStateEnum_name {
+		m.State.WithLabelValues(s.IdentityKey, name).Set(oneIf(v == int32(s.State)))
+	}
+	for v, name := range stakev1alpha1.BondingState_BondingStateEnum_name {
+		m.Bonding.WithLabelValues(s.IdentityKey, name).Set(oneIf(v == int32(s.Bonding)))
+	}
+	if s.Uptime == nil {
+		m.MissedBlocks.DeleteLabelValues(s.IdentityKey)
+		m.BlocksUntilJailed.DeleteLabelValues(s.IdentityKey)
+		return
+	}
+	m.MissedBlocks.WithLabelValues(s.IdentityKey).Set(float64(s.MissedBlocks))
+	if s.CanBeJailed {
+		m.BlocksUntilJailed.WithLabelValues(s.IdentityKey).Set(float64(s.BlocksUntilJailed))
+	} else {
+		m.BlocksUntilJailed.DeleteLabelValues(s.IdentityKey)
+	}
+}
+
+func oneIf(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+// Package monitor watches the uptime and state of validators and raises
+// alerts before they are jailed.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/params"
+	"github.com/penumbra-zone/penumbra/proto/go/stake"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Status is the state of a validator at one poll.
+type Status struct {
+	IdentityKey string
+	State       stakev1alpha1.ValidatorState_ValidatorStateEnum
+	Bonding     stakev1alpha1.BondingState_BondingStateEnum
+	// UnbondingEpoch is the epoch the validator's delegations finish
+	// unbonding, if it is unbonding.
+	UnbondingEpoch uint64
+	VotingPower    uint64
+	// Uptime is nil if pd is not tracking the validator's uptime, which it
+	// only does for active validators.
+	Uptime              *stake.Uptime
+	MissedBlocks        uint64
+	MissedBlocksMaximum uint64
+	// BlocksUntilJailed is the number of consecutive blocks the validator
+	// can miss before being jailed, valid if CanBeJailed is set.
+	BlocksUntilJailed uint64
+	CanBeJailed       bool
+	// TimeUntilJailed estimates BlocksUntilJailed in time, zero if the
+	// monitor has no block time.
+	TimeUntilJailed time.Duration
+}
+
+// AlertKind is the reason for an alert.
+type AlertKind int
+
+const (
+	// AlertMissedBlock is raised when a validator misses more blocks than
+	// at the previous poll.
+	AlertMissedBlock AlertKind = iota
+	// AlertJailRisk is raised when a validator is within the monitor's
+	// warning distance of being jailed.
+	AlertJailRisk
+	// AlertStateChange is raised when a validator's state changes, e.g. when
+	// it is jailed.
+	AlertStateChange
+	// AlertBondingStateChange is raised when a validator's bonding state
+	// changes.
+	AlertBondingStateChange
+)
+
+var alertKindNames = map[AlertKind]string{
+	AlertMissedBlock:        "missed_block",
+	AlertJailRisk:           "jail_risk",
+	AlertStateChange:        "state_change",
+	AlertBondingStateChange: "bonding_state_change",
+}
+
+func (k AlertKind) String() string {
+	return alertKindNames[k]
+}
+
+// Alert is a notification about a validator.
+type Alert struct {
+	Kind    AlertKind
+	Status  *Status
+	Message string
+}
+
+// Notifier delivers alerts, e.g. to a chat channel or a pager.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, alert Alert) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+// Monitor polls the state of validators.
+type Monitor struct {
+	State cnidarium.QueryService
+	App   params.QueryService
+	// ChainId, if set, is checked by the fullnode when fetching parameters.
+	ChainId    string
+	Validators []*keysv1alpha1.IdentityKey
+	// Notifier, if set, receives alerts.
+	Notifier Notifier
+	// Metrics, if set, is updated after every poll.
+	Metrics *Metrics
+	// OnError, if set, is called with the error of every failed poll made
+	// by Run.
+	OnError func(error)
+	// Interval is the time between polls.
+	Interval time.Duration
+	// BlockTime, if set, is used to estimate the time until jailing.
+	BlockTime time.Duration
+	// WarnBlocks raises AlertJailRisk once a validator can miss at most
+	// this many more blocks.
+	WarnBlocks uint64
+
+	last map[string]*Status
+}
+
+// Run polls until the context is done. A failed poll is counted in the
+// metrics and passed to OnError, and polling continues; without OnError,
+// Run returns the error instead.
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Poll(ctx); err != nil {
+			if m.Metrics != nil {
+				m.Metrics.Errors.Inc()
+			}
+			if m.OnError == nil {
+				return err
+			}
+			m.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the status of every validator and raises alerts for the
+// changes since the last poll.
+func (m *Monitor) Poll(ctx context.Context) ([]*Status, error) {
+	app, err := params.Fetch(ctx, m.App, m.ChainId)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch stake parameters: %w", err)
+	}
+	missedBlocksMaximum := app.GetStakeParams().GetMissedBlocksMaximum()
+
+	if m.last == nil {
+		m.last = make(map[string]*Status)
+	}
+	var statuses []*Status
+	var errs []error
+	for _, ik := range m.Validators {
+		s, err := m.status(ctx, ik, missedBlocksMaximum)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		statuses = append(statuses, s)
+		if m.Metrics != nil {
+			m.Metrics.observe(s)
+		}
+		for _, alert := range m.alerts(m.last[s.IdentityKey], s) {
+			if m.Notifier == nil {
+				continue
+			}
+			if err := m.Notifier.Notify(ctx, alert); err != nil {
+				errs = append(errs, fmt.Errorf("could not send %s alert for %s: %w", alert.Kind, s.IdentityKey, err))
+			}
+		}
+		m.last[s.IdentityKey] = s
+	}
+	return statuses, errors.Join(errs...)
+}
+
+// FetchStatus fetches the status of a validator.
+func FetchStatus(ctx context.Context, state cnidarium.QueryService, ik *keysv1alpha1.IdentityKey, missedBlocksMaximum uint64) (*Status, error) {
+	return (&Monitor{State: state}).status(ctx, ik, missedBlocksMaximum)
+}
+
+func (m *Monitor) status(ctx context.Context, ik *keysv1alpha1.IdentityKey, missedBlocksMaximum uint64) (*Status, error) {
+	id := keys.FormatIdentityKey(ik)
+	s := &Status{IdentityKey: id, MissedBlocksMaximum: missedBlocksMaximum}
+
+	state, err := cnidarium.Get[*stakev1alpha1.ValidatorState](ctx, m.State, cnidarium.DefaultSchema.Key("staking/validator_state/{identity_key}", id))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch state of %s: %w", id, err)
+	}
+	s.State = state.GetState()
+
+	bonding, err := cnidarium.Get[*stakev1alpha1.BondingState](ctx, m.State, cnidarium.DefaultSchema.Key("staking/validator_bonding_state/{identity_key}", id))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch bonding state of %s: %w", id, err)
+	}
+	s.Bonding = bonding.GetState()
+	s.UnbondingEpoch = bonding.GetUnbondingEpoch()
+
+	power, err := cnidarium.Get[*wrapperspb.UInt64Value](ctx, m.State, cnidarium.DefaultSchema.Key("staking/validator_power/{identity_key}", id))
+	if err != nil && !errors.Is(err, cnidarium.ErrNotFound) {
+		return nil, fmt.Errorf("could not fetch voting power of %s: %w", id, err)
+	}
+	s.VotingPower = power.GetValue()
+
+	uptime, err := cnidarium.Get[*stakev1alpha1.Uptime](ctx, m.State, cnidarium.DefaultSchema.Key("staking/validator_uptime/{identity_key}", id))
+	if errors.Is(err, cnidarium.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch uptime of %s: %w", id, err)
+	}
+	if s.Uptime, err = stake.UptimeFromProto(uptime); err != nil {
+		return nil, fmt.Errorf("invalid uptime of %s: %w", id, err)
+	}
+	s.MissedBlocks = s.Uptime.NumMissedBlocks()
+	s.BlocksUntilJailed, s.CanBeJailed = s.Uptime.BlocksUntilJailed(missedBlocksMaximum)
+	if s.CanBeJailed {
+		s.TimeUntilJailed = time.Duration(s.BlocksUntilJailed) * m.BlockTime
+	}
+	return s, nil
+}
+
+// alerts compares a validator's status with the previous one, which is nil
+// at the first poll.
+func (m *Monitor) alerts(prev, s *Status) []Alert {
+	var alerts []Alert
+	if prev != nil && prev.State != s.State {
+		alerts = append(alerts, Alert{
+			Kind:    AlertStateChange,
+			Status:  s,
+			Message: fmt.Sprintf("validator %s changed state from %s to %s", s.IdentityKey, prev.State, s.State),
+		})
+	}
+	if prev != nil && prev.Bonding != s.Bonding {
+		msg := fmt.Sprintf("validator %s changed bonding state from %s to %s", s.IdentityKey, prev.Bonding, s.Bonding)
+		if s.Bonding == stakev1alpha1.BondingState_BONDING_STATE_ENUM_UNBONDING {
+			msg += fmt.Sprintf(", unbonding at epoch %d", s.UnbondingEpoch)
+		}
+		alerts = append(alerts, Alert{Kind: AlertBondingStateChange, Status: s, Message: msg})
+	}
+	if s.Uptime == nil {
+		return alerts
+	}
+	if prev != nil && prev.Uptime != nil && s.MissedBlocks > prev.MissedBlocks {
+		alerts = append(alerts, Alert{
+			Kind:    AlertMissedBlock,
+			Status:  s,
+			Message: fmt.Sprintf("validator %s has missed %d of the last %d blocks", s.IdentityKey, s.MissedBlocks, s.Uptime.WindowLen()),
+		})
+	}
+	atRisk := s.CanBeJailed && s.BlocksUntilJailed <= m.WarnBlocks
+	wasAtRisk := prev != nil && prev.CanBeJailed && prev.BlocksUntilJailed <= m.WarnBlocks
+	if atRisk && !wasAtRisk {
+		msg := fmt.Sprintf("validator %s will be jailed after missing %d more blocks", s.IdentityKey, s.BlocksUntilJailed)
+		if s.TimeUntilJailed > 0 {
+			msg += fmt.Sprintf(" (about %s)", s.TimeUntilJailed)
+		}
+		alerts = append(alerts, Alert{Kind: AlertJailRisk, Status: s, Message: msg})
+	}
+	return alerts
+}
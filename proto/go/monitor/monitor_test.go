@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/stake"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeState serves keys from a map.
+type fakeState map[string]proto.Message
+
+func (f fakeState) KeyValue(_ context.Context, req *cnidariumv1alpha1.KeyValueRequest) (*cnidariumv1alpha1.KeyValueResponse, error) {
+	rsp := &cnidariumv1alpha1.KeyValueResponse{}
+	if msg, ok := f[req.GetKey()]; ok {
+		value, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		rsp.Value = &cnidariumv1alpha1.KeyValueResponse_Value{Value: value}
+	}
+	return rsp, nil
+}
+
+func (f fakeState) PrefixValue(context.Context, *cnidariumv1alpha1.PrefixValueRequest) (grpcclient.Stream[cnidariumv1alpha1.PrefixValueResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
+// fakeApp serves stake parameters, or fails if err is set.
+type fakeApp struct {
+	missedBlocksMaximum uint64
+	err                 error
+}
+
+func (f *fakeApp) AppParameters(context.Context, *appv1alpha1.AppParametersRequest) (*appv1alpha1.AppParametersResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &appv1alpha1.AppParametersResponse{
+		AppParameters: &appv1alpha1.AppParameters{
+			StakeParams: &stakev1alpha1.StakeParameters{MissedBlocksMaximum: f.missedBlocksMaximum},
+		},
+	}, nil
+}
+
+func (f *fakeApp) TransactionsByHeight(context.Context, *appv1alpha1.TransactionsByHeightRequest) (*appv1alpha1.TransactionsByHeightResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+var testIk = &keysv1alpha1.IdentityKey{Ik: make([]byte, 32)}
+
+// setValidator writes a validator's state to the fake state. A nil uptime
+// leaves it untracked.
+func setValidator(state fakeState, s stakev1alpha1.ValidatorState_ValidatorStateEnum, power uint64, uptime *stake.Uptime) {
+	id := keys.FormatIdentityKey(testIk)
+	state[cnidarium.DefaultSchema.Key("staking/validator_state/{identity_key}", id)] = &stakev1alpha1.ValidatorState{State: s}
+	state[cnidarium.DefaultSchema.Key("staking/validator_bonding_state/{identity_key}", id)] = &stakev1alpha1.BondingState{
+		State: stakev1alpha1.BondingState_BONDING_STATE_ENUM_BONDED,
+	}
+	state[cnidarium.DefaultSchema.Key("staking/validator_power/{identity_key}", id)] = wrapperspb.UInt64(power)
+	uptimeKey := cnidarium.DefaultSchema.Key("staking/validator_uptime/{identity_key}", id)
+	delete(state, uptimeKey)
+	if uptime != nil {
+		state[uptimeKey] = uptime.Proto()
+	}
+}
+
+func alertKinds(alerts []Alert) []AlertKind {
+	var kinds []AlertKind
+	for _, a := range alerts {
+		kinds = append(kinds, a.Kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	// A window of 8 blocks that missed the last two; with a maximum of 4
+	// missed blocks, missing 2 more jails the validator.
+	uptime := stake.NewUptime(0, 8)
+	for h := uint64(1); h <= 8; h++ {
+		if err := uptime.MarkHeightAsSigned(h, h < 7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := fakeState{}
+	setValidator(state, stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE, 100, uptime)
+
+	var alerts []Alert
+	m := &Monitor{
+		State:      state,
+		App:        &fakeApp{missedBlocksMaximum: 4},
+		Validators: []*keysv1alpha1.IdentityKey{testIk},
+		Notifier: NotifierFunc(func(_ context.Context, a Alert) error {
+			alerts = append(alerts, a)
+			return nil
+		}),
+		Metrics:    NewMetrics(nil),
+		BlockTime:  5 * time.Second,
+		WarnBlocks: 2,
+	}
+	statuses, err := m.Poll(ctx)
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("Poll = %v, %v", statuses, err)
+	}
+	s := statuses[0]
+	if s.VotingPower != 100 || s.MissedBlocks != 2 || !s.CanBeJailed || s.BlocksUntilJailed != 2 || s.TimeUntilJailed != 10*time.Second {
+		t.Errorf("status = %+v", s)
+	}
+	if got, want := alertKinds(alerts), []AlertKind{AlertJailRisk}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first poll alerts = %v, want %v", got, want)
+	}
+
+	// The validator misses another block and is jailed: it is already at
+	// risk, so only the new miss and the state change are reported.
+	alerts = nil
+	if err := uptime.MarkHeightAsSigned(9, false); err != nil {
+		t.Fatal(err)
+	}
+	setValidator(state, stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_JAILED, 100, uptime)
+	if _, err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := alertKinds(alerts), []AlertKind{AlertMissedBlock, AlertStateChange}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second poll alerts = %v, want %v", got, want)
+	}
+
+	// A validator whose uptime is untracked raises no uptime alerts.
+	alerts = nil
+	setValidator(state, stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_JAILED, 0, nil)
+	statuses, err = m.Poll(ctx)
+	if err != nil || statuses[0].Uptime != nil || statuses[0].CanBeJailed {
+		t.Errorf("untracked uptime: got %+v, %v", statuses, err)
+	}
+	if len(alerts) != 0 {
+		t.Errorf("untracked uptime: got alerts %v", alertKinds(alerts))
+	}
+}
+
+func TestPollMissingValidator(t *testing.T) {
+	m := &Monitor{
+		State:      fakeState{},
+		App:        &fakeApp{missedBlocksMaximum: 4},
+		Validators: []*keysv1alpha1.IdentityKey{testIk},
+	}
+	if _, err := m.Poll(context.Background()); !errors.Is(err, cnidarium.ErrNotFound) {
+		t.Errorf("Poll of an unknown validator = %v, want ErrNotFound", err)
+	}
+}
+
+func errorCount(t *testing.T, m *Metrics) float64 {
+	var metric dto.Metric
+	if err := m.Errors.Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestRunErrors(t *testing.T) {
+	pollErr := errors.New("fullnode unavailable")
+
+	// Without OnError, Run stops at the first failed poll.
+	m := &Monitor{App: &fakeApp{err: pollErr}, Metrics: NewMetrics(nil), Interval: time.Millisecond}
+	if err := m.Run(context.Background()); !errors.Is(err, pollErr) {
+		t.Errorf("Run = %v, want the poll error", err)
+	}
+	if n := errorCount(t, m.Metrics); n != 1 {
+		t.Errorf("counted %v errors, want 1", n)
+	}
+
+	// With OnError, Run reports every failed poll and keeps going.
+	ctx, cancel := context.WithCancel(context.Background())
+	var reported []error
+	m = &Monitor{
+		App:      &fakeApp{err: pollErr},
+		Metrics:  NewMetrics(nil),
+		Interval: time.Millisecond,
+		OnError: func(err error) {
+			reported = append(reported, err)
+			if len(reported) == 3 {
+				cancel()
+			}
+		},
+	}
+	if err := m.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+	if len(reported) != 3 || !strings.Contains(reported[0].Error(), pollErr.Error()) {
+		t.Errorf("reported %v", reported)
+	}
+	if n := errorCount(t, m.Metrics); n != 3 {
+		t.Errorf("counted %v errors, want 3", n)
+	}
+}
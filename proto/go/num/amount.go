@@ -0,0 +1,218 @@
+// Package num provides exact 128-bit amount arithmetic, mirroring
+// `penumbra_num::Amount`.
+package num
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strings"
+
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+)
+
+// ErrOverflow is returned when an operation does not fit in 128 bits.
+var ErrOverflow = errors.New("amount overflow")
+
+// Amount is an unsigned 128-bit integer.
+type Amount struct {
+	hi, lo uint64
+}
+
+// Zero is the zero amount.
+var Zero = Amount{}
+
+// MaxAmount is the largest representable amount, 2^128 - 1.
+var MaxAmount = Amount{hi: ^uint64(0), lo: ^uint64(0)}
+
+// NewAmount returns the amount for a 64-bit value.
+func NewAmount(v uint64) Amount {
+	return Amount{lo: v}
+}
+
+// NewAmountHiLo returns the amount hi * 2^64 + lo.
+func NewAmountHiLo(hi, lo uint64) Amount {
+	return Amount{hi: hi, lo: lo}
+}
+
+// AmountFromProto converts a protobuf amount. A nil amount is zero.
+func AmountFromProto(a *numv1alpha1.Amount) Amount {
+	return Amount{hi: a.GetHi(), lo: a.GetLo()}
+}
+
+// Proto converts the amount to its protobuf representation.
+func (a Amount) Proto() *numv1alpha1.Amount {
+	return &numv1alpha1.Amount{Lo: a.lo, Hi: a.hi}
+}
+
+// Hi returns the high 64 bits.
+func (a Amount) Hi() uint64 { return a.hi }
+
+// Lo returns the low 64 bits.
+func (a Amount) Lo() uint64 { return a.lo }
+
+// AmountFromLEBytes decodes a 16-byte little-endian amount.
+func AmountFromLEBytes(b []byte) (Amount, error) {
+	if len(b) != 16 {
+		return Zero, fmt.Errorf("amount must be 16 bytes, got %d", len(b))
+	}
+	return Amount{lo: binary.LittleEndian.Uint64(b[0:8]), hi: binary.LittleEndian.Uint64(b[8:16])}, nil
+}
+
+// LEBytes encodes the amount as 16 little-endian bytes.
+func (a Amount) LEBytes() []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b[0:8], a.lo)
+	binary.LittleEndian.PutUint64(b[8:16], a.hi)
+	return b
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.hi == 0 && a.lo == 0
+}
+
+// Cmp compares a and b, returning -1, 0 or 1.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.hi < b.hi:
+		return -1
+	case a.hi > b.hi:
+		return 1
+	case a.lo < b.lo:
+		return -1
+	case a.lo > b.lo:
+		return 1
+	}
+	return 0
+}
+
+// CheckedAdd returns a + b, or false if the sum overflows.
+func (a Amount) CheckedAdd(b Amount) (Amount, bool) {
+	lo, carry := bits.Add64(a.lo, b.lo, 0)
+	hi, carry := bits.Add64(a.hi, b.hi, carry)
+	return Amount{hi: hi, lo: lo}, carry == 0
+}
+
+// CheckedSub returns a - b, or false if the difference underflows.
+func (a Amount) CheckedSub(b Amount) (Amount, bool) {
+	lo, borrow := bits.Sub64(a.lo, b.lo, 0)
+	hi, borrow := bits.Sub64(a.hi, b.hi, borrow)
+	return Amount{hi: hi, lo: lo}, borrow == 0
+}
+
+// CheckedMul returns a * b, or false if the product overflows.
+func (a Amount) CheckedMul(b Amount) (Amount, bool) {
+	if a.hi != 0 && b.hi != 0 {
+		return Zero, false
+	}
+	hi, lo := bits.Mul64(a.lo, b.lo)
+	// At most one of the cross terms is non-zero.
+	crossHi, cross := bits.Mul64(a.hi, b.lo)
+	if crossHi != 0 {
+		return Zero, false
+	}
+	crossHi2, cross2 := bits.Mul64(a.lo, b.hi)
+	if crossHi2 != 0 {
+		return Zero, false
+	}
+	hi, carry := bits.Add64(hi, cross, 0)
+	if carry != 0 {
+		return Zero, false
+	}
+	hi, carry = bits.Add64(hi, cross2, 0)
+	if carry != 0 {
+		return Zero, false
+	}
+	return Amount{hi: hi, lo: lo}, true
+}
+
+// QuoRem returns the quotient and remainder of a / b. It panics if b is zero.
+func (a Amount) QuoRem(b Amount) (Amount, Amount) {
+	if b.IsZero() {
+		panic("num: division by zero")
+	}
+	if a.hi == 0 && b.hi == 0 {
+		return Amount{lo: a.lo / b.lo}, Amount{lo: a.lo % b.lo}
+	}
+	q, r := new(big.Int).QuoRem(a.Big(), b.Big(), new(big.Int))
+	return mustFromBig(q), mustFromBig(r)
+}
+
+// Big returns the amount as a big integer.
+func (a Amount) Big() *big.Int {
+	v := new(big.Int).SetUint64(a.hi)
+	v.Lsh(v, 64)
+	return v.Or(v, new(big.Int).SetUint64(a.lo))
+}
+
+// AmountFromBig converts a big integer, which must be in [0, 2^128).
+func AmountFromBig(v *big.Int) (Amount, error) {
+	if v.Sign() < 0 || v.BitLen() > 128 {
+		return Zero, ErrOverflow
+	}
+	lo := new(big.Int).And(v, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	hi := new(big.Int).Rsh(v, 64).Uint64()
+	return Amount{hi: hi, lo: lo}, nil
+}
+
+func mustFromBig(v *big.Int) Amount {
+	a, err := AmountFromBig(v)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Pow10 returns 10^n, or false if it does not fit in 128 bits.
+func Pow10(n uint32) (Amount, bool) {
+	v := NewAmount(1)
+	ten := NewAmount(10)
+	for i := uint32(0); i < n; i++ {
+		var ok bool
+		if v, ok = v.CheckedMul(ten); !ok {
+			return Zero, false
+		}
+	}
+	return v, true
+}
+
+// ParseAmount parses a decimal amount. Underscores are accepted as digit
+// separators, as in Rust integer literals (e.g. `10_000_000__000_000`).
+func ParseAmount(s string) (Amount, error) {
+	digits := strings.ReplaceAll(s, "_", "")
+	if digits == "" {
+		return Zero, fmt.Errorf("invalid amount %q", s)
+	}
+	v, ok := new(big.Int).SetString(digits, 10)
+	if !ok || strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		return Zero, fmt.Errorf("invalid amount %q", s)
+	}
+	return AmountFromBig(v)
+}
+
+// String formats the amount in decimal.
+func (a Amount) String() string {
+	if a.hi == 0 {
+		return fmt.Sprintf("%d", a.lo)
+	}
+	return a.Big().String()
+}
+
+// MarshalText formats the amount in decimal, so that it is saved as a
+// string in JSON.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText parses a decimal amount.
+func (a *Amount) UnmarshalText(text []byte) error {
+	v, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
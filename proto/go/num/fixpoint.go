@@ -0,0 +1,156 @@
+package num
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrUnderflow is returned when a fixed-point subtraction is negative.
+	ErrUnderflow = errors.New("fixed-point underflow")
+	// ErrDivisionByZero is returned when dividing by a zero fixed-point
+	// number.
+	ErrDivisionByZero = errors.New("fixed-point division by zero")
+	// ErrNonIntegral is returned when converting a fixed-point number with a
+	// fractional part to an amount.
+	ErrNonIntegral = errors.New("fixed-point number is not integral")
+)
+
+// U128x128Len is the length of an encoded U128x128.
+const U128x128Len = 32
+
+// one128 is 1 in U128x128.
+var one128 = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// max256 is the largest U128x128, 2^256 - 1.
+var max256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// U128x128 is an unsigned fixed-point number with 128 integral and 128
+// fractional bits, mirroring `penumbra_num::fixpoint::U128x128`. Arithmetic
+// is exact, rounding down as the Rust type does. The zero value is zero.
+type U128x128 struct {
+	// v is the number times 2^128, or nil for zero.
+	v *big.Int
+}
+
+// U128x128FromAmount returns the fixed-point number equal to an amount.
+func U128x128FromAmount(a Amount) U128x128 {
+	return U128x128{v: a.Big().Lsh(a.Big(), 128)}
+}
+
+// Ratio returns numerator / denominator, rounded down.
+func Ratio(numerator, denominator Amount) (U128x128, error) {
+	return U128x128FromAmount(numerator).Div(U128x128FromAmount(denominator))
+}
+
+// U128x128FromBytes decodes 32 big-endian bytes.
+func U128x128FromBytes(b []byte) (U128x128, error) {
+	if len(b) != U128x128Len {
+		return U128x128{}, fmt.Errorf("fixed-point number has length %d, expected %d", len(b), U128x128Len)
+	}
+	return U128x128{v: new(big.Int).SetBytes(b)}, nil
+}
+
+// Bytes encodes the number as 32 big-endian bytes, which sort as the numbers
+// do.
+func (x U128x128) Bytes() []byte {
+	return x.big().FillBytes(make([]byte, U128x128Len))
+}
+
+func (x U128x128) big() *big.Int {
+	if x.v == nil {
+		return new(big.Int)
+	}
+	return x.v
+}
+
+func checked(v *big.Int) (U128x128, error) {
+	if v.Cmp(max256) > 0 {
+		return U128x128{}, ErrOverflow
+	}
+	return U128x128{v: v}, nil
+}
+
+// Add returns x + y.
+func (x U128x128) Add(y U128x128) (U128x128, error) {
+	return checked(new(big.Int).Add(x.big(), y.big()))
+}
+
+// Sub returns x - y.
+func (x U128x128) Sub(y U128x128) (U128x128, error) {
+	if x.Cmp(y) < 0 {
+		return U128x128{}, ErrUnderflow
+	}
+	return U128x128{v: new(big.Int).Sub(x.big(), y.big())}, nil
+}
+
+// Mul returns x * y, rounded down.
+func (x U128x128) Mul(y U128x128) (U128x128, error) {
+	v := new(big.Int).Mul(x.big(), y.big())
+	return checked(v.Rsh(v, 128))
+}
+
+// Div returns x / y, rounded down.
+func (x U128x128) Div(y U128x128) (U128x128, error) {
+	if y.IsZero() {
+		return U128x128{}, ErrDivisionByZero
+	}
+	v := new(big.Int).Lsh(x.big(), 128)
+	return checked(v.Quo(v, y.big()))
+}
+
+// Cmp compares x and y, returning -1, 0 or 1.
+func (x U128x128) Cmp(y U128x128) int {
+	return x.big().Cmp(y.big())
+}
+
+// IsZero reports whether x is zero.
+func (x U128x128) IsZero() bool {
+	return x.big().Sign() == 0
+}
+
+// IsIntegral reports whether x has no fractional part.
+func (x U128x128) IsIntegral() bool {
+	return new(big.Int).Mod(x.big(), one128).Sign() == 0
+}
+
+// RoundDown returns x without its fractional part.
+func (x U128x128) RoundDown() U128x128 {
+	v := new(big.Int).Rsh(x.big(), 128)
+	return U128x128{v: v.Lsh(v, 128)}
+}
+
+// RoundUp returns the smallest integer at least x.
+func (x U128x128) RoundUp() (U128x128, error) {
+	if x.IsIntegral() {
+		return x, nil
+	}
+	return x.RoundDown().Add(U128x128{v: one128})
+}
+
+// Amount converts an integral number to an amount.
+func (x U128x128) Amount() (Amount, error) {
+	if !x.IsIntegral() {
+		return Zero, ErrNonIntegral
+	}
+	return AmountFromBig(new(big.Int).Rsh(x.big(), 128))
+}
+
+// Float64 approximates x.
+func (x U128x128) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(x.big(), one128).Float64()
+	return f
+}
+
+// String formats x in decimal, with up to 18 fractional digits.
+func (x U128x128) String() string {
+	s := new(big.Rat).SetFrac(x.big(), one128).FloatString(18)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
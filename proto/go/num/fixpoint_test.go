@@ -0,0 +1,102 @@
+package num
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func fp(v uint64) U128x128 {
+	return U128x128FromAmount(NewAmount(v))
+}
+
+func TestU128x128Ops(t *testing.T) {
+	third, err := Ratio(NewAmount(1), NewAmount(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(third.Bytes()); got != "0000000000000000000000000000000055555555555555555555555555555555" {
+		t.Errorf("1/3 encodes as %s", got)
+	}
+	if got := third.String(); got != "0.333333333333333333" {
+		t.Errorf("1/3 formats as %s", got)
+	}
+
+	// Multiplication rounds down, so 1/3 * 3 is just below 1.
+	almostOne, err := third.Mul(fp(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(almostOne.Bytes()); got != "00000000000000000000000000000000ffffffffffffffffffffffffffffffff" {
+		t.Errorf("1/3 * 3 = %s", got)
+	}
+	if !almostOne.RoundDown().IsZero() {
+		t.Errorf("1/3 * 3 rounds down to %s", almostOne.RoundDown())
+	}
+	if up, _ := almostOne.RoundUp(); up.Cmp(fp(1)) != 0 {
+		t.Errorf("1/3 * 3 rounds up to %s", up)
+	}
+	if _, err := almostOne.Amount(); !errors.Is(err, ErrNonIntegral) {
+		t.Errorf("Amount of a fraction = %v", err)
+	}
+	if a, err := fp(7).Amount(); err != nil || a != NewAmount(7) {
+		t.Errorf("Amount(7) = %s, %v", a, err)
+	}
+
+	sum, _ := third.Add(third)
+	if diff, _ := sum.Sub(third); diff.Cmp(third) != 0 {
+		t.Errorf("2/3 - 1/3 = %s", diff)
+	}
+	if _, err := third.Sub(sum); !errors.Is(err, ErrUnderflow) {
+		t.Errorf("1/3 - 2/3 = %v", err)
+	}
+	if _, err := third.Div(U128x128{}); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("division by zero = %v", err)
+	}
+	if q, _ := fp(10).Div(fp(4)); q.String() != "2.5" {
+		t.Errorf("10 / 4 = %s", q)
+	}
+}
+
+// TestU128x128Overflow follows `multiply_large_failure` in
+// `penumbra_num::fixpoint`.
+func TestU128x128Overflow(t *testing.T) {
+	a, _ := ParseAmount("1788000000000000000000")
+	b, _ := ParseAmount("1000000000000000000000")
+	if _, err := U128x128FromAmount(a).Mul(U128x128FromAmount(b)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("large multiplication = %v, want ErrOverflow", err)
+	}
+	max := U128x128FromAmount(NewAmountHiLo(^uint64(0), ^uint64(0)))
+	if _, err := max.RoundDown().Add(fp(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("2^128 - 1 + 1 = %v, want ErrOverflow", err)
+	}
+	if _, err := fp(1).Div(U128x128{v: big.NewInt(1)}); !errors.Is(err, ErrOverflow) {
+		t.Errorf("1 / 2^-128 = %v, want ErrOverflow", err)
+	}
+}
+
+func TestU128x128Encoding(t *testing.T) {
+	values := []U128x128{{}, must(Ratio(NewAmount(1), NewAmount(3))), fp(1), must(Ratio(NewAmount(7), NewAmount(2))), fp(1 << 40)}
+	for i, x := range values {
+		decoded, err := U128x128FromBytes(x.Bytes())
+		if err != nil || decoded.Cmp(x) != 0 {
+			t.Errorf("U128x128FromBytes(%s) = %s, %v", x, decoded, err)
+		}
+		// The encoding sorts as the numbers do.
+		if i > 0 && bytes.Compare(values[i-1].Bytes(), x.Bytes()) >= 0 {
+			t.Errorf("%s does not sort before %s", values[i-1], x)
+		}
+	}
+	if _, err := U128x128FromBytes(make([]byte, 31)); err == nil {
+		t.Errorf("U128x128FromBytes accepted 31 bytes")
+	}
+}
+
+func must(x U128x128, err error) U128x128 {
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
@@ -0,0 +1,84 @@
+package params
+
+import (
+	"fmt"
+	"strings"
+
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldChange is a change to a single parameter.
+type FieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// Diff returns the parameters that differ between two messages of the same
+// type, in field order. Components that are unset in both are skipped.
+func Diff(old, new proto.Message) []FieldChange {
+	var changes []FieldChange
+	var walk func(prefix string, a, b protoreflect.Message)
+	walk = func(prefix string, a, b protoreflect.Message) {
+		fields := a.Descriptor().Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if fd.IsList() || fd.IsMap() {
+				continue
+			}
+			path := prefix + string(fd.Name())
+			if !isLeaf(fd) {
+				if a.Has(fd) || b.Has(fd) {
+					walk(path+".", a.Get(fd).Message(), b.Get(fd).Message())
+				}
+				continue
+			}
+			oldValue, newValue := formatValue(fd, a.Get(fd)), formatValue(fd, b.Get(fd))
+			if oldValue != newValue {
+				changes = append(changes, FieldChange{Path: path, Old: oldValue, New: newValue})
+			}
+		}
+	}
+	walk("", old.ProtoReflect(), new.ProtoReflect())
+	return changes
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.Message() != nil:
+		a, _ := v.Message().Interface().(*numv1alpha1.Amount)
+		return num.AmountFromProto(a).String()
+	case fd.Kind() == protoreflect.StringKind:
+		return fmt.Sprintf("%q", v.String())
+	default:
+		return v.String()
+	}
+}
+
+// DiffSet returns the parameters changed by a parameter change, such as one
+// recorded by the app for an enacted proposal. The old parameters must be
+// complete, and unset components of the new parameters are unchanged.
+func DiffSet(set *governancev1alpha1.ChangedAppParametersSet) ([]FieldChange, error) {
+	old, err := FromChanged(set.GetOld(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old parameters: %w", err)
+	}
+	updated, err := FromChanged(set.GetNew(), old)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(old, updated), nil
+}
+
+// FormatDiff formats changes one per line, as `path: old -> new`.
+func FormatDiff(changes []FieldChange) string {
+	var b strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&b, "%s: %s -> %s\n", c.Path, c.Old, c.New)
+	}
+	return b.String()
+}
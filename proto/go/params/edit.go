@@ -0,0 +1,123 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrUnknownPath is returned when editing a parameter that does not exist.
+var ErrUnknownPath = errors.New("unknown parameter")
+
+var amountName = (&numv1alpha1.Amount{}).ProtoReflect().Descriptor().FullName()
+
+// isLeaf reports whether a field holds a single parameter value.
+func isLeaf(fd protoreflect.FieldDescriptor) bool {
+	return fd.Message() == nil || fd.Message().FullName() == amountName
+}
+
+// Paths returns the dotted paths of every parameter in msg, such as
+// `stake_params.unbonding_epochs` for app parameters.
+func Paths(msg proto.Message) []string {
+	var paths []string
+	var walk func(prefix string, md protoreflect.MessageDescriptor)
+	walk = func(prefix string, md protoreflect.MessageDescriptor) {
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if fd.IsList() || fd.IsMap() {
+				continue
+			}
+			path := prefix + string(fd.Name())
+			if isLeaf(fd) {
+				paths = append(paths, path)
+			} else {
+				walk(path+".", fd.Message())
+			}
+		}
+	}
+	walk("", msg.ProtoReflect().Descriptor())
+	return paths
+}
+
+// field finds the field of md named by its proto or JSON name.
+func field(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return md.Fields().ByJSONName(name)
+}
+
+// Set parses value and assigns it to the parameter at the dotted path in
+// msg, such as `stake_params.unbonding_epochs`. Path segments may use proto
+// or JSON field names. Integers and amounts may contain underscores, and
+// ratios are written as `numerator/denominator`.
+func Set(msg proto.Message, path, value string) error {
+	m := msg.ProtoReflect()
+	segments := strings.Split(path, ".")
+	for i, name := range segments {
+		fd := field(m.Descriptor(), name)
+		if fd == nil || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("%w %q, expected one of: %s", ErrUnknownPath, path, strings.Join(Paths(msg), ", "))
+		}
+		if i < len(segments)-1 {
+			if isLeaf(fd) {
+				return fmt.Errorf("%w %q, expected one of: %s", ErrUnknownPath, path, strings.Join(Paths(msg), ", "))
+			}
+			m = m.Mutable(fd).Message()
+			continue
+		}
+		if !isLeaf(fd) {
+			return fmt.Errorf("%w %q, expected one of: %s", ErrUnknownPath, path, strings.Join(Paths(msg), ", "))
+		}
+		v, err := parseValue(fd, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", path, err)
+		}
+		m.Set(fd, v)
+	}
+	return nil
+}
+
+func parseValue(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	if fd.Message() != nil {
+		a, err := num.ParseAmount(value)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfMessage(a.Proto().ProtoReflect()), nil
+	}
+	digits := strings.ReplaceAll(value, "_", "")
+	switch fd.Kind() {
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(digits, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(digits, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(digits, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(digits, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.StringKind:
+		if strings.HasSuffix(string(fd.Name()), "_quorum") || strings.HasSuffix(string(fd.Name()), "_threshold") {
+			if _, err := ParseRatio(value); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return protoreflect.ValueOfString(value), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("parameters of kind %s cannot be edited", fd.Kind())
+	}
+}
@@ -0,0 +1,252 @@
+// Package params reads, edits and validates Penumbra's app parameters, and
+// builds parameter change proposals, mirroring `penumbra_app::params`.
+package params
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// QueryServiceName is the full name of the app's QueryService.
+const QueryServiceName = "penumbra.core.app.v1alpha1.QueryService"
+
+// QueryService is the app's QueryService API.
+type QueryService interface {
+	AppParameters(ctx context.Context, req *appv1alpha1.AppParametersRequest) (*appv1alpha1.AppParametersResponse, error)
+	TransactionsByHeight(ctx context.Context, req *appv1alpha1.TransactionsByHeightRequest) (*appv1alpha1.TransactionsByHeightResponse, error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) AppParameters(ctx context.Context, req *appv1alpha1.AppParametersRequest) (*appv1alpha1.AppParametersResponse, error) {
+	return grpcclient.Call[appv1alpha1.AppParametersResponse](ctx, s.svc, "AppParameters", req)
+}
+
+func (s *grpcQueryService) TransactionsByHeight(ctx context.Context, req *appv1alpha1.TransactionsByHeightRequest) (*appv1alpha1.TransactionsByHeightResponse, error) {
+	return grpcclient.Call[appv1alpha1.TransactionsByHeightResponse](ctx, s.svc, "TransactionsByHeight", req)
+}
+
+// Fetch returns the current app parameters. If chainId is non-empty, the
+// fullnode checks that it serves that chain.
+func Fetch(ctx context.Context, q QueryService, chainId string) (*appv1alpha1.AppParameters, error) {
+	rsp, err := q.AppParameters(ctx, &appv1alpha1.AppParametersRequest{ChainId: chainId})
+	if err != nil {
+		return nil, err
+	}
+	if rsp.GetAppParameters() == nil {
+		return nil, errors.New("response is missing app parameters")
+	}
+	return rsp.GetAppParameters(), nil
+}
+
+// AsChanged converts complete app parameters to changed app parameters with
+// every component set.
+func AsChanged(p *appv1alpha1.AppParameters) *governancev1alpha1.ChangedAppParameters {
+	p = proto.Clone(p).(*appv1alpha1.AppParameters)
+	return &governancev1alpha1.ChangedAppParameters{
+		ChainParams:         p.ChainParams,
+		DaoParams:           p.DaoParams,
+		GovernanceParams:    p.GovernanceParams,
+		IbcParams:           p.IbcParams,
+		StakeParams:         p.StakeParams,
+		FeeParams:           p.FeeParams,
+		DistributionsParams: p.DistributionsParams,
+	}
+}
+
+// FromChanged converts sparse changed app parameters to complete app
+// parameters, taking the components that are not set from old. If old is
+// nil, every component must be set.
+func FromChanged(changed *governancev1alpha1.ChangedAppParameters, old *appv1alpha1.AppParameters) (*appv1alpha1.AppParameters, error) {
+	if old == nil && (changed.GetChainParams() == nil ||
+		changed.GetStakeParams() == nil ||
+		changed.GetIbcParams() == nil ||
+		changed.GetGovernanceParams() == nil ||
+		changed.GetFeeParams() == nil ||
+		changed.GetDaoParams() == nil ||
+		changed.GetDistributionsParams() == nil) {
+		return nil, errors.New("all parameters must be specified if no old parameters are provided")
+	}
+	p := new(appv1alpha1.AppParameters)
+	if old != nil {
+		p = proto.Clone(old).(*appv1alpha1.AppParameters)
+	}
+	changed = proto.Clone(changed).(*governancev1alpha1.ChangedAppParameters)
+	if changed.ChainParams != nil {
+		p.ChainParams = changed.ChainParams
+	}
+	if changed.DaoParams != nil {
+		p.DaoParams = changed.DaoParams
+	}
+	if changed.GovernanceParams != nil {
+		p.GovernanceParams = changed.GovernanceParams
+	}
+	if changed.IbcParams != nil {
+		p.IbcParams = changed.IbcParams
+	}
+	if changed.StakeParams != nil {
+		p.StakeParams = changed.StakeParams
+	}
+	if changed.FeeParams != nil {
+		p.FeeParams = changed.FeeParams
+	}
+	if changed.DistributionsParams != nil {
+		p.DistributionsParams = changed.DistributionsParams
+	}
+	return p, nil
+}
+
+// checkAll returns an error describing every failed check, as pd does.
+func checkAll(checks []check) error {
+	var failed []string
+	for _, c := range checks {
+		if !c.ok {
+			failed = append(failed, c.description)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("invalid chain parameters: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+type check struct {
+	ok          bool
+	description string
+}
+
+// CheckValid checks app parameters against the constraints enforced by pd
+// on parameter changes.
+func CheckValid(p *appv1alpha1.AppParameters) error {
+	chain := p.GetChainParams()
+	stake := p.GetStakeParams()
+	ibc := p.GetIbcParams()
+	gov := p.GetGovernanceParams()
+
+	quorum, err := ParseRatio(gov.GetProposalValidQuorum())
+	if err != nil {
+		return fmt.Errorf("invalid proposal valid quorum: %w", err)
+	}
+	pass, err := ParseRatio(gov.GetProposalPassThreshold())
+	if err != nil {
+		return fmt.Errorf("invalid proposal pass threshold: %w", err)
+	}
+	slash, err := ParseRatio(gov.GetProposalSlashThreshold())
+	if err != nil {
+		return fmt.Errorf("invalid proposal slash threshold: %w", err)
+	}
+	half := NewRatio(1, 2)
+
+	return checkAll([]check{
+		{chain.GetChainId() != "", "chain ID must be a non-empty string"},
+		{chain.GetEpochDuration() >= 1, "epoch duration must be at least one block"},
+		{stake.GetUnbondingEpochs() >= 1, "unbonding must take at least one epoch"},
+		{stake.GetActiveValidatorLimit() > 3, "active validator limit must be at least 4"},
+		{stake.GetBaseRewardRate() >= 1, "base reward rate must be at least 1 basis point"},
+		{stake.GetSlashingPenaltyMisbehavior() >= 1, "slashing penalty (misbehavior) must be at least 1 basis point"},
+		{stake.GetSlashingPenaltyMisbehavior() <= 100_000_000, "slashing penalty (misbehavior) must be at most 10,000 basis points^2"},
+		{stake.GetSlashingPenaltyDowntime() >= 1, "slashing penalty (downtime) must be at least 1 basis point"},
+		{stake.GetSlashingPenaltyDowntime() <= 100_000_000, "slashing penalty (downtime) must be at most 10,000 basis points^2"},
+		{stake.GetSignedBlocksWindowLen() >= 2, "signed blocks window length must be at least 2"},
+		{stake.GetMissedBlocksMaximum() >= 1, "missed blocks maximum must be at least 1"},
+		{
+			(!ibc.GetInboundIcs20TransfersEnabled() && !ibc.GetOutboundIcs20TransfersEnabled()) || ibc.GetIbcEnabled(),
+			"IBC must be enabled if either inbound or outbound ICS20 transfers are enabled",
+		},
+		{gov.GetProposalVotingBlocks() >= 1, "proposal voting blocks must be at least 1"},
+		{!num.AmountFromProto(gov.GetProposalDepositAmount()).IsZero(), "proposal deposit amount must be at least 1"},
+		{quorum.Cmp(NewRatio(0, 1)) > 0, "proposal valid quorum must be greater than 0"},
+		{pass.Cmp(half) >= 0, "proposal pass threshold must be greater than or equal to 1/2"},
+		{slash.Cmp(half) > 0, "proposal slash threshold must be greater than 1/2"},
+	})
+}
+
+// CheckValidUpdate checks that new parameters are valid, and that they do
+// not change the parameters that pd does not allow governance to change.
+func CheckValidUpdate(old, new *appv1alpha1.AppParameters) error {
+	if err := CheckValid(new); err != nil {
+		return err
+	}
+	ratiosEqual := func(a, b string) bool {
+		ra, errA := ParseRatio(a)
+		rb, errB := ParseRatio(b)
+		return errA == nil && errB == nil && ra.Cmp(rb) == 0
+	}
+	oldGov, newGov := old.GetGovernanceParams(), new.GetGovernanceParams()
+	return checkAll([]check{
+		{old.GetChainParams().GetChainId() == new.GetChainParams().GetChainId(), "chain ID can't be changed"},
+		{old.GetChainParams().GetEpochDuration() == new.GetChainParams().GetEpochDuration(), "epoch duration can't be changed"},
+		{old.GetStakeParams().GetActiveValidatorLimit() == new.GetStakeParams().GetActiveValidatorLimit(), "active validator limit can't be changed"},
+		{old.GetStakeParams().GetSignedBlocksWindowLen() == new.GetStakeParams().GetSignedBlocksWindowLen(), "signed blocks window length can't be changed"},
+		{ratiosEqual(oldGov.GetProposalValidQuorum(), newGov.GetProposalValidQuorum()), "proposal valid quorum can't be changed"},
+		{ratiosEqual(oldGov.GetProposalPassThreshold(), newGov.GetProposalPassThreshold()), "proposal pass threshold can't be changed"},
+		{ratiosEqual(oldGov.GetProposalSlashThreshold(), newGov.GetProposalSlashThreshold()), "proposal slash threshold can't be changed"},
+	})
+}
+
+// NewParameterChange builds the payload of a parameter change proposal
+// applying edits to the current parameters. Edits are of the form
+// `path=value`, as described in Set. The old parameters are complete, as
+// pd requires, and the new parameters only include the components changed
+// by the edits.
+func NewParameterChange(current *appv1alpha1.AppParameters, edits ...string) (*governancev1alpha1.Proposal_ParameterChange, error) {
+	updated := proto.Clone(current).(*appv1alpha1.AppParameters)
+	for _, edit := range edits {
+		path, value, ok := strings.Cut(edit, "=")
+		if !ok {
+			return nil, fmt.Errorf("edit %q is not of the form path=value", edit)
+		}
+		if err := Set(updated, strings.TrimSpace(path), strings.TrimSpace(value)); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckValidUpdate(current, updated); err != nil {
+		return nil, err
+	}
+
+	old := AsChanged(current)
+	all := AsChanged(updated)
+	changed := new(governancev1alpha1.ChangedAppParameters)
+	if !proto.Equal(old.ChainParams, all.ChainParams) {
+		changed.ChainParams = all.ChainParams
+	}
+	if !proto.Equal(old.DaoParams, all.DaoParams) {
+		changed.DaoParams = all.DaoParams
+	}
+	if !proto.Equal(old.GovernanceParams, all.GovernanceParams) {
+		changed.GovernanceParams = all.GovernanceParams
+	}
+	if !proto.Equal(old.IbcParams, all.IbcParams) {
+		changed.IbcParams = all.IbcParams
+	}
+	if !proto.Equal(old.StakeParams, all.StakeParams) {
+		changed.StakeParams = all.StakeParams
+	}
+	if !proto.Equal(old.FeeParams, all.FeeParams) {
+		changed.FeeParams = all.FeeParams
+	}
+	if !proto.Equal(old.DistributionsParams, all.DistributionsParams) {
+		changed.DistributionsParams = all.DistributionsParams
+	}
+	return &governancev1alpha1.Proposal_ParameterChange{
+		OldParameters: old,
+		NewParameters: changed,
+	}, nil
+}
@@ -0,0 +1,285 @@
+package params
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	daov1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dao/v1alpha1"
+	distributionsv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/distributions/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	ibcv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/ibc/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"google.golang.org/protobuf/proto"
+)
+
+// testParams returns pd's default app parameters.
+func testParams() *appv1alpha1.AppParameters {
+	return &appv1alpha1.AppParameters{
+		ChainParams: &chainv1alpha1.ChainParameters{ChainId: "penumbra-testnet", EpochDuration: 719},
+		DaoParams:   &daov1alpha1.DaoParameters{DaoSpendProposalsEnabled: true},
+		GovernanceParams: &governancev1alpha1.GovernanceParameters{
+			ProposalVotingBlocks:   17_280,
+			ProposalDepositAmount:  num.NewAmount(10_000_000).Proto(),
+			ProposalValidQuorum:    "40/100",
+			ProposalPassThreshold:  "50/100",
+			ProposalSlashThreshold: "80/100",
+		},
+		IbcParams: &ibcv1alpha1.IbcParameters{IbcEnabled: true, InboundIcs20TransfersEnabled: true, OutboundIcs20TransfersEnabled: true},
+		StakeParams: &stakev1alpha1.StakeParameters{
+			UnbondingEpochs:            2,
+			ActiveValidatorLimit:       80,
+			BaseRewardRate:             30_000,
+			SlashingPenaltyMisbehavior: 10_000_000,
+			SlashingPenaltyDowntime:    10_000,
+			SignedBlocksWindowLen:      10_000,
+			MissedBlocksMaximum:        9_500,
+		},
+		FeeParams:           &feev1alpha1.FeeParameters{},
+		DistributionsParams: &distributionsv1alpha1.DistributionsParameters{StakingIssuancePerBlock: 1_000_000},
+	}
+}
+
+func TestSet(t *testing.T) {
+	p := testParams()
+	for _, edit := range [][2]string{
+		{"stake_params.unbonding_epochs", "4"},
+		{"stakeParams.missedBlocksMaximum", "9_000"},
+		{"governance_params.proposal_deposit_amount", "1_000_000_000_000_000_000_000"},
+		{"governance_params.proposal_pass_threshold", "2/3"},
+		{"ibc_params.ibc_enabled", "false"},
+		{"chain_params.chain_id", "penumbra-testnet-2"},
+	} {
+		if err := Set(p, edit[0], edit[1]); err != nil {
+			t.Errorf("Set(%s, %s): %v", edit[0], edit[1], err)
+		}
+	}
+	deposit, _ := num.ParseAmount("1000000000000000000000")
+	switch {
+	case p.StakeParams.UnbondingEpochs != 4,
+		p.StakeParams.MissedBlocksMaximum != 9_000,
+		num.AmountFromProto(p.GovernanceParams.ProposalDepositAmount) != deposit,
+		p.GovernanceParams.ProposalPassThreshold != "2/3",
+		p.IbcParams.IbcEnabled,
+		p.ChainParams.ChainId != "penumbra-testnet-2":
+		t.Errorf("parameters after Set = %v", p)
+	}
+
+	// Setting a parameter of an unset component creates it.
+	empty := new(appv1alpha1.AppParameters)
+	if err := Set(empty, "dao_params.dao_spend_proposals_enabled", "true"); err != nil || !empty.GetDaoParams().GetDaoSpendProposalsEnabled() {
+		t.Errorf("Set on an unset component = %v, %v", err, empty)
+	}
+
+	tests := []struct {
+		path, value string
+		unknown     bool
+	}{
+		{"stake_params.unbonding", "4", true},
+		{"stake_params", "4", true},
+		{"stake_params.unbonding_epochs.value", "4", true},
+		{"governance_params.proposal_deposit_amount.lo", "4", true},
+		{"", "4", true},
+		{"stake_params.unbonding_epochs", "-1", false},
+		{"stake_params.unbonding_epochs", "four", false},
+		{"stake_params.unbonding_epochs", "18446744073709551616", false},
+		{"governance_params.proposal_deposit_amount", "1.5", false},
+		{"governance_params.proposal_valid_quorum", "40%", false},
+		{"governance_params.proposal_slash_threshold", "1/2/3", false},
+		{"ibc_params.ibc_enabled", "maybe", false},
+	}
+	for _, tt := range tests {
+		err := Set(testParams(), tt.path, tt.value)
+		if err == nil || errors.Is(err, ErrUnknownPath) != tt.unknown {
+			t.Errorf("Set(%q, %q) = %v, want unknown path %v", tt.path, tt.value, err, tt.unknown)
+		}
+	}
+	if err := Set(testParams(), "stake_params.unbonding", "4"); !strings.Contains(err.Error(), "stake_params.unbonding_epochs") {
+		t.Errorf("unknown path error does not list the parameters: %v", err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	paths := Paths(testParams())
+	if len(paths) != 19 || paths[0] != "chain_params.chain_id" || paths[len(paths)-1] != "distributions_params.staking_issuance_per_block" {
+		t.Errorf("Paths = %v", paths)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".lo") || strings.HasSuffix(path, ".hi") {
+			t.Errorf("Paths lists the parts of an amount: %s", path)
+		}
+	}
+}
+
+func TestRatio(t *testing.T) {
+	r, err := ParseRatio(" 2 / 3 ")
+	if err != nil || r != NewRatio(2, 3) || r.String() != "2/3" {
+		t.Errorf("ParseRatio = %v, %v", r, err)
+	}
+	tests := []struct {
+		a, b Ratio
+		cmp  int
+	}{
+		{NewRatio(1, 2), NewRatio(50, 100), 0},
+		{NewRatio(1, 3), NewRatio(1, 2), -1},
+		{NewRatio(2, 3), NewRatio(1, 2), 1},
+		// The cross products overflow 64 bits.
+		{NewRatio(1<<63, 3), NewRatio(1<<63-1, 3), 1},
+		{NewRatio(1<<62, 1<<63), NewRatio(1, 2), 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Cmp(tt.b); got != tt.cmp {
+			t.Errorf("%s.Cmp(%s) = %d, want %d", tt.a, tt.b, got, tt.cmp)
+		}
+	}
+	for _, s := range []string{"1", "1/2/3", "a/2", "1/b", "-1/2"} {
+		if _, err := ParseRatio(s); err == nil {
+			t.Errorf("ParseRatio(%q) succeeded", s)
+		}
+	}
+}
+
+func TestCheckValid(t *testing.T) {
+	if err := CheckValid(testParams()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		edit func(p *appv1alpha1.AppParameters)
+		want string
+	}{
+		{func(p *appv1alpha1.AppParameters) { p.ChainParams.ChainId = "" }, "chain ID must be a non-empty string"},
+		{func(p *appv1alpha1.AppParameters) { p.ChainParams.EpochDuration = 0 }, "epoch duration must be at least one block"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.UnbondingEpochs = 0 }, "unbonding must take at least one epoch"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.ActiveValidatorLimit = 3 }, "active validator limit must be at least 4"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.BaseRewardRate = 0 }, "base reward rate must be at least 1 basis point"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SlashingPenaltyMisbehavior = 0 }, "slashing penalty (misbehavior) must be at least 1 basis point"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SlashingPenaltyMisbehavior = 100_000_001 }, "slashing penalty (misbehavior) must be at most"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SlashingPenaltyDowntime = 0 }, "slashing penalty (downtime) must be at least 1 basis point"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SlashingPenaltyDowntime = 100_000_001 }, "slashing penalty (downtime) must be at most"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SignedBlocksWindowLen = 1 }, "signed blocks window length must be at least 2"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.MissedBlocksMaximum = 0 }, "missed blocks maximum must be at least 1"},
+		{func(p *appv1alpha1.AppParameters) { p.IbcParams.IbcEnabled = false }, "IBC must be enabled"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalVotingBlocks = 0 }, "proposal voting blocks must be at least 1"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalDepositAmount = nil }, "proposal deposit amount must be at least 1"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalValidQuorum = "0/100" }, "proposal valid quorum must be greater than 0"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalPassThreshold = "49/100" }, "proposal pass threshold must be greater than or equal to 1/2"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalSlashThreshold = "1/2" }, "proposal slash threshold must be greater than 1/2"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalValidQuorum = "40" }, "invalid proposal valid quorum"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalPassThreshold = "" }, "invalid proposal pass threshold"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalSlashThreshold = "x/2" }, "invalid proposal slash threshold"},
+	}
+	for _, tt := range tests {
+		p := testParams()
+		tt.edit(p)
+		if err := CheckValid(p); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CheckValid = %v, want %q", err, tt.want)
+		}
+	}
+
+	// Every failed check is reported.
+	p := testParams()
+	p.ChainParams.ChainId = ""
+	p.StakeParams.UnbondingEpochs = 0
+	if err := CheckValid(p); err == nil || !strings.Contains(err.Error(), "chain ID must be a non-empty string, unbonding must take at least one epoch") {
+		t.Errorf("CheckValid = %v, want both failures", err)
+	}
+	// Disabling IBC along with ICS-20 transfers is valid.
+	p = testParams()
+	p.IbcParams = &ibcv1alpha1.IbcParameters{}
+	if err := CheckValid(p); err != nil {
+		t.Errorf("CheckValid with IBC disabled: %v", err)
+	}
+}
+
+func TestCheckValidUpdate(t *testing.T) {
+	// Equal ratios may be written differently.
+	p := testParams()
+	p.GovernanceParams.ProposalPassThreshold = "1/2"
+	p.StakeParams.UnbondingEpochs = 4
+	if err := CheckValidUpdate(testParams(), p); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		edit func(p *appv1alpha1.AppParameters)
+		want string
+	}{
+		{func(p *appv1alpha1.AppParameters) { p.ChainParams.ChainId = "penumbra-testnet-2" }, "chain ID can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.ChainParams.EpochDuration = 720 }, "epoch duration can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.ActiveValidatorLimit = 81 }, "active validator limit can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.SignedBlocksWindowLen = 10_001 }, "signed blocks window length can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalValidQuorum = "41/100" }, "proposal valid quorum can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalPassThreshold = "2/3" }, "proposal pass threshold can't be changed"},
+		{func(p *appv1alpha1.AppParameters) { p.GovernanceParams.ProposalSlashThreshold = "9/10" }, "proposal slash threshold can't be changed"},
+		// The new parameters must also be valid.
+		{func(p *appv1alpha1.AppParameters) { p.StakeParams.UnbondingEpochs = 0 }, "unbonding must take at least one epoch"},
+	}
+	for _, tt := range tests {
+		p := testParams()
+		tt.edit(p)
+		if err := CheckValidUpdate(testParams(), p); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CheckValidUpdate = %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestNewParameterChange(t *testing.T) {
+	current := testParams()
+	change, err := NewParameterChange(current, "stake_params.unbonding_epochs = 4", "governance_params.proposal_deposit_amount=20_000_000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(change.GetOldParameters(), AsChanged(current)) {
+		t.Errorf("old parameters = %v", change.GetOldParameters())
+	}
+	changed := change.GetNewParameters()
+	if changed.GetStakeParams().GetUnbondingEpochs() != 4 || num.AmountFromProto(changed.GetGovernanceParams().GetProposalDepositAmount()) != num.NewAmount(20_000_000) {
+		t.Errorf("new parameters = %v", changed)
+	}
+	if changed.ChainParams != nil || changed.IbcParams != nil || changed.DaoParams != nil || changed.FeeParams != nil || changed.DistributionsParams != nil {
+		t.Errorf("new parameters include unchanged components: %v", changed)
+	}
+	if current.StakeParams.UnbondingEpochs != 2 {
+		t.Errorf("NewParameterChange modified the current parameters")
+	}
+
+	for _, edits := range [][]string{
+		{"stake_params.unbonding_epochs"},
+		{"stake_params.unbonding=4"},
+		{"chain_params.epoch_duration=1"},
+		{"stake_params.unbonding_epochs=0"},
+	} {
+		if _, err := NewParameterChange(current, edits...); err == nil {
+			t.Errorf("NewParameterChange(%q) succeeded", edits)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := testParams()
+	change, err := NewParameterChange(old, "stake_params.unbonding_epochs=4", "governance_params.proposal_deposit_amount=20_000_000", "dao_params.dao_spend_proposals_enabled=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := FromChanged(change.GetNewParameters(), old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dao_params.dao_spend_proposals_enabled: true -> false\n" +
+		"governance_params.proposal_deposit_amount: 10000000 -> 20000000\n" +
+		"stake_params.unbonding_epochs: 2 -> 4\n"
+	if got := FormatDiff(Diff(old, updated)); got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+
+	changes, err := DiffSet(&governancev1alpha1.ChangedAppParametersSet{Old: change.GetOldParameters(), New: change.GetNewParameters()})
+	if err != nil || FormatDiff(changes) != want {
+		t.Errorf("DiffSet = %v, %v", changes, err)
+	}
+	if _, err := DiffSet(&governancev1alpha1.ChangedAppParametersSet{Old: change.GetNewParameters(), New: change.GetNewParameters()}); err == nil {
+		t.Errorf("DiffSet accepted incomplete old parameters")
+	}
+}
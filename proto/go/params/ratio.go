@@ -0,0 +1,57 @@
+package params
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Ratio is a fraction of two u64s, used for the governance thresholds and
+// written as `numerator/denominator`, mirroring `penumbra_chain::params::Ratio`.
+type Ratio struct {
+	Numerator   uint64
+	Denominator uint64
+}
+
+// NewRatio returns the ratio numerator/denominator.
+func NewRatio(numerator, denominator uint64) Ratio {
+	return Ratio{Numerator: numerator, Denominator: denominator}
+}
+
+// ParseRatio parses a ratio of the form `numerator/denominator`.
+func ParseRatio(s string) (Ratio, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return Ratio{}, fmt.Errorf("ratio %q must have exactly one '/'", s)
+	}
+	numerator, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil {
+		return Ratio{}, fmt.Errorf("invalid ratio numerator: %w", err)
+	}
+	denominator, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return Ratio{}, fmt.Errorf("invalid ratio denominator: %w", err)
+	}
+	return NewRatio(numerator, denominator), nil
+}
+
+// String formats the ratio as `numerator/denominator`.
+func (r Ratio) String() string {
+	return fmt.Sprintf("%d/%d", r.Numerator, r.Denominator)
+}
+
+// Cmp compares two ratios by cross-multiplication in 128 bits, as pd does,
+// returning -1, 0 or +1.
+func (r Ratio) Cmp(o Ratio) int {
+	lhsHi, lhsLo := bits.Mul64(r.Numerator, o.Denominator)
+	rhsHi, rhsLo := bits.Mul64(o.Numerator, r.Denominator)
+	switch {
+	case lhsHi < rhsHi || (lhsHi == rhsHi && lhsLo < rhsLo):
+		return -1
+	case lhsHi == rhsHi && lhsLo == rhsLo:
+		return 0
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,126 @@
+// Package poseidon377 implements the Poseidon hash over the decaf377 base
+// field Fq, as the `poseidon377` crate does, for rates 1 to 6.
+//
+// The parameters are not embedded: they are derived on first use the way
+// `poseidon-paramgen` generates them, from a Merlin transcript bound to the
+// width, security level, modulus and round numbers, with a Cauchy MDS
+// matrix. The hashes are checked against the test vectors of the protocol
+// specification.
+package poseidon377
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/gtank/merlin"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+)
+
+const (
+	// MaxRate is the largest number of field elements hashed at once.
+	MaxRate = 6
+
+	securityLevel  = 128
+	fullRounds     = 8
+	partialRounds  = 31
+	alpha          = 17
+	constantLength = (253 + 135) / 8
+)
+
+type parameters struct {
+	arc [][]*big.Int
+	mds [][]*big.Int
+}
+
+var (
+	paramsOnce [MaxRate + 1]sync.Once
+	params     [MaxRate + 1]*parameters
+)
+
+func paramsFor(rate int) *parameters {
+	paramsOnce[rate].Do(func() {
+		params[rate] = generate(rate + 1)
+	})
+	return params[rate]
+}
+
+// generate derives the round constants and MDS matrix of the permutation of
+// width t.
+func generate(t int) *parameters {
+	tr := merlin.NewTranscript("round-constants")
+	tr.AppendMessage([]byte("dom-sep"), []byte("poseidon-paramgen"))
+	tr.AppendMessage([]byte("t"), binary.LittleEndian.AppendUint64(nil, uint64(t)))
+	tr.AppendMessage([]byte("M"), binary.LittleEndian.AppendUint64(nil, securityLevel))
+	tr.AppendMessage([]byte("p"), decaf377.EncodeScalar(decaf377.FqModulus))
+	tr.AppendMessage([]byte("r_F"), []byte{fullRounds})
+	tr.AppendMessage([]byte("r_P"), []byte{partialRounds})
+	tr.AppendMessage([]byte("alpha"), binary.LittleEndian.AppendUint32(nil, alpha))
+
+	p := &parameters{
+		arc: make([][]*big.Int, fullRounds+partialRounds),
+		mds: make([][]*big.Int, t),
+	}
+	for r := range p.arc {
+		p.arc[r] = make([]*big.Int, t)
+		for i := range p.arc[r] {
+			b := tr.ExtractBytes([]byte("round-constant"), constantLength)
+			p.arc[r][i] = decaf377.ReduceScalar(b, decaf377.FqModulus)
+		}
+	}
+	// M_ij = 1 / (x_i + y_j) with x = [0, t) and y = [t, 2t).
+	for i := range p.mds {
+		p.mds[i] = make([]*big.Int, t)
+		for j := range p.mds[i] {
+			p.mds[i][j] = new(big.Int).ModInverse(big.NewInt(int64(i+t+j)), decaf377.FqModulus)
+		}
+	}
+	return p
+}
+
+// Hash returns the fixed-width hash of between 1 and MaxRate field elements
+// under a domain separator, as `poseidon377::hash_n` does: the domain
+// separator fills the capacity element of the state, the inputs its rate,
+// and the output is the first rate element after one permutation. It panics
+// if the number of inputs is not supported.
+func Hash(domain *big.Int, inputs ...*big.Int) *big.Int {
+	if len(inputs) < 1 || len(inputs) > MaxRate {
+		panic("poseidon377: unsupported rate")
+	}
+	p := paramsFor(len(inputs))
+	state := make([]*big.Int, 0, len(inputs)+1)
+	state = append(state, new(big.Int).Mod(domain, decaf377.FqModulus))
+	for _, x := range inputs {
+		state = append(state, new(big.Int).Mod(x, decaf377.FqModulus))
+	}
+	p.permute(state)
+	return state[1]
+}
+
+// permute applies half the full rounds, the partial rounds, and the other
+// half of the full rounds to the state.
+func (p *parameters) permute(state []*big.Int) {
+	exp := big.NewInt(alpha)
+	for r, constants := range p.arc {
+		for i := range state {
+			state[i].Add(state[i], constants[i]).Mod(state[i], decaf377.FqModulus)
+		}
+		if full := r < fullRounds/2 || r >= fullRounds/2+partialRounds; full {
+			for i := range state {
+				state[i].Exp(state[i], exp, decaf377.FqModulus)
+			}
+		} else {
+			state[0].Exp(state[0], exp, decaf377.FqModulus)
+		}
+		mixed := make([]*big.Int, len(state))
+		for i, row := range p.mds {
+			mixed[i] = new(big.Int)
+			for j, m := range row {
+				mixed[i].Add(mixed[i], new(big.Int).Mul(m, state[j]))
+			}
+			mixed[i].Mod(mixed[i], decaf377.FqModulus)
+		}
+		copy(state, mixed)
+	}
+}
@@ -0,0 +1,268 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrRejected is returned when the fullnode rejects a transaction from
+	// its mempool, or when a transaction fails to execute in a block.
+	ErrRejected = errors.New("transaction rejected")
+	// ErrNullifierSpent is returned when a transaction spends a note that
+	// was already spent.
+	ErrNullifierSpent = errors.New("nullifier already spent")
+	// ErrInvalidAnchor is returned when a transaction claims an anchor that
+	// is not a root of the state commitment tree.
+	ErrInvalidAnchor = errors.New("invalid anchor")
+	// ErrInsufficientFee is returned when a transaction pays less than its
+	// base fee.
+	ErrInsufficientFee = errors.New("insufficient fee")
+	// ErrInvalidClue is returned when a transaction's detection clues use
+	// the wrong precision.
+	ErrInvalidClue = errors.New("invalid clue precision")
+	// ErrAlreadyInMempool is returned when the transaction was already
+	// broadcast and is waiting in the mempool.
+	ErrAlreadyInMempool = errors.New("transaction already in mempool")
+	// ErrExpired is returned when a transaction was not included before its
+	// expiry height.
+	ErrExpired = errors.New("transaction expired")
+	// ErrTimeout is returned when a transaction was not included before the
+	// client's confirmation timeout.
+	ErrTimeout = errors.New("timed out waiting for transaction")
+)
+
+// logErrors classifies the log of a rejected transaction, by matching the
+// error messages produced by pd and CometBFT.
+var logErrors = []struct {
+	substr string
+	err    error
+}{
+	{"was already spent", ErrNullifierSpent},
+	{"Duplicate nullifier", ErrNullifierSpent},
+	{"is not a valid SCT root", ErrInvalidAnchor},
+	{"must be greater than or equal to transaction's base fee", ErrInsufficientFee},
+	{"invalid clue precision", ErrInvalidClue},
+	{"tx already exists in cache", ErrAlreadyInMempool},
+}
+
+// TxError is a transaction rejected by the fullnode or by consensus. It
+// wraps ErrRejected, and a more specific error if the log could be
+// classified.
+type TxError struct {
+	// Code is the ABCI result code.
+	Code uint64
+	Log  string
+	// Kind is the classified error, or nil.
+	Kind error
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("error submitting transaction: code %d, log: %s", e.Code, e.Log)
+}
+
+// Unwrap returns ErrRejected and the classified error, if any.
+func (e *TxError) Unwrap() []error {
+	if e.Kind != nil {
+		return []error{ErrRejected, e.Kind}
+	}
+	return []error{ErrRejected}
+}
+
+func newTxError(code uint64, log string) *TxError {
+	return &TxError{Code: code, Log: log, Kind: ClassifyLog(log)}
+}
+
+// ClassifyLog returns the error a rejection log or error message is
+// classified as, such as ErrNullifierSpent, or nil if it is not recognized.
+func ClassifyLog(log string) error {
+	for _, le := range logErrors {
+		if strings.Contains(log, le.substr) {
+			return le.err
+		}
+	}
+	return nil
+}
+
+// Confirmation describes a transaction included in a block.
+type Confirmation struct {
+	// Hash is the transaction ID, the SHA-256 hash of the encoded
+	// transaction.
+	Hash   []byte
+	Height uint64
+	// Index is the position of the transaction within its block.
+	Index     uint64
+	GasWanted uint64
+	GasUsed   uint64
+	Result    *tendermint_proxyv1alpha1.TxResult
+}
+
+// Client submits transactions through a TendermintProxyService and waits for
+// them to be included in a block.
+type Client struct {
+	Service Service
+	// PollInterval is how often to poll for inclusion.
+	PollInterval time.Duration
+	// Timeout bounds how long to wait for inclusion; zero means no bound
+	// other than the context and the transaction's expiry height.
+	Timeout time.Duration
+}
+
+// NewClient returns a client with a one-second poll interval and a
+// two-minute confirmation timeout.
+func NewClient(service Service) *Client {
+	return &Client{
+		Service:      service,
+		PollInterval: time.Second,
+		Timeout:      2 * time.Minute,
+	}
+}
+
+// TxHash returns the ID of a transaction, which CometBFT also uses as its
+// hash.
+func TxHash(tx *transactionv1alpha1.Transaction) ([]byte, error) {
+	params, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(params)
+	return hash[:], nil
+}
+
+func reqId() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Broadcast submits a transaction and waits for it to be accepted into the
+// fullnode's mempool, but not for it to be included in a block. It returns
+// the transaction hash, or a *TxError if the transaction was rejected.
+func (c *Client) Broadcast(ctx context.Context, tx *transactionv1alpha1.Transaction) ([]byte, error) {
+	params, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := c.Service.BroadcastTxSync(ctx, &tendermint_proxyv1alpha1.BroadcastTxSyncRequest{
+		Params: params,
+		ReqId:  reqId(),
+	})
+	if err != nil {
+		// CometBFT reports duplicate transactions as RPC errors rather than
+		// result codes.
+		if strings.Contains(err.Error(), "tx already exists in cache") {
+			return nil, &TxError{Log: err.Error(), Kind: ErrAlreadyInMempool}
+		}
+		return nil, fmt.Errorf("error broadcasting transaction: %w", err)
+	}
+	if rsp.GetCode() != 0 {
+		return nil, newTxError(rsp.GetCode(), rsp.GetLog())
+	}
+	return rsp.GetHash(), nil
+}
+
+// BroadcastAsync submits a transaction without waiting for the mempool to
+// check it, returning its hash.
+func (c *Client) BroadcastAsync(ctx context.Context, tx *transactionv1alpha1.Transaction) ([]byte, error) {
+	params, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := c.Service.BroadcastTxAsync(ctx, &tendermint_proxyv1alpha1.BroadcastTxAsyncRequest{
+		Params: params,
+		ReqId:  reqId(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error broadcasting transaction: %w", err)
+	}
+	if rsp.GetCode() != 0 {
+		return nil, newTxError(rsp.GetCode(), rsp.GetLog())
+	}
+	return rsp.GetHash(), nil
+}
+
+// LatestBlockHeight returns the latest block height known to the fullnode.
+func (c *Client) LatestBlockHeight(ctx context.Context) (uint64, error) {
+	rsp, err := c.Service.GetStatus(ctx, &tendermint_proxyv1alpha1.GetStatusRequest{})
+	if err != nil {
+		return 0, err
+	}
+	if rsp.GetSyncInfo() == nil {
+		return 0, errors.New("could not parse sync_info in gRPC response")
+	}
+	return rsp.GetSyncInfo().GetLatestBlockHeight(), nil
+}
+
+// WaitForTx polls until the transaction with the given hash is included in a
+// block. If expiryHeight is non-zero, it gives up with ErrExpired once the
+// chain has passed that height without including the transaction. A
+// transaction that was included but failed to execute is reported as a
+// *TxError.
+func (c *Client) WaitForTx(ctx context.Context, hash []byte, expiryHeight uint64) (*Confirmation, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	for {
+		// The proxy reports a missing transaction as an error, so errors are
+		// retried until the transaction expires or the context ends.
+		rsp, err := c.Service.GetTx(ctx, &tendermint_proxyv1alpha1.GetTxRequest{Hash: hash})
+		if err == nil {
+			result := rsp.GetTxResult()
+			confirmation := &Confirmation{
+				Hash:      rsp.GetHash(),
+				Height:    rsp.GetHeight(),
+				Index:     rsp.GetIndex(),
+				GasWanted: result.GetGasWanted(),
+				GasUsed:   result.GetGasUsed(),
+				Result:    result,
+			}
+			if result.GetCode() != 0 {
+				return confirmation, newTxError(uint64(result.GetCode()), result.GetLog())
+			}
+			return confirmation, nil
+		}
+		if expiryHeight != 0 {
+			height, err := c.LatestBlockHeight(ctx)
+			if err == nil && height > expiryHeight {
+				return nil, fmt.Errorf("%w: chain is at height %d, past expiry height %d", ErrExpired, height, expiryHeight)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w %s: %v", ErrTimeout, hex.EncodeToString(hash), err)
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// BroadcastAndConfirm submits a transaction and waits for it to be included
+// in a block, honoring its expiry height.
+func (c *Client) BroadcastAndConfirm(ctx context.Context, tx *transactionv1alpha1.Transaction) (*Confirmation, error) {
+	hash, err := c.Broadcast(ctx, tx)
+	if errors.Is(err, ErrAlreadyInMempool) {
+		// A previous broadcast of this transaction is pending; wait for it.
+		hash, err = TxHash(tx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.WaitForTx(ctx, hash, tx.GetBody().GetTransactionParameters().GetExpiryHeight())
+}
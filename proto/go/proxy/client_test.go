@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+// stubService answers GetTx with an error until the transaction is
+// included, and broadcasts with a fixed response or error.
+type stubService struct {
+	height       uint64
+	broadcast    *tendermint_proxyv1alpha1.BroadcastTxSyncResponse
+	broadcastErr error
+	// tx is returned by GetTx once includedAfter polls have failed.
+	tx            *tendermint_proxyv1alpha1.GetTxResponse
+	includedAfter int
+	polls         int
+	polledHash    []byte
+}
+
+func (s *stubService) GetStatus(context.Context, *tendermint_proxyv1alpha1.GetStatusRequest) (*tendermint_proxyv1alpha1.GetStatusResponse, error) {
+	return &tendermint_proxyv1alpha1.GetStatusResponse{
+		SyncInfo: &tendermint_proxyv1alpha1.SyncInfo{LatestBlockHeight: s.height},
+	}, nil
+}
+
+func (s *stubService) BroadcastTxAsync(context.Context, *tendermint_proxyv1alpha1.BroadcastTxAsyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxAsyncResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubService) BroadcastTxSync(context.Context, *tendermint_proxyv1alpha1.BroadcastTxSyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxSyncResponse, error) {
+	return s.broadcast, s.broadcastErr
+}
+
+func (s *stubService) GetTx(_ context.Context, req *tendermint_proxyv1alpha1.GetTxRequest) (*tendermint_proxyv1alpha1.GetTxResponse, error) {
+	s.polledHash = req.GetHash()
+	s.polls++
+	if s.tx == nil || s.polls <= s.includedAfter {
+		return nil, errors.New("tx not found")
+	}
+	return s.tx, nil
+}
+
+func (s *stubService) ABCIQuery(context.Context, *tendermint_proxyv1alpha1.ABCIQueryRequest) (*tendermint_proxyv1alpha1.ABCIQueryResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubService) GetBlockByHeight(context.Context, *tendermint_proxyv1alpha1.GetBlockByHeightRequest) (*tendermint_proxyv1alpha1.GetBlockByHeightResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func testClient(s *stubService) *Client {
+	return &Client{Service: s, PollInterval: time.Millisecond, Timeout: time.Second}
+}
+
+func testTx(expiryHeight uint64) *transactionv1alpha1.Transaction {
+	return &transactionv1alpha1.Transaction{
+		Body: &transactionv1alpha1.TransactionBody{
+			TransactionParameters: &transactionv1alpha1.TransactionParameters{ChainId: "test", ExpiryHeight: expiryHeight},
+		},
+	}
+}
+
+func TestTxHash(t *testing.T) {
+	tx := testTx(0)
+	encoded, _ := proto.Marshal(tx)
+	want := sha256.Sum256(encoded)
+	got, err := TxHash(tx)
+	if err != nil || !bytes.Equal(got, want[:]) {
+		t.Errorf("TxHash = %x, %v, want %x", got, err, want)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rsp      *tendermint_proxyv1alpha1.BroadcastTxSyncResponse
+		err      error
+		wantErrs []error
+		wantCode uint64
+		wantHash []byte
+	}{
+		{
+			name:     "accepted",
+			rsp:      &tendermint_proxyv1alpha1.BroadcastTxSyncResponse{Hash: []byte{1, 2}},
+			wantHash: []byte{1, 2},
+		},
+		{
+			name:     "nullifier spent",
+			rsp:      &tendermint_proxyv1alpha1.BroadcastTxSyncResponse{Code: 1, Log: "nullifier 0x12 was already spent"},
+			wantErrs: []error{ErrRejected, ErrNullifierSpent},
+			wantCode: 1,
+		},
+		{
+			name:     "unclassified",
+			rsp:      &tendermint_proxyv1alpha1.BroadcastTxSyncResponse{Code: 7, Log: "something else"},
+			wantErrs: []error{ErrRejected},
+			wantCode: 7,
+		},
+		{
+			name:     "already in mempool",
+			err:      errors.New("rpc error: tx already exists in cache"),
+			wantErrs: []error{ErrRejected, ErrAlreadyInMempool},
+		},
+	} {
+		hash, err := testClient(&stubService{broadcast: tc.rsp, broadcastErr: tc.err}).Broadcast(context.Background(), testTx(0))
+		if tc.wantErrs == nil {
+			if err != nil || !bytes.Equal(hash, tc.wantHash) {
+				t.Errorf("%s: got %x, %v, want %x", tc.name, hash, err, tc.wantHash)
+			}
+			continue
+		}
+		for _, want := range tc.wantErrs {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: error %v is not %v", tc.name, err, want)
+			}
+		}
+		var txErr *TxError
+		if !errors.As(err, &txErr) || txErr.Code != tc.wantCode {
+			t.Errorf("%s: error %v does not have code %d", tc.name, err, tc.wantCode)
+		}
+	}
+}
+
+func TestWaitForTx(t *testing.T) {
+	ctx := context.Background()
+	hash := []byte{0xaa}
+
+	s := &stubService{
+		tx: &tendermint_proxyv1alpha1.GetTxResponse{
+			Hash:     hash,
+			Height:   10,
+			Index:    2,
+			TxResult: &tendermint_proxyv1alpha1.TxResult{GasUsed: 5},
+		},
+		includedAfter: 2,
+	}
+	c, err := testClient(s).WaitForTx(ctx, hash, 0)
+	if err != nil || c.Height != 10 || c.Index != 2 || c.GasUsed != 5 {
+		t.Errorf("included: got %+v, %v", c, err)
+	}
+	if s.polls != 3 || !bytes.Equal(s.polledHash, hash) {
+		t.Errorf("included: polled %d times for %x", s.polls, s.polledHash)
+	}
+
+	// A transaction that failed to execute carries its result code.
+	s = &stubService{
+		tx: &tendermint_proxyv1alpha1.GetTxResponse{
+			Height:   10,
+			TxResult: &tendermint_proxyv1alpha1.TxResult{Code: 5, Log: "Duplicate nullifier"},
+		},
+	}
+	c, err = testClient(s).WaitForTx(ctx, hash, 0)
+	var txErr *TxError
+	if !errors.As(err, &txErr) || txErr.Code != 5 || !errors.Is(err, ErrNullifierSpent) {
+		t.Errorf("failed: got error %v", err)
+	}
+	if c == nil || c.Height != 10 {
+		t.Errorf("failed: no confirmation of the failed transaction")
+	}
+
+	// A log alone, as pd writes for successful transactions' events, is
+	// not a failure.
+	s = &stubService{tx: &tendermint_proxyv1alpha1.GetTxResponse{TxResult: &tendermint_proxyv1alpha1.TxResult{Log: "[]"}}}
+	if _, err := testClient(s).WaitForTx(ctx, hash, 0); err != nil {
+		t.Errorf("log without code: got error %v", err)
+	}
+
+	s = &stubService{height: 21}
+	if _, err := testClient(s).WaitForTx(ctx, hash, 20); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired: got error %v", err)
+	}
+	s = &stubService{height: 20}
+	client := testClient(s)
+	client.Timeout = 20 * time.Millisecond
+	if _, err := client.WaitForTx(ctx, hash, 20); !errors.Is(err, ErrTimeout) {
+		t.Errorf("timeout: got error %v", err)
+	}
+}
+
+func TestBroadcastAndConfirmAlreadyInMempool(t *testing.T) {
+	tx := testTx(100)
+	s := &stubService{
+		height:       50,
+		broadcastErr: errors.New("tx already exists in cache"),
+		tx:           &tendermint_proxyv1alpha1.GetTxResponse{Height: 51, TxResult: &tendermint_proxyv1alpha1.TxResult{}},
+	}
+	c, err := testClient(s).BroadcastAndConfirm(context.Background(), tx)
+	if err != nil || c.Height != 51 {
+		t.Fatalf("got %+v, %v", c, err)
+	}
+	if want, _ := TxHash(tx); !bytes.Equal(s.polledHash, want) {
+		t.Errorf("waited for %x, want the transaction's hash %x", s.polledHash, want)
+	}
+}
+
+func TestLatestBlockHeight(t *testing.T) {
+	height, err := testClient(&stubService{height: 1234}).LatestBlockHeight(context.Background())
+	if err != nil || height != 1234 {
+		t.Errorf("LatestBlockHeight = %d, %v", height, err)
+	}
+}
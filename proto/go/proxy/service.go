@@ -0,0 +1,58 @@
+// Package proxy provides a client for the TendermintProxyService, which
+// relays requests from Penumbra clients to the CometBFT RPC of a fullnode.
+package proxy
+
+import (
+	"context"
+
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// ServiceName is the full name of the TendermintProxyService.
+const ServiceName = "penumbra.util.tendermint_proxy.v1alpha1.TendermintProxyService"
+
+// Service is the TendermintProxyService API.
+type Service interface {
+	GetStatus(ctx context.Context, req *tendermint_proxyv1alpha1.GetStatusRequest) (*tendermint_proxyv1alpha1.GetStatusResponse, error)
+	BroadcastTxAsync(ctx context.Context, req *tendermint_proxyv1alpha1.BroadcastTxAsyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxAsyncResponse, error)
+	BroadcastTxSync(ctx context.Context, req *tendermint_proxyv1alpha1.BroadcastTxSyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxSyncResponse, error)
+	GetTx(ctx context.Context, req *tendermint_proxyv1alpha1.GetTxRequest) (*tendermint_proxyv1alpha1.GetTxResponse, error)
+	ABCIQuery(ctx context.Context, req *tendermint_proxyv1alpha1.ABCIQueryRequest) (*tendermint_proxyv1alpha1.ABCIQueryResponse, error)
+	GetBlockByHeight(ctx context.Context, req *tendermint_proxyv1alpha1.GetBlockByHeightRequest) (*tendermint_proxyv1alpha1.GetBlockByHeightResponse, error)
+}
+
+type grpcService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCService returns a Service that calls the TendermintProxyService over
+// a gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCService(conn grpc.ClientConnInterface) Service {
+	return &grpcService{svc: grpcclient.Service{Conn: conn, Name: ServiceName}}
+}
+
+func (s *grpcService) GetStatus(ctx context.Context, req *tendermint_proxyv1alpha1.GetStatusRequest) (*tendermint_proxyv1alpha1.GetStatusResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.GetStatusResponse](ctx, s.svc, "GetStatus", req)
+}
+
+func (s *grpcService) BroadcastTxAsync(ctx context.Context, req *tendermint_proxyv1alpha1.BroadcastTxAsyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxAsyncResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.BroadcastTxAsyncResponse](ctx, s.svc, "BroadcastTxAsync", req)
+}
+
+func (s *grpcService) BroadcastTxSync(ctx context.Context, req *tendermint_proxyv1alpha1.BroadcastTxSyncRequest) (*tendermint_proxyv1alpha1.BroadcastTxSyncResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.BroadcastTxSyncResponse](ctx, s.svc, "BroadcastTxSync", req)
+}
+
+func (s *grpcService) GetTx(ctx context.Context, req *tendermint_proxyv1alpha1.GetTxRequest) (*tendermint_proxyv1alpha1.GetTxResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.GetTxResponse](ctx, s.svc, "GetTx", req)
+}
+
+func (s *grpcService) ABCIQuery(ctx context.Context, req *tendermint_proxyv1alpha1.ABCIQueryRequest) (*tendermint_proxyv1alpha1.ABCIQueryResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.ABCIQueryResponse](ctx, s.svc, "ABCIQuery", req)
+}
+
+func (s *grpcService) GetBlockByHeight(ctx context.Context, req *tendermint_proxyv1alpha1.GetBlockByHeightRequest) (*tendermint_proxyv1alpha1.GetBlockByHeightResponse, error) {
+	return grpcclient.Call[tendermint_proxyv1alpha1.GetBlockByHeightResponse](ctx, s.svc, "GetBlockByHeight", req)
+}
@@ -0,0 +1,87 @@
+// Package shieldedpool computes the commitments and ephemeral keys of notes,
+// as the `Note` type of `penumbra-shielded-pool` does.
+package shieldedpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/penumbra-zone/penumbra/proto/go/blake2b"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	tctv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/tct/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/poseidon377"
+)
+
+// RseedLen is the length of a note's rseed.
+const RseedLen = 32
+
+// noteCommitDomainSep is the domain separator of note commitments.
+var noteCommitDomainSep = func() *big.Int {
+	sum := blake2b.Sum512("", []byte("penumbra.notecommit"))
+	return decaf377.ReduceScalar(sum[:], decaf377.FqModulus)
+}()
+
+// NoteBlinding returns the blinding factor of a note commitment, derived
+// from the note's rseed.
+func NoteBlinding(rseed []byte) (*big.Int, error) {
+	if len(rseed) != RseedLen {
+		return nil, fmt.Errorf("rseed has incorrect length %d", len(rseed))
+	}
+	return keys.ExpandField("Penumbra_DeriRcm", rseed, []byte{5}, decaf377.FqModulus), nil
+}
+
+// EphemeralSecretKey returns the ephemeral secret key of a note, derived
+// from its rseed.
+func EphemeralSecretKey(rseed []byte) (*big.Int, error) {
+	if len(rseed) != RseedLen {
+		return nil, fmt.Errorf("rseed has incorrect length %d", len(rseed))
+	}
+	return keys.ExpandField("Penumbra_DeriEsk", rseed, []byte{4}, decaf377.FrModulus), nil
+}
+
+// EphemeralPublicKey returns the ephemeral public key a note is encrypted
+// with: its ephemeral secret key times the diversified basepoint of its
+// address.
+func EphemeralPublicKey(note *shielded_poolv1alpha1.Note) ([]byte, error) {
+	esk, err := EphemeralSecretKey(note.GetRseed())
+	if err != nil {
+		return nil, err
+	}
+	address, err := keys.Components(note.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	return keys.DiversifiedGenerator(address.Diversifier).ScalarMul(esk).Encode(), nil
+}
+
+// Commit returns the state commitment of a note: the Poseidon hash of its
+// blinding factor, amount, asset ID, diversified basepoint, transmission key
+// and clue key.
+func Commit(note *shielded_poolv1alpha1.Note) (*tctv1alpha1.StateCommitment, error) {
+	blinding, err := NoteBlinding(note.GetRseed())
+	if err != nil {
+		return nil, err
+	}
+	address, err := keys.Components(note.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	assetId, ok := decaf377.DecodeScalar(note.GetValue().GetAssetId().GetInner(), decaf377.FqModulus)
+	if !ok {
+		return nil, errors.New("note has an invalid asset ID")
+	}
+	transmissionKey, _ := decaf377.DecodeScalar(address.TransmissionKey, decaf377.FqModulus)
+	commitment := poseidon377.Hash(noteCommitDomainSep,
+		blinding,
+		num.AmountFromProto(note.GetValue().GetAmount()).Big(),
+		assetId,
+		keys.DiversifiedGenerator(address.Diversifier).CompressToField(),
+		transmissionKey,
+		decaf377.ReduceScalar(address.ClueKey, decaf377.FqModulus),
+	)
+	return &tctv1alpha1.StateCommitment{Inner: decaf377.EncodeScalar(commitment)}, nil
+}
@@ -0,0 +1,90 @@
+package shieldedpool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+)
+
+func testNote(t *testing.T, amount uint64, rseedByte byte) *shielded_poolv1alpha1.Note {
+	t.Helper()
+	address, err := keys.ParseAddress("penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetId := make([]byte, 32)
+	assetId[0] = 1
+	return &shielded_poolv1alpha1.Note{
+		Address: address,
+		Value: &assetv1alpha1.Value{
+			Amount:  &numv1alpha1.Amount{Lo: amount},
+			AssetId: &assetv1alpha1.AssetId{Inner: assetId},
+		},
+		Rseed: bytes.Repeat([]byte{rseedByte}, RseedLen),
+	}
+}
+
+func TestCommit(t *testing.T) {
+	note := testNote(t, 100, 7)
+	cm, err := Commit(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decaf377.DecodeScalar(cm.GetInner(), decaf377.FqModulus); !ok {
+		t.Errorf("commitment %x is not a field element", cm.GetInner())
+	}
+	again, _ := Commit(testNote(t, 100, 7))
+	if !bytes.Equal(cm.GetInner(), again.GetInner()) {
+		t.Errorf("Commit is not deterministic")
+	}
+	for name, other := range map[string]*shielded_poolv1alpha1.Note{
+		"amount": testNote(t, 101, 7),
+		"rseed":  testNote(t, 100, 8),
+	} {
+		c, err := Commit(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(c.GetInner(), cm.GetInner()) {
+			t.Errorf("changing the %s does not change the commitment", name)
+		}
+	}
+
+	bad := testNote(t, 100, 7)
+	bad.Value.AssetId.Inner = bytes.Repeat([]byte{0xff}, 32)
+	if _, err := Commit(bad); err == nil {
+		t.Errorf("Commit accepted a non-canonical asset ID")
+	}
+	bad = testNote(t, 100, 7)
+	bad.Rseed = bad.Rseed[:31]
+	if _, err := Commit(bad); err == nil {
+		t.Errorf("Commit accepted a short rseed")
+	}
+}
+
+func TestEphemeralPublicKey(t *testing.T) {
+	note := testNote(t, 100, 7)
+	epk, err := EphemeralPublicKey(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esk, _ := EphemeralSecretKey(note.GetRseed())
+	address, _ := keys.Components(note.GetAddress())
+	want := keys.DiversifiedGenerator(address.Diversifier).ScalarMul(esk)
+	got, err := decaf377.Decode(epk)
+	if err != nil {
+		t.Fatalf("epk does not decode: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("epk is not esk * B_d")
+	}
+	other, _ := EphemeralPublicKey(testNote(t, 100, 8))
+	if bytes.Equal(epk, other) {
+		t.Errorf("epk does not depend on the rseed")
+	}
+}
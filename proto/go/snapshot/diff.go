@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+)
+
+// Change is a key whose value differs between two snapshots. Old is nil if
+// the key was added, and New is nil if it was removed.
+type Change struct {
+	Key string
+	Old []byte
+	New []byte
+}
+
+// Diff returns the keys whose values differ between two snapshots, sorted by
+// key.
+func Diff(a, b *Snapshot) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < len(a.Entries) || j < len(b.Entries) {
+		switch {
+		case j == len(b.Entries) || (i < len(a.Entries) && a.Entries[i].Key < b.Entries[j].Key):
+			changes = append(changes, Change{Key: a.Entries[i].Key, Old: a.Entries[i].Value})
+			i++
+		case i == len(a.Entries) || b.Entries[j].Key < a.Entries[i].Key:
+			changes = append(changes, Change{Key: b.Entries[j].Key, New: b.Entries[j].Value})
+			j++
+		default:
+			if !bytes.Equal(a.Entries[i].Value, b.Entries[j].Value) {
+				changes = append(changes, Change{Key: a.Entries[i].Key, Old: a.Entries[i].Value, New: b.Entries[j].Value})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+// WriteDiff writes changes to w, with values decoded using the schema. Added
+// and removed keys are written on one line as `+ key = value` and
+// `- key = value`; modified keys are written as `~ key`, followed by their
+// old and new values on indented `-` and `+` lines.
+func WriteDiff(w io.Writer, schema *cnidarium.Schema, changes []Change) error {
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.Old == nil:
+			_, err = fmt.Fprintf(w, "+ %s = %s\n", c.Key, schema.Format(c.Key, c.New))
+		case c.New == nil:
+			_, err = fmt.Fprintf(w, "- %s = %s\n", c.Key, schema.Format(c.Key, c.Old))
+		default:
+			_, err = fmt.Fprintf(w, "~ %s\n  - %s\n  + %s\n", c.Key, schema.Format(c.Key, c.Old), schema.Format(c.Key, c.New))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
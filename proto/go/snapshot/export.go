@@ -0,0 +1,146 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+)
+
+// Exporter streams application state from a fullnode into snapshot files.
+type Exporter struct {
+	Query cnidarium.QueryService
+	// Status, if set, is used to record the height and app hash at which
+	// each section was exported.
+	Status  proxy.Service
+	ChainId string
+	// Progress, if set, is called after each section is written.
+	Progress func(section Section)
+}
+
+// Export writes the keys under the given prefixes to the snapshot file at
+// path. If the file already holds a partial export of the same chain, the
+// prefixes it completed are skipped and its incomplete section is
+// discarded, so that an interrupted export can be resumed by calling Export
+// again with the same arguments.
+func (e *Exporter) Export(ctx context.Context, path string, prefixes []string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	done, err := e.prepare(f)
+	if err != nil {
+		return fmt.Errorf("could not resume %s: %w", path, err)
+	}
+	enc := &encoder{w: bufio.NewWriter(f)}
+	for _, prefix := range prefixes {
+		if done[prefix] {
+			continue
+		}
+		section, err := e.exportSection(ctx, enc, prefix)
+		if err != nil {
+			return fmt.Errorf("could not export %q: %w", prefix, err)
+		}
+		if err := enc.w.Flush(); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		done[prefix] = true
+		if e.Progress != nil {
+			e.Progress(section)
+		}
+	}
+	return nil
+}
+
+// prepare writes the header of a new file, or truncates an existing file to
+// its last complete section, returning the prefixes already exported.
+func (e *Exporter) prepare(f *os.File) (map[string]bool, error) {
+	done := make(map[string]bool)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		enc := &encoder{w: bufio.NewWriter(f)}
+		enc.w.WriteString(Magic)
+		enc.w.WriteByte(recordHeader)
+		enc.bytes([]byte(e.ChainId))
+		return done, enc.w.Flush()
+	}
+
+	s, offset, err := read(f)
+	if err != nil {
+		return nil, err
+	}
+	if s.ChainId != e.ChainId {
+		return nil, fmt.Errorf("snapshot is of chain %q, not %q", s.ChainId, e.ChainId)
+	}
+	for _, sec := range s.Sections {
+		done[sec.Prefix] = true
+	}
+	if err := f.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+func (e *Exporter) exportSection(ctx context.Context, enc *encoder, prefix string) (Section, error) {
+	section := Section{Prefix: prefix}
+	if e.Status != nil {
+		rsp, err := e.Status.GetStatus(ctx, &tendermint_proxyv1alpha1.GetStatusRequest{})
+		if err != nil {
+			return section, err
+		}
+		section.Height = uint64(rsp.GetSyncInfo().GetLatestBlockHeight())
+		section.AppHash = rsp.GetSyncInfo().GetLatestAppHash()
+	}
+	enc.w.WriteByte(recordPrefix)
+	enc.bytes([]byte(prefix))
+	enc.uvarint(section.Height)
+	enc.bytes(section.AppHash)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := e.Query.PrefixValue(ctx, &cnidariumv1alpha1.PrefixValueRequest{
+		ChainId: e.ChainId,
+		Prefix:  prefix,
+	})
+	if err != nil {
+		return section, err
+	}
+	var last string
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return section, err
+		}
+		if section.Count > 0 && rsp.GetKey() <= last {
+			return section, fmt.Errorf("key %q was streamed out of order", rsp.GetKey())
+		}
+		last = rsp.GetKey()
+		enc.w.WriteByte(recordKey)
+		enc.bytes([]byte(rsp.GetKey()))
+		enc.bytes(rsp.GetValue())
+		section.Count++
+	}
+	enc.w.WriteByte(recordSection)
+	enc.uvarint(section.Count)
+	return section, nil
+}
@@ -0,0 +1,92 @@
+// Package snapshot exports Penumbra application state to disk by streaming
+// cnidarium's PrefixValue queries, and compares exported snapshots.
+//
+// A snapshot file starts with a magic string followed by a sequence of
+// records. Each record is a kind byte followed by fields, where integers are
+// uvarints and byte strings are prefixed with their uvarint length:
+//
+//	'H' chain_id                    file header
+//	'P' prefix height app_hash      start of a prefix section
+//	'K' key value                   a key in the current section
+//	'E' count                       end of the current section
+//
+// Sections are written one prefix at a time, in key order, and are only
+// considered complete once their end record is written. This lets an
+// interrupted export resume by discarding its last, incomplete section.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Magic is the string at the start of every snapshot file.
+const Magic = "PENUMBRA-SNAPSHOT\x01"
+
+const (
+	recordHeader  = 'H'
+	recordPrefix  = 'P'
+	recordKey     = 'K'
+	recordSection = 'E'
+)
+
+// ErrCorrupt is returned when a snapshot file cannot be parsed.
+var ErrCorrupt = errors.New("corrupt snapshot")
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.w.Write(e.buf[:n])
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.w.Write(b)
+}
+
+type decoder struct {
+	r *bufio.Reader
+	// n is the number of bytes consumed so far.
+	n int64
+}
+
+func (d *decoder) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == nil {
+		d.n++
+	}
+	return b, err
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(d)
+	return v, unexpectedEOF(err)
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	l, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > 1<<32 {
+		return nil, fmt.Errorf("%w: field of length %d", ErrCorrupt, l)
+	}
+	b := make([]byte, l)
+	n, err := io.ReadFull(d.r, b)
+	d.n += int64(n)
+	return b, unexpectedEOF(err)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,194 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Section describes the keys exported under one prefix.
+type Section struct {
+	Prefix string
+	// Height is the latest block height reported by the fullnode when the
+	// section was exported, and AppHash the app hash in that block's header.
+	// Both are zero if the exporter had no status service.
+	Height  uint64
+	AppHash []byte
+	Count   uint64
+}
+
+// Entry is a key and its value.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+// Snapshot is the contents of a snapshot file.
+type Snapshot struct {
+	ChainId  string
+	Sections []Section
+	// Entries holds the keys of all complete sections, sorted by key.
+	Entries []Entry
+	// Partial is set if the file ends with an incomplete section, which was
+	// discarded.
+	Partial bool
+}
+
+// Height returns the height of the snapshot, and whether all of its sections
+// were exported at that height. Sections exported at different heights, such
+// as after resuming an export, may not be consistent with each other.
+func (s *Snapshot) Height() (uint64, bool) {
+	if len(s.Sections) == 0 {
+		return 0, true
+	}
+	height := s.Sections[0].Height
+	for _, sec := range s.Sections[1:] {
+		if sec.Height != height {
+			return height, false
+		}
+	}
+	return height, true
+}
+
+// Get returns the value of a key, or nil if it is not in the snapshot.
+func (s *Snapshot) Get(key string) []byte {
+	i := sort.Search(len(s.Entries), func(i int) bool { return s.Entries[i].Key >= key })
+	if i < len(s.Entries) && s.Entries[i].Key == key {
+		return s.Entries[i].Value
+	}
+	return nil
+}
+
+// ReadFile reads a snapshot file.
+func ReadFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a snapshot, keeping only its complete sections.
+func Read(r io.Reader) (*Snapshot, error) {
+	s, _, err := read(r)
+	return s, err
+}
+
+// read parses a snapshot and also returns the offset just past its last
+// complete section, or past its header if it has none.
+func read(r io.Reader) (*Snapshot, int64, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(d.r, magic); err != nil || string(magic) != Magic {
+		return nil, 0, fmt.Errorf("%w: missing magic", ErrCorrupt)
+	}
+	d.n = int64(len(Magic))
+	kind, err := d.ReadByte()
+	if err != nil || kind != recordHeader {
+		return nil, 0, fmt.Errorf("%w: missing header", ErrCorrupt)
+	}
+	chainId, err := d.bytes()
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: header: %v", ErrCorrupt, err)
+	}
+
+	s := &Snapshot{ChainId: string(chainId)}
+	offset := d.n
+	var (
+		section *Section
+		entries []Entry
+	)
+	for {
+		kind, err := d.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := d.record(kind, &section, &entries); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				break
+			}
+			return nil, 0, fmt.Errorf("%w: at offset %d: %v", ErrCorrupt, d.n, err)
+		}
+		if kind == recordSection {
+			s.Sections = append(s.Sections, *section)
+			s.Entries = append(s.Entries, entries...)
+			section, entries = nil, nil
+			offset = d.n
+		}
+	}
+	s.Partial = section != nil || d.n != offset
+
+	// Sections are individually sorted, but may be out of order relative to
+	// each other or overlap.
+	sort.SliceStable(s.Entries, func(i, j int) bool { return s.Entries[i].Key < s.Entries[j].Key })
+	deduped := s.Entries[:0]
+	for _, e := range s.Entries {
+		if n := len(deduped); n > 0 && deduped[n-1].Key == e.Key {
+			deduped[n-1] = e
+			continue
+		}
+		deduped = append(deduped, e)
+	}
+	s.Entries = deduped
+	return s, offset, nil
+}
+
+func (d *decoder) record(kind byte, section **Section, entries *[]Entry) error {
+	switch kind {
+	case recordPrefix:
+		if *section != nil {
+			return fmt.Errorf("section %q is not terminated", (*section).Prefix)
+		}
+		prefix, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		height, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		appHash, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		*section = &Section{Prefix: string(prefix), Height: height, AppHash: appHash}
+	case recordKey:
+		if *section == nil {
+			return fmt.Errorf("key outside of a section")
+		}
+		key, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		value, err := d.bytes()
+		if err != nil {
+			return err
+		}
+		if n := len(*entries); n > 0 && bytes.Compare([]byte((*entries)[n-1].Key), key) >= 0 {
+			return fmt.Errorf("key %q is out of order", key)
+		}
+		*entries = append(*entries, Entry{Key: string(key), Value: value})
+	case recordSection:
+		if *section == nil {
+			return fmt.Errorf("end of section outside of a section")
+		}
+		count, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if count != uint64(len(*entries)) {
+			return fmt.Errorf("section %q has %d keys, expected %d", (*section).Prefix, len(*entries), count)
+		}
+		(*section).Count = count
+	default:
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	cnidariumv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/cnidarium/v1alpha1"
+	tendermint_proxyv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/util/tendermint_proxy/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/proxy"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeState streams the keys of a map under a prefix, in the given order.
+type fakeState struct {
+	cnidarium.QueryService
+	keys   []string
+	values map[string][]byte
+}
+
+func (f *fakeState) PrefixValue(_ context.Context, req *cnidariumv1alpha1.PrefixValueRequest) (grpcclient.Stream[cnidariumv1alpha1.PrefixValueResponse], error) {
+	s := &fakeStream{}
+	for _, k := range f.keys {
+		if strings.HasPrefix(k, req.GetPrefix()) {
+			s.rsps = append(s.rsps, &cnidariumv1alpha1.PrefixValueResponse{Key: k, Value: f.values[k]})
+		}
+	}
+	return s, nil
+}
+
+type fakeStream struct {
+	rsps []*cnidariumv1alpha1.PrefixValueResponse
+}
+
+func (s *fakeStream) Recv() (*cnidariumv1alpha1.PrefixValueResponse, error) {
+	if len(s.rsps) == 0 {
+		return nil, io.EOF
+	}
+	rsp := s.rsps[0]
+	s.rsps = s.rsps[1:]
+	return rsp, nil
+}
+
+// fakeStatus reports a new block each time it is asked.
+type fakeStatus struct {
+	proxy.Service
+	height uint64
+}
+
+func (f *fakeStatus) GetStatus(context.Context, *tendermint_proxyv1alpha1.GetStatusRequest) (*tendermint_proxyv1alpha1.GetStatusResponse, error) {
+	f.height++
+	return &tendermint_proxyv1alpha1.GetStatusResponse{SyncInfo: &tendermint_proxyv1alpha1.SyncInfo{
+		LatestBlockHeight: f.height,
+		LatestAppHash:     bytes.Repeat([]byte{byte(f.height)}, 32),
+	}}, nil
+}
+
+func testState() *fakeState {
+	return &fakeState{
+		keys: []string{"chain/block_height", "chain/epoch", "dex/positions/1", "stake/validators/a", "stake/validators/b"},
+		values: map[string][]byte{
+			"chain/block_height": []byte("1"),
+			"chain/epoch":        []byte("2"),
+			"dex/positions/1":    []byte("3"),
+			"stake/validators/a": []byte("4"),
+			"stake/validators/b": []byte("5"),
+		},
+	}
+}
+
+var testPrefixes = []string{"stake/", "chain/", "dex/"}
+
+func TestExportResume(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.snapshot")
+	state := testState()
+	var exported []string
+	e := &Exporter{
+		Query:    state,
+		Status:   &fakeStatus{},
+		ChainId:  "penumbra-testnet",
+		Progress: func(sec Section) { exported = append(exported, sec.Prefix) },
+	}
+	if err := e.Export(ctx, path, testPrefixes); err != nil {
+		t.Fatal(err)
+	}
+	full, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.ChainId != "penumbra-testnet" || full.Partial || len(full.Sections) != 3 || len(full.Entries) != 5 {
+		t.Fatalf("ReadFile = %+v", full)
+	}
+	for i, sec := range full.Sections {
+		if sec.Prefix != testPrefixes[i] || sec.Height != uint64(i+1) || !bytes.Equal(sec.AppHash, bytes.Repeat([]byte{byte(i + 1)}, 32)) {
+			t.Errorf("section %d = %+v", i, sec)
+		}
+	}
+	// The entries are sorted across sections.
+	for i, e := range full.Entries {
+		if e.Key != state.keys[i] || !bytes.Equal(e.Value, state.values[e.Key]) {
+			t.Errorf("entry %d = %s", i, e.Key)
+		}
+	}
+	if h, ok := full.Height(); h != 1 || ok {
+		t.Errorf("Height = %d, %v, want sections at different heights", h, ok)
+	}
+
+	// Cut the file within its last section, as an interrupted export would.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	partial, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !partial.Partial || len(partial.Sections) != 2 || partial.Get("dex/positions/1") != nil {
+		t.Fatalf("ReadFile of a partial export = %+v", partial)
+	}
+
+	// Resuming exports only the incomplete section, and leaves the file as
+	// if it had not been interrupted.
+	exported = nil
+	e.Status = &fakeStatus{height: 2}
+	if err := e.Export(ctx, path, testPrefixes); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exported, []string{"dex/"}) {
+		t.Errorf("resume exported %v, want only dex/", exported)
+	}
+	resumed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resumed, data) {
+		t.Errorf("resumed export differs from the uninterrupted one")
+	}
+
+	e.ChainId = "penumbra-mainnet"
+	if err := e.Export(ctx, path, testPrefixes); err == nil {
+		t.Errorf("Export resumed a snapshot of another chain")
+	}
+}
+
+func TestExportOutOfOrder(t *testing.T) {
+	state := testState()
+	state.keys[0], state.keys[1] = state.keys[1], state.keys[0]
+	e := &Exporter{Query: state, ChainId: "penumbra-testnet"}
+	path := filepath.Join(t.TempDir(), "state.snapshot")
+	if err := e.Export(context.Background(), path, []string{"chain/"}); err == nil {
+		t.Errorf("Export accepted keys streamed out of order")
+	}
+}
+
+func TestReadCorrupt(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"NOT-A-SNAPSHOT",
+		Magic + "X",
+		Magic + "H\x01a" + "K\x01k\x01v",
+		Magic + "H\x01a" + "P\x01p\x00\x00" + "E\x02",
+	} {
+		if s, err := Read(strings.NewReader(data)); err == nil {
+			t.Errorf("Read(%q) = %+v", data, s)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	height := func(h uint64) []byte {
+		b, _ := proto.Marshal(wrapperspb.UInt64(h))
+		return b
+	}
+	a := &Snapshot{Entries: []Entry{
+		{Key: "chain/block_height", Value: height(10)},
+		{Key: "dex/removed", Value: []byte{1}},
+		{Key: "stake/same", Value: []byte{2}},
+	}}
+	b := &Snapshot{Entries: []Entry{
+		{Key: "chain/block_height", Value: height(20)},
+		{Key: "dex/added", Value: []byte{3}},
+		{Key: "stake/same", Value: []byte{2}},
+	}}
+	changes := Diff(a, b)
+	want := []Change{
+		{Key: "chain/block_height", Old: height(10), New: height(20)},
+		{Key: "dex/added", New: []byte{3}},
+		{Key: "dex/removed", Old: []byte{1}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("Diff = %+v, want %+v", changes, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, cnidarium.DefaultSchema, changes); err != nil {
+		t.Fatal(err)
+	}
+	wantText := "~ chain/block_height\n  - \"10\"\n  + \"20\"\n+ dex/added = 03\n- dex/removed = 01\n"
+	if buf.String() != wantText {
+		t.Errorf("WriteDiff =\n%s\nwant\n%s", buf.String(), wantText)
+	}
+}
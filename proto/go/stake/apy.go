@@ -0,0 +1,41 @@
+package stake
+
+import (
+	"fmt"
+	"math"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+)
+
+// APY returns the annual yield of a reward rate paid every epoch,
+// compounded over epochsPerYear epochs, as a fraction (0.05 for 5%).
+func APY(rewardRate uint64, epochsPerYear float64) float64 {
+	return math.Pow(1+float64(rewardRate)/RateScale, epochsPerYear) - 1
+}
+
+// ValidatorAPY returns the annual yield of delegating to a validator if its
+// current reward rate is sustained.
+func ValidatorAPY(rate *stakev1alpha1.RateData, epochsPerYear float64) float64 {
+	return APY(rate.GetValidatorRewardRate(), epochsPerYear)
+}
+
+// ObservedAPY returns the annual yield implied by the growth of a validator's
+// exchange rate between two epochs, which includes the effect of commission
+// changes, inactive epochs and slashing.
+func ObservedAPY(from, to *stakev1alpha1.RateData, epochsPerYear float64) (float64, error) {
+	if from.GetValidatorExchangeRate() == 0 {
+		return 0, ErrZeroExchangeRate
+	}
+	if to.GetEpochIndex() <= from.GetEpochIndex() {
+		return 0, fmt.Errorf("epoch %d does not follow epoch %d", to.GetEpochIndex(), from.GetEpochIndex())
+	}
+	growth := float64(to.GetValidatorExchangeRate()) / float64(from.GetValidatorExchangeRate())
+	epochs := float64(to.GetEpochIndex() - from.GetEpochIndex())
+	return math.Pow(growth, epochsPerYear/epochs) - 1, nil
+}
+
+// EpochsPerYear returns the number of epochs in a year, for epochs of
+// epochDuration blocks produced every blockTime seconds.
+func EpochsPerYear(epochDuration uint64, blockTime float64) float64 {
+	return 365 * 24 * 60 * 60 / (float64(epochDuration) * blockTime)
+}
@@ -0,0 +1,119 @@
+package stake
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// PenaltyLen is the length of an encoded penalty.
+const PenaltyLen = num.U128x128Len
+
+// Penalty is the fraction of a validator's delegations slashed, mirroring
+// `penumbra_stake::Penalty`. Like the Rust type, it is represented by the
+// fraction kept, 1 - penalty, as a U128x128 between 0 and 1. The zero value
+// is no penalty.
+type Penalty struct {
+	// penalty is the fraction slashed, so that the zero value keeps all of
+	// a delegation.
+	penalty num.U128x128
+}
+
+// NoPenalty is the zero penalty.
+var NoPenalty = Penalty{}
+
+var one = num.U128x128FromAmount(num.NewAmount(1))
+
+// PenaltyFromBpsSquared returns a penalty expressed in basis points of basis
+// points, as the slashing penalties of the stake parameters are.
+func PenaltyFromBpsSquared(bpsSquared uint64) (Penalty, error) {
+	if bpsSquared > RateScale {
+		return Penalty{}, fmt.Errorf("penalty of %d bps^2 exceeds 100%%", bpsSquared)
+	}
+	// As in `Penalty::from_bps_squared`, the fraction kept is 1 minus the
+	// penalty rounded down.
+	penalty, err := num.Ratio(num.NewAmount(bpsSquared), num.NewAmount(RateScale))
+	if err != nil {
+		return Penalty{}, err
+	}
+	return Penalty{penalty: penalty}, nil
+}
+
+// PenaltyFromBps returns a penalty expressed in basis points, capped at
+// 100%.
+func PenaltyFromBps(bps uint64) Penalty {
+	p, _ := PenaltyFromBpsSquared(min(bps, MaxCommissionBps) * 1_0000)
+	return p
+}
+
+// PenaltyFromProto decodes a penalty.
+func PenaltyFromProto(p *stakev1alpha1.Penalty) (Penalty, error) {
+	kept, err := num.U128x128FromBytes(p.GetInner())
+	if err != nil {
+		return Penalty{}, fmt.Errorf("invalid penalty: %w", err)
+	}
+	if kept.Cmp(one) > 0 {
+		return Penalty{}, errors.New("penalty keeps more than 100%")
+	}
+	return penaltyKeeping(kept), nil
+}
+
+// penaltyKeeping returns the penalty keeping a fraction of at most 1.
+func penaltyKeeping(kept num.U128x128) Penalty {
+	penalty, _ := one.Sub(kept)
+	return Penalty{penalty: penalty}
+}
+
+// Proto encodes the penalty.
+func (p Penalty) Proto() *stakev1alpha1.Penalty {
+	return &stakev1alpha1.Penalty{Inner: p.KeptRate().Bytes()}
+}
+
+// KeptRate returns the fraction of a delegation kept after the penalty.
+func (p Penalty) KeptRate() num.U128x128 {
+	kept, _ := one.Sub(p.penalty)
+	return kept
+}
+
+// Compound returns the penalty of applying both penalties in turn, as
+// `Penalty::compound` does.
+func (p Penalty) Compound(q Penalty) Penalty {
+	kept, _ := p.KeptRate().Mul(q.KeptRate())
+	return penaltyKeeping(kept)
+}
+
+// ApplyTo returns the fraction of x kept after the penalty, as
+// `Penalty::apply_to` does. It cannot overflow, as the kept fraction is at
+// most 1.
+func (p Penalty) ApplyTo(x num.U128x128) num.U128x128 {
+	kept, _ := x.Mul(p.KeptRate())
+	return kept
+}
+
+// ApplyToAmount returns the amount left after the penalty, rounding down.
+func (p Penalty) ApplyToAmount(amount num.Amount) num.Amount {
+	a, _ := p.ApplyTo(num.U128x128FromAmount(amount)).RoundDown().Amount()
+	return a
+}
+
+// Slash returns a validator's rate data after the penalty, as
+// `RateData::slash` does: the exchange rate is reduced by the penalty,
+// rounding down.
+func Slash(rate *stakev1alpha1.RateData, p Penalty) *stakev1alpha1.RateData {
+	return &stakev1alpha1.RateData{
+		IdentityKey:           rate.GetIdentityKey(),
+		EpochIndex:            rate.GetEpochIndex(),
+		ValidatorRewardRate:   rate.GetValidatorRewardRate(),
+		ValidatorExchangeRate: p.ApplyToAmount(num.NewAmount(rate.GetValidatorExchangeRate())).Lo(),
+	}
+}
+
+// String formats the penalty as a percentage.
+func (p Penalty) String() string {
+	percent, _ := p.penalty.Mul(num.U128x128FromAmount(num.NewAmount(100)))
+	r, _ := new(big.Rat).SetString(percent.String())
+	return r.FloatString(6) + "%"
+}
@@ -0,0 +1,89 @@
+package stake
+
+import (
+	"encoding/hex"
+	"testing"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+func TestPenalty(t *testing.T) {
+	tenPercent := PenaltyFromBps(1000)
+	tests := []struct {
+		name    string
+		penalty Penalty
+		amount  uint64
+		want    uint64
+	}{
+		{"none", NoPenalty, 1_000, 1_000},
+		{"1%", PenaltyFromBps(100), 1_000, 990},
+		{"10%", tenPercent, 2_0000_0000, 1_8000_0000},
+		{"100%", PenaltyFromBps(1_0000), 1_000, 0},
+		{"capped at 100%", PenaltyFromBps(2_0000), 1_000, 0},
+		// The kept fraction is 1 minus the penalty rounded down, so it is
+		// slightly more than 1 - 1e-8, and rounding down loses a unit.
+		{"1 bps^2", mustPenalty(t, 1), 1_0000_0000, 9999_9999},
+		{"1 bps^2 of more", mustPenalty(t, 1), 1_000_000_000_000, 999_999_990_000},
+		{"10% compounded", tenPercent.Compound(tenPercent), 1_0000_0000, 8100_0000},
+		{"50% compounded", PenaltyFromBps(5000).Compound(PenaltyFromBps(5000)), 1_000, 250},
+		{"a third", mustPenalty(t, 3333_3333), 3_0000_0000, 2_0000_0001},
+	}
+	for _, tt := range tests {
+		if got := tt.penalty.ApplyToAmount(num.NewAmount(tt.amount)); got != num.NewAmount(tt.want) {
+			t.Errorf("%s: ApplyToAmount(%d) = %s, want %d", tt.name, tt.amount, got, tt.want)
+		}
+	}
+
+	// `Penalty::from_percent(10)` slashes an exchange rate of 2 to 1.8.
+	slashed := Slash(&stakev1alpha1.RateData{ValidatorRewardRate: 1_0000_0000, ValidatorExchangeRate: 2_0000_0000}, tenPercent)
+	if slashed.GetValidatorExchangeRate() != 1_8000_0000 || slashed.GetValidatorRewardRate() != 1_0000_0000 {
+		t.Errorf("Slash = %v", slashed)
+	}
+
+	if _, err := PenaltyFromBpsSquared(1_0000_0001); err == nil {
+		t.Errorf("PenaltyFromBpsSquared accepted a penalty above 100%%")
+	}
+	if got := tenPercent.String(); got != "10.000000%" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func mustPenalty(t *testing.T, bpsSquared uint64) Penalty {
+	t.Helper()
+	p, err := PenaltyFromBpsSquared(bpsSquared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPenaltyProto(t *testing.T) {
+	tests := []struct {
+		penalty Penalty
+		kept    string
+	}{
+		{NoPenalty, "0000000000000000000000000000000100000000000000000000000000000000"},
+		{PenaltyFromBps(1000), "00000000000000000000000000000000e6666666666666666666666666666667"},
+		{mustPenalty(t, 3333_3333).Compound(mustPenalty(t, 3333_3333)), "0000000000000000000000000000000071c71c84ddd458148fd41b0393ad55f5"},
+		{PenaltyFromBps(1_0000), "0000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		encoded := tt.penalty.Proto()
+		if got := hex.EncodeToString(encoded.GetInner()); got != tt.kept {
+			t.Errorf("%s: encoded as %s, want %s", tt.penalty, got, tt.kept)
+		}
+		decoded, err := PenaltyFromProto(encoded)
+		if err != nil || decoded.KeptRate().Cmp(tt.penalty.KeptRate()) != 0 {
+			t.Errorf("%s: PenaltyFromProto = %s, %v", tt.penalty, decoded, err)
+		}
+	}
+
+	more, _ := hex.DecodeString("0000000000000000000000000000000100000000000000000000000000000001")
+	if _, err := PenaltyFromProto(&stakev1alpha1.Penalty{Inner: more}); err == nil {
+		t.Errorf("PenaltyFromProto accepted a penalty keeping more than 100%%")
+	}
+	if _, err := PenaltyFromProto(&stakev1alpha1.Penalty{Inner: more[1:]}); err == nil {
+		t.Errorf("PenaltyFromProto accepted a short penalty")
+	}
+}
@@ -0,0 +1,163 @@
+// Package stake provides the fixed-point staking math of Penumbra, mirroring
+// `penumbra_stake`: conversion between staking and delegation tokens, voting
+// power, slashing penalties and the projection of rates to later epochs.
+package stake
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// RateScale is the scale of the fixed-point rates of RateData and
+// BaseRateData: a rate of 1 is represented as 1_0000_0000, so reward rates
+// are in basis points of basis points.
+const RateScale = 1_0000_0000
+
+// MaxCommissionBps is the most a validator's funding streams may take of its
+// rewards.
+const MaxCommissionBps = 1_0000
+
+// ErrZeroExchangeRate is returned when converting with an exchange rate of
+// zero, which pd never produces.
+var ErrZeroExchangeRate = errors.New("exchange rate is zero")
+
+// DelegationAmount returns the delegation tokens minted for delegating an
+// amount of staking tokens at a validator's exchange rate, rounding down, as
+// `RateData::delegation_amount` does.
+func DelegationAmount(rate *stakev1alpha1.RateData, unbonded num.Amount) (num.Amount, error) {
+	if rate.GetValidatorExchangeRate() == 0 {
+		return num.Zero, ErrZeroExchangeRate
+	}
+	product, ok := unbonded.CheckedMul(num.NewAmount(RateScale))
+	if !ok {
+		return num.Zero, fmt.Errorf("delegation value of %s staking tokens overflows", unbonded)
+	}
+	delegation, _ := product.QuoRem(num.NewAmount(rate.GetValidatorExchangeRate()))
+	return delegation, nil
+}
+
+// UnbondedAmount returns the staking tokens released by undelegating an
+// amount of delegation tokens at a validator's exchange rate, rounding down,
+// as `RateData::unbonded_amount` does.
+func UnbondedAmount(rate *stakev1alpha1.RateData, delegation num.Amount) (num.Amount, error) {
+	product, ok := delegation.CheckedMul(num.NewAmount(rate.GetValidatorExchangeRate()))
+	if !ok {
+		return num.Zero, fmt.Errorf("unbonded value of %s delegation tokens overflows", delegation)
+	}
+	unbonded, _ := product.QuoRem(num.NewAmount(RateScale))
+	return unbonded, nil
+}
+
+// VotingPower returns the voting power of a validator whose delegation pool
+// holds delegationPoolSize delegation tokens, as `RateData::voting_power`
+// does: the pool's value in staking tokens, normalized by the base exchange
+// rate so that power does not grow with inflation alone.
+func VotingPower(rate *stakev1alpha1.RateData, delegationPoolSize num.Amount, base *stakev1alpha1.BaseRateData) (uint64, error) {
+	if base.GetBaseExchangeRate() == 0 {
+		return 0, ErrZeroExchangeRate
+	}
+	product, ok := delegationPoolSize.CheckedMul(num.NewAmount(rate.GetValidatorExchangeRate()))
+	if !ok {
+		return 0, fmt.Errorf("voting power of %s delegation tokens overflows", delegationPoolSize)
+	}
+	power, _ := product.QuoRem(num.NewAmount(base.GetBaseExchangeRate()))
+	if power.Hi() != 0 {
+		return 0, fmt.Errorf("voting power %s does not fit in 64 bits", power)
+	}
+	return power.Lo(), nil
+}
+
+// Commission returns the total rate of a validator's funding streams, in
+// basis points.
+func Commission(streams []*stakev1alpha1.FundingStream) (uint64, error) {
+	var total uint64
+	for _, fs := range streams {
+		switch r := fs.GetRecipient().(type) {
+		case *stakev1alpha1.FundingStream_ToAddress_:
+			total += uint64(r.ToAddress.GetRateBps())
+		case *stakev1alpha1.FundingStream_ToDao_:
+			total += uint64(r.ToDao.GetRateBps())
+		}
+	}
+	if total > MaxCommissionBps {
+		return 0, fmt.Errorf("commission rate sums to %dbps, above the maximum of %dbps", total, MaxCommissionBps)
+	}
+	return total, nil
+}
+
+// NextBaseRate returns the base rate data of the next epoch, as
+// `BaseRateData::next` does: the base exchange rate grows by the base reward
+// rate from the stake parameters.
+func NextBaseRate(prev *stakev1alpha1.BaseRateData, baseRewardRate uint64) (*stakev1alpha1.BaseRateData, error) {
+	exchangeRate, err := compound(prev.GetBaseExchangeRate(), baseRewardRate)
+	if err != nil {
+		return nil, fmt.Errorf("base exchange rate: %w", err)
+	}
+	return &stakev1alpha1.BaseRateData{
+		EpochIndex:       prev.GetEpochIndex() + 1,
+		BaseRewardRate:   baseRewardRate,
+		BaseExchangeRate: exchangeRate,
+	}, nil
+}
+
+// NextRate returns a validator's rate data for the next epoch, as
+// `RateData::next` does, given the base rate data of the next epoch. An
+// active validator's reward rate is the base reward rate less its
+// commission, and its exchange rate grows by that reward rate; the rates of
+// any other validator are unchanged.
+func NextRate(prev *stakev1alpha1.RateData, nextBase *stakev1alpha1.BaseRateData, streams []*stakev1alpha1.FundingStream, state stakev1alpha1.ValidatorState_ValidatorStateEnum) (*stakev1alpha1.RateData, error) {
+	next := &stakev1alpha1.RateData{
+		IdentityKey:           prev.GetIdentityKey(),
+		EpochIndex:            prev.GetEpochIndex() + 1,
+		ValidatorRewardRate:   prev.GetValidatorRewardRate(),
+		ValidatorExchangeRate: prev.GetValidatorExchangeRate(),
+	}
+	if state != stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE {
+		return next, nil
+	}
+	commission, err := Commission(streams)
+	if err != nil {
+		return nil, err
+	}
+	hi, lo := bits.Mul64(nextBase.GetBaseRewardRate(), MaxCommissionBps-commission)
+	next.ValidatorRewardRate, _ = bits.Div64(hi, lo, MaxCommissionBps)
+	if next.ValidatorExchangeRate, err = compound(prev.GetValidatorExchangeRate(), next.ValidatorRewardRate); err != nil {
+		return nil, fmt.Errorf("validator exchange rate: %w", err)
+	}
+	return next, nil
+}
+
+// ProjectRate applies NextBaseRate and NextRate for a number of epochs,
+// assuming the base reward rate, funding streams and validator state stay
+// as they are.
+func ProjectRate(rate *stakev1alpha1.RateData, base *stakev1alpha1.BaseRateData, baseRewardRate uint64, streams []*stakev1alpha1.FundingStream, state stakev1alpha1.ValidatorState_ValidatorStateEnum, epochs uint64) (*stakev1alpha1.RateData, *stakev1alpha1.BaseRateData, error) {
+	var err error
+	for i := uint64(0); i < epochs; i++ {
+		if base, err = NextBaseRate(base, baseRewardRate); err != nil {
+			return nil, nil, err
+		}
+		if rate, err = NextRate(rate, base, streams, state); err != nil {
+			return nil, nil, err
+		}
+	}
+	return rate, base, nil
+}
+
+// compound returns exchangeRate * (1 + rewardRate), in RateScale fixed
+// point, rounding down.
+func compound(exchangeRate, rewardRate uint64) (uint64, error) {
+	growth, carry := bits.Add64(rewardRate, RateScale, 0)
+	if carry != 0 {
+		return 0, errors.New("reward rate overflows")
+	}
+	hi, lo := bits.Mul64(exchangeRate, growth)
+	if hi >= RateScale {
+		return 0, errors.New("exchange rate overflows")
+	}
+	q, _ := bits.Div64(hi, lo, RateScale)
+	return q, nil
+}
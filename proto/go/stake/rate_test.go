@@ -0,0 +1,112 @@
+package stake
+
+import (
+	"errors"
+	"testing"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// The expected values below follow the u128 arithmetic of `RateData` and
+// `BaseRateData` in `penumbra_stake::rate`.
+
+func testStreams(bps ...uint32) []*stakev1alpha1.FundingStream {
+	var streams []*stakev1alpha1.FundingStream
+	for i, rate := range bps {
+		if i%2 == 0 {
+			streams = append(streams, &stakev1alpha1.FundingStream{Recipient: &stakev1alpha1.FundingStream_ToAddress_{
+				ToAddress: &stakev1alpha1.FundingStream_ToAddress{RateBps: rate},
+			}})
+		} else {
+			streams = append(streams, &stakev1alpha1.FundingStream{Recipient: &stakev1alpha1.FundingStream_ToDao_{
+				ToDao: &stakev1alpha1.FundingStream_ToDao{RateBps: rate},
+			}})
+		}
+	}
+	return streams
+}
+
+func TestNextRate(t *testing.T) {
+	base := &stakev1alpha1.BaseRateData{EpochIndex: 4, BaseRewardRate: 3_0000, BaseExchangeRate: 1_0200_0000}
+	rate := &stakev1alpha1.RateData{EpochIndex: 4, ValidatorRewardRate: 2_0000, ValidatorExchangeRate: 1_0500_0000}
+	streams := testStreams(500, 300)
+
+	nextBase, err := NextBaseRate(base, 3_0000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextBase.GetEpochIndex() != 5 || nextBase.GetBaseExchangeRate() != 1_0203_0600 {
+		t.Errorf("NextBaseRate = %v", nextBase)
+	}
+
+	next, err := NextRate(rate, nextBase, streams, stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (1_0000_0000 - 800 * 1_0000) * 3_0000 / 1_0000_0000
+	if next.GetValidatorRewardRate() != 2_7600 {
+		t.Errorf("reward rate = %d, want 27600", next.GetValidatorRewardRate())
+	}
+	if next.GetEpochIndex() != 5 || next.GetValidatorExchangeRate() != 1_0502_8980 {
+		t.Errorf("NextRate = %v", next)
+	}
+
+	for _, state := range []stakev1alpha1.ValidatorState_ValidatorStateEnum{
+		stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_INACTIVE,
+		stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_JAILED,
+		stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_DISABLED,
+	} {
+		next, err := NextRate(rate, nextBase, streams, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.GetEpochIndex() != 5 || next.GetValidatorRewardRate() != 2_0000 || next.GetValidatorExchangeRate() != 1_0500_0000 {
+			t.Errorf("NextRate in state %s = %v, want the rates unchanged", state, next)
+		}
+	}
+
+	if _, err := NextRate(rate, nextBase, testStreams(6000, 4001), stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE); err == nil {
+		t.Errorf("NextRate accepted a commission above 100%%")
+	}
+
+	projected, projectedBase, err := ProjectRate(rate, base, 3_0000, streams, stakev1alpha1.ValidatorState_VALIDATOR_STATE_ENUM_ACTIVE, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projected.GetEpochIndex() != 7 || projected.GetValidatorExchangeRate() != 1_0508_6962 {
+		t.Errorf("ProjectRate = %v", projected)
+	}
+	if projectedBase.GetEpochIndex() != 7 || projectedBase.GetBaseExchangeRate() != 1_0209_1827 {
+		t.Errorf("projected base rate = %v", projectedBase)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	rate := &stakev1alpha1.RateData{ValidatorExchangeRate: 1_0500_0000}
+	base := &stakev1alpha1.BaseRateData{BaseExchangeRate: 1_0200_0000}
+
+	delegation, err := DelegationAmount(rate, num.NewAmount(1_000_000))
+	if err != nil || delegation != num.NewAmount(952_380) {
+		t.Errorf("DelegationAmount = %s, %v; want 952380", delegation, err)
+	}
+	// Rounding down both ways loses a unit.
+	unbonded, err := UnbondedAmount(rate, delegation)
+	if err != nil || unbonded != num.NewAmount(999_999) {
+		t.Errorf("UnbondedAmount = %s, %v; want 999999", unbonded, err)
+	}
+	power, err := VotingPower(rate, num.NewAmount(1_000_000), base)
+	if err != nil || power != 1_029_411 {
+		t.Errorf("VotingPower = %d, %v; want 1029411", power, err)
+	}
+
+	if _, err := DelegationAmount(&stakev1alpha1.RateData{}, num.NewAmount(1)); !errors.Is(err, ErrZeroExchangeRate) {
+		t.Errorf("DelegationAmount at a zero exchange rate = %v", err)
+	}
+	if _, err := VotingPower(rate, num.NewAmount(1), &stakev1alpha1.BaseRateData{}); !errors.Is(err, ErrZeroExchangeRate) {
+		t.Errorf("VotingPower at a zero base exchange rate = %v", err)
+	}
+	if _, err := VotingPower(rate, num.NewAmountHiLo(1, 0), base); err == nil {
+		t.Errorf("VotingPower accepted a power above 64 bits")
+	}
+}
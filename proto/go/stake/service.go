@@ -0,0 +1,47 @@
+package stake
+
+import (
+	"context"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// QueryServiceName is the full name of the staking component's
+// QueryService.
+const QueryServiceName = "penumbra.core.component.stake.v1alpha1.QueryService"
+
+// QueryService is the staking component's QueryService API.
+type QueryService interface {
+	ValidatorInfo(ctx context.Context, req *stakev1alpha1.ValidatorInfoRequest) (grpcclient.Stream[stakev1alpha1.ValidatorInfoResponse], error)
+	ValidatorStatus(ctx context.Context, req *stakev1alpha1.ValidatorStatusRequest) (*stakev1alpha1.ValidatorStatusResponse, error)
+	ValidatorPenalty(ctx context.Context, req *stakev1alpha1.ValidatorPenaltyRequest) (*stakev1alpha1.ValidatorPenaltyResponse, error)
+	CurrentValidatorRate(ctx context.Context, req *stakev1alpha1.CurrentValidatorRateRequest) (*stakev1alpha1.CurrentValidatorRateResponse, error)
+}
+
+type grpcQueryService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCQueryService returns a QueryService that calls a fullnode over a
+// gRPC connection, such as one returned by grpc.NewClient.
+func NewGRPCQueryService(conn grpc.ClientConnInterface) QueryService {
+	return &grpcQueryService{svc: grpcclient.Service{Conn: conn, Name: QueryServiceName}}
+}
+
+func (s *grpcQueryService) ValidatorInfo(ctx context.Context, req *stakev1alpha1.ValidatorInfoRequest) (grpcclient.Stream[stakev1alpha1.ValidatorInfoResponse], error) {
+	return grpcclient.OpenStream[stakev1alpha1.ValidatorInfoResponse](ctx, s.svc, "ValidatorInfo", req)
+}
+
+func (s *grpcQueryService) ValidatorStatus(ctx context.Context, req *stakev1alpha1.ValidatorStatusRequest) (*stakev1alpha1.ValidatorStatusResponse, error) {
+	return grpcclient.Call[stakev1alpha1.ValidatorStatusResponse](ctx, s.svc, "ValidatorStatus", req)
+}
+
+func (s *grpcQueryService) ValidatorPenalty(ctx context.Context, req *stakev1alpha1.ValidatorPenaltyRequest) (*stakev1alpha1.ValidatorPenaltyResponse, error) {
+	return grpcclient.Call[stakev1alpha1.ValidatorPenaltyResponse](ctx, s.svc, "ValidatorPenalty", req)
+}
+
+func (s *grpcQueryService) CurrentValidatorRate(ctx context.Context, req *stakev1alpha1.CurrentValidatorRateRequest) (*stakev1alpha1.CurrentValidatorRateResponse, error) {
+	return grpcclient.Call[stakev1alpha1.CurrentValidatorRateResponse](ctx, s.svc, "CurrentValidatorRate", req)
+}
@@ -0,0 +1,38 @@
+package stake
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+)
+
+// unbondingDenom matches the base denom of unbonding tokens, as
+// `penumbra_stake::UnbondingToken` does.
+var unbondingDenom = regexp.MustCompile(`^uunbonding_epoch_([0-9]+)_(penumbravalid1[a-zA-HJ-NP-Z0-9]+)$`)
+
+// UnbondingDenom returns the base denom of the tokens undelegated from a
+// validator in an epoch.
+func UnbondingDenom(ik *keysv1alpha1.IdentityKey, startEpochIndex uint64) string {
+	return fmt.Sprintf("uunbonding_epoch_%d_%s", startEpochIndex, keys.FormatIdentityKey(ik))
+}
+
+// ParseUnbondingDenom returns the validator and the start epoch of an
+// unbonding token's base denom.
+func ParseUnbondingDenom(denom string) (*keysv1alpha1.IdentityKey, uint64, error) {
+	m := unbondingDenom.FindStringSubmatch(denom)
+	if m == nil {
+		return nil, 0, fmt.Errorf("%q is not an unbonding token", denom)
+	}
+	start, err := strconv.ParseUint(m[1], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid start epoch in %q: %w", denom, err)
+	}
+	ik, err := keys.ParseIdentityKey(m[2])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid identity key in %q: %w", denom, err)
+	}
+	return ik, start, nil
+}
@@ -0,0 +1,472 @@
+package stake
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/chain"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/transaction"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+)
+
+// ErrNotClaimable is returned when planning a claim of unbonding tokens
+// before their unbonding period has ended.
+var ErrNotClaimable = errors.New("unbonding tokens are not yet claimable")
+
+// Unbonding is a wallet's unspent unbonding tokens of one validator and
+// start epoch, held by one address.
+type Unbonding struct {
+	IdentityKey     *keysv1alpha1.IdentityKey
+	StartEpochIndex uint64
+	// EndEpochIndex is the epoch the tokens can be claimed from: the end of
+	// the unbonding period, or earlier if the validator itself finishes
+	// unbonding first.
+	EndEpochIndex uint64
+	// Claimable is set if the current epoch is EndEpochIndex or later.
+	Claimable    bool
+	AssetId      *assetv1alpha1.AssetId
+	AddressIndex *keysv1alpha1.AddressIndex
+	Notes        []*viewv1alpha1.SpendableNoteRecord
+	Amount       num.Amount
+}
+
+// Denom returns the base denom of the unbonding tokens.
+func (u *Unbonding) Denom() string {
+	return UnbondingDenom(u.IdentityKey, u.StartEpochIndex)
+}
+
+// Claim is a planned claim of unbonding tokens.
+type Claim struct {
+	Unbonding *Unbonding
+	Penalty   Penalty
+	// Amount is the staking token amount released by the claim, after the
+	// penalty and before the fee.
+	Amount num.Amount
+	Fee    num.Amount
+	Plan   *transactionv1alpha1.TransactionPlan
+}
+
+// Claimer tracks a wallet's unbonding tokens and plans their claims,
+// mirroring `pcli tx undelegate-claim`.
+type Claimer struct {
+	Stake QueryService
+	Chain chain.QueryService
+	View  view.Service
+	// ChainId, if set, is sent with the staking queries, so that a fullnode
+	// serving another chain refuses them, and is recorded in the plans.
+	ChainId  string
+	WalletId *keysv1alpha1.WalletId
+	// Source, if set, restricts the claims to the notes of one account.
+	Source *keysv1alpha1.AddressIndex
+	// Rand is the source of randomizers and blinding factors, crypto/rand
+	// if nil.
+	Rand io.Reader
+}
+
+// Unbondings returns the wallet's unspent unbonding tokens, grouped by
+// address, validator and start epoch, as of the epoch the view server has
+// synced to.
+func (c *Claimer) Unbondings(ctx context.Context) ([]*Unbonding, error) {
+	assets, err := c.assets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch assets: %w", err)
+	}
+	current, err := c.currentEpoch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := c.View.AppParameters(ctx, &viewv1alpha1.AppParametersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch app parameters: %w", err)
+	}
+	if params.GetParameters().GetStakeParams() == nil {
+		return nil, errors.New("app parameters are missing stake parameters")
+	}
+	unbondingEpochs := params.GetParameters().GetStakeParams().GetUnbondingEpochs()
+
+	notes, err := c.View.Notes(ctx, &viewv1alpha1.NotesRequest{AddressIndex: c.Source, WalletId: c.WalletId})
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*Unbonding)
+	var unbondings []*Unbonding
+	for {
+		rsp, err := notes.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := rsp.GetNoteRecord()
+		if record.GetHeightSpent() != 0 {
+			continue
+		}
+		value := record.GetNote().GetValue()
+		d, ok := assets.Get(value.GetAssetId())
+		if !ok {
+			continue
+		}
+		ik, start, err := ParseUnbondingDenom(d.GetBase())
+		if err != nil {
+			continue
+		}
+		index := record.GetAddressIndex()
+		key := fmt.Sprintf("%d/%x/%s", index.GetAccount(), index.GetRandomizer(), d.GetBase())
+		u, ok := byKey[key]
+		if !ok {
+			u = &Unbonding{IdentityKey: ik, StartEpochIndex: start, AssetId: value.GetAssetId(), AddressIndex: index}
+			byKey[key] = u
+			unbondings = append(unbondings, u)
+		}
+		amount, ok := u.Amount.CheckedAdd(num.AmountFromProto(value.GetAmount()))
+		if !ok {
+			return nil, fmt.Errorf("%s notes overflow", d.GetBase())
+		}
+		u.Amount = amount
+		u.Notes = append(u.Notes, record)
+	}
+
+	validatorEnd := make(map[string]uint64)
+	for _, u := range unbondings {
+		id := keys.FormatIdentityKey(u.IdentityKey)
+		end, ok := validatorEnd[id]
+		if !ok {
+			if end, err = c.validatorUnbondingEpoch(ctx, u.IdentityKey); err != nil {
+				return nil, fmt.Errorf("could not fetch status of %s: %w", id, err)
+			}
+			validatorEnd[id] = end
+		}
+		u.EndEpochIndex = min(u.StartEpochIndex+unbondingEpochs, end)
+		u.Claimable = current >= u.EndEpochIndex
+	}
+	sort.SliceStable(unbondings, func(i, j int) bool {
+		return unbondings[i].EndEpochIndex < unbondings[j].EndEpochIndex
+	})
+	return unbondings, nil
+}
+
+// assets returns the staking token and the unbonding tokens known to the
+// view server.
+func (c *Claimer) assets(ctx context.Context) (*asset.Cache, error) {
+	stream, err := c.View.Assets(ctx, &viewv1alpha1.AssetsRequest{
+		Filtered:                     true,
+		IncludeSpecificDenominations: []*assetv1alpha1.Denom{{Denom: asset.StakingTokenBase}},
+		IncludeUnbondingTokens:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache := asset.NewCache()
+	for {
+		rsp, err := stream.Recv()
+		if err == io.EOF {
+			return cache, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cache.Add(rsp.GetDenomMetadata())
+	}
+}
+
+// currentEpoch returns the index of the epoch the view server has synced
+// to.
+func (c *Claimer) currentEpoch(ctx context.Context) (uint64, error) {
+	status, err := c.View.Status(ctx, &viewv1alpha1.StatusRequest{WalletId: c.WalletId})
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch view status: %w", err)
+	}
+	epoch, err := chain.EpochByHeight(ctx, c.Chain, status.GetFullSyncHeight())
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch epoch of height %d: %w", status.GetFullSyncHeight(), err)
+	}
+	return epoch.GetIndex(), nil
+}
+
+// validatorUnbondingEpoch returns the epoch a validator finishes unbonding,
+// or the maximum epoch if it is not unbonding.
+func (c *Claimer) validatorUnbondingEpoch(ctx context.Context, ik *keysv1alpha1.IdentityKey) (uint64, error) {
+	rsp, err := c.Stake.ValidatorStatus(ctx, &stakev1alpha1.ValidatorStatusRequest{ChainId: c.ChainId, IdentityKey: ik})
+	if err != nil {
+		return 0, err
+	}
+	bonding := rsp.GetStatus().GetBondingState()
+	if bonding.GetState() == stakev1alpha1.BondingState_BONDING_STATE_ENUM_UNBONDING {
+		return bonding.GetUnbondingEpoch(), nil
+	}
+	return ^uint64(0), nil
+}
+
+// Plan plans a transaction claiming unbonding tokens: it spends their notes,
+// converts them to staking tokens less the validator's penalty over the
+// unbonding period, and sends those less the fee back to the address that
+// held them.
+func (c *Claimer) Plan(ctx context.Context, u *Unbonding) (*Claim, error) {
+	if !u.Claimable {
+		return nil, fmt.Errorf("%s until epoch %d: %w", u.Denom(), u.EndEpochIndex, ErrNotClaimable)
+	}
+	rsp, err := c.Stake.ValidatorPenalty(ctx, &stakev1alpha1.ValidatorPenaltyRequest{
+		ChainId:         c.ChainId,
+		IdentityKey:     u.IdentityKey,
+		StartEpochIndex: u.StartEpochIndex,
+		EndEpochIndex:   u.EndEpochIndex,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch penalty: %w", err)
+	}
+	if rsp.GetPenalty() == nil {
+		return nil, errors.New("response is missing the penalty")
+	}
+	penalty, err := PenaltyFromProto(rsp.GetPenalty())
+	if err != nil {
+		return nil, err
+	}
+	assets, err := c.assets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch assets: %w", err)
+	}
+	stakingToken, ok := assets.GetByBase(asset.StakingTokenBase)
+	if !ok {
+		return nil, errors.New("view server does not know the staking token")
+	}
+	prices, err := c.View.GasPrices(ctx, &viewv1alpha1.GasPricesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch gas prices: %w", err)
+	}
+	fmd, err := c.View.FMDParameters(ctx, &viewv1alpha1.FMDParametersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch FMD parameters: %w", err)
+	}
+	address, err := c.View.AddressByIndex(ctx, &viewv1alpha1.AddressByIndexRequest{AddressIndex: u.AddressIndex})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch address: %w", err)
+	}
+
+	plan := &transactionv1alpha1.TransactionPlan{ChainId: c.ChainId}
+	for _, record := range u.Notes {
+		spend, err := c.spendPlan(record)
+		if err != nil {
+			return nil, err
+		}
+		plan.Actions = append(plan.Actions, spend)
+	}
+	claim, err := c.claimPlan(u, penalty)
+	if err != nil {
+		return nil, err
+	}
+	plan.Actions = append(plan.Actions, claim)
+
+	// The fee is paid from the claimed tokens, so the output is planned
+	// first to include it in the gas cost.
+	amount := penalty.ApplyToAmount(u.Amount)
+	output, err := c.outputPlan(address.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	gas, err := transaction.PlanGasCost(&transactionv1alpha1.TransactionPlan{Actions: append(plan.Actions, output)})
+	if err != nil {
+		return nil, err
+	}
+	fee := transaction.Price(prices.GetGasPrices(), gas)
+	change, ok := amount.CheckedSub(fee)
+	if !ok {
+		return nil, fmt.Errorf("claimed amount %s does not cover the fee of %s", amount, fee)
+	}
+	plan.Fee = &feev1alpha1.Fee{Amount: fee.Proto()}
+	if !change.IsZero() {
+		output.GetOutput().Value = &assetv1alpha1.Value{Amount: change.Proto(), AssetId: stakingToken.GetPenumbraAssetId()}
+		plan.Actions = append(plan.Actions, output)
+		if plan.CluePlans, err = c.cluePlans(address.GetAddress(), fmd.GetParameters().GetPrecisionBits()); err != nil {
+			return nil, err
+		}
+		if plan.MemoPlan, err = c.memoPlan(address.GetAddress()); err != nil {
+			return nil, err
+		}
+	}
+	return &Claim{Unbonding: u, Penalty: penalty, Amount: amount, Fee: fee, Plan: plan}, nil
+}
+
+func (c *Claimer) spendPlan(record *viewv1alpha1.SpendableNoteRecord) (*transactionv1alpha1.ActionPlan, error) {
+	randomizer, err := decaf377.RandomFr(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	valueBlinding, err := decaf377.RandomFr(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingR, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingS, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_Spend{
+			Spend: &shielded_poolv1alpha1.SpendPlan{
+				Note:           record.GetNote(),
+				Position:       record.GetPosition(),
+				Randomizer:     randomizer,
+				ValueBlinding:  valueBlinding,
+				ProofBlindingR: blindingR,
+				ProofBlindingS: blindingS,
+			},
+		},
+	}, nil
+}
+
+func (c *Claimer) claimPlan(u *Unbonding, penalty Penalty) (*transactionv1alpha1.ActionPlan, error) {
+	balanceBlinding, err := decaf377.RandomFr(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingR, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingS, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_UndelegateClaim{
+			UndelegateClaim: &stakev1alpha1.UndelegateClaimPlan{
+				ValidatorIdentity: u.IdentityKey,
+				StartEpochIndex:   u.StartEpochIndex,
+				Penalty:           penalty.Proto(),
+				UnbondingAmount:   u.Amount.Proto(),
+				BalanceBlinding:   balanceBlinding,
+				ProofBlindingR:    blindingR,
+				ProofBlindingS:    blindingS,
+			},
+		},
+	}, nil
+}
+
+// outputPlan returns an output to an address, with its value left to be
+// filled in.
+func (c *Claimer) outputPlan(address *keysv1alpha1.Address) (*transactionv1alpha1.ActionPlan, error) {
+	rseed, err := c.randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	valueBlinding, err := decaf377.RandomFr(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingR, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	blindingS, err := decaf377.RandomFq(c.Rand)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_Output{
+			Output: &shielded_poolv1alpha1.OutputPlan{
+				DestAddress:    address,
+				Rseed:          rseed,
+				ValueBlinding:  valueBlinding,
+				ProofBlindingR: blindingR,
+				ProofBlindingS: blindingS,
+			},
+		},
+	}, nil
+}
+
+// cluePlans returns the clue of the single output, as pd requires one clue
+// per output.
+func (c *Claimer) cluePlans(address *keysv1alpha1.Address, precisionBits uint32) ([]*transactionv1alpha1.CluePlan, error) {
+	rseed, err := c.randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	return []*transactionv1alpha1.CluePlan{{Address: address, Rseed: rseed, PrecisionBits: uint64(precisionBits)}}, nil
+}
+
+// memoPlan returns a blank memo, which pd requires of transactions with
+// outputs.
+func (c *Claimer) memoPlan(returnAddress *keysv1alpha1.Address) (*transactionv1alpha1.MemoPlan, error) {
+	key, err := c.randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionv1alpha1.MemoPlan{
+		Plaintext: &transactionv1alpha1.MemoPlaintext{ReturnAddress: returnAddress},
+		Key:       key,
+	}, nil
+}
+
+func (c *Claimer) randomBytes(n int) ([]byte, error) {
+	r := c.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Submit authorizes, builds and broadcasts a planned claim through the view
+// server, waiting until the view server detects the transaction. auth may
+// be nil if the view server authorizes plans with its own custody service.
+func (c *Claimer) Submit(ctx context.Context, claim *Claim, auth *transactionv1alpha1.AuthorizationData) (*viewv1alpha1.BroadcastTransactionResponse, error) {
+	built, err := c.View.AuthorizeAndBuild(ctx, &viewv1alpha1.AuthorizeAndBuildRequest{TransactionPlan: claim.Plan, AuthorizationData: auth})
+	if err != nil {
+		return nil, fmt.Errorf("could not build claim of %s: %w", claim.Unbonding.Denom(), err)
+	}
+	rsp, err := c.View.BroadcastTransaction(ctx, &viewv1alpha1.BroadcastTransactionRequest{Transaction: built.GetTransaction(), AwaitDetection: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not broadcast claim of %s: %w", claim.Unbonding.Denom(), err)
+	}
+	return rsp, nil
+}
+
+// ClaimAll plans and submits a claim of every claimable unbonding, one
+// transaction each, using the view server's custody service. It returns the
+// submitted claims, and keeps going after a failed claim.
+func (c *Claimer) ClaimAll(ctx context.Context) ([]*Claim, error) {
+	unbondings, err := c.Unbondings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims []*Claim
+	var errs []error
+	for _, u := range unbondings {
+		if !u.Claimable {
+			continue
+		}
+		claim, err := c.Plan(ctx, u)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not plan claim of %s: %w", u.Denom(), err))
+			continue
+		}
+		if _, err := c.Submit(ctx, claim, nil); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		claims = append(claims, claim)
+	}
+	return claims, errors.Join(errs...)
+}
@@ -0,0 +1,280 @@
+package stake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	appv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/app/v1alpha1"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	chainv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/chain/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+	"github.com/penumbra-zone/penumbra/proto/go/transaction"
+	"github.com/penumbra-zone/penumbra/proto/go/view"
+)
+
+const testUnbondingEpochs = 10
+
+type sliceStream[T any] struct {
+	items []*T
+}
+
+func (s *sliceStream[T]) Recv() (*T, error) {
+	if len(s.items) == 0 {
+		return nil, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, nil
+}
+
+// fakeStake serves validator bonding states, and penalties compounded over
+// epoch ranges from per-epoch penalties, as pd's
+// `compounded_penalty_over_range` does.
+type fakeStake struct {
+	QueryService
+	bonding   map[string]*stakev1alpha1.BondingState
+	penalties map[string]map[uint64]Penalty
+	requests  []*stakev1alpha1.ValidatorPenaltyRequest
+}
+
+func (s *fakeStake) ValidatorStatus(_ context.Context, req *stakev1alpha1.ValidatorStatusRequest) (*stakev1alpha1.ValidatorStatusResponse, error) {
+	bonding, ok := s.bonding[keys.FormatIdentityKey(req.GetIdentityKey())]
+	if !ok {
+		bonding = &stakev1alpha1.BondingState{State: stakev1alpha1.BondingState_BONDING_STATE_ENUM_BONDED}
+	}
+	return &stakev1alpha1.ValidatorStatusResponse{Status: &stakev1alpha1.ValidatorStatus{
+		IdentityKey:  req.GetIdentityKey(),
+		BondingState: bonding,
+	}}, nil
+}
+
+func (s *fakeStake) ValidatorPenalty(_ context.Context, req *stakev1alpha1.ValidatorPenaltyRequest) (*stakev1alpha1.ValidatorPenaltyResponse, error) {
+	s.requests = append(s.requests, req)
+	compounded := NoPenalty
+	for epoch := req.GetStartEpochIndex(); epoch < req.GetEndEpochIndex(); epoch++ {
+		if p, ok := s.penalties[keys.FormatIdentityKey(req.GetIdentityKey())][epoch]; ok {
+			compounded = compounded.Compound(p)
+		}
+	}
+	return &stakev1alpha1.ValidatorPenaltyResponse{Penalty: compounded.Proto()}, nil
+}
+
+type fakeChain struct {
+	epoch uint64
+}
+
+func (c *fakeChain) EpochByHeight(context.Context, *chainv1alpha1.EpochByHeightRequest) (*chainv1alpha1.EpochByHeightResponse, error) {
+	return &chainv1alpha1.EpochByHeightResponse{Epoch: &chainv1alpha1.Epoch{Index: c.epoch}}, nil
+}
+
+// fakeWallet holds unbonding notes.
+type fakeWallet struct {
+	view.Service
+	assets []*assetv1alpha1.DenomMetadata
+	notes  []*viewv1alpha1.SpendableNoteRecord
+}
+
+var (
+	testStakingToken = &assetv1alpha1.DenomMetadata{Base: "upenumbra", PenumbraAssetId: &assetv1alpha1.AssetId{Inner: bytes.Repeat([]byte{0xff}, 32)}}
+	testGasPrices    = &feev1alpha1.GasPrices{BlockSpacePrice: 1, CompactBlockSpacePrice: 2, VerificationPrice: 3, ExecutionPrice: 4}
+	testAddress      = &keysv1alpha1.Address{Inner: bytes.Repeat([]byte{9}, 80)}
+)
+
+func testIdentityKey(n byte) *keysv1alpha1.IdentityKey {
+	return &keysv1alpha1.IdentityKey{Ik: bytes.Repeat([]byte{n}, 32)}
+}
+
+// addNote adds a note of unbonding tokens of a validator and start epoch.
+func (w *fakeWallet) addNote(ik *keysv1alpha1.IdentityKey, start, amount, heightSpent uint64) {
+	base := UnbondingDenom(ik, start)
+	var id *assetv1alpha1.AssetId
+	for _, d := range w.assets {
+		if d.GetBase() == base {
+			id = d.GetPenumbraAssetId()
+		}
+	}
+	if id == nil {
+		id = &assetv1alpha1.AssetId{Inner: bytes.Repeat([]byte{byte(len(w.assets) + 1)}, 32)}
+		w.assets = append(w.assets, &assetv1alpha1.DenomMetadata{Base: base, PenumbraAssetId: id})
+	}
+	w.notes = append(w.notes, &viewv1alpha1.SpendableNoteRecord{
+		Note:         &shielded_poolv1alpha1.Note{Value: &assetv1alpha1.Value{Amount: num.NewAmount(amount).Proto(), AssetId: id}},
+		AddressIndex: &keysv1alpha1.AddressIndex{Account: 0},
+		HeightSpent:  heightSpent,
+	})
+}
+
+func (w *fakeWallet) Assets(context.Context, *viewv1alpha1.AssetsRequest) (grpcclient.Stream[viewv1alpha1.AssetsResponse], error) {
+	s := &sliceStream[viewv1alpha1.AssetsResponse]{}
+	for _, d := range append([]*assetv1alpha1.DenomMetadata{testStakingToken}, w.assets...) {
+		s.items = append(s.items, &viewv1alpha1.AssetsResponse{DenomMetadata: d})
+	}
+	return s, nil
+}
+
+func (w *fakeWallet) Notes(context.Context, *viewv1alpha1.NotesRequest) (grpcclient.Stream[viewv1alpha1.NotesResponse], error) {
+	s := &sliceStream[viewv1alpha1.NotesResponse]{}
+	for _, n := range w.notes {
+		s.items = append(s.items, &viewv1alpha1.NotesResponse{NoteRecord: n})
+	}
+	return s, nil
+}
+
+func (w *fakeWallet) Status(context.Context, *viewv1alpha1.StatusRequest) (*viewv1alpha1.StatusResponse, error) {
+	return &viewv1alpha1.StatusResponse{FullSyncHeight: 1200}, nil
+}
+
+func (w *fakeWallet) AppParameters(context.Context, *viewv1alpha1.AppParametersRequest) (*viewv1alpha1.AppParametersResponse, error) {
+	return &viewv1alpha1.AppParametersResponse{Parameters: &appv1alpha1.AppParameters{
+		StakeParams: &stakev1alpha1.StakeParameters{UnbondingEpochs: testUnbondingEpochs},
+	}}, nil
+}
+
+func (w *fakeWallet) GasPrices(context.Context, *viewv1alpha1.GasPricesRequest) (*viewv1alpha1.GasPricesResponse, error) {
+	return &viewv1alpha1.GasPricesResponse{GasPrices: testGasPrices}, nil
+}
+
+func (w *fakeWallet) FMDParameters(context.Context, *viewv1alpha1.FMDParametersRequest) (*viewv1alpha1.FMDParametersResponse, error) {
+	return &viewv1alpha1.FMDParametersResponse{}, nil
+}
+
+func (w *fakeWallet) AddressByIndex(context.Context, *viewv1alpha1.AddressByIndexRequest) (*viewv1alpha1.AddressByIndexResponse, error) {
+	return &viewv1alpha1.AddressByIndexResponse{Address: testAddress}, nil
+}
+
+func TestUnbondings(t *testing.T) {
+	bonded, unbonding, unbonded, late := testIdentityKey(1), testIdentityKey(2), testIdentityKey(3), testIdentityKey(4)
+	stake := &fakeStake{bonding: map[string]*stakev1alpha1.BondingState{
+		keys.FormatIdentityKey(unbonding): {State: stakev1alpha1.BondingState_BONDING_STATE_ENUM_UNBONDING, UnbondingEpoch: 8},
+		keys.FormatIdentityKey(unbonded):  {State: stakev1alpha1.BondingState_BONDING_STATE_ENUM_UNBONDED},
+		keys.FormatIdentityKey(late):      {State: stakev1alpha1.BondingState_BONDING_STATE_ENUM_UNBONDING, UnbondingEpoch: 20},
+	}}
+	w := &fakeWallet{}
+	w.addNote(bonded, 5, 100, 0)
+	w.addNote(unbonding, 5, 200, 0)
+	w.addNote(unbonding, 5, 300, 0)
+	w.addNote(unbonding, 5, 400, 7)
+	w.addNote(unbonded, 1, 500, 0)
+	w.addNote(late, 5, 600, 0)
+	c := &Claimer{Stake: stake, Chain: &fakeChain{epoch: 12}, View: w}
+
+	unbondings, err := c.Unbondings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The end epoch is the end of the unbonding period, or the epoch the
+	// validator finishes unbonding if that is earlier, as in pd's
+	// `unbonding_end_epoch_for`. A validator that has already unbonded
+	// leaves the unbonding period as it is.
+	tests := []struct {
+		ik         *keysv1alpha1.IdentityKey
+		start, end uint64
+		claimable  bool
+		amount     uint64
+		notes      int
+	}{
+		{unbonding, 5, 8, true, 500, 2},
+		{unbonded, 1, 11, true, 500, 1},
+		{bonded, 5, 15, false, 100, 1},
+		{late, 5, 15, false, 600, 1},
+	}
+	if len(unbondings) != len(tests) {
+		t.Fatalf("%d unbondings, want %d", len(unbondings), len(tests))
+	}
+	for i, tt := range tests {
+		u := unbondings[i]
+		if !bytes.Equal(u.IdentityKey.GetIk(), tt.ik.GetIk()) || u.StartEpochIndex != tt.start {
+			t.Errorf("unbonding %d is %s, want %s", i, u.Denom(), UnbondingDenom(tt.ik, tt.start))
+			continue
+		}
+		if u.EndEpochIndex != tt.end || u.Claimable != tt.claimable || u.Amount != num.NewAmount(tt.amount) || len(u.Notes) != tt.notes {
+			t.Errorf("%s: end %d, claimable %v, amount %s over %d notes; want %d, %v, %d over %d",
+				u.Denom(), u.EndEpochIndex, u.Claimable, u.Amount, len(u.Notes), tt.end, tt.claimable, tt.amount, tt.notes)
+		}
+	}
+}
+
+func TestClaimPenalty(t *testing.T) {
+	ik := testIdentityKey(1)
+	id := keys.FormatIdentityKey(ik)
+	tenPercent, fivePercent := PenaltyFromBps(1000), PenaltyFromBps(500)
+	tests := []struct {
+		name      string
+		penalties map[uint64]Penalty
+		want      Penalty
+		amount    uint64
+	}{
+		{"unslashed", nil, NoPenalty, 1_000_000},
+		// Slashing at epoch 7, between the start at 5 and the end at 15.
+		{"slashed mid-range", map[uint64]Penalty{7: tenPercent}, tenPercent, 900_000},
+		{"slashed twice", map[uint64]Penalty{6: tenPercent, 9: fivePercent}, tenPercent.Compound(fivePercent), 855_000},
+		// The range includes the start epoch but not the end epoch.
+		{"slashed at the bounds", map[uint64]Penalty{4: tenPercent, 5: fivePercent, 15: tenPercent}, fivePercent, 950_000},
+	}
+	for _, tt := range tests {
+		stake := &fakeStake{penalties: map[string]map[uint64]Penalty{id: tt.penalties}}
+		w := &fakeWallet{}
+		w.addNote(ik, 5, 400_000, 0)
+		w.addNote(ik, 5, 600_000, 0)
+		c := &Claimer{Stake: stake, Chain: &fakeChain{epoch: 15}, View: w, ChainId: "penumbra-testnet"}
+		unbondings, err := c.Unbondings(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		claim, err := c.Plan(context.Background(), unbondings[0])
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		req := stake.requests[0]
+		if req.GetStartEpochIndex() != 5 || req.GetEndEpochIndex() != 15 || req.GetChainId() != "penumbra-testnet" {
+			t.Errorf("%s: penalty requested over %d..%d", tt.name, req.GetStartEpochIndex(), req.GetEndEpochIndex())
+		}
+		if claim.Penalty.KeptRate().Cmp(tt.want.KeptRate()) != 0 {
+			t.Errorf("%s: penalty %s, want %s", tt.name, claim.Penalty, tt.want)
+		}
+		if claim.Amount != num.NewAmount(tt.amount) {
+			t.Errorf("%s: claimed %s, want %d", tt.name, claim.Amount, tt.amount)
+		}
+
+		actions := claim.Plan.GetActions()
+		if len(actions) != 4 || actions[0].GetSpend() == nil || actions[1].GetSpend() == nil {
+			t.Fatalf("%s: plan actions %v, want two spends, the claim and an output", tt.name, actions)
+		}
+		uc := actions[2].GetUndelegateClaim()
+		if !bytes.Equal(uc.GetPenalty().GetInner(), tt.want.Proto().GetInner()) ||
+			uc.GetStartEpochIndex() != 5 || num.AmountFromProto(uc.GetUnbondingAmount()) != num.NewAmount(1_000_000) {
+			t.Errorf("%s: claim plan %v", tt.name, uc)
+		}
+		gas, _ := transaction.PlanGasCost(claim.Plan)
+		fee := transaction.Price(testGasPrices, gas)
+		out := actions[3].GetOutput().GetValue()
+		if change, _ := claim.Amount.CheckedSub(fee); num.AmountFromProto(out.GetAmount()) != change || claim.Fee != fee ||
+			!bytes.Equal(out.GetAssetId().GetInner(), testStakingToken.GetPenumbraAssetId().GetInner()) {
+			t.Errorf("%s: output of %v with fee %s, want the claim less a fee of %s", tt.name, out, claim.Fee, fee)
+		}
+	}
+}
+
+func TestPlanNotClaimable(t *testing.T) {
+	ik := testIdentityKey(1)
+	w := &fakeWallet{}
+	w.addNote(ik, 5, 100, 0)
+	c := &Claimer{Stake: &fakeStake{}, Chain: &fakeChain{epoch: 14}, View: w}
+	unbondings, err := c.Unbondings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Plan(context.Background(), unbondings[0]); !errors.Is(err, ErrNotClaimable) {
+		t.Errorf("Plan before the end epoch = %v, want ErrNotClaimable", err)
+	}
+}
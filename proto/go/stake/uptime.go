@@ -0,0 +1,146 @@
+package stake
+
+import (
+	"fmt"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+)
+
+// Uptime records which of the last blocks a validator signed, mirroring
+// `penumbra_stake::Uptime`. The signature for height h is bit h mod
+// WindowLen of the window, which is packed least significant bit first, as
+// a `BitVec<u8, Lsb0>`.
+type Uptime struct {
+	AsOfBlockHeight uint64
+	windowLen       uint64
+	bitvec          []byte
+}
+
+// NewUptime returns the uptime of a validator that starts signing after
+// initialBlockHeight: every block of the window counts as signed.
+func NewUptime(initialBlockHeight, windowLen uint64) *Uptime {
+	u := &Uptime{AsOfBlockHeight: initialBlockHeight, windowLen: windowLen, bitvec: make([]byte, (windowLen+7)/8)}
+	for i := range u.bitvec {
+		u.bitvec[i] = 0xff
+	}
+	return u
+}
+
+// UptimeFromProto decodes an uptime.
+func UptimeFromProto(u *stakev1alpha1.Uptime) (*Uptime, error) {
+	windowLen := uint64(u.GetWindowLen())
+	if windowLen == 0 {
+		return nil, fmt.Errorf("uptime window is empty")
+	}
+	if windowLen > uint64(len(u.GetBitvec()))*8 {
+		return nil, fmt.Errorf("uptime window of %d blocks is longer than its %d-byte bitvec", windowLen, len(u.GetBitvec()))
+	}
+	return &Uptime{
+		AsOfBlockHeight: u.GetAsOfBlockHeight(),
+		windowLen:       windowLen,
+		bitvec:          append([]byte(nil), u.GetBitvec()[:(windowLen+7)/8]...),
+	}, nil
+}
+
+// Proto encodes the uptime. Bits past the end of the window are set, as
+// pd canonicalizes them.
+func (u *Uptime) Proto() *stakev1alpha1.Uptime {
+	bitvec := append([]byte(nil), u.bitvec...)
+	if rem := u.windowLen % 8; rem != 0 {
+		bitvec[len(bitvec)-1] |= 0xff << rem
+	}
+	return &stakev1alpha1.Uptime{
+		AsOfBlockHeight: u.AsOfBlockHeight,
+		WindowLen:       uint32(u.windowLen),
+		Bitvec:          bitvec,
+	}
+}
+
+// WindowLen returns the number of blocks recorded.
+func (u *Uptime) WindowLen() uint64 {
+	return u.windowLen
+}
+
+func (u *Uptime) bit(height uint64) bool {
+	i := height % u.windowLen
+	return u.bitvec[i/8]&(1<<(i%8)) != 0
+}
+
+func (u *Uptime) setBit(height uint64, signed bool) {
+	i := height % u.windowLen
+	if signed {
+		u.bitvec[i/8] |= 1 << (i % 8)
+	} else {
+		u.bitvec[i/8] &^= 1 << (i % 8)
+	}
+}
+
+// MarkHeightAsSigned records whether the validator signed the block
+// following AsOfBlockHeight.
+func (u *Uptime) MarkHeightAsSigned(height uint64, signed bool) error {
+	if height != u.AsOfBlockHeight+1 {
+		return fmt.Errorf("uptime is as of height %d, cannot record height %d", u.AsOfBlockHeight, height)
+	}
+	u.setBit(height, signed)
+	u.AsOfBlockHeight = height
+	return nil
+}
+
+// earliestHeight returns the first height in the window.
+func (u *Uptime) earliestHeight() uint64 {
+	if u.AsOfBlockHeight < u.windowLen-1 {
+		return 0
+	}
+	return u.AsOfBlockHeight - (u.windowLen - 1)
+}
+
+// MissedBlocks returns the heights in the window the validator did not
+// sign, in increasing order.
+func (u *Uptime) MissedBlocks() []uint64 {
+	var missed []uint64
+	for h := u.earliestHeight(); h <= u.AsOfBlockHeight; h++ {
+		if !u.bit(h) {
+			missed = append(missed, h)
+		}
+	}
+	return missed
+}
+
+// NumMissedBlocks returns the number of unsigned blocks in the window.
+func (u *Uptime) NumMissedBlocks() uint64 {
+	var n uint64
+	for i := uint64(0); i < u.windowLen; i++ {
+		if u.bitvec[i/8]&(1<<(i%8)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Jailed reports whether pd jails a validator with this uptime: once it has
+// missed missedBlocksMaximum blocks of the window.
+func (u *Uptime) Jailed(missedBlocksMaximum uint64) bool {
+	return u.NumMissedBlocks() >= missedBlocksMaximum
+}
+
+// BlocksUntilJailed returns how many more consecutive blocks the validator
+// can miss before being jailed, counting the block that jails it. It is
+// false if the validator cannot be jailed by missing blocks, because the
+// window is shorter than missedBlocksMaximum.
+func (u *Uptime) BlocksUntilJailed(missedBlocksMaximum uint64) (uint64, bool) {
+	missed := u.NumMissedBlocks()
+	if missed >= missedBlocksMaximum {
+		return 0, true
+	}
+	// Each missed block overwrites the oldest record in the window, which
+	// only adds a missed block if that record was signed.
+	for n := uint64(1); n <= u.windowLen; n++ {
+		if u.bit(u.AsOfBlockHeight + n) {
+			missed++
+		}
+		if missed >= missedBlocksMaximum {
+			return n, true
+		}
+	}
+	return 0, false
+}
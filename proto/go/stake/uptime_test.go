@@ -0,0 +1,104 @@
+package stake
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+)
+
+// TestUptimeCountsMissedBlocks follows `counts_missed_blocks` in
+// `penumbra_stake::uptime`.
+func TestUptimeCountsMissedBlocks(t *testing.T) {
+	const window = 128
+	u := NewUptime(0, window)
+	// Miss every 4th block for a full window.
+	for h := uint64(1); h <= window; h++ {
+		if err := u.MarkHeightAsSigned(h, h%4 != 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := u.NumMissedBlocks(); got != window/4 {
+		t.Errorf("missed %d blocks, want %d", got, window/4)
+	}
+	// Then miss none, which forgets the old records.
+	for h := uint64(window + 1); h <= 2*window; h++ {
+		if err := u.MarkHeightAsSigned(h, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := u.NumMissedBlocks(); got != 0 {
+		t.Errorf("missed %d blocks after a full window of signatures", got)
+	}
+	if err := u.MarkHeightAsSigned(0, true); err == nil {
+		t.Errorf("MarkHeightAsSigned accepted a past height")
+	}
+	if err := u.MarkHeightAsSigned(2*window+2, true); err == nil {
+		t.Errorf("MarkHeightAsSigned accepted a skipped height")
+	}
+}
+
+// TestUptimeProtoRoundTrip follows `proto_round_trip` in
+// `penumbra_stake::uptime`.
+func TestUptimeProtoRoundTrip(t *testing.T) {
+	u := NewUptime(0, 113)
+	for h := uint64(1); h < 300; h++ {
+		if err := u.MarkHeightAsSigned(h, h%13 != 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decoded, err := UptimeFromProto(u.Proto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Proto(), u.Proto()) || decoded.NumMissedBlocks() != u.NumMissedBlocks() {
+		t.Errorf("round trip changed the uptime")
+	}
+	if !reflect.DeepEqual(decoded.MissedBlocks(), u.MissedBlocks()) {
+		t.Errorf("round trip changed the missed blocks: %v, want %v", decoded.MissedBlocks(), u.MissedBlocks())
+	}
+}
+
+func TestUptimeBitvec(t *testing.T) {
+	u := NewUptime(0, 10)
+	u.MarkHeightAsSigned(1, false)
+	u.MarkHeightAsSigned(2, true)
+	u.MarkHeightAsSigned(3, false)
+
+	// Bits 1 and 3 are clear, and the bits past the window are set.
+	if got := u.Proto().GetBitvec(); !bytes.Equal(got, []byte{0xf5, 0xff}) {
+		t.Errorf("bitvec = %x, want f5ff", got)
+	}
+	if got := u.MissedBlocks(); !reflect.DeepEqual(got, []uint64{1, 3}) {
+		t.Errorf("MissedBlocks = %v, want [1 3]", got)
+	}
+	if !u.Jailed(2) || u.Jailed(3) {
+		t.Errorf("Jailed is wrong with 2 missed blocks")
+	}
+	if n, ok := u.BlocksUntilJailed(5); !ok || n != 3 {
+		t.Errorf("BlocksUntilJailed(5) = %d, %v; want 3", n, ok)
+	}
+	if _, ok := u.BlocksUntilJailed(11); ok {
+		t.Errorf("BlocksUntilJailed reported jailing beyond the window")
+	}
+
+	// Decoding ignores the bits past the window, and encoding sets them.
+	decoded, err := UptimeFromProto(&stakev1alpha1.Uptime{AsOfBlockHeight: 3, WindowLen: 10, Bitvec: []byte{0xf5, 0x00}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.NumMissedBlocks(); got != 4 {
+		t.Errorf("decoded uptime missed %d blocks, want 4", got)
+	}
+	if got := decoded.Proto().GetBitvec(); !bytes.Equal(got, []byte{0xf5, 0xfc}) {
+		t.Errorf("re-encoded bitvec = %x, want f5fc", got)
+	}
+
+	if _, err := UptimeFromProto(&stakev1alpha1.Uptime{WindowLen: 17, Bitvec: []byte{0xff, 0xff}}); err == nil {
+		t.Errorf("UptimeFromProto accepted a bitvec shorter than the window")
+	}
+	if _, err := UptimeFromProto(&stakev1alpha1.Uptime{}); err == nil {
+		t.Errorf("UptimeFromProto accepted an empty window")
+	}
+}
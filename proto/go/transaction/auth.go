@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"fmt"
+
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+)
+
+// AuthSlot is a signature required to authorize a transaction plan: the
+// spend authorization key, randomized by Randomizer, must sign the effect
+// hash of the plan.
+type AuthSlot struct {
+	// Index is the position of the signature in its AuthorizationData list.
+	Index int
+	// ActionIndex is the position of the action in the plan.
+	ActionIndex int
+	Randomizer  []byte
+}
+
+// SpendAuthSlots returns the layout of AuthorizationData.SpendAuths: one
+// signature per Spend action, in plan order.
+func SpendAuthSlots(plan *transactionv1alpha1.TransactionPlan) []AuthSlot {
+	var slots []AuthSlot
+	for i, action := range plan.GetActions() {
+		if spend := action.GetSpend(); spend != nil {
+			slots = append(slots, AuthSlot{Index: len(slots), ActionIndex: i, Randomizer: spend.GetRandomizer()})
+		}
+	}
+	return slots
+}
+
+// DelegatorVoteAuthSlots returns the layout of
+// AuthorizationData.DelegatorVoteAuths: one signature per DelegatorVote
+// action, in plan order.
+func DelegatorVoteAuthSlots(plan *transactionv1alpha1.TransactionPlan) []AuthSlot {
+	var slots []AuthSlot
+	for i, action := range plan.GetActions() {
+		if vote := action.GetDelegatorVote(); vote != nil {
+			slots = append(slots, AuthSlot{Index: len(slots), ActionIndex: i, Randomizer: vote.GetRandomizer()})
+		}
+	}
+	return slots
+}
+
+// CheckAuthorizationData checks that authorization data has a signature for
+// every slot of the plan.
+func CheckAuthorizationData(plan *transactionv1alpha1.TransactionPlan, auth *transactionv1alpha1.AuthorizationData) error {
+	if n, want := len(auth.GetSpendAuths()), len(SpendAuthSlots(plan)); n != want {
+		return fmt.Errorf("authorization data has %d spend auths, expected %d", n, want)
+	}
+	if n, want := len(auth.GetDelegatorVoteAuths()), len(DelegatorVoteAuthSlots(plan)); n != want {
+		return fmt.Errorf("authorization data has %d delegator vote auths, expected %d", n, want)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/shieldedpool"
+)
+
+// Lengths of the fixed-size plaintexts, matching the Rust definitions in
+// `penumbra-shielded-pool`, `penumbra-dex` and `penumbra-transaction`.
+const (
+	PayloadKeyLenBytes = 32
+	AddressLenBytes    = 80
+	NoteLenBytes       = 160
+	SwapLenBytes       = 256
+	MemoLenBytes       = 512
+)
+
+// ErrDecryption is returned when a payload cannot be decrypted or does not
+// decode to a well-formed plaintext.
+var ErrDecryption = errors.New("decryption error")
+
+// PayloadKind selects the nonce used when decrypting a payload with a
+// PayloadKey, mirroring `penumbra_keys::symmetric::PayloadKind`.
+type PayloadKind int
+
+const (
+	// PayloadKindNote is action-scoped.
+	PayloadKindNote PayloadKind = iota
+	// PayloadKindMemoKey is action-scoped.
+	PayloadKindMemoKey
+	// PayloadKindMemo is transaction-scoped.
+	PayloadKindMemo
+	// PayloadKindSwap is action-scoped, and uses the swap commitment as nonce.
+	PayloadKindSwap
+)
+
+func (k PayloadKind) nonce(commitment []byte) ([]byte, error) {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	switch k {
+	case PayloadKindNote:
+	case PayloadKindMemoKey:
+		nonce[0] = 1
+	case PayloadKindMemo:
+		nonce[0] = 3
+	case PayloadKindSwap:
+		if len(commitment) < chacha20poly1305.NonceSize {
+			return nil, fmt.Errorf("swap commitment too short for nonce: %d bytes", len(commitment))
+		}
+		copy(nonce, commitment[:chacha20poly1305.NonceSize])
+	default:
+		return nil, fmt.Errorf("unknown payload kind %d", k)
+	}
+	return nonce, nil
+}
+
+// DecryptPayload decrypts a ChaCha20Poly1305 ciphertext with the given payload
+// key. The commitment is only used for PayloadKindSwap.
+func DecryptPayload(key *keysv1alpha1.PayloadKey, kind PayloadKind, ciphertext, commitment []byte) ([]byte, error) {
+	if len(key.GetInner()) != PayloadKeyLenBytes {
+		return nil, fmt.Errorf("payload key has incorrect length %d", len(key.GetInner()))
+	}
+	aead, err := chacha20poly1305.New(key.GetInner())
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := kind.nonce(commitment)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+// DecryptNote decrypts an encrypted note with its payload key, and checks
+// that the note's ephemeral public key is the one in its payload, as
+// `Note::decrypt_with_payload_key` does (see ZIP 212).
+func DecryptNote(key *keysv1alpha1.PayloadKey, ciphertext *shielded_poolv1alpha1.NoteCiphertext, epk []byte) (*shielded_poolv1alpha1.Note, error) {
+	plaintext, err := DecryptPayload(key, PayloadKindNote, ciphertext.GetInner(), nil)
+	if err != nil {
+		return nil, err
+	}
+	note, err := NoteFromBytes(plaintext)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	derived, err := shieldedpool.EphemeralPublicKey(note)
+	if err != nil || !bytes.Equal(derived, epk) {
+		return nil, ErrDecryption
+	}
+	return note, nil
+}
+
+// DecryptMemoKey unwraps the memo key carried by an output, using the
+// output's payload key.
+func DecryptMemoKey(key *keysv1alpha1.PayloadKey, wrappedMemoKey []byte) (*keysv1alpha1.PayloadKey, error) {
+	plaintext, err := DecryptPayload(key, PayloadKindMemoKey, wrappedMemoKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) != PayloadKeyLenBytes {
+		return nil, ErrDecryption
+	}
+	return &keysv1alpha1.PayloadKey{Inner: plaintext}, nil
+}
+
+// DecryptMemo decrypts the transaction memo with the memo key.
+func DecryptMemo(memoKey *keysv1alpha1.PayloadKey, ciphertext *transactionv1alpha1.MemoCiphertext) (*transactionv1alpha1.MemoPlaintext, error) {
+	plaintext, err := DecryptPayload(memoKey, PayloadKindMemo, ciphertext.GetInner(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) != MemoLenBytes {
+		return nil, ErrDecryption
+	}
+	return &transactionv1alpha1.MemoPlaintext{
+		ReturnAddress: &keysv1alpha1.Address{Inner: plaintext[:AddressLenBytes]},
+		Text:          string(bytes.ToValidUTF8(bytes.TrimRight(plaintext[AddressLenBytes:], "\x00"), []byte("\uFFFD"))),
+	}, nil
+}
+
+// DecryptSwap decrypts a swap payload with its payload key.
+func DecryptSwap(key *keysv1alpha1.PayloadKey, payload *dexv1alpha1.SwapPayload) (*dexv1alpha1.SwapPlaintext, error) {
+	plaintext, err := DecryptPayload(key, PayloadKindSwap, payload.GetEncryptedSwap(), payload.GetCommitment().GetInner())
+	if err != nil {
+		return nil, err
+	}
+	return SwapPlaintextFromBytes(plaintext)
+}
+
+// NoteFromBytes decodes the 160-byte note plaintext encoding:
+// address (80) || amount (16, LE) || asset ID (32) || rseed (32).
+func NoteFromBytes(b []byte) (*shielded_poolv1alpha1.Note, error) {
+	if len(b) != NoteLenBytes {
+		return nil, fmt.Errorf("note plaintext has incorrect length %d", len(b))
+	}
+	return &shielded_poolv1alpha1.Note{
+		Address: &keysv1alpha1.Address{Inner: clone(b[0:80])},
+		Value: &assetv1alpha1.Value{
+			Amount:  amountFromLEBytes(b[80:96]),
+			AssetId: &assetv1alpha1.AssetId{Inner: clone(b[96:128])},
+		},
+		Rseed: clone(b[128:160]),
+	}, nil
+}
+
+// SwapPlaintextFromBytes decodes the 256-byte swap plaintext encoding:
+// trading pair (64) || delta_1 (16) || delta_2 (16) || claim fee amount (16)
+// || claim fee asset ID (32) || claim address (80) || rseed (32).
+func SwapPlaintextFromBytes(b []byte) (*dexv1alpha1.SwapPlaintext, error) {
+	if len(b) != SwapLenBytes {
+		return nil, fmt.Errorf("swap plaintext has incorrect length %d", len(b))
+	}
+	return &dexv1alpha1.SwapPlaintext{
+		TradingPair: &dexv1alpha1.TradingPair{
+			Asset_1: &assetv1alpha1.AssetId{Inner: clone(b[0:32])},
+			Asset_2: &assetv1alpha1.AssetId{Inner: clone(b[32:64])},
+		},
+		Delta_1I: amountFromLEBytes(b[64:80]),
+		Delta_2I: amountFromLEBytes(b[80:96]),
+		ClaimFee: &feev1alpha1.Fee{
+			Amount:  amountFromLEBytes(b[96:112]),
+			AssetId: &assetv1alpha1.AssetId{Inner: clone(b[112:144])},
+		},
+		ClaimAddress: &keysv1alpha1.Address{Inner: clone(b[144:224])},
+		Rseed:        clone(b[224:256]),
+	}, nil
+}
+
+func amountFromLEBytes(b []byte) *numv1alpha1.Amount {
+	return &numv1alpha1.Amount{
+		Lo: binary.LittleEndian.Uint64(b[0:8]),
+		Hi: binary.LittleEndian.Uint64(b[8:16]),
+	}
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,56 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/shieldedpool"
+)
+
+func TestDecryptNote(t *testing.T) {
+	address, err := keys.ParseAddress("penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := make([]byte, 0, NoteLenBytes)
+	plaintext = append(plaintext, address.GetInner()...)
+	plaintext = binary.LittleEndian.AppendUint64(plaintext, 42)
+	plaintext = binary.LittleEndian.AppendUint64(plaintext, 0)
+	plaintext = append(plaintext, make([]byte, 32)...)
+	plaintext = append(plaintext, bytes.Repeat([]byte{9}, 32)...)
+
+	key := &keysv1alpha1.PayloadKey{Inner: bytes.Repeat([]byte{3}, PayloadKeyLenBytes)}
+	aead, _ := chacha20poly1305.New(key.GetInner())
+	ciphertext := &shielded_poolv1alpha1.NoteCiphertext{
+		Inner: aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), plaintext, nil),
+	}
+	want, _ := NoteFromBytes(plaintext)
+	epk, err := shieldedpool.EphemeralPublicKey(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note, err := DecryptNote(key, ciphertext, epk)
+	if err != nil {
+		t.Fatalf("DecryptNote: %v", err)
+	}
+	if note.GetValue().GetAmount().GetLo() != 42 || !bytes.Equal(note.GetRseed(), want.GetRseed()) {
+		t.Errorf("DecryptNote returned %v, want %v", note, want)
+	}
+
+	// The ephemeral key of another note must be rejected.
+	other := append([]byte(nil), plaintext...)
+	other[NoteLenBytes-1] ^= 1
+	otherNote, _ := NoteFromBytes(other)
+	otherEpk, _ := shieldedpool.EphemeralPublicKey(otherNote)
+	if _, err := DecryptNote(key, ciphertext, otherEpk); !errors.Is(err, ErrDecryption) {
+		t.Errorf("DecryptNote with a mismatched epk = %v, want ErrDecryption", err)
+	}
+}
@@ -0,0 +1,113 @@
+package transaction
+
+import (
+	"fmt"
+
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// Sizes of the data actions add to compact blocks, as in
+// `penumbra_transaction::gas`.
+const (
+	nullifierSize   = 2 + 32
+	notePayloadSize = 2 + 32 + 2 + 32 + 2 + 132
+	swapPayloadSize = 2 + 32 + 2 + 272
+	// bsodSize approximates the size of the batch swap output data.
+	bsodSize = 16 + 16 + 0 + 4 + 64 + 4
+)
+
+// recvPacketTypeURL is the type of the IBC relay action that mints a note.
+const recvPacketTypeURL = "/ibc.core.channel.v1.MsgRecvPacket"
+
+// Gas is the resource consumption of an action or transaction, mirroring
+// `penumbra_fee::Gas`.
+type Gas struct {
+	BlockSpace        uint64
+	CompactBlockSpace uint64
+	Verification      uint64
+	Execution         uint64
+}
+
+// Add returns the sum of two gas costs.
+func (g Gas) Add(h Gas) Gas {
+	return Gas{
+		BlockSpace:        g.BlockSpace + h.BlockSpace,
+		CompactBlockSpace: g.CompactBlockSpace + h.CompactBlockSpace,
+		Verification:      g.Verification + h.Verification,
+		Execution:         g.Execution + h.Execution,
+	}
+}
+
+// ActionPlanGasCost returns the gas cost pd charges for the action an action
+// plan builds. Actions have no block space cost of their own. Parameter
+// change proposals are not supported, since pd charges them the in-memory
+// size of its chain parameters.
+func ActionPlanGasCost(action *transactionv1alpha1.ActionPlan) (Gas, error) {
+	switch a := action.GetAction().(type) {
+	case *transactionv1alpha1.ActionPlan_Spend:
+		return Gas{CompactBlockSpace: nullifierSize, Verification: 1000, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_Output:
+		return Gas{CompactBlockSpace: notePayloadSize, Verification: 1000, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_Swap:
+		return Gas{CompactBlockSpace: swapPayloadSize + bsodSize, Verification: 1000, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_SwapClaim,
+		*transactionv1alpha1.ActionPlan_UndelegateClaim,
+		*transactionv1alpha1.ActionPlan_DelegatorVote:
+		return Gas{Verification: 1000, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_ValidatorDefinition,
+		*transactionv1alpha1.ActionPlan_ValidatorVote:
+		return Gas{Verification: 200, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_PositionOpen:
+		return Gas{Verification: 50, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_ProposalSubmit:
+		if a.ProposalSubmit.GetProposal().GetParameterChange() != nil {
+			return Gas{}, fmt.Errorf("gas cost of parameter change proposals is not supported")
+		}
+		return Gas{Verification: 100, Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_IbcRelayAction:
+		if a.IbcRelayAction.GetRawAction().GetTypeUrl() == recvPacketTypeURL {
+			return Gas{CompactBlockSpace: notePayloadSize, Verification: 1000, Execution: 10}, nil
+		}
+		return Gas{Execution: 10}, nil
+	case *transactionv1alpha1.ActionPlan_DaoOutput:
+		return Gas{}, nil
+	case *transactionv1alpha1.ActionPlan_Delegate,
+		*transactionv1alpha1.ActionPlan_Undelegate,
+		*transactionv1alpha1.ActionPlan_ProposalWithdraw,
+		*transactionv1alpha1.ActionPlan_ProposalDepositClaim,
+		*transactionv1alpha1.ActionPlan_Withdrawal,
+		*transactionv1alpha1.ActionPlan_PositionClose,
+		*transactionv1alpha1.ActionPlan_PositionWithdraw,
+		*transactionv1alpha1.ActionPlan_PositionRewardClaim,
+		*transactionv1alpha1.ActionPlan_DaoSpend,
+		*transactionv1alpha1.ActionPlan_DaoDeposit:
+		return Gas{Execution: 10}, nil
+	default:
+		return Gas{}, fmt.Errorf("unknown action %T", a)
+	}
+}
+
+// PlanGasCost returns the gas cost of the actions of a transaction plan.
+func PlanGasCost(plan *transactionv1alpha1.TransactionPlan) (Gas, error) {
+	var gas Gas
+	for i, action := range plan.GetActions() {
+		g, err := ActionPlanGasCost(action)
+		if err != nil {
+			return Gas{}, fmt.Errorf("action %d: %w", i, err)
+		}
+		gas = gas.Add(g)
+	}
+	return gas, nil
+}
+
+// Price returns the fee in staking tokens for a gas cost. Prices have an
+// implicit denominator of 1000, and each resource is rounded down
+// separately, as pd does.
+func Price(prices *feev1alpha1.GasPrices, gas Gas) num.Amount {
+	return num.NewAmount(prices.GetBlockSpacePrice()*gas.BlockSpace/1000 +
+		prices.GetCompactBlockSpacePrice()*gas.CompactBlockSpace/1000 +
+		prices.GetVerificationPrice()*gas.Verification/1000 +
+		prices.GetExecutionPrice()*gas.Execution/1000)
+}
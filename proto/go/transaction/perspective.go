@@ -0,0 +1,128 @@
+package transaction
+
+import (
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/shieldedpool"
+)
+
+// Perspective is an indexed form of a TransactionPerspective, used to look up
+// the data needed to view each action of a transaction.
+type Perspective struct {
+	payloadKeys     map[string]*keysv1alpha1.PayloadKey
+	spendNullifiers map[string]*shielded_poolv1alpha1.Note
+	adviceNotes     map[string]*shielded_poolv1alpha1.Note
+	addressViews    []*keysv1alpha1.AddressView
+	denoms          *asset.Cache
+}
+
+// NewPerspective indexes a TransactionPerspective. Advice notes are keyed by
+// their note commitment, as in the Rust `TransactionPerspective`; notes whose
+// commitment cannot be computed are dropped.
+func NewPerspective(txp *transactionv1alpha1.TransactionPerspective) *Perspective {
+	p := &Perspective{
+		payloadKeys:     make(map[string]*keysv1alpha1.PayloadKey),
+		spendNullifiers: make(map[string]*shielded_poolv1alpha1.Note),
+		adviceNotes:     make(map[string]*shielded_poolv1alpha1.Note),
+		addressViews:    txp.GetAddressViews(),
+		denoms:          asset.NewCache(txp.GetDenoms()...),
+	}
+	for _, pk := range txp.GetPayloadKeys() {
+		if pk.GetCommitment() == nil || pk.GetPayloadKey() == nil {
+			continue
+		}
+		p.payloadKeys[string(pk.GetCommitment().GetInner())] = pk.GetPayloadKey()
+	}
+	for _, nwn := range txp.GetSpendNullifiers() {
+		if nwn.GetNullifier() == nil || nwn.GetNote() == nil {
+			continue
+		}
+		p.spendNullifiers[string(nwn.GetNullifier().GetInner())] = nwn.GetNote()
+	}
+	for _, note := range txp.GetAdviceNotes() {
+		cm, err := shieldedpool.Commit(note)
+		if err != nil {
+			continue
+		}
+		p.adviceNotes[string(cm.GetInner())] = note
+	}
+	return p
+}
+
+// PayloadKey returns the payload key for the given note or swap commitment.
+func (p *Perspective) PayloadKey(commitment []byte) (*keysv1alpha1.PayloadKey, bool) {
+	key, ok := p.payloadKeys[string(commitment)]
+	return key, ok
+}
+
+// SpentNote returns the note revealed by the given nullifier.
+func (p *Perspective) SpentNote(nullifier []byte) (*shielded_poolv1alpha1.Note, bool) {
+	note, ok := p.spendNullifiers[string(nullifier)]
+	return note, ok
+}
+
+// Denom returns the denom metadata for the given asset ID.
+func (p *Perspective) Denom(id *assetv1alpha1.AssetId) (*assetv1alpha1.DenomMetadata, bool) {
+	return p.denoms.Get(id)
+}
+
+// Denoms returns the denoms known to the perspective.
+func (p *Perspective) Denoms() *asset.Cache {
+	return p.denoms
+}
+
+// ViewAddress returns the address view for the address, or an opaque view if
+// the address is not known to the perspective.
+func (p *Perspective) ViewAddress(address *keysv1alpha1.Address) *keysv1alpha1.AddressView {
+	for _, av := range p.addressViews {
+		if keys.AddressEqual(keys.AddressOf(av), address) {
+			return av
+		}
+	}
+	return &keysv1alpha1.AddressView{
+		AddressView: &keysv1alpha1.AddressView_Opaque_{
+			Opaque: &keysv1alpha1.AddressView_Opaque{Address: address},
+		},
+	}
+}
+
+// ViewValue returns a value view, with the denom filled in if known.
+func (p *Perspective) ViewValue(value *assetv1alpha1.Value) *assetv1alpha1.ValueView {
+	if denom, ok := p.Denom(value.GetAssetId()); ok {
+		return &assetv1alpha1.ValueView{
+			ValueView: &assetv1alpha1.ValueView_KnownDenom_{
+				KnownDenom: &assetv1alpha1.ValueView_KnownDenom{
+					Amount: value.GetAmount(),
+					Denom:  denom,
+				},
+			},
+		}
+	}
+	return &assetv1alpha1.ValueView{
+		ValueView: &assetv1alpha1.ValueView_UnknownDenom_{
+			UnknownDenom: &assetv1alpha1.ValueView_UnknownDenom{
+				Amount:  value.GetAmount(),
+				AssetId: value.GetAssetId(),
+			},
+		},
+	}
+}
+
+// ViewNote returns a note view, resolving its address and denom.
+func (p *Perspective) ViewNote(note *shielded_poolv1alpha1.Note) *shielded_poolv1alpha1.NoteView {
+	return &shielded_poolv1alpha1.NoteView{
+		Value:   p.ViewValue(note.GetValue()),
+		Rseed:   note.GetRseed(),
+		Address: p.ViewAddress(note.GetAddress()),
+	}
+}
+
+// AdviceNote returns the advice note with the given note commitment.
+func (p *Perspective) AdviceNote(commitment []byte) (*shielded_poolv1alpha1.Note, bool) {
+	note, ok := p.adviceNotes[string(commitment)]
+	return note, ok
+}
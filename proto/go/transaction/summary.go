@@ -0,0 +1,425 @@
+package transaction
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	numv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/num/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// ActionKind identifies the kind of action described by an ActionSummary.
+type ActionKind int
+
+const (
+	ActionUnknown ActionKind = iota
+	ActionSpend
+	ActionOutput
+	ActionSwap
+	ActionSwapClaim
+	ActionValidatorDefinition
+	ActionIbcRelay
+	ActionProposalSubmit
+	ActionProposalWithdraw
+	ActionProposalDepositClaim
+	ActionValidatorVote
+	ActionDelegatorVote
+	ActionPositionOpen
+	ActionPositionClose
+	ActionPositionWithdraw
+	ActionPositionRewardClaim
+	ActionDelegate
+	ActionUndelegate
+	ActionUndelegateClaim
+	ActionDaoSpend
+	ActionDaoOutput
+	ActionDaoDeposit
+	ActionIcs20Withdrawal
+)
+
+var actionKindNames = map[ActionKind]string{
+	ActionUnknown:              "Unknown",
+	ActionSpend:                "Spend",
+	ActionOutput:               "Output",
+	ActionSwap:                 "Swap",
+	ActionSwapClaim:            "SwapClaim",
+	ActionValidatorDefinition:  "ValidatorDefinition",
+	ActionIbcRelay:             "IbcRelay",
+	ActionProposalSubmit:       "ProposalSubmit",
+	ActionProposalWithdraw:     "ProposalWithdraw",
+	ActionProposalDepositClaim: "ProposalDepositClaim",
+	ActionValidatorVote:        "ValidatorVote",
+	ActionDelegatorVote:        "DelegatorVote",
+	ActionPositionOpen:         "PositionOpen",
+	ActionPositionClose:        "PositionClose",
+	ActionPositionWithdraw:     "PositionWithdraw",
+	ActionPositionRewardClaim:  "PositionRewardClaim",
+	ActionDelegate:             "Delegate",
+	ActionUndelegate:           "Undelegate",
+	ActionUndelegateClaim:      "UndelegateClaim",
+	ActionDaoSpend:             "DaoSpend",
+	ActionDaoOutput:            "DaoOutput",
+	ActionDaoDeposit:           "DaoDeposit",
+	ActionIcs20Withdrawal:      "Ics20Withdrawal",
+}
+
+func (k ActionKind) String() string {
+	if name, ok := actionKindNames[k]; ok {
+		return name
+	}
+	return actionKindNames[ActionUnknown]
+}
+
+// ActionSummary is the structured summary of a single action view. Which
+// fields are set depends on the Kind.
+type ActionSummary struct {
+	Kind ActionKind
+	// Visible reports whether the private contents of the action were
+	// revealed by the transaction perspective.
+	Visible bool
+	// Values are the amounts involved in the action, in a kind-specific order:
+	//   - Spend, Output, DaoSpend, DaoOutput, DaoDeposit: the value moved;
+	//   - Swap: input 1, input 2, and (if visible) the prepaid claim fee;
+	//   - SwapClaim: (if visible) output 1, output 2, then the claim fee;
+	//   - PositionOpen: reserves 1 and 2;
+	//   - Delegate, Undelegate, DelegatorVote: the unbonded amount;
+	//   - ProposalSubmit, ProposalDepositClaim: the deposit.
+	// Staking token values have a nil asset ID unless its denom is known.
+	Values []*assetv1alpha1.Value
+	// Address is the counterparty of a spend or output.
+	Address     *keysv1alpha1.AddressView
+	Validator   *keysv1alpha1.IdentityKey
+	Proposal    uint64
+	Vote        governancev1alpha1.Vote_Vote
+	Position    *dexv1alpha1.PositionId
+	TradingPair *dexv1alpha1.TradingPair
+	// Fee is the position fee, in basis points.
+	Fee uint32
+	// Denom, Channel and Destination describe an ICS-20 withdrawal.
+	Denom       string
+	Channel     string
+	Destination string
+}
+
+// Delta is a signed change in the balance of a single asset.
+type Delta struct {
+	AssetId *assetv1alpha1.AssetId
+	Amount  *big.Int
+}
+
+// AccountDelta is the net change in balance of one account of the viewing
+// wallet.
+type AccountDelta struct {
+	Account uint32
+	Deltas  []Delta
+}
+
+// Summary is a structured summary of a transaction view.
+type Summary struct {
+	Actions []ActionSummary
+	Fee     *assetv1alpha1.Value
+	Memo    *transactionv1alpha1.MemoPlaintextView
+	// Accounts holds the net balance change of each account of the viewing
+	// wallet, ordered by account index, computed from the visible notes
+	// spent from and created for addresses with a visible index.
+	Accounts []AccountDelta
+
+	denoms *asset.Cache
+}
+
+// Summarize walks every action of a transaction view and summarizes its
+// effects. The denoms are used to resolve assets that are not already
+// described by the view, such as the staking token.
+func Summarize(view *transactionv1alpha1.TransactionView, denoms *asset.Cache) *Summary {
+	s := &Summary{denoms: denoms.Clone()}
+	deltas := make(map[uint32]map[string]*Delta)
+
+	credit := func(note *shielded_poolv1alpha1.NoteView, sign int) {
+		index, ok := keys.AddressIndexOf(note.GetAddress())
+		if !ok {
+			return
+		}
+		value := s.valueOf(note.GetValue())
+		account := deltas[index.GetAccount()]
+		if account == nil {
+			account = make(map[string]*Delta)
+			deltas[index.GetAccount()] = account
+		}
+		key := string(value.GetAssetId().GetInner())
+		d := account[key]
+		if d == nil {
+			d = &Delta{AssetId: value.GetAssetId(), Amount: new(big.Int)}
+			account[key] = d
+		}
+		amount := num.AmountFromProto(value.GetAmount()).Big()
+		if sign < 0 {
+			d.Amount.Sub(d.Amount, amount)
+		} else {
+			d.Amount.Add(d.Amount, amount)
+		}
+	}
+
+	body := view.GetBodyView()
+	for _, av := range body.GetActionViews() {
+		summary := s.summarizeAction(av)
+		switch a := av.GetActionView().(type) {
+		case *transactionv1alpha1.ActionView_Spend:
+			if v, ok := a.Spend.GetSpendView().(*shielded_poolv1alpha1.SpendView_Visible_); ok {
+				credit(v.Visible.GetNote(), -1)
+			}
+		case *transactionv1alpha1.ActionView_Output:
+			if v, ok := a.Output.GetOutputView().(*shielded_poolv1alpha1.OutputView_Visible_); ok {
+				credit(v.Visible.GetNote(), 1)
+			}
+		case *transactionv1alpha1.ActionView_SwapClaim:
+			if v, ok := a.SwapClaim.GetSwapClaimView().(*dexv1alpha1.SwapClaimView_Visible_); ok {
+				credit(v.Visible.GetOutput_1(), 1)
+				credit(v.Visible.GetOutput_2(), 1)
+			}
+		}
+		s.Actions = append(s.Actions, summary)
+	}
+
+	s.Fee = s.feeValue(body.GetFee())
+	if memo, ok := body.GetMemoView().GetMemoView().(*transactionv1alpha1.MemoView_Visible_); ok {
+		s.Memo = memo.Visible.GetPlaintext()
+	}
+
+	for account, byAsset := range deltas {
+		ad := AccountDelta{Account: account}
+		for _, d := range byAsset {
+			if d.Amount.Sign() != 0 {
+				ad.Deltas = append(ad.Deltas, *d)
+			}
+		}
+		sort.Slice(ad.Deltas, func(i, j int) bool {
+			return bytes.Compare(ad.Deltas[i].AssetId.GetInner(), ad.Deltas[j].AssetId.GetInner()) < 0
+		})
+		s.Accounts = append(s.Accounts, ad)
+	}
+	sort.Slice(s.Accounts, func(i, j int) bool { return s.Accounts[i].Account < s.Accounts[j].Account })
+
+	return s
+}
+
+// valueOf converts a value view to a value, remembering its denom.
+func (s *Summary) valueOf(vv *assetv1alpha1.ValueView) *assetv1alpha1.Value {
+	switch v := vv.GetValueView().(type) {
+	case *assetv1alpha1.ValueView_KnownDenom_:
+		s.denoms.Add(v.KnownDenom.GetDenom())
+		return &assetv1alpha1.Value{Amount: v.KnownDenom.GetAmount(), AssetId: v.KnownDenom.GetDenom().GetPenumbraAssetId()}
+	case *assetv1alpha1.ValueView_UnknownDenom_:
+		return &assetv1alpha1.Value{Amount: v.UnknownDenom.GetAmount(), AssetId: v.UnknownDenom.GetAssetId()}
+	}
+	return &assetv1alpha1.Value{}
+}
+
+// stakingValue returns a value of the staking token.
+func (s *Summary) stakingValue(amount *numv1alpha1.Amount) *assetv1alpha1.Value {
+	value := &assetv1alpha1.Value{Amount: amount}
+	if d, ok := s.denoms.GetByBase(asset.StakingTokenBase); ok {
+		value.AssetId = d.GetPenumbraAssetId()
+	}
+	return value
+}
+
+// feeValue returns the value of a fee; a fee without an asset ID is paid in
+// the staking token.
+func (s *Summary) feeValue(fee *feev1alpha1.Fee) *assetv1alpha1.Value {
+	if fee.GetAssetId() == nil {
+		return s.stakingValue(fee.GetAmount())
+	}
+	return &assetv1alpha1.Value{Amount: fee.GetAmount(), AssetId: fee.GetAssetId()}
+}
+
+func (s *Summary) summarizeAction(av *transactionv1alpha1.ActionView) ActionSummary {
+	switch a := av.GetActionView().(type) {
+	case *transactionv1alpha1.ActionView_Spend:
+		switch v := a.Spend.GetSpendView().(type) {
+		case *shielded_poolv1alpha1.SpendView_Visible_:
+			note := v.Visible.GetNote()
+			return ActionSummary{Kind: ActionSpend, Visible: true, Values: []*assetv1alpha1.Value{s.valueOf(note.GetValue())}, Address: note.GetAddress()}
+		}
+		return ActionSummary{Kind: ActionSpend}
+	case *transactionv1alpha1.ActionView_Output:
+		switch v := a.Output.GetOutputView().(type) {
+		case *shielded_poolv1alpha1.OutputView_Visible_:
+			note := v.Visible.GetNote()
+			return ActionSummary{Kind: ActionOutput, Visible: true, Values: []*assetv1alpha1.Value{s.valueOf(note.GetValue())}, Address: note.GetAddress()}
+		}
+		return ActionSummary{Kind: ActionOutput}
+	case *transactionv1alpha1.ActionView_Swap:
+		switch v := a.Swap.GetSwapView().(type) {
+		case *dexv1alpha1.SwapView_Visible_:
+			sp := v.Visible.GetSwapPlaintext()
+			return ActionSummary{
+				Kind:    ActionSwap,
+				Visible: true,
+				Values: []*assetv1alpha1.Value{
+					{Amount: sp.GetDelta_1I(), AssetId: sp.GetTradingPair().GetAsset_1()},
+					{Amount: sp.GetDelta_2I(), AssetId: sp.GetTradingPair().GetAsset_2()},
+					s.feeValue(sp.GetClaimFee()),
+				},
+				Address:     &keysv1alpha1.AddressView{AddressView: &keysv1alpha1.AddressView_Opaque_{Opaque: &keysv1alpha1.AddressView_Opaque{Address: sp.GetClaimAddress()}}},
+				TradingPair: sp.GetTradingPair(),
+			}
+		case *dexv1alpha1.SwapView_Opaque_:
+			body := v.Opaque.GetSwap().GetBody()
+			return ActionSummary{
+				Kind: ActionSwap,
+				Values: []*assetv1alpha1.Value{
+					{Amount: body.GetDelta_1I(), AssetId: body.GetTradingPair().GetAsset_1()},
+					{Amount: body.GetDelta_2I(), AssetId: body.GetTradingPair().GetAsset_2()},
+				},
+				TradingPair: body.GetTradingPair(),
+			}
+		}
+		return ActionSummary{Kind: ActionSwap}
+	case *transactionv1alpha1.ActionView_SwapClaim:
+		switch v := a.SwapClaim.GetSwapClaimView().(type) {
+		case *dexv1alpha1.SwapClaimView_Visible_:
+			body := v.Visible.GetSwapClaim().GetBody()
+			return ActionSummary{
+				Kind:    ActionSwapClaim,
+				Visible: true,
+				Values: []*assetv1alpha1.Value{
+					s.valueOf(v.Visible.GetOutput_1().GetValue()),
+					s.valueOf(v.Visible.GetOutput_2().GetValue()),
+					s.feeValue(body.GetFee()),
+				},
+				Address:     v.Visible.GetOutput_1().GetAddress(),
+				TradingPair: body.GetOutputData().GetTradingPair(),
+			}
+		case *dexv1alpha1.SwapClaimView_Opaque_:
+			body := v.Opaque.GetSwapClaim().GetBody()
+			return ActionSummary{
+				Kind:        ActionSwapClaim,
+				Values:      []*assetv1alpha1.Value{s.feeValue(body.GetFee())},
+				TradingPair: body.GetOutputData().GetTradingPair(),
+			}
+		}
+		return ActionSummary{Kind: ActionSwapClaim}
+	case *transactionv1alpha1.ActionView_ValidatorDefinition:
+		return ActionSummary{Kind: ActionValidatorDefinition, Visible: true, Validator: a.ValidatorDefinition.GetValidator().GetIdentityKey()}
+	case *transactionv1alpha1.ActionView_IbcRelayAction:
+		return ActionSummary{Kind: ActionIbcRelay, Visible: true}
+	case *transactionv1alpha1.ActionView_ProposalSubmit:
+		return ActionSummary{
+			Kind:     ActionProposalSubmit,
+			Visible:  true,
+			Proposal: a.ProposalSubmit.GetProposal().GetId(),
+			Values:   []*assetv1alpha1.Value{s.stakingValue(a.ProposalSubmit.GetDepositAmount())},
+		}
+	case *transactionv1alpha1.ActionView_ProposalWithdraw:
+		return ActionSummary{Kind: ActionProposalWithdraw, Visible: true, Proposal: a.ProposalWithdraw.GetProposal()}
+	case *transactionv1alpha1.ActionView_ProposalDepositClaim:
+		return ActionSummary{
+			Kind:     ActionProposalDepositClaim,
+			Visible:  true,
+			Proposal: a.ProposalDepositClaim.GetProposal(),
+			Values:   []*assetv1alpha1.Value{s.stakingValue(a.ProposalDepositClaim.GetDepositAmount())},
+		}
+	case *transactionv1alpha1.ActionView_ValidatorVote:
+		body := a.ValidatorVote.GetBody()
+		return ActionSummary{
+			Kind:      ActionValidatorVote,
+			Visible:   true,
+			Validator: body.GetIdentityKey(),
+			Proposal:  body.GetProposal(),
+			Vote:      body.GetVote().GetVote(),
+		}
+	case *transactionv1alpha1.ActionView_DelegatorVote:
+		switch v := a.DelegatorVote.GetDelegatorVote().(type) {
+		case *governancev1alpha1.DelegatorVoteView_Visible_:
+			body := v.Visible.GetDelegatorVote().GetBody()
+			return ActionSummary{
+				Kind:     ActionDelegatorVote,
+				Visible:  true,
+				Proposal: body.GetProposal(),
+				Vote:     body.GetVote().GetVote(),
+				Values:   []*assetv1alpha1.Value{s.stakingValue(body.GetUnbondedAmount())},
+				Address:  v.Visible.GetNote().GetAddress(),
+			}
+		case *governancev1alpha1.DelegatorVoteView_Opaque_:
+			body := v.Opaque.GetDelegatorVote().GetBody()
+			return ActionSummary{
+				Kind:     ActionDelegatorVote,
+				Proposal: body.GetProposal(),
+				Vote:     body.GetVote().GetVote(),
+				Values:   []*assetv1alpha1.Value{s.stakingValue(body.GetUnbondedAmount())},
+			}
+		}
+		return ActionSummary{Kind: ActionDelegatorVote}
+	case *transactionv1alpha1.ActionView_PositionOpen:
+		position := a.PositionOpen.GetPosition()
+		pair := position.GetPhi().GetPair()
+		return ActionSummary{
+			Kind:    ActionPositionOpen,
+			Visible: true,
+			Values: []*assetv1alpha1.Value{
+				{Amount: position.GetReserves().GetR1(), AssetId: pair.GetAsset_1()},
+				{Amount: position.GetReserves().GetR2(), AssetId: pair.GetAsset_2()},
+			},
+			TradingPair: pair,
+			Fee:         position.GetPhi().GetComponent().GetFee(),
+		}
+	case *transactionv1alpha1.ActionView_PositionClose:
+		return ActionSummary{Kind: ActionPositionClose, Visible: true, Position: a.PositionClose.GetPositionId()}
+	case *transactionv1alpha1.ActionView_PositionWithdraw:
+		return ActionSummary{Kind: ActionPositionWithdraw, Visible: true, Position: a.PositionWithdraw.GetPositionId()}
+	case *transactionv1alpha1.ActionView_PositionRewardClaim:
+		return ActionSummary{Kind: ActionPositionRewardClaim, Visible: true, Position: a.PositionRewardClaim.GetPositionId()}
+	case *transactionv1alpha1.ActionView_Delegate:
+		return ActionSummary{
+			Kind:      ActionDelegate,
+			Visible:   true,
+			Validator: a.Delegate.GetValidatorIdentity(),
+			Values:    []*assetv1alpha1.Value{s.stakingValue(a.Delegate.GetUnbondedAmount())},
+		}
+	case *transactionv1alpha1.ActionView_Undelegate:
+		return ActionSummary{
+			Kind:      ActionUndelegate,
+			Visible:   true,
+			Validator: a.Undelegate.GetValidatorIdentity(),
+			Values:    []*assetv1alpha1.Value{s.stakingValue(a.Undelegate.GetUnbondedAmount())},
+		}
+	case *transactionv1alpha1.ActionView_UndelegateClaim:
+		return ActionSummary{Kind: ActionUndelegateClaim, Visible: true, Validator: a.UndelegateClaim.GetBody().GetValidatorIdentity()}
+	case *transactionv1alpha1.ActionView_DaoSpend:
+		return ActionSummary{Kind: ActionDaoSpend, Visible: true, Values: []*assetv1alpha1.Value{a.DaoSpend.GetValue()}}
+	case *transactionv1alpha1.ActionView_DaoOutput:
+		return ActionSummary{
+			Kind:    ActionDaoOutput,
+			Visible: true,
+			Values:  []*assetv1alpha1.Value{a.DaoOutput.GetValue()},
+			Address: &keysv1alpha1.AddressView{AddressView: &keysv1alpha1.AddressView_Opaque_{Opaque: &keysv1alpha1.AddressView_Opaque{Address: a.DaoOutput.GetAddress()}}},
+		}
+	case *transactionv1alpha1.ActionView_DaoDeposit:
+		return ActionSummary{Kind: ActionDaoDeposit, Visible: true, Values: []*assetv1alpha1.Value{a.DaoDeposit.GetValue()}}
+	case *transactionv1alpha1.ActionView_Ics20Withdrawal:
+		w := a.Ics20Withdrawal
+		value := &assetv1alpha1.Value{Amount: w.GetAmount()}
+		if d, ok := s.denoms.GetByBase(w.GetDenom().GetDenom()); ok {
+			value.AssetId = d.GetPenumbraAssetId()
+		}
+		return ActionSummary{
+			Kind:        ActionIcs20Withdrawal,
+			Visible:     true,
+			Values:      []*assetv1alpha1.Value{value},
+			Denom:       w.GetDenom().GetDenom(),
+			Channel:     w.GetSourceChannel(),
+			Destination: w.GetDestinationChainAddress(),
+		}
+	}
+	return ActionSummary{Kind: ActionUnknown}
+}
@@ -0,0 +1,170 @@
+package transaction
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	feev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/fee/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	ibcv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/ibc/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+var testPenumbra = &assetv1alpha1.DenomMetadata{
+	Base:    asset.StakingTokenBase,
+	Display: "penumbra",
+	DenomUnits: []*assetv1alpha1.DenomUnit{
+		{Denom: "penumbra", Exponent: 6},
+		{Denom: "mpenumbra", Exponent: 3},
+		{Denom: "upenumbra"},
+	},
+	PenumbraAssetId: &assetv1alpha1.AssetId{Inner: bytes.Repeat([]byte{1}, 32)},
+}
+
+// noteView returns a visible note of the staking token, for an address of
+// the given account, or for an address outside the wallet if account is
+// negative.
+func noteView(t *testing.T, amount uint64, account int) *shielded_poolv1alpha1.NoteView {
+	address := testAddress(t)
+	av := &keysv1alpha1.AddressView{AddressView: &keysv1alpha1.AddressView_Opaque_{Opaque: &keysv1alpha1.AddressView_Opaque{Address: address}}}
+	if account >= 0 {
+		av.AddressView = &keysv1alpha1.AddressView_Visible_{Visible: &keysv1alpha1.AddressView_Visible{
+			Address: address,
+			Index:   &keysv1alpha1.AddressIndex{Account: uint32(account)},
+		}}
+	}
+	return &shielded_poolv1alpha1.NoteView{
+		Value: &assetv1alpha1.ValueView{ValueView: &assetv1alpha1.ValueView_KnownDenom_{KnownDenom: &assetv1alpha1.ValueView_KnownDenom{
+			Amount: num.NewAmount(amount).Proto(),
+			Denom:  testPenumbra,
+		}}},
+		Address: av,
+	}
+}
+
+func testAddress(t *testing.T) *keysv1alpha1.Address {
+	address, err := keys.ParseAddress("penumbra147mfall0zr6am5r45qkwht7xqqrdsp50czde7empv7yq2nk3z8yyfh9k9520ddgswkmzar22vhz9dwtuem7uxw0qytfpv7lk3q9dp8ccaw2fn5c838rfackazmgf3ahh09cxmz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+func testSummaryView(t *testing.T) *transactionv1alpha1.TransactionView {
+	ik := &keysv1alpha1.IdentityKey{Ik: bytes.Repeat([]byte{2}, 32)}
+	return &transactionv1alpha1.TransactionView{BodyView: &transactionv1alpha1.TransactionBodyView{
+		ActionViews: []*transactionv1alpha1.ActionView{
+			{ActionView: &transactionv1alpha1.ActionView_Spend{Spend: &shielded_poolv1alpha1.SpendView{SpendView: &shielded_poolv1alpha1.SpendView_Visible_{Visible: &shielded_poolv1alpha1.SpendView_Visible{
+				Note: noteView(t, 10_000_000, 0),
+			}}}}},
+			{ActionView: &transactionv1alpha1.ActionView_Output{Output: &shielded_poolv1alpha1.OutputView{OutputView: &shielded_poolv1alpha1.OutputView_Visible_{Visible: &shielded_poolv1alpha1.OutputView_Visible{
+				Note: noteView(t, 7_000_000, -1),
+			}}}}},
+			{ActionView: &transactionv1alpha1.ActionView_Output{Output: &shielded_poolv1alpha1.OutputView{OutputView: &shielded_poolv1alpha1.OutputView_Visible_{Visible: &shielded_poolv1alpha1.OutputView_Visible{
+				Note: noteView(t, 2_500_000, 1),
+			}}}}},
+			{ActionView: &transactionv1alpha1.ActionView_Output{Output: &shielded_poolv1alpha1.OutputView{OutputView: &shielded_poolv1alpha1.OutputView_Opaque_{Opaque: &shielded_poolv1alpha1.OutputView_Opaque{}}}}},
+			{ActionView: &transactionv1alpha1.ActionView_Delegate{Delegate: &stakev1alpha1.Delegate{
+				ValidatorIdentity: ik,
+				UnbondedAmount:    num.NewAmount(5_000_000).Proto(),
+			}}},
+			{ActionView: &transactionv1alpha1.ActionView_ValidatorVote{ValidatorVote: &governancev1alpha1.ValidatorVote{Body: &governancev1alpha1.ValidatorVoteBody{
+				Proposal:    3,
+				Vote:        &governancev1alpha1.Vote{Vote: governancev1alpha1.Vote_VOTE_YES},
+				IdentityKey: ik,
+			}}}},
+			{ActionView: &transactionv1alpha1.ActionView_PositionClose{PositionClose: &dexv1alpha1.PositionClose{
+				PositionId: &dexv1alpha1.PositionId{AltBech32M: "plpid1abc"},
+			}}},
+			{ActionView: &transactionv1alpha1.ActionView_Ics20Withdrawal{Ics20Withdrawal: &ibcv1alpha1.Ics20Withdrawal{
+				Amount:                  num.NewAmount(42).Proto(),
+				Denom:                   &assetv1alpha1.Denom{Denom: "transfer/channel-0/uosmo"},
+				DestinationChainAddress: "osmo1xyz",
+				SourceChannel:           "channel-0",
+			}}},
+			{},
+		},
+		Fee: &feev1alpha1.Fee{Amount: num.NewAmount(1000).Proto()},
+		MemoView: &transactionv1alpha1.MemoView{MemoView: &transactionv1alpha1.MemoView_Visible_{Visible: &transactionv1alpha1.MemoView_Visible{
+			Plaintext: &transactionv1alpha1.MemoPlaintextView{Text: "thanks"},
+		}}},
+	}}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(testSummaryView(t), nil)
+
+	var kinds []ActionKind
+	for _, a := range s.Actions {
+		kinds = append(kinds, a.Kind)
+	}
+	wantKinds := []ActionKind{ActionSpend, ActionOutput, ActionOutput, ActionOutput, ActionDelegate, ActionValidatorVote, ActionPositionClose, ActionIcs20Withdrawal, ActionUnknown}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("action kinds = %v, want %v", kinds, wantKinds)
+	}
+	if s.Actions[3].Visible || !s.Actions[2].Visible {
+		t.Errorf("visibility of outputs = %v, %v", s.Actions[2].Visible, s.Actions[3].Visible)
+	}
+	// The staking token is known from the notes, so the delegation and fee
+	// resolve to its asset ID.
+	if id := s.Actions[4].Values[0].GetAssetId(); !bytes.Equal(id.GetInner(), testPenumbra.GetPenumbraAssetId().GetInner()) {
+		t.Errorf("delegation asset = %v", id)
+	}
+	if s.Fee.GetAssetId() == nil || s.Memo.GetText() != "thanks" {
+		t.Errorf("fee %v and memo %v", s.Fee, s.Memo)
+	}
+
+	// The external output does not count; the internal one is a credit.
+	if len(s.Accounts) != 2 {
+		t.Fatalf("Accounts = %+v", s.Accounts)
+	}
+	for i, want := range []int64{-10_000_000, 2_500_000} {
+		ad := s.Accounts[i]
+		if ad.Account != uint32(i) || len(ad.Deltas) != 1 || ad.Deltas[0].Amount.Int64() != want {
+			t.Errorf("account %d delta = %+v, want %d", i, ad, want)
+		}
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	s := Summarize(testSummaryView(t), asset.NewCache(testPenumbra))
+	ik := keys.FormatIdentityKey(&keysv1alpha1.IdentityKey{Ik: bytes.Repeat([]byte{2}, 32)})
+	want := []string{
+		"Spent 10penumbra from [account 0]",
+		"Sent 7penumbra to " + keys.ShortForm(testAddress(t)),
+		"Sent 2.5penumbra to [account 1]",
+		"Created a private note",
+		"Delegated 5penumbra to validator " + ik,
+		"Validator " + ik + " voted yes on proposal #3",
+		"Closed position plpid1abc",
+		"Withdrew 42transfer/channel-0/uosmo to osmo1xyz over channel-0",
+		"Performed an unknown action",
+		"Fee: 1mpenumbra",
+		"Memo: thanks",
+		"Account 0: -10penumbra",
+		"Account 1: +2.5penumbra",
+	}
+	if got := s.Text(language.English); !reflect.DeepEqual(got, want) {
+		t.Errorf("Text =\n%q\nwant\n%q", got, want)
+	}
+
+	tag := language.MustParse("fr")
+	if err := Translate(tag, MsgFee, "Frais : %[1]s"); err != nil {
+		t.Fatal(err)
+	}
+	lines := s.Text(tag)
+	if lines[9] != "Frais : 1mpenumbra" || lines[0] != want[0] {
+		t.Errorf("French text = %q", lines)
+	}
+}
@@ -0,0 +1,243 @@
+package transaction
+
+import (
+	"math/big"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+
+	"github.com/penumbra-zone/penumbra/proto/go/asset"
+	"github.com/penumbra-zone/penumbra/proto/go/bech32str"
+	assetv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/asset/v1alpha1"
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"github.com/penumbra-zone/penumbra/proto/go/num"
+)
+
+// Summary messages, in English. The English text is also the message key
+// used to register translations with Translate.
+const (
+	MsgSpend                = "Spent %[1]s from %[2]s"
+	MsgSpendOpaque          = "Spent a private note"
+	MsgOutput               = "Sent %[1]s to %[2]s"
+	MsgOutputOpaque         = "Created a private note"
+	MsgSwap                 = "Swapped %[1]s and %[2]s on %[3]s, prepaying a claim fee of %[4]s"
+	MsgSwapOpaque           = "Swapped %[1]s and %[2]s on %[3]s"
+	MsgSwapClaim            = "Claimed %[1]s and %[2]s to %[3]s, paying a claim fee of %[4]s"
+	MsgSwapClaimOpaque      = "Claimed a swap on %[1]s, paying a claim fee of %[2]s"
+	MsgValidatorDefinition  = "Uploaded the definition of validator %[1]s"
+	MsgIbcRelay             = "Relayed an IBC message"
+	MsgProposalSubmit       = "Submitted proposal #%[1]d with a deposit of %[2]s"
+	MsgProposalWithdraw     = "Withdrew proposal #%[1]d"
+	MsgProposalDepositClaim = "Claimed the deposit of %[2]s for proposal #%[1]d"
+	MsgValidatorVote        = "Validator %[1]s voted %[2]s on proposal #%[3]d"
+	MsgDelegatorVote        = "Voted %[1]s on proposal #%[2]d with %[3]s"
+	MsgPositionOpen         = "Opened a position on %[1]s with reserves %[2]s and %[3]s at a fee of %[4]d bps"
+	MsgPositionClose        = "Closed position %[1]s"
+	MsgPositionWithdraw     = "Withdrew position %[1]s"
+	MsgPositionRewardClaim  = "Claimed the rewards of position %[1]s"
+	MsgDelegate             = "Delegated %[1]s to validator %[2]s"
+	MsgUndelegate           = "Undelegated %[1]s from validator %[2]s"
+	MsgUndelegateClaim      = "Claimed undelegated funds from validator %[1]s"
+	MsgDaoSpend             = "Spent %[1]s from the DAO"
+	MsgDaoOutput            = "Sent %[1]s from the DAO to %[2]s"
+	MsgDaoDeposit           = "Deposited %[1]s into the DAO"
+	MsgIcs20Withdrawal      = "Withdrew %[1]s to %[2]s over %[3]s"
+	MsgUnknownAction        = "Performed an unknown action"
+	MsgFee                  = "Fee: %[1]s"
+	MsgMemo                 = "Memo: %[1]s"
+	MsgAccountDelta         = "Account %[1]d: %[2]s"
+	MsgAccountNoChange      = "Account %[1]d: no change"
+	MsgOwnAccount           = "[account %[1]d]"
+	MsgVoteYes              = "yes"
+	MsgVoteNo               = "no"
+	MsgVoteAbstain          = "abstain"
+)
+
+var messages = []string{
+	MsgSpend, MsgSpendOpaque, MsgOutput, MsgOutputOpaque, MsgSwap, MsgSwapOpaque,
+	MsgSwapClaim, MsgSwapClaimOpaque, MsgValidatorDefinition, MsgIbcRelay,
+	MsgProposalSubmit, MsgProposalWithdraw, MsgProposalDepositClaim,
+	MsgValidatorVote, MsgDelegatorVote, MsgPositionOpen, MsgPositionClose,
+	MsgPositionWithdraw, MsgPositionRewardClaim, MsgDelegate, MsgUndelegate,
+	MsgUndelegateClaim, MsgDaoSpend, MsgDaoOutput, MsgDaoDeposit,
+	MsgIcs20Withdrawal, MsgUnknownAction, MsgFee, MsgMemo, MsgAccountDelta,
+	MsgAccountNoChange, MsgOwnAccount, MsgVoteYes, MsgVoteNo, MsgVoteAbstain,
+}
+
+var summaryCatalog = catalog.NewBuilder(catalog.Fallback(language.English))
+
+func init() {
+	for _, msg := range messages {
+		if err := summaryCatalog.SetString(language.English, msg, msg); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Messages returns the English text of every summary message, for use as
+// keys when registering translations.
+func Messages() []string {
+	return append([]string(nil), messages...)
+}
+
+// Translate registers the translation of a summary message into a language.
+// Messages without a translation are rendered in English.
+func Translate(tag language.Tag, msg, translation string) error {
+	return summaryCatalog.SetString(tag, msg, translation)
+}
+
+// Text renders the summary as lines of text in the given language: one line
+// per action, then the fee, the memo (if visible), and the net change of
+// each account of the viewing wallet.
+func (s *Summary) Text(tag language.Tag) []string {
+	p := message.NewPrinter(tag, message.Catalog(summaryCatalog))
+	lines := make([]string, 0, len(s.Actions)+2+len(s.Accounts))
+	for _, a := range s.Actions {
+		lines = append(lines, s.actionText(p, a))
+	}
+	lines = append(lines, p.Sprintf(MsgFee, s.denoms.Format(s.Fee)))
+	if text := s.Memo.GetText(); text != "" {
+		lines = append(lines, p.Sprintf(MsgMemo, text))
+	}
+	for _, ad := range s.Accounts {
+		if len(ad.Deltas) == 0 {
+			lines = append(lines, p.Sprintf(MsgAccountNoChange, ad.Account))
+			continue
+		}
+		deltas := make([]string, 0, len(ad.Deltas))
+		for _, d := range ad.Deltas {
+			deltas = append(deltas, s.formatDelta(d))
+		}
+		lines = append(lines, p.Sprintf(MsgAccountDelta, ad.Account, strings.Join(deltas, ", ")))
+	}
+	return lines
+}
+
+// String renders the summary in English.
+func (s *Summary) String() string {
+	return strings.Join(s.Text(language.English), "\n")
+}
+
+func (s *Summary) actionText(p *message.Printer, a ActionSummary) string {
+	value := func(i int) string {
+		if i >= len(a.Values) {
+			return ""
+		}
+		return s.denoms.Format(a.Values[i])
+	}
+	switch a.Kind {
+	case ActionSpend:
+		if !a.Visible {
+			return p.Sprintf(MsgSpendOpaque)
+		}
+		return p.Sprintf(MsgSpend, value(0), formatAddressView(p, a.Address))
+	case ActionOutput:
+		if !a.Visible {
+			return p.Sprintf(MsgOutputOpaque)
+		}
+		return p.Sprintf(MsgOutput, value(0), formatAddressView(p, a.Address))
+	case ActionSwap:
+		pair := s.formatTradingPair(a.TradingPair)
+		if !a.Visible {
+			return p.Sprintf(MsgSwapOpaque, value(0), value(1), pair)
+		}
+		return p.Sprintf(MsgSwap, value(0), value(1), pair, value(2))
+	case ActionSwapClaim:
+		if !a.Visible {
+			return p.Sprintf(MsgSwapClaimOpaque, s.formatTradingPair(a.TradingPair), value(0))
+		}
+		return p.Sprintf(MsgSwapClaim, value(0), value(1), formatAddressView(p, a.Address), value(2))
+	case ActionValidatorDefinition:
+		return p.Sprintf(MsgValidatorDefinition, keys.FormatIdentityKey(a.Validator))
+	case ActionIbcRelay:
+		return p.Sprintf(MsgIbcRelay)
+	case ActionProposalSubmit:
+		return p.Sprintf(MsgProposalSubmit, a.Proposal, value(0))
+	case ActionProposalWithdraw:
+		return p.Sprintf(MsgProposalWithdraw, a.Proposal)
+	case ActionProposalDepositClaim:
+		return p.Sprintf(MsgProposalDepositClaim, a.Proposal, value(0))
+	case ActionValidatorVote:
+		return p.Sprintf(MsgValidatorVote, keys.FormatIdentityKey(a.Validator), formatVote(p, a.Vote), a.Proposal)
+	case ActionDelegatorVote:
+		return p.Sprintf(MsgDelegatorVote, formatVote(p, a.Vote), a.Proposal, value(0))
+	case ActionPositionOpen:
+		return p.Sprintf(MsgPositionOpen, s.formatTradingPair(a.TradingPair), value(0), value(1), a.Fee)
+	case ActionPositionClose:
+		return p.Sprintf(MsgPositionClose, formatPositionId(a.Position))
+	case ActionPositionWithdraw:
+		return p.Sprintf(MsgPositionWithdraw, formatPositionId(a.Position))
+	case ActionPositionRewardClaim:
+		return p.Sprintf(MsgPositionRewardClaim, formatPositionId(a.Position))
+	case ActionDelegate:
+		return p.Sprintf(MsgDelegate, value(0), keys.FormatIdentityKey(a.Validator))
+	case ActionUndelegate:
+		return p.Sprintf(MsgUndelegate, value(0), keys.FormatIdentityKey(a.Validator))
+	case ActionUndelegateClaim:
+		return p.Sprintf(MsgUndelegateClaim, keys.FormatIdentityKey(a.Validator))
+	case ActionDaoSpend:
+		return p.Sprintf(MsgDaoSpend, value(0))
+	case ActionDaoOutput:
+		return p.Sprintf(MsgDaoOutput, value(0), formatAddressView(p, a.Address))
+	case ActionDaoDeposit:
+		return p.Sprintf(MsgDaoDeposit, value(0))
+	case ActionIcs20Withdrawal:
+		amount := value(0)
+		if a.Values[0].GetAssetId() == nil {
+			// The denom is not known, so the amount is in its base units.
+			amount = num.AmountFromProto(a.Values[0].GetAmount()).String() + a.Denom
+		}
+		return p.Sprintf(MsgIcs20Withdrawal, amount, a.Destination, a.Channel)
+	}
+	return p.Sprintf(MsgUnknownAction)
+}
+
+// formatDelta formats a signed balance change, such as "+1.5penumbra".
+func (s *Summary) formatDelta(d Delta) string {
+	sign := "+"
+	if d.Amount.Sign() < 0 {
+		sign = "-"
+	}
+	amount, err := num.AmountFromBig(new(big.Int).Abs(d.Amount))
+	if err != nil {
+		return d.Amount.String() + asset.FormatAssetId(d.AssetId)
+	}
+	return sign + s.denoms.Format(&assetv1alpha1.Value{Amount: amount.Proto(), AssetId: d.AssetId})
+}
+
+func (s *Summary) formatTradingPair(pair *dexv1alpha1.TradingPair) string {
+	return s.denoms.FormatDenom(pair.GetAsset_1()) + ":" + s.denoms.FormatDenom(pair.GetAsset_2())
+}
+
+// formatAddressView formats an address as its account if it belongs to the
+// viewing wallet, and in short form otherwise.
+func formatAddressView(p *message.Printer, av *keysv1alpha1.AddressView) string {
+	if index, ok := keys.AddressIndexOf(av); ok {
+		return p.Sprintf(MsgOwnAccount, index.GetAccount())
+	}
+	return keys.ShortForm(keys.AddressOf(av))
+}
+
+func formatVote(p *message.Printer, vote governancev1alpha1.Vote_Vote) string {
+	switch vote {
+	case governancev1alpha1.Vote_VOTE_YES:
+		return p.Sprintf(MsgVoteYes)
+	case governancev1alpha1.Vote_VOTE_NO:
+		return p.Sprintf(MsgVoteNo)
+	case governancev1alpha1.Vote_VOTE_ABSTAIN:
+		return p.Sprintf(MsgVoteAbstain)
+	}
+	return vote.String()
+}
+
+func formatPositionId(id *dexv1alpha1.PositionId) string {
+	if id.GetAltBech32M() != "" {
+		return id.GetAltBech32M()
+	}
+	return bech32str.Encode(id.GetInner(), bech32str.LpIdPrefix, bech32str.Bech32m)
+}
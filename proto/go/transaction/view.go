@@ -0,0 +1,226 @@
+// Package transaction provides Go helpers for working with Penumbra
+// transactions, mirroring the viewing logic of the Rust
+// `penumbra-transaction` crate.
+package transaction
+
+import (
+	dexv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/dex/v1alpha1"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	shielded_poolv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/shielded_pool/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+)
+
+// View produces the TransactionView of tx as seen from the perspective txp,
+// as returned by the view service's `TransactionInfo` RPC.
+//
+// Actions whose contents are revealed by the perspective are rendered as
+// Visible views, all others as Opaque views.
+func View(tx *transactionv1alpha1.Transaction, txp *transactionv1alpha1.TransactionPerspective) *transactionv1alpha1.TransactionView {
+	return NewPerspective(txp).View(tx)
+}
+
+// View produces the TransactionView of tx from this perspective.
+func (p *Perspective) View(tx *transactionv1alpha1.Transaction) *transactionv1alpha1.TransactionView {
+	body := tx.GetBody()
+
+	var memoKey *keysv1alpha1.PayloadKey
+	actionViews := make([]*transactionv1alpha1.ActionView, 0, len(body.GetActions()))
+	for _, action := range body.GetActions() {
+		view := p.ViewAction(action)
+		// All outputs share the transaction memo, so the memo key from the
+		// first visible output is enough to decrypt it.
+		if output, ok := view.GetActionView().(*transactionv1alpha1.ActionView_Output); ok && memoKey == nil {
+			if visible, ok := output.Output.GetOutputView().(*shielded_poolv1alpha1.OutputView_Visible_); ok {
+				memoKey = visible.Visible.GetPayloadKey()
+			}
+		}
+		actionViews = append(actionViews, view)
+	}
+
+	return &transactionv1alpha1.TransactionView{
+		BodyView: &transactionv1alpha1.TransactionBodyView{
+			ActionViews:           actionViews,
+			TransactionParameters: body.GetTransactionParameters(),
+			Fee:                   body.GetFee(),
+			DetectionData:         body.GetDetectionData(),
+			MemoView:              p.viewMemo(body.GetMemoData(), memoKey),
+		},
+		BindingSig: tx.GetBindingSig(),
+		Anchor:     tx.GetAnchor(),
+	}
+}
+
+func (p *Perspective) viewMemo(data *transactionv1alpha1.MemoData, memoKey *keysv1alpha1.PayloadKey) *transactionv1alpha1.MemoView {
+	if len(data.GetEncryptedMemo()) == 0 {
+		return nil
+	}
+	ciphertext := &transactionv1alpha1.MemoCiphertext{Inner: data.GetEncryptedMemo()}
+	if memoKey != nil {
+		if plaintext, err := DecryptMemo(memoKey, ciphertext); err == nil {
+			return &transactionv1alpha1.MemoView{
+				MemoView: &transactionv1alpha1.MemoView_Visible_{
+					Visible: &transactionv1alpha1.MemoView_Visible{
+						Ciphertext: ciphertext,
+						Plaintext: &transactionv1alpha1.MemoPlaintextView{
+							ReturnAddress: p.ViewAddress(plaintext.GetReturnAddress()),
+							Text:          plaintext.GetText(),
+						},
+					},
+				},
+			}
+		}
+	}
+	return &transactionv1alpha1.MemoView{
+		MemoView: &transactionv1alpha1.MemoView_Opaque_{
+			Opaque: &transactionv1alpha1.MemoView_Opaque{Ciphertext: ciphertext},
+		},
+	}
+}
+
+// ViewAction produces the ActionView of a single action. Actions that carry
+// no private data are passed through unchanged.
+func (p *Perspective) ViewAction(action *transactionv1alpha1.Action) *transactionv1alpha1.ActionView {
+	switch a := action.GetAction().(type) {
+	case *transactionv1alpha1.Action_Spend:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Spend{Spend: p.viewSpend(a.Spend)}}
+	case *transactionv1alpha1.Action_Output:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Output{Output: p.viewOutput(a.Output)}}
+	case *transactionv1alpha1.Action_Swap:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Swap{Swap: p.viewSwap(a.Swap)}}
+	case *transactionv1alpha1.Action_SwapClaim:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_SwapClaim{SwapClaim: p.viewSwapClaim(a.SwapClaim)}}
+	case *transactionv1alpha1.Action_DelegatorVote:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_DelegatorVote{DelegatorVote: p.viewDelegatorVote(a.DelegatorVote)}}
+	case *transactionv1alpha1.Action_ValidatorDefinition:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_ValidatorDefinition{ValidatorDefinition: a.ValidatorDefinition}}
+	case *transactionv1alpha1.Action_IbcRelayAction:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_IbcRelayAction{IbcRelayAction: a.IbcRelayAction}}
+	case *transactionv1alpha1.Action_ProposalSubmit:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_ProposalSubmit{ProposalSubmit: a.ProposalSubmit}}
+	case *transactionv1alpha1.Action_ProposalWithdraw:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_ProposalWithdraw{ProposalWithdraw: a.ProposalWithdraw}}
+	case *transactionv1alpha1.Action_ValidatorVote:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_ValidatorVote{ValidatorVote: a.ValidatorVote}}
+	case *transactionv1alpha1.Action_ProposalDepositClaim:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_ProposalDepositClaim{ProposalDepositClaim: a.ProposalDepositClaim}}
+	case *transactionv1alpha1.Action_PositionOpen:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_PositionOpen{PositionOpen: a.PositionOpen}}
+	case *transactionv1alpha1.Action_PositionClose:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_PositionClose{PositionClose: a.PositionClose}}
+	case *transactionv1alpha1.Action_PositionWithdraw:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_PositionWithdraw{PositionWithdraw: a.PositionWithdraw}}
+	case *transactionv1alpha1.Action_PositionRewardClaim:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_PositionRewardClaim{PositionRewardClaim: a.PositionRewardClaim}}
+	case *transactionv1alpha1.Action_Delegate:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Delegate{Delegate: a.Delegate}}
+	case *transactionv1alpha1.Action_Undelegate:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Undelegate{Undelegate: a.Undelegate}}
+	case *transactionv1alpha1.Action_UndelegateClaim:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_UndelegateClaim{UndelegateClaim: a.UndelegateClaim}}
+	case *transactionv1alpha1.Action_DaoSpend:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_DaoSpend{DaoSpend: a.DaoSpend}}
+	case *transactionv1alpha1.Action_DaoOutput:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_DaoOutput{DaoOutput: a.DaoOutput}}
+	case *transactionv1alpha1.Action_DaoDeposit:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_DaoDeposit{DaoDeposit: a.DaoDeposit}}
+	case *transactionv1alpha1.Action_Ics20Withdrawal:
+		return &transactionv1alpha1.ActionView{ActionView: &transactionv1alpha1.ActionView_Ics20Withdrawal{Ics20Withdrawal: a.Ics20Withdrawal}}
+	}
+	return &transactionv1alpha1.ActionView{}
+}
+
+func (p *Perspective) viewSpend(spend *shielded_poolv1alpha1.Spend) *shielded_poolv1alpha1.SpendView {
+	if note, ok := p.SpentNote(spend.GetBody().GetNullifier()); ok {
+		return &shielded_poolv1alpha1.SpendView{
+			SpendView: &shielded_poolv1alpha1.SpendView_Visible_{
+				Visible: &shielded_poolv1alpha1.SpendView_Visible{Spend: spend, Note: p.ViewNote(note)},
+			},
+		}
+	}
+	return &shielded_poolv1alpha1.SpendView{
+		SpendView: &shielded_poolv1alpha1.SpendView_Opaque_{
+			Opaque: &shielded_poolv1alpha1.SpendView_Opaque{Spend: spend},
+		},
+	}
+}
+
+func (p *Perspective) viewOutput(output *shielded_poolv1alpha1.Output) *shielded_poolv1alpha1.OutputView {
+	payload := output.GetBody().GetNotePayload()
+	if key, ok := p.PayloadKey(payload.GetNoteCommitment().GetInner()); ok {
+		note, noteErr := DecryptNote(key, payload.GetEncryptedNote(), payload.GetEphemeralKey())
+		memoKey, memoErr := DecryptMemoKey(key, output.GetBody().GetWrappedMemoKey())
+		// Both the note and the memo key must decrypt for the output to be visible.
+		if noteErr == nil && memoErr == nil {
+			return &shielded_poolv1alpha1.OutputView{
+				OutputView: &shielded_poolv1alpha1.OutputView_Visible_{
+					Visible: &shielded_poolv1alpha1.OutputView_Visible{
+						Output:     output,
+						Note:       p.ViewNote(note),
+						PayloadKey: memoKey,
+					},
+				},
+			}
+		}
+	}
+	return &shielded_poolv1alpha1.OutputView{
+		OutputView: &shielded_poolv1alpha1.OutputView_Opaque_{
+			Opaque: &shielded_poolv1alpha1.OutputView_Opaque{Output: output},
+		},
+	}
+}
+
+func (p *Perspective) viewSwap(swap *dexv1alpha1.Swap) *dexv1alpha1.SwapView {
+	payload := swap.GetBody().GetPayload()
+	if key, ok := p.PayloadKey(payload.GetCommitment().GetInner()); ok {
+		if plaintext, err := DecryptSwap(key, payload); err == nil {
+			return &dexv1alpha1.SwapView{
+				SwapView: &dexv1alpha1.SwapView_Visible_{
+					Visible: &dexv1alpha1.SwapView_Visible{Swap: swap, SwapPlaintext: plaintext},
+				},
+			}
+		}
+	}
+	return &dexv1alpha1.SwapView{
+		SwapView: &dexv1alpha1.SwapView_Opaque_{
+			Opaque: &dexv1alpha1.SwapView_Opaque{Swap: swap},
+		},
+	}
+}
+
+func (p *Perspective) viewSwapClaim(claim *dexv1alpha1.SwapClaim) *dexv1alpha1.SwapClaimView {
+	body := claim.GetBody()
+	out1, ok1 := p.AdviceNote(body.GetOutput_1Commitment().GetInner())
+	out2, ok2 := p.AdviceNote(body.GetOutput_2Commitment().GetInner())
+	if ok1 && ok2 {
+		return &dexv1alpha1.SwapClaimView{
+			SwapClaimView: &dexv1alpha1.SwapClaimView_Visible_{
+				Visible: &dexv1alpha1.SwapClaimView_Visible{
+					SwapClaim: claim,
+					Output_1:  p.ViewNote(out1),
+					Output_2:  p.ViewNote(out2),
+				},
+			},
+		}
+	}
+	return &dexv1alpha1.SwapClaimView{
+		SwapClaimView: &dexv1alpha1.SwapClaimView_Opaque_{
+			Opaque: &dexv1alpha1.SwapClaimView_Opaque{SwapClaim: claim},
+		},
+	}
+}
+
+func (p *Perspective) viewDelegatorVote(vote *governancev1alpha1.DelegatorVote) *governancev1alpha1.DelegatorVoteView {
+	if note, ok := p.SpentNote(vote.GetBody().GetNullifier()); ok {
+		return &governancev1alpha1.DelegatorVoteView{
+			DelegatorVote: &governancev1alpha1.DelegatorVoteView_Visible_{
+				Visible: &governancev1alpha1.DelegatorVoteView_Visible{DelegatorVote: vote, Note: p.ViewNote(note)},
+			},
+		}
+	}
+	return &governancev1alpha1.DelegatorVoteView{
+		DelegatorVote: &governancev1alpha1.DelegatorVoteView_Opaque_{
+			Opaque: &governancev1alpha1.DelegatorVoteView_Opaque{DelegatorVote: vote},
+		},
+	}
+}
@@ -0,0 +1,172 @@
+// Package validator helps validator operators maintain their validator
+// definitions and cast validator votes, mirroring `pcli validator`.
+package validator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/genesis"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+)
+
+// DaoRecipient is the recipient of a funding stream to the DAO.
+const DaoRecipient = "DAO"
+
+// ConsensusKeyType is the type of the Ed25519 consensus keys used by
+// CometBFT.
+const ConsensusKeyType = "tendermint/PubKeyEd25519"
+
+// FundingStream is a funding stream in a validator file. Recipient is
+// either an address or DaoRecipient.
+type FundingStream struct {
+	Recipient string `toml:"recipient" json:"recipient"`
+	RateBps   uint16 `toml:"rate_bps" json:"rate_bps"`
+}
+
+// ConsensusKey is a CometBFT public key, as in `priv_validator_key.json`.
+type ConsensusKey struct {
+	Type  string `toml:"type" json:"type"`
+	Value string `toml:"value" json:"value"`
+}
+
+// File is a validator definition in the TOML format written by
+// `pcli validator definition template` and `fetch`. The same fields are
+// accepted in JSON.
+type File struct {
+	SequenceNumber uint32          `toml:"sequence_number" json:"sequence_number"`
+	Enabled        bool            `toml:"enabled" json:"enabled"`
+	Name           string          `toml:"name" json:"name"`
+	Website        string          `toml:"website" json:"website"`
+	Description    string          `toml:"description" json:"description"`
+	IdentityKey    string          `toml:"identity_key" json:"identity_key"`
+	GovernanceKey  string          `toml:"governance_key" json:"governance_key"`
+	ConsensusKey   ConsensusKey    `toml:"consensus_key" json:"consensus_key"`
+	FundingStreams []FundingStream `toml:"funding_stream" json:"funding_stream"`
+}
+
+// ReadFile reads a validator definition from a TOML file, or a JSON file if
+// its name ends in `.json`.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f *File
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		f, err = ParseJSON(bytes.NewReader(data))
+	} else {
+		f, err = ParseTOML(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse validator definition %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseTOML parses a validator definition in TOML.
+func ParseTOML(r io.Reader) (*File, error) {
+	f := new(File)
+	if _, err := toml.NewDecoder(r).Decode(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseJSON parses a validator definition in JSON.
+func ParseJSON(r io.Reader) (*File, error) {
+	f := new(File)
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// WriteTOML writes the definition in TOML, as pcli does.
+func (f *File) WriteTOML(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(f)
+}
+
+// FromProto converts a validator to the file format, such as to write back
+// the definition fetched from the chain.
+func FromProto(v *stakev1alpha1.Validator) *File {
+	f := &File{
+		SequenceNumber: v.GetSequenceNumber(),
+		Enabled:        v.GetEnabled(),
+		Name:           v.GetName(),
+		Website:        v.GetWebsite(),
+		Description:    v.GetDescription(),
+		IdentityKey:    keys.FormatIdentityKey(v.GetIdentityKey()),
+		GovernanceKey:  keys.FormatGovernanceKey(v.GetGovernanceKey()),
+		ConsensusKey: ConsensusKey{
+			Type:  ConsensusKeyType,
+			Value: base64.StdEncoding.EncodeToString(v.GetConsensusKey()),
+		},
+	}
+	for _, fs := range v.GetFundingStreams() {
+		switch r := fs.GetRecipient().(type) {
+		case *stakev1alpha1.FundingStream_ToAddress_:
+			f.FundingStreams = append(f.FundingStreams, FundingStream{Recipient: r.ToAddress.GetAddress(), RateBps: uint16(r.ToAddress.GetRateBps())})
+		case *stakev1alpha1.FundingStream_ToDao_:
+			f.FundingStreams = append(f.FundingStreams, FundingStream{Recipient: DaoRecipient, RateBps: uint16(r.ToDao.GetRateBps())})
+		}
+	}
+	return f
+}
+
+// Proto converts the definition to a validator, checking its keys and that
+// its funding streams sum to at most 10,000 bps.
+func (f *File) Proto() (*stakev1alpha1.Validator, error) {
+	ik, err := keys.ParseIdentityKey(f.IdentityKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key: %w", err)
+	}
+	gk, err := keys.ParseGovernanceKey(f.GovernanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid governance key: %w", err)
+	}
+	if f.ConsensusKey.Type != ConsensusKeyType {
+		return nil, fmt.Errorf("unsupported consensus key type %q", f.ConsensusKey.Type)
+	}
+	ck, err := base64.StdEncoding.DecodeString(f.ConsensusKey.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consensus key: %w", err)
+	}
+	v := &stakev1alpha1.Validator{
+		IdentityKey:    ik,
+		ConsensusKey:   ck,
+		Name:           f.Name,
+		Website:        f.Website,
+		Description:    f.Description,
+		Enabled:        f.Enabled,
+		SequenceNumber: f.SequenceNumber,
+		GovernanceKey:  gk,
+	}
+	for _, fs := range f.FundingStreams {
+		if fs.Recipient == DaoRecipient {
+			v.FundingStreams = append(v.FundingStreams, &stakev1alpha1.FundingStream{
+				Recipient: &stakev1alpha1.FundingStream_ToDao_{
+					ToDao: &stakev1alpha1.FundingStream_ToDao{RateBps: uint32(fs.RateBps)},
+				},
+			})
+			continue
+		}
+		v.FundingStreams = append(v.FundingStreams, &stakev1alpha1.FundingStream{
+			Recipient: &stakev1alpha1.FundingStream_ToAddress_{
+				ToAddress: &stakev1alpha1.FundingStream_ToAddress{Address: fs.Recipient, RateBps: uint32(fs.RateBps)},
+			},
+		})
+	}
+	if err := genesis.ValidateValidator(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,182 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/penumbra-zone/penumbra/proto/go/cnidarium"
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377rdsa"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	transactionv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/transaction/v1alpha1"
+	decaf377_rdsav1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/crypto/decaf377_rdsa/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/keys"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxVoteReasonLength is the longest reason pd accepts on a validator vote,
+// in bytes.
+const MaxVoteReasonLength = 1024
+
+// SignatureLen is the length of a decaf377-rdsa signature.
+const SignatureLen = decaf377rdsa.SignatureLen
+
+// Signer signs with a decaf377-rdsa spend authorization key. It is
+// implemented by decaf377rdsa.SigningKey, such as the key returned by
+// keys.SpendAuthKey for a wallet's spend key, and can be implemented by a
+// custody service or hardware wallet. The identity key of a validator is the
+// verification key of its spend authorization key, and its governance key
+// defaults to the same key.
+type Signer interface {
+	// VerificationKey returns the encoded verification key.
+	VerificationKey() []byte
+	// Sign returns the signature of msg.
+	Sign(msg []byte) ([]byte, error)
+}
+
+// ErrKeyMismatch is returned when a signer's key is not the key a message
+// must be signed with.
+var ErrKeyMismatch = errors.New("signing key does not match")
+
+// SignDefinition signs a validator definition with its identity key, as
+// `pcli validator definition upload` does: the signature is over the
+// protobuf encoding of the validator.
+func SignDefinition(v *stakev1alpha1.Validator, identity Signer) (*stakev1alpha1.ValidatorDefinition, error) {
+	if !bytes.Equal(identity.VerificationKey(), v.GetIdentityKey().GetIk()) {
+		return nil, fmt.Errorf("%w: validator %s", ErrKeyMismatch, keys.FormatIdentityKey(v.GetIdentityKey()))
+	}
+	msg, err := proto.MarshalOptions{Deterministic: true}.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(identity, msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign validator definition: %w", err)
+	}
+	return &stakev1alpha1.ValidatorDefinition{Validator: v, AuthSig: sig}, nil
+}
+
+// SignVote signs a validator vote with the validator's governance key.
+func SignVote(body *governancev1alpha1.ValidatorVoteBody, governance Signer) (*governancev1alpha1.ValidatorVote, error) {
+	if len(body.GetReason().GetReason()) > MaxVoteReasonLength {
+		return nil, fmt.Errorf("validator vote reason is too long, max %d bytes", MaxVoteReasonLength)
+	}
+	if !bytes.Equal(governance.VerificationKey(), body.GetGovernanceKey().GetGk()) {
+		return nil, fmt.Errorf("%w: governance key %s", ErrKeyMismatch, keys.FormatGovernanceKey(body.GetGovernanceKey()))
+	}
+	msg, err := proto.MarshalOptions{Deterministic: true}.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(governance, msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign validator vote: %w", err)
+	}
+	return &governancev1alpha1.ValidatorVote{
+		Body:    body,
+		AuthSig: &decaf377_rdsav1alpha1.SpendAuthSignature{Inner: sig},
+	}, nil
+}
+
+func sign(s Signer, msg []byte) ([]byte, error) {
+	sig, err := s.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != SignatureLen {
+		return nil, fmt.Errorf("signature has length %d, expected %d", len(sig), SignatureLen)
+	}
+	return sig, nil
+}
+
+// Current returns the definition of a validator on chain, or
+// cnidarium.ErrNotFound if it has never been uploaded.
+func Current(ctx context.Context, state cnidarium.QueryService, ik *keysv1alpha1.IdentityKey) (*stakev1alpha1.Validator, error) {
+	return cnidarium.Get[*stakev1alpha1.Validator](ctx, state, cnidarium.DefaultSchema.Key("staking/validator/{identity_key}", keys.FormatIdentityKey(ik)))
+}
+
+// NextSequenceNumber sets the sequence number of a definition to follow the
+// one on chain, since pd only accepts definitions with a higher sequence
+// number than the current one. A higher sequence number already in the
+// definition is kept.
+func NextSequenceNumber(ctx context.Context, state cnidarium.QueryService, v *stakev1alpha1.Validator) error {
+	current, err := Current(ctx, state, v.GetIdentityKey())
+	if errors.Is(err, cnidarium.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not fetch current validator definition: %w", err)
+	}
+	if next := current.GetSequenceNumber() + 1; v.GetSequenceNumber() < next {
+		v.SequenceNumber = next
+	}
+	return nil
+}
+
+// DefinitionPlan returns the action plan uploading a signed definition.
+func DefinitionPlan(d *stakev1alpha1.ValidatorDefinition) *transactionv1alpha1.ActionPlan {
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_ValidatorDefinition{ValidatorDefinition: d},
+	}
+}
+
+// VotePlan returns the action plan casting a signed validator vote.
+func VotePlan(v *governancev1alpha1.ValidatorVote) *transactionv1alpha1.ActionPlan {
+	return &transactionv1alpha1.ActionPlan{
+		Action: &transactionv1alpha1.ActionPlan_ValidatorVote{ValidatorVote: v},
+	}
+}
+
+// Operator prepares the actions of a validator operator.
+type Operator struct {
+	State cnidarium.QueryService
+	// Identity signs definitions with the validator's identity key.
+	Identity Signer
+	// Governance signs votes with the validator's governance key. If nil,
+	// Identity is used, as the governance key defaults to the identity key.
+	Governance Signer
+}
+
+// Define checks a definition, advances its sequence number past the one on
+// chain, and signs it, returning the action plan uploading it.
+func (o *Operator) Define(ctx context.Context, f *File) (*transactionv1alpha1.ActionPlan, error) {
+	v, err := f.Proto()
+	if err != nil {
+		return nil, err
+	}
+	if err := NextSequenceNumber(ctx, o.State, v); err != nil {
+		return nil, err
+	}
+	d, err := SignDefinition(v, o.Identity)
+	if err != nil {
+		return nil, err
+	}
+	return DefinitionPlan(d), nil
+}
+
+// Vote signs a validator vote on a proposal, returning the action plan
+// casting it.
+func (o *Operator) Vote(ctx context.Context, proposalId uint64, vote governancev1alpha1.Vote_Vote, reason string) (*transactionv1alpha1.ActionPlan, error) {
+	v, err := Current(ctx, o.State, &keysv1alpha1.IdentityKey{Ik: o.Identity.VerificationKey()})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch validator definition: %w", err)
+	}
+	signer := o.Governance
+	if signer == nil {
+		signer = o.Identity
+	}
+	signed, err := SignVote(&governancev1alpha1.ValidatorVoteBody{
+		Proposal:      proposalId,
+		Vote:          &governancev1alpha1.Vote{Vote: vote},
+		IdentityKey:   v.GetIdentityKey(),
+		GovernanceKey: v.GetGovernanceKey(),
+		Reason:        &governancev1alpha1.ValidatorVoteReason{Reason: reason},
+	}, signer)
+	if err != nil {
+		return nil, err
+	}
+	return VotePlan(signed), nil
+}
@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/penumbra-zone/penumbra/proto/go/decaf377rdsa"
+	governancev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/governance/v1alpha1"
+	stakev1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/component/stake/v1alpha1"
+	keysv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/core/keys/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+func testSigner(t *testing.T, a int64) *decaf377rdsa.SigningKey {
+	t.Helper()
+	k, err := decaf377rdsa.NewSigningKey(big.NewInt(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSignDefinition(t *testing.T) {
+	k := testSigner(t, 42)
+	v := &stakev1alpha1.Validator{
+		IdentityKey:    &keysv1alpha1.IdentityKey{Ik: k.VerificationKey()},
+		GovernanceKey:  &keysv1alpha1.GovernanceKey{Gk: k.VerificationKey()},
+		Name:           "test",
+		SequenceNumber: 3,
+	}
+	d, err := SignDefinition(v, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := proto.Marshal(v)
+	if err := decaf377rdsa.Verify(k.VerificationKey(), msg, d.GetAuthSig()); err != nil {
+		t.Errorf("definition signature does not verify: %v", err)
+	}
+
+	if _, err := SignDefinition(v, testSigner(t, 43)); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("SignDefinition with another key = %v, want ErrKeyMismatch", err)
+	}
+}
+
+func TestSignVote(t *testing.T) {
+	k := testSigner(t, 42)
+	body := &governancev1alpha1.ValidatorVoteBody{
+		Proposal:      7,
+		Vote:          &governancev1alpha1.Vote{Vote: governancev1alpha1.Vote_VOTE_YES},
+		IdentityKey:   &keysv1alpha1.IdentityKey{Ik: k.VerificationKey()},
+		GovernanceKey: &keysv1alpha1.GovernanceKey{Gk: k.VerificationKey()},
+		Reason:        &governancev1alpha1.ValidatorVoteReason{Reason: "ok"},
+	}
+	vote, err := SignVote(body, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := proto.Marshal(body)
+	if err := decaf377rdsa.Verify(k.VerificationKey(), msg, vote.GetAuthSig().GetInner()); err != nil {
+		t.Errorf("vote signature does not verify: %v", err)
+	}
+
+	if _, err := SignVote(body, testSigner(t, 43)); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("SignVote with another key = %v, want ErrKeyMismatch", err)
+	}
+	body.Reason.Reason = strings.Repeat("x", MaxVoteReasonLength+1)
+	if _, err := SignVote(body, k); err == nil {
+		t.Errorf("SignVote accepted a reason of %d bytes", MaxVoteReasonLength+1)
+	}
+}
@@ -0,0 +1,119 @@
+// Package view provides a client for the ViewProtocolService, through which
+// a view server (such as pclientd) exposes a wallet's chain state and builds
+// its transactions.
+package view
+
+import (
+	"context"
+
+	viewv1alpha1 "github.com/penumbra-zone/penumbra/proto/go/gen/penumbra/view/v1alpha1"
+	"github.com/penumbra-zone/penumbra/proto/go/internal/grpcclient"
+	"google.golang.org/grpc"
+)
+
+// ServiceName is the full name of the ViewProtocolService.
+const ServiceName = "penumbra.view.v1alpha1.ViewProtocolService"
+
+// Service is the subset of the ViewProtocolService API used by this module.
+type Service interface {
+	Status(ctx context.Context, req *viewv1alpha1.StatusRequest) (*viewv1alpha1.StatusResponse, error)
+	Notes(ctx context.Context, req *viewv1alpha1.NotesRequest) (grpcclient.Stream[viewv1alpha1.NotesResponse], error)
+	NotesForVoting(ctx context.Context, req *viewv1alpha1.NotesForVotingRequest) (grpcclient.Stream[viewv1alpha1.NotesForVotingResponse], error)
+	WitnessAndBuild(ctx context.Context, req *viewv1alpha1.WitnessAndBuildRequest) (*viewv1alpha1.WitnessAndBuildResponse, error)
+	Assets(ctx context.Context, req *viewv1alpha1.AssetsRequest) (grpcclient.Stream[viewv1alpha1.AssetsResponse], error)
+	AppParameters(ctx context.Context, req *viewv1alpha1.AppParametersRequest) (*viewv1alpha1.AppParametersResponse, error)
+	GasPrices(ctx context.Context, req *viewv1alpha1.GasPricesRequest) (*viewv1alpha1.GasPricesResponse, error)
+	FMDParameters(ctx context.Context, req *viewv1alpha1.FMDParametersRequest) (*viewv1alpha1.FMDParametersResponse, error)
+	AddressByIndex(ctx context.Context, req *viewv1alpha1.AddressByIndexRequest) (*viewv1alpha1.AddressByIndexResponse, error)
+	Balances(ctx context.Context, req *viewv1alpha1.BalancesRequest) (grpcclient.Stream[viewv1alpha1.BalancesResponse], error)
+	NoteByCommitment(ctx context.Context, req *viewv1alpha1.NoteByCommitmentRequest) (*viewv1alpha1.NoteByCommitmentResponse, error)
+	SwapByCommitment(ctx context.Context, req *viewv1alpha1.SwapByCommitmentRequest) (*viewv1alpha1.SwapByCommitmentResponse, error)
+	UnclaimedSwaps(ctx context.Context, req *viewv1alpha1.UnclaimedSwapsRequest) (grpcclient.Stream[viewv1alpha1.UnclaimedSwapsResponse], error)
+	NullifierStatus(ctx context.Context, req *viewv1alpha1.NullifierStatusRequest) (*viewv1alpha1.NullifierStatusResponse, error)
+	TransactionPlanner(ctx context.Context, req *viewv1alpha1.TransactionPlannerRequest) (*viewv1alpha1.TransactionPlannerResponse, error)
+	BroadcastTransaction(ctx context.Context, req *viewv1alpha1.BroadcastTransactionRequest) (*viewv1alpha1.BroadcastTransactionResponse, error)
+	OwnedPositionIds(ctx context.Context, req *viewv1alpha1.OwnedPositionIdsRequest) (grpcclient.Stream[viewv1alpha1.OwnedPositionIdsResponse], error)
+	AuthorizeAndBuild(ctx context.Context, req *viewv1alpha1.AuthorizeAndBuildRequest) (*viewv1alpha1.AuthorizeAndBuildResponse, error)
+}
+
+type grpcService struct {
+	svc grpcclient.Service
+}
+
+// NewGRPCService returns a Service that calls a view server over a gRPC
+// connection, such as one returned by grpc.NewClient.
+func NewGRPCService(conn grpc.ClientConnInterface) Service {
+	return &grpcService{svc: grpcclient.Service{Conn: conn, Name: ServiceName}}
+}
+
+func (s *grpcService) Status(ctx context.Context, req *viewv1alpha1.StatusRequest) (*viewv1alpha1.StatusResponse, error) {
+	return grpcclient.Call[viewv1alpha1.StatusResponse](ctx, s.svc, "Status", req)
+}
+
+func (s *grpcService) Notes(ctx context.Context, req *viewv1alpha1.NotesRequest) (grpcclient.Stream[viewv1alpha1.NotesResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.NotesResponse](ctx, s.svc, "Notes", req)
+}
+
+func (s *grpcService) NotesForVoting(ctx context.Context, req *viewv1alpha1.NotesForVotingRequest) (grpcclient.Stream[viewv1alpha1.NotesForVotingResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.NotesForVotingResponse](ctx, s.svc, "NotesForVoting", req)
+}
+
+func (s *grpcService) WitnessAndBuild(ctx context.Context, req *viewv1alpha1.WitnessAndBuildRequest) (*viewv1alpha1.WitnessAndBuildResponse, error) {
+	return grpcclient.Call[viewv1alpha1.WitnessAndBuildResponse](ctx, s.svc, "WitnessAndBuild", req)
+}
+
+func (s *grpcService) Assets(ctx context.Context, req *viewv1alpha1.AssetsRequest) (grpcclient.Stream[viewv1alpha1.AssetsResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.AssetsResponse](ctx, s.svc, "Assets", req)
+}
+
+func (s *grpcService) AppParameters(ctx context.Context, req *viewv1alpha1.AppParametersRequest) (*viewv1alpha1.AppParametersResponse, error) {
+	return grpcclient.Call[viewv1alpha1.AppParametersResponse](ctx, s.svc, "AppParameters", req)
+}
+
+func (s *grpcService) GasPrices(ctx context.Context, req *viewv1alpha1.GasPricesRequest) (*viewv1alpha1.GasPricesResponse, error) {
+	return grpcclient.Call[viewv1alpha1.GasPricesResponse](ctx, s.svc, "GasPrices", req)
+}
+
+func (s *grpcService) FMDParameters(ctx context.Context, req *viewv1alpha1.FMDParametersRequest) (*viewv1alpha1.FMDParametersResponse, error) {
+	return grpcclient.Call[viewv1alpha1.FMDParametersResponse](ctx, s.svc, "FMDParameters", req)
+}
+
+func (s *grpcService) AddressByIndex(ctx context.Context, req *viewv1alpha1.AddressByIndexRequest) (*viewv1alpha1.AddressByIndexResponse, error) {
+	return grpcclient.Call[viewv1alpha1.AddressByIndexResponse](ctx, s.svc, "AddressByIndex", req)
+}
+
+func (s *grpcService) Balances(ctx context.Context, req *viewv1alpha1.BalancesRequest) (grpcclient.Stream[viewv1alpha1.BalancesResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.BalancesResponse](ctx, s.svc, "Balances", req)
+}
+
+func (s *grpcService) NoteByCommitment(ctx context.Context, req *viewv1alpha1.NoteByCommitmentRequest) (*viewv1alpha1.NoteByCommitmentResponse, error) {
+	return grpcclient.Call[viewv1alpha1.NoteByCommitmentResponse](ctx, s.svc, "NoteByCommitment", req)
+}
+
+func (s *grpcService) SwapByCommitment(ctx context.Context, req *viewv1alpha1.SwapByCommitmentRequest) (*viewv1alpha1.SwapByCommitmentResponse, error) {
+	return grpcclient.Call[viewv1alpha1.SwapByCommitmentResponse](ctx, s.svc, "SwapByCommitment", req)
+}
+
+func (s *grpcService) UnclaimedSwaps(ctx context.Context, req *viewv1alpha1.UnclaimedSwapsRequest) (grpcclient.Stream[viewv1alpha1.UnclaimedSwapsResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.UnclaimedSwapsResponse](ctx, s.svc, "UnclaimedSwaps", req)
+}
+
+func (s *grpcService) NullifierStatus(ctx context.Context, req *viewv1alpha1.NullifierStatusRequest) (*viewv1alpha1.NullifierStatusResponse, error) {
+	return grpcclient.Call[viewv1alpha1.NullifierStatusResponse](ctx, s.svc, "NullifierStatus", req)
+}
+
+func (s *grpcService) TransactionPlanner(ctx context.Context, req *viewv1alpha1.TransactionPlannerRequest) (*viewv1alpha1.TransactionPlannerResponse, error) {
+	return grpcclient.Call[viewv1alpha1.TransactionPlannerResponse](ctx, s.svc, "TransactionPlanner", req)
+}
+
+func (s *grpcService) BroadcastTransaction(ctx context.Context, req *viewv1alpha1.BroadcastTransactionRequest) (*viewv1alpha1.BroadcastTransactionResponse, error) {
+	return grpcclient.Call[viewv1alpha1.BroadcastTransactionResponse](ctx, s.svc, "BroadcastTransaction", req)
+}
+
+func (s *grpcService) OwnedPositionIds(ctx context.Context, req *viewv1alpha1.OwnedPositionIdsRequest) (grpcclient.Stream[viewv1alpha1.OwnedPositionIdsResponse], error) {
+	return grpcclient.OpenStream[viewv1alpha1.OwnedPositionIdsResponse](ctx, s.svc, "OwnedPositionIds", req)
+}
+
+func (s *grpcService) AuthorizeAndBuild(ctx context.Context, req *viewv1alpha1.AuthorizeAndBuildRequest) (*viewv1alpha1.AuthorizeAndBuildResponse, error) {
+	return grpcclient.Call[viewv1alpha1.AuthorizeAndBuildResponse](ctx, s.svc, "AuthorizeAndBuild", req)
+}